@@ -29,6 +29,8 @@ function App() {
   const handleLogout = () => {
     localStorage.removeItem('isAuthenticated');
     localStorage.removeItem('username');
+    localStorage.removeItem('token');
+    localStorage.removeItem('role');
     setIsAuthenticated(false);
     setIsMenuOpen(false);
   };
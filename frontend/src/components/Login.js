@@ -1,5 +1,6 @@
 import React, { useState } from 'react';
 import { useNavigate } from 'react-router-dom';
+import { authApi } from '../services/api';
 
 const Login = ({ onLogin }) => {
   const [credentials, setCredentials] = useState({
@@ -25,20 +26,19 @@ const Login = ({ onLogin }) => {
     setIsLoading(true);
     setError('');
 
-    // Имитация задержки для реалистичности
-    await new Promise(resolve => setTimeout(resolve, 1000));
-
-    // Проверка логина и пароля
-    if (credentials.username === 'user' && credentials.password === '123') {
+    try {
+      const response = await authApi.login(credentials.username, credentials.password);
       // Успешная авторизация
+      localStorage.setItem('token', response.data.token);
+      localStorage.setItem('role', response.data.role);
       localStorage.setItem('isAuthenticated', 'true');
       localStorage.setItem('username', credentials.username);
       onLogin(true);
       navigate('/');
-    } else {
-      setError('Неверный логин или пароль');
+    } catch (err) {
+      setError(err.response?.data?.error || 'Неверный логин или пароль');
     }
-    
+
     setIsLoading(false);
   };
 
@@ -68,7 +68,7 @@ const Login = ({ onLogin }) => {
         <form className="login-form" onSubmit={handleSubmit}>
           <div className="form-group">
             <label htmlFor="username" className="form-label">
-              Логин
+              Логин или ИИН
             </label>
             <input
               type="text"
@@ -77,7 +77,7 @@ const Login = ({ onLogin }) => {
               value={credentials.username}
               onChange={handleChange}
               className="form-input"
-              placeholder="Введите логин"
+              placeholder="Введите логин или ИИН"
               required
               disabled={isLoading}
             />
@@ -94,8 +94,7 @@ const Login = ({ onLogin }) => {
               value={credentials.password}
               onChange={handleChange}
               className="form-input"
-              placeholder="Введите пароль"
-              required
+              placeholder="Пароль (только для администратора)"
               disabled={isLoading}
             />
           </div>
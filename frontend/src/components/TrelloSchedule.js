@@ -1,5 +1,5 @@
   import React, { useState, useEffect } from 'react';
-import { groupsApi, teachersApi, subjectsApi, lessonsApi, timeSlotsApi } from '../services/api';
+import { groupsApi, teachersApi, subjectsApi, lessonsApi, timeSlotsApi, eventsApi, availabilityApi } from '../services/api';
 import LessonForm from './LessonForm';
 
 const TrelloSchedule = () => {
@@ -19,6 +19,8 @@ const TrelloSchedule = () => {
   const [removingLessons, setRemovingLessons] = useState(new Set());
   const [isCreatingLesson, setIsCreatingLesson] = useState(false);
   const [notification, setNotification] = useState(null);
+  const [liveUpdates, setLiveUpdates] = useState(0);
+  const [teacherAvailability, setTeacherAvailability] = useState(null);
 
   const daysOfWeek = [
     { value: 1, label: 'Понедельник' },
@@ -47,7 +49,43 @@ const TrelloSchedule = () => {
 
   useEffect(() => {
     fetchLessons();
-  }, [selectedDay, selectedShift]);
+  }, [selectedDay, selectedShift, liveUpdates]);
+
+  // Доступность преподавателя выбранного урока: недоступные пары закрашиваются
+  useEffect(() => {
+    setTeacherAvailability(null);
+    if (!selectedLesson) return;
+
+    let cancelled = false;
+    availabilityApi.get(selectedLesson.teacher_id)
+      .then((response) => {
+        if (!cancelled) setTeacherAvailability(response.data);
+      })
+      .catch(() => {});
+    return () => {
+      cancelled = true;
+    };
+  }, [selectedLesson, liveUpdates]);
+
+  // Живые обновления: перезагружаем уроки, когда расписание меняют другие пользователи
+  useEffect(() => {
+    const reloadLessons = () => setLiveUpdates((n) => n + 1);
+    const reloadTimeSlots = async () => {
+      try {
+        const response = await timeSlotsApi.getActive();
+        setTimeSlots(response.data || []);
+      } catch (err) {
+        console.error('Ошибка обновления пар:', err);
+      }
+    };
+
+    const listeners = {};
+    ['lesson.created', 'lesson.updated', 'lesson.moved', 'lesson.cancelled', 'lesson.deleted',
+      'lessons.generated', 'schedule.updated'].forEach((type) => { listeners[type] = reloadLessons; });
+    ['time_slot.created', 'time_slot.updated', 'time_slot.deleted'].forEach((type) => { listeners[type] = reloadTimeSlots; });
+
+    return eventsApi.subscribe({}, listeners);
+  }, []);
 
   const fetchData = async () => {
     try {
@@ -159,7 +197,8 @@ const TrelloSchedule = () => {
       
       
       // Создаем новый урок в расписании
-      await lessonsApi.create(newScheduledLesson);
+      const response = await lessonsApi.create(newScheduledLesson);
+      const warnings = response.data?.warnings || [];
       
       // Перезагружаем доступные уроки (оригинал должен остаться)
       const availableLessonsResponse = await lessonsApi.getAvailable();
@@ -170,7 +209,11 @@ const TrelloSchedule = () => {
 
       setSelectedLesson(null);
       setError(''); // Очищаем ошибки при успешном добавлении
-      showNotification('Урок успешно добавлен в расписание!', 'success');
+      if (warnings.length > 0) {
+        showNotification('Урок добавлен, но: ' + warnings.join('; '), 'warning');
+      } else {
+        showNotification('Урок успешно добавлен в расписание!', 'success');
+      }
     } catch (err) {
       setError('Ошибка обновления урока: ' + (err.response?.data?.error || err.message));
     }
@@ -367,6 +410,13 @@ const TrelloSchedule = () => {
     );
   };
 
+  // Состояние пары для преподавателя выбранного урока в выбранный день
+  const getSlotAvailability = (timeSlotId) => {
+    if (!teacherAvailability) return null;
+    const day = teacherAvailability.days.find(d => d.day_of_week === selectedDay);
+    return day ? day.slots.find(s => s.time_slot_id === timeSlotId) : null;
+  };
+
   const currentTimeSlots = getTimeSlotsByShift(selectedShift);
 
   if (loading) {
@@ -507,8 +557,11 @@ const TrelloSchedule = () => {
       <div className="schedule-board">
         {currentTimeSlots.map((timeSlot) => {
           const isDuplicate = selectedLesson && isLessonAlreadyInSlot(timeSlot.id, selectedLesson);
+          const availability = selectedLesson ? getSlotAvailability(timeSlot.id) : null;
+          const isUnavailable = availability?.status === 'unavailable';
+          const hasWarning = availability && ['busy', 'limit_reached', 'not_preferred'].includes(availability.status);
           return (
-            <div key={timeSlot.id} className={`time-slot-column ${isDuplicate ? 'duplicate-slot' : ''}`}>
+            <div key={timeSlot.id} className={`time-slot-column ${isDuplicate ? 'duplicate-slot' : ''} ${isUnavailable ? 'unavailable-slot' : ''}`}>
               <div className="time-slot-header">
                 <h3>{timeSlot.label}</h3>
                 <small style={{color: '#666'}}>
@@ -519,6 +572,16 @@ const TrelloSchedule = () => {
                     Уже добавлен!
                   </div>
                 )}
+                {isUnavailable && (
+                  <div style={{color: '#999', fontSize: '12px', fontWeight: 'bold'}}>
+                    Преподаватель недоступен{availability.reason ? `: ${availability.reason}` : ''}
+                  </div>
+                )}
+                {hasWarning && (
+                  <div style={{color: '#e6a23c', fontSize: '12px'}}>
+                    {availability.status === 'busy' ? 'У преподавателя уже есть занятие' : availability.reason}
+                  </div>
+                )}
               </div>
             
             <div className="lesson-list">
@@ -570,6 +633,8 @@ const TrelloSchedule = () => {
                 <button 
                   className="add-lesson-btn slot-selection"
                   onClick={() => handleSlotSelect(timeSlot.id)}
+                  disabled={isUnavailable}
+                  title={isUnavailable ? 'Преподаватель недоступен в это время' : undefined}
                 >
                   +
                 </button>
@@ -9,6 +9,15 @@ const api = axios.create({
   timeout: 10000, // 10 секунд таймаут
 });
 
+// Добавляем JWT токен к каждому запросу
+api.interceptors.request.use((config) => {
+  const token = localStorage.getItem('token');
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  return config;
+});
+
 // Интерцептор для обработки ошибок
 api.interceptors.response.use(
   (response) => response,
@@ -19,6 +28,12 @@ api.interceptors.response.use(
       error.message = 'Ошибка сети. Проверьте подключение к серверу';
     } else if (error.response?.status === 500) {
       error.message = 'Внутренняя ошибка сервера';
+    } else if (error.response?.status === 401) {
+      error.message = 'Требуется авторизация';
+      localStorage.removeItem('token');
+      localStorage.removeItem('isAuthenticated');
+    } else if (error.response?.status === 403) {
+      error.message = 'Недостаточно прав';
     } else if (error.response?.status === 404) {
       error.message = 'Ресурс не найден';
     }
@@ -26,6 +41,12 @@ api.interceptors.response.use(
   }
 );
 
+// Авторизация
+export const authApi = {
+  login: (login, password) => api.post('/auth/login', { login, password }),
+  me: () => api.get('/auth/me'),
+};
+
 // Группы
 export const groupsApi = {
   getAll: () => api.get('/groups'),
@@ -34,6 +55,14 @@ export const groupsApi = {
   delete: (id) => api.delete(`/groups/${id}`),
 };
 
+// Подгруппы
+export const subgroupsApi = {
+  getAll: (groupId) => api.get(`/groups/${groupId}/subgroups`),
+  create: (groupId, data) => api.post(`/groups/${groupId}/subgroups`, data),
+  update: (groupId, id, data) => api.put(`/groups/${groupId}/subgroups/${id}`, data),
+  delete: (groupId, id) => api.delete(`/groups/${groupId}/subgroups/${id}`),
+};
+
 // Предметы
 export const subjectsApi = {
   getAll: () => api.get('/subjects'),
@@ -60,6 +89,12 @@ export const teachersApi = {
   getSchedule: (iin) => api.get(`/teachers/${iin}/schedule`),
 };
 
+// Доступность преподавателей
+export const availabilityApi = {
+  get: (teacherId, params = {}) => api.get(`/availability/teachers/${teacherId}`, { params }),
+  update: (teacherId, data) => api.put(`/availability/teachers/${teacherId}`, data),
+};
+
 // Расписание
 export const schedulesApi = {
   getAll: () => api.get('/schedules'),
@@ -94,6 +129,49 @@ export const timeSlotsApi = {
   getActive: () => api.get('/time-slots', { params: { is_active: true } }),
 };
 
+// Поток событий: EventSource не умеет отправлять заголовки, поэтому подключаемся
+// по билету из POST /events/ticket. Билет действует минуту, поэтому после обрыва
+// берем новый и продолжаем с последнего полученного события.
+// listeners - обработчики по типам событий, возвращает функцию отписки
+export const eventsApi = {
+  subscribe: (params, listeners) => {
+    let source = null;
+    let retry = null;
+    let closed = false;
+    let lastEventId = '';
+
+    const reconnect = () => {
+      if (!closed) retry = setTimeout(connect, 3000);
+    };
+    const connect = async () => {
+      try {
+        const response = await api.post('/events/ticket');
+        if (closed) return;
+        const query = new URLSearchParams({ ...params, ticket: response.data.ticket });
+        if (lastEventId) query.set('last_event_id', lastEventId);
+        source = new EventSource(`${API_BASE_URL}/events/stream?${query}`);
+        Object.entries(listeners).forEach(([type, listener]) => source.addEventListener(type, (event) => {
+          lastEventId = event.lastEventId || lastEventId;
+          listener(event);
+        }));
+        source.onerror = () => {
+          source.close();
+          reconnect();
+        };
+      } catch (err) {
+        if (err.response?.status !== 401) reconnect();
+      }
+    };
+
+    connect();
+    return () => {
+      closed = true;
+      clearTimeout(retry);
+      if (source) source.close();
+    };
+  },
+};
+
 // Статистика
 export const statisticsApi = {
   getLessonStatistics: (params = {}) => api.get('/statistics/lessons', { params }),
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+
+	"innovativecollege/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleSystem автор изменений, сделанных не из HTTP-запроса (миграции, фоновые задачи)
+const RoleSystem = "system"
+
+// Actor пользователь, от имени которого выполняется изменение
+type Actor struct {
+	UserID string
+	Role   string
+	Login  string
+}
+
+type actorKey struct{}
+
+// WithActor возвращает контекст с автором изменений
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom возвращает автора изменений из контекста. Без автора - system
+func ActorFrom(ctx context.Context) Actor {
+	if actor, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return actor
+	}
+	return Actor{Role: RoleSystem, Login: RoleSystem}
+}
+
+// Middleware переносит данные токена в контекст запроса, чтобы репозитории
+// записали автора изменений. Подключается после auth.Middleware
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims := auth.GetClaims(c); claims != nil {
+			actor := Actor{UserID: claims.UserID, Role: claims.Role, Login: claims.Subject}
+			c.Request = c.Request.WithContext(WithActor(c.Request.Context(), actor))
+		}
+		c.Next()
+	}
+}
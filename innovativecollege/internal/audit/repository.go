@@ -0,0 +1,267 @@
+package audit
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ignoredFields поля, изменение которых само по себе не попадает в журнал
+var ignoredFields = map[string]bool{
+	"updated_at":       true,
+	"last_accessed_at": true, // Календарные приложения опрашивают ленту постоянно
+}
+
+// redactedFields поля, значения которых не сохраняются в журнале
+var redactedFields = map[string]bool{
+	"token":  true,
+	"secret": true,
+}
+
+// Wrap возвращает хранилище, в котором каждое изменение документов записывается
+// в store.AuditLog. Автор изменения берется из контекста (WithActor)
+func Wrap(store *storage.Store) *storage.Store {
+	auditLog := store.AuditLog
+	return &storage.Store{
+		Groups:                NewRepository(store.Groups, "groups", auditLog),
+		Subjects:              NewRepository(store.Subjects, "subjects", auditLog),
+		Students:              NewRepository(store.Students, "students", auditLog),
+		Teachers:              NewRepository(store.Teachers, "teachers", auditLog),
+		Schedules:             NewRepository(store.Schedules, "schedules", auditLog),
+		Lessons:               NewRepository(store.Lessons, "lessons", auditLog),
+		TimeSlots:             NewRepository(store.TimeSlots, "time_slots", auditLog),
+		Rooms:                 NewRepository(store.Rooms, "rooms", auditLog),
+		Terms:                 NewRepository(store.Terms, "terms", auditLog),
+		CalendarEvents:        NewRepository(store.CalendarEvents, "calendar_events", auditLog),
+		ScheduleDrafts:        NewRepository(store.ScheduleDrafts, "schedule_drafts", auditLog),
+		CalendarSubscriptions: NewRepository(store.CalendarSubscriptions, "calendar_subscriptions", auditLog),
+		TeacherAbsences:       NewRepository(store.TeacherAbsences, "teacher_absences", auditLog),
+		Substitutions:         NewRepository(store.Substitutions, "substitutions", auditLog),
+		Attendance:            NewRepository(store.Attendance, "attendance", auditLog),
+		Grades:                NewRepository(store.Grades, "grades", auditLog),
+		Webhooks:              NewRepository(store.Webhooks, "webhooks", auditLog),
+		WebhookDeliveries:     store.WebhookDeliveries, // Журнал доставок ведется отдельно
+		TelegramChats:         store.TelegramChats,     // Чаты привязывает бот, а не пользователи API
+		AuditLog:              store.AuditLog,
+	}
+}
+
+// repository пишет в журнал состояние документа до и после каждого изменения.
+// Чтение передается без изменений
+type repository[T any] struct {
+	storage.Repository[T]
+	collection string
+	log        storage.Repository[models.AuditEntry]
+}
+
+// NewRepository оборачивает репозиторий коллекции collection журналированием
+func NewRepository[T any](inner storage.Repository[T], collection string, auditLog storage.Repository[models.AuditEntry]) storage.Repository[T] {
+	return &repository[T]{Repository: inner, collection: collection, log: auditLog}
+}
+
+func (r *repository[T]) Insert(ctx context.Context, doc T) (primitive.ObjectID, error) {
+	id, err := r.Repository.Insert(ctx, doc)
+	if err != nil {
+		return id, err
+	}
+	r.record(ctx, models.AuditCreate, id, nil, snapshot(doc, id))
+	return id, nil
+}
+
+func (r *repository[T]) InsertMany(ctx context.Context, docs []T) ([]primitive.ObjectID, error) {
+	ids, err := r.Repository.InsertMany(ctx, docs)
+	if err != nil {
+		return ids, err
+	}
+	for i, id := range ids {
+		r.record(ctx, models.AuditCreate, id, nil, snapshot(docs[i], id))
+	}
+	return ids, nil
+}
+
+func (r *repository[T]) Update(ctx context.Context, filter, update bson.M) (storage.UpdateResult, error) {
+	before, err := r.Repository.FindOne(ctx, filter)
+	if err == storage.ErrNotFound {
+		return storage.UpdateResult{}, nil
+	}
+	if err != nil {
+		return storage.UpdateResult{}, err
+	}
+
+	// Обновляем именно тот документ, состояние которого сохранили
+	id := documentID(before)
+	result, err := r.Repository.Update(ctx, bson.M{"$and": bson.A{filter, bson.M{"_id": id}}}, update)
+	if err != nil || result.Modified == 0 {
+		return result, err
+	}
+
+	if after, err := r.Repository.FindByID(ctx, id); err == nil {
+		r.record(ctx, models.AuditUpdate, id, snapshot(*before, id), snapshot(*after, id))
+	}
+	return result, nil
+}
+
+func (r *repository[T]) UpdateMany(ctx context.Context, filter, update bson.M) (storage.UpdateResult, error) {
+	befores, err := r.Repository.Find(ctx, filter)
+	if err != nil || len(befores) == 0 {
+		return storage.UpdateResult{}, err
+	}
+
+	ids := make([]primitive.ObjectID, len(befores))
+	for i := range befores {
+		ids[i] = documentID(&befores[i])
+	}
+	result, err := r.Repository.UpdateMany(ctx, bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$in": ids}}}}, update)
+	if err != nil || result.Modified == 0 {
+		return result, err
+	}
+
+	afters, err := r.Repository.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		log.Printf("Журнал изменений %s: не удалось прочитать обновленные документы: %v", r.collection, err)
+		return result, nil
+	}
+	afterByID := make(map[primitive.ObjectID]*T, len(afters))
+	for i := range afters {
+		afterByID[documentID(&afters[i])] = &afters[i]
+	}
+	for i, id := range ids {
+		if after, ok := afterByID[id]; ok {
+			r.record(ctx, models.AuditUpdate, id, snapshot(befores[i], id), snapshot(*after, id))
+		}
+	}
+	return result, nil
+}
+
+func (r *repository[T]) Delete(ctx context.Context, filter bson.M) (int64, error) {
+	before, err := r.Repository.FindOne(ctx, filter)
+	if err == storage.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id := documentID(before)
+	deleted, err := r.Repository.Delete(ctx, bson.M{"$and": bson.A{filter, bson.M{"_id": id}}})
+	if err != nil || deleted == 0 {
+		return deleted, err
+	}
+	r.record(ctx, models.AuditDelete, id, snapshot(*before, id), nil)
+	return deleted, nil
+}
+
+func (r *repository[T]) DeleteMany(ctx context.Context, filter bson.M) (int64, error) {
+	befores, err := r.Repository.Find(ctx, filter)
+	if err != nil || len(befores) == 0 {
+		return 0, err
+	}
+
+	ids := make([]primitive.ObjectID, len(befores))
+	for i := range befores {
+		ids[i] = documentID(&befores[i])
+	}
+	deleted, err := r.Repository.DeleteMany(ctx, bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$in": ids}}}})
+	if err != nil {
+		return deleted, err
+	}
+	for i, id := range ids {
+		r.record(ctx, models.AuditDelete, id, snapshot(befores[i], id), nil)
+	}
+	return deleted, nil
+}
+
+// record сохраняет запись журнала. Ошибка записи журнала не отменяет изменение
+func (r *repository[T]) record(ctx context.Context, action string, id primitive.ObjectID, before, after bson.M) {
+	entry := models.AuditEntry{
+		Collection: r.collection,
+		EntityID:   id,
+		Action:     action,
+		Before:     before,
+		After:      after,
+		CreatedAt:  time.Now(),
+	}
+	if action == models.AuditUpdate {
+		entry.Changes = diff(before, after)
+		if len(entry.Changes) == 0 {
+			return
+		}
+		// Перенос в корзину и восстановление - это обновление поля deleted_at
+		_, wasDeleted := before["deleted_at"]
+		_, isDeleted := after["deleted_at"]
+		switch {
+		case !wasDeleted && isDeleted:
+			entry.Action = models.AuditDelete
+		case wasDeleted && !isDeleted:
+			entry.Action = models.AuditRestore
+		}
+	}
+
+	actor := ActorFrom(ctx)
+	entry.UserID = actor.UserID
+	entry.Role = actor.Role
+	entry.Login = actor.Login
+
+	// Запись журнала не должна зависеть от отмены запроса после изменения
+	if _, err := r.log.Insert(context.Background(), entry); err != nil {
+		log.Printf("Журнал изменений %s %s: %v", r.collection, id.Hex(), err)
+	}
+}
+
+// snapshot состояние документа в том виде, в каком он хранится в базе
+func snapshot[T any](doc T, id primitive.ObjectID) bson.M {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil
+	}
+	var result bson.M
+	if err := bson.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	result["_id"] = id
+	for field := range redactedFields {
+		if _, ok := result[field]; ok {
+			result[field] = "***"
+		}
+	}
+	return result
+}
+
+// diff список полей, которые отличаются в before и after
+func diff(before, after bson.M) []models.AuditChange {
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	var changes []models.AuditChange
+	for field := range fields {
+		if ignoredFields[field] || reflect.DeepEqual(before[field], after[field]) {
+			continue
+		}
+		changes = append(changes, models.AuditChange{Field: field, Before: before[field], After: after[field]})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// documentID читает _id документа
+func documentID[T any](doc *T) primitive.ObjectID {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	id, _ := bson.Raw(data).Lookup("_id").ObjectIDOK()
+	return id
+}
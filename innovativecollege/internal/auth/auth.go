@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Роли пользователей системы
+const (
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+	RoleStudent = "student"
+)
+
+// claimsKey ключ, под которым данные токена хранятся в gin.Context
+const claimsKey = "auth_claims"
+
+// Билет на поток событий: короткоживущий токен для EventSource, который
+// принимается только при подключении к /events/stream
+const (
+	ticketAudience = "events-stream"
+	TicketTTL      = time.Minute
+)
+
+// Claims данные, которые хранятся в JWT
+type Claims struct {
+	Role   string `json:"role"`
+	UserID string `json:"user_id,omitempty"` // ID преподавателя или студента
+	IIN    string `json:"iin,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Manager выпускает и проверяет JWT
+type Manager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewManager создает менеджер токенов
+func NewManager(secret string, ttl time.Duration) *Manager {
+	return &Manager{secret: []byte(secret), ttl: ttl}
+}
+
+// Issue выпускает подписанный токен для пользователя
+func (m *Manager) Issue(role, userID, iin, subject string) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+
+	claims := Claims{
+		Role:   role,
+		UserID: userID,
+		IIN:    iin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// IssueTicket выпускает билет на поток событий с ролью и пользователем из claims
+func (m *Manager) IssueTicket(claims *Claims) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(TicketTTL)
+
+	ticket := Claims{
+		Role:   claims.Role,
+		UserID: claims.UserID,
+		IIN:    claims.IIN,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   claims.Subject,
+			Audience:  jwt.ClaimStrings{ticketAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, ticket).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return token, expiresAt, nil
+}
+
+// IsTicket проверяет, что токен - билет на поток событий, а не сессия
+func (c *Claims) IsTicket() bool {
+	for _, audience := range c.Audience {
+		if audience == ticketAudience {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse проверяет подпись и срок действия токена
+func (m *Manager) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("недействительный токен")
+	}
+
+	return claims, nil
+}
+
+// Middleware требует валидный Bearer токен сессии и сохраняет его данные в контексте
+func Middleware(m *Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || tokenString == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Требуется авторизация"})
+			return
+		}
+		authorize(c, m, tokenString, false)
+	}
+}
+
+// StreamMiddleware авторизация потока событий. EventSource в браузере не умеет
+// отправлять заголовки, поэтому кроме Bearer токена принимается билет из
+// POST /events/ticket в параметре ticket. Токен сессии в URL не принимается
+func StreamMiddleware(m *Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" {
+			Middleware(m)(c)
+			return
+		}
+
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Требуется авторизация"})
+			return
+		}
+		authorize(c, m, ticket, true)
+	}
+}
+
+// authorize проверяет токен и сохраняет его данные в контексте. Билет на поток
+// событий не заменяет токен сессии, и наоборот
+func authorize(c *gin.Context, m *Manager, tokenString string, ticket bool) {
+	claims, err := m.Parse(tokenString)
+	if err != nil || claims.IsTicket() != ticket {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Недействительный или просроченный токен"})
+		return
+	}
+
+	c.Set(claimsKey, claims)
+	c.Next()
+}
+
+// RequireRoles пропускает только пользователей с одной из указанных ролей
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := GetClaims(c)
+		if claims == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Требуется авторизация"})
+			return
+		}
+
+		for _, role := range roles {
+			if claims.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Недостаточно прав"})
+	}
+}
+
+// GetClaims возвращает данные токена текущего запроса или nil
+func GetClaims(c *gin.Context) *Claims {
+	value, exists := c.Get(claimsKey)
+	if !exists {
+		return nil
+	}
+	claims, _ := value.(*Claims)
+	return claims
+}
+
+// IsAdmin проверяет, что текущий пользователь - администратор
+func IsAdmin(c *gin.Context) bool {
+	claims := GetClaims(c)
+	return claims != nil && claims.Role == RoleAdmin
+}
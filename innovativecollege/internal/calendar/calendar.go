@@ -0,0 +1,172 @@
+package calendar
+
+import (
+	"time"
+
+	"innovativecollege/internal/models"
+)
+
+// Причины, по которым день не является учебным
+const (
+	ReasonWeekend   = "weekend"
+	ReasonHoliday   = "holiday"
+	ReasonVacation  = "vacation"
+	ReasonOutOfTerm = "out_of_term"
+)
+
+// Calendar академический календарь: семестры, праздники, каникулы и переносы
+type Calendar struct {
+	Terms           []models.Term
+	Events          []models.CalendarEvent
+	WorkingWeekdays map[int]bool // Рабочие дни недели по умолчанию (1 - понедельник, 7 - воскресенье)
+}
+
+// Day описание одной даты календаря
+type Day struct {
+	Date        string `json:"date"`
+	DayOfWeek   int    `json:"day_of_week"`
+	Working     bool   `json:"working"`
+	TimetableOf int    `json:"timetable_of,omitempty"` // По расписанию какого дня недели идут занятия
+	Reason      string `json:"reason,omitempty"`       // Почему день нерабочий
+	EventName   string `json:"event_name,omitempty"`
+	WeekNumber  int    `json:"week_number,omitempty"` // Номер учебной недели в семестре
+}
+
+// Week учебная неделя семестра
+type Week struct {
+	Number      int    `json:"number"`
+	StartDate   string `json:"start_date"` // Понедельник
+	EndDate     string `json:"end_date"`   // Воскресенье
+	WorkingDays int    `json:"working_days"`
+}
+
+// New создает календарь с рабочими днями недели по умолчанию
+func New(terms []models.Term, events []models.CalendarEvent, workingWeekdays []int) *Calendar {
+	weekdays := make(map[int]bool, len(workingWeekdays))
+	for _, day := range workingWeekdays {
+		weekdays[day] = true
+	}
+	return &Calendar{Terms: terms, Events: events, WorkingWeekdays: weekdays}
+}
+
+// Describe возвращает описание даты: рабочий ли день и по какому дню недели идут занятия
+func (cal *Calendar) Describe(date time.Time) Day {
+	date = truncateDay(date)
+	day := Day{
+		Date:      date.Format("2006-01-02"),
+		DayOfWeek: models.DayOfWeek(date),
+	}
+
+	term := cal.TermFor(date)
+	if len(cal.Terms) > 0 && term == nil {
+		day.Reason = ReasonOutOfTerm
+		return day
+	}
+	if term != nil {
+		day.WeekNumber = WeekNumber(*term, date)
+	}
+
+	// Перенесенный рабочий день важнее выходного
+	for _, event := range cal.eventsOn(date) {
+		if event.Type == models.CalendarWorkingDay {
+			day.Working = true
+			day.EventName = event.Name
+			day.TimetableOf = day.DayOfWeek
+			if event.AsDayOfWeek != 0 {
+				day.TimetableOf = event.AsDayOfWeek
+			}
+			return day
+		}
+	}
+
+	for _, event := range cal.eventsOn(date) {
+		switch event.Type {
+		case models.CalendarHoliday:
+			day.Reason = ReasonHoliday
+			day.EventName = event.Name
+			return day
+		case models.CalendarVacation:
+			day.Reason = ReasonVacation
+			day.EventName = event.Name
+			return day
+		}
+	}
+
+	if !cal.WorkingWeekdays[day.DayOfWeek] {
+		day.Reason = ReasonWeekend
+		return day
+	}
+
+	day.Working = true
+	day.TimetableOf = day.DayOfWeek
+	return day
+}
+
+// IsWorkingDay проверяет, идут ли занятия в эту дату
+func (cal *Calendar) IsWorkingDay(date time.Time) bool {
+	return cal.Describe(date).Working
+}
+
+// TermFor возвращает семестр, в который попадает дата
+func (cal *Calendar) TermFor(date time.Time) *models.Term {
+	date = truncateDay(date)
+	for i := range cal.Terms {
+		if !date.Before(truncateDay(cal.Terms[i].StartDate)) && !date.After(truncateDay(cal.Terms[i].EndDate)) {
+			return &cal.Terms[i]
+		}
+	}
+	return nil
+}
+
+// Weeks возвращает учебные недели семестра с количеством рабочих дней
+func (cal *Calendar) Weeks(term models.Term) []Week {
+	var weeks []Week
+	start := weekStart(term.StartDate)
+	end := truncateDay(term.EndDate)
+
+	for monday, number := start, 1; !monday.After(end); monday, number = monday.AddDate(0, 0, 7), number+1 {
+		week := Week{
+			Number:    number,
+			StartDate: monday.Format("2006-01-02"),
+			EndDate:   monday.AddDate(0, 0, 6).Format("2006-01-02"),
+		}
+		for i := 0; i < 7; i++ {
+			date := monday.AddDate(0, 0, i)
+			if date.Before(truncateDay(term.StartDate)) || date.After(end) {
+				continue
+			}
+			if cal.IsWorkingDay(date) {
+				week.WorkingDays++
+			}
+		}
+		weeks = append(weeks, week)
+	}
+
+	return weeks
+}
+
+// WeekNumber номер учебной недели семестра, в которую попадает дата (с 1)
+func WeekNumber(term models.Term, date time.Time) int {
+	days := int(weekStart(date).Sub(weekStart(term.StartDate)).Hours() / 24)
+	return days/7 + 1
+}
+
+func (cal *Calendar) eventsOn(date time.Time) []models.CalendarEvent {
+	var events []models.CalendarEvent
+	for _, event := range cal.Events {
+		if !date.Before(truncateDay(event.StartDate)) && !date.After(truncateDay(event.EndDate)) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// weekStart возвращает понедельник недели, в которую попадает дата
+func weekStart(date time.Time) time.Time {
+	date = truncateDay(date)
+	return date.AddDate(0, 0, 1-models.DayOfWeek(date))
+}
+
+func truncateDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -2,19 +2,76 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// DefaultJWTSecret заглушка секрета из примеров. Сервер с ней не запускается:
+// любой, кто знает это значение, может подписать себе токен администратора
+const DefaultJWTSecret = "change-me-in-production"
+
 type Config struct {
-	MongoURI     string
-	DatabaseName string
-	Port         string
+	MongoURI      string
+	DatabaseName  string
+	Port          string
+	JWTSecret     string
+	JWTTTLHours   int
+	AdminLogin    string
+	AdminPassword string
+
+	WorkingWeekdays []int  // Учебные дни недели: 1 - понедельник, 7 - воскресенье
+	Timezone        string // Часовой пояс колледжа для .ics-лент
+	PDFFontPath     string // TTF-шрифт для печати расписания (по умолчанию встроенный DejaVu Sans)
+	PDFBoldFontPath string
+
+	TrashRetentionDays int           // Сколько дней удаленные документы хранятся в корзине
+	TrashPurgeInterval time.Duration // Как часто запускается очистка корзины
+
+	AttendanceAlertPercent int // Доля пропусков по предмету (в процентах), после которой студент попадает в список предупреждений
+	GradeMin               int // Шкала оценок журнала
+	GradeMax               int
+
+	WebhookMaxAttempts  int           // Сколько раз пытаться доставить событие
+	WebhookRetryDelay   time.Duration // Пауза перед второй попыткой, дальше удваивается
+	WebhookTimeout      time.Duration // Сколько ждать ответа получателя
+	WebhookPollInterval time.Duration // Как часто проверяются доставки, которые пора повторить
+
+	TelegramBotToken    string        // Токен бота от @BotFather. Пустой - бот отключен
+	TelegramAPIURL      string        // Адрес Bot API, например локальный сервер для проверки
+	TelegramPollTimeout time.Duration // Длительность long polling getUpdates
 }
 
 func Load() *Config {
 	return &Config{
-		MongoURI:     getEnv("MONGODB_URI", "mongodb://localhost:27017"),
-		DatabaseName: getEnv("DATABASE_NAME", "innovativecollege"),
-		Port:         getEnv("PORT", "9090"),
+		MongoURI:      getEnv("MONGODB_URI", "mongodb://localhost:27017"),
+		DatabaseName:  getEnv("DATABASE_NAME", "innovativecollege"),
+		Port:          getEnv("PORT", "9090"),
+		JWTSecret:     getEnv("JWT_SECRET", DefaultJWTSecret),
+		JWTTTLHours:   getEnvInt("JWT_TTL_HOURS", 24),
+		AdminLogin:    getEnv("ADMIN_LOGIN", "admin"),
+		AdminPassword: getEnv("ADMIN_PASSWORD", ""),
+
+		WorkingWeekdays: getEnvIntList("WORKING_WEEKDAYS", []int{1, 2, 3, 4, 5}),
+		Timezone:        getEnv("TIMEZONE", "Asia/Almaty"),
+		PDFFontPath:     getEnv("PDF_FONT_PATH", ""),
+		PDFBoldFontPath: getEnv("PDF_BOLD_FONT_PATH", ""),
+
+		TrashRetentionDays: getEnvInt("TRASH_RETENTION_DAYS", 30),
+		TrashPurgeInterval: time.Duration(getEnvInt("TRASH_PURGE_INTERVAL_HOURS", 24)) * time.Hour,
+
+		AttendanceAlertPercent: getEnvInt("ATTENDANCE_ALERT_PERCENT", 25),
+		GradeMin:               getEnvInt("GRADE_MIN", 1),
+		GradeMax:               getEnvInt("GRADE_MAX", 5),
+
+		WebhookMaxAttempts:  getEnvInt("WEBHOOK_MAX_ATTEMPTS", 6),
+		WebhookRetryDelay:   time.Duration(getEnvInt("WEBHOOK_RETRY_SECONDS", 30)) * time.Second,
+		WebhookTimeout:      time.Duration(getEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+		WebhookPollInterval: time.Duration(getEnvInt("WEBHOOK_POLL_SECONDS", 5)) * time.Second,
+
+		TelegramBotToken:    getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramAPIURL:      getEnv("TELEGRAM_API_URL", "https://api.telegram.org"),
+		TelegramPollTimeout: time.Duration(getEnvInt("TELEGRAM_POLL_SECONDS", 30)) * time.Second,
 	}
 }
 
@@ -25,3 +82,28 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvIntList(key string, defaultValue []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []int
+	for _, part := range strings.Split(value, ",") {
+		parsed, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return defaultValue
+		}
+		result = append(result, parsed)
+	}
+	return result
+}
@@ -42,7 +42,7 @@ func Close(client *mongo.Client) {
 }
 
 func CreateCollections(db *mongo.Database) {
-	collections := []string{"groups", "students", "teachers", "schedules", "subjects", "lessons", "time_slots"}
+	collections := []string{"groups", "students", "teachers", "schedules", "subjects", "lessons", "time_slots", "rooms", "terms", "calendar_events", "schedule_drafts", "calendar_subscriptions", "teacher_absences", "substitutions", "attendance", "grades", "webhooks", "webhook_deliveries", "telegram_chats", "audit_log"}
 
 	for _, collectionName := range collections {
 		// Создаем коллекцию если она не существует
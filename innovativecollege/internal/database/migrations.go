@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RoomMigrationReport результат переноса текстовых аудиторий в коллекцию rooms
+type RoomMigrationReport struct {
+	CreatedRooms     []string `json:"created_rooms"`
+	UpdatedSchedules int64    `json:"updated_schedules"`
+	UpdatedLessons   int64    `json:"updated_lessons"`
+	Skipped          []string `json:"skipped"` // Значения, которые не удалось распознать как номер
+}
+
+// MigrateRooms сопоставляет строковые значения room в schedules и lessons с документами rooms.
+// Недостающие аудитории создаются с типом lecture. Повторный запуск ничего не меняет
+func MigrateRooms(ctx context.Context, store *storage.Store) (*RoomMigrationReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	report := &RoomMigrationReport{CreatedRooms: []string{}, Skipped: []string{}}
+	roomIDs := make(map[string]primitive.ObjectID)
+	withoutRoomID := bson.M{"room_id": bson.M{"$exists": false}, "room": bson.M{"$nin": bson.A{nil, ""}}}
+
+	schedules, err := store.Schedules.Find(ctx, withoutRoomID)
+	if err != nil {
+		return nil, err
+	}
+	scheduleRooms := make([]string, len(schedules))
+	for i, schedule := range schedules {
+		scheduleRooms[i] = schedule.Room
+	}
+	report.UpdatedSchedules, err = migrateRoomValues(ctx, store, store.Schedules, scheduleRooms, roomIDs, report)
+	if err != nil {
+		return nil, err
+	}
+
+	lessons, err := store.Lessons.Find(ctx, withoutRoomID)
+	if err != nil {
+		return nil, err
+	}
+	lessonRooms := make([]string, len(lessons))
+	for i, lesson := range lessons {
+		lessonRooms[i] = lesson.Room
+	}
+	report.UpdatedLessons, err = migrateRoomValues(ctx, store, store.Lessons, lessonRooms, roomIDs, report)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Миграция аудиторий: создано %d, обновлено расписаний %d, уроков %d",
+		len(report.CreatedRooms), report.UpdatedSchedules, report.UpdatedLessons)
+	return report, nil
+}
+
+// migrateRoomValues проставляет room_id документам коллекции для каждого различного значения room
+// и возвращает число обновленных документов
+func migrateRoomValues[T any](ctx context.Context, store *storage.Store, repo storage.Repository[T], values []string,
+	roomIDs map[string]primitive.ObjectID, report *RoomMigrationReport) (int64, error) {
+	var updated int64
+	seen := make(map[string]bool)
+	for _, raw := range values {
+		if seen[raw] {
+			continue
+		}
+		seen[raw] = true
+
+		number := models.NormalizeRoomNumber(raw)
+		if number == "" {
+			report.Skipped = append(report.Skipped, raw)
+			continue
+		}
+
+		roomID, ok := roomIDs[number]
+		if !ok {
+			var err error
+			roomID, err = findOrCreateRoom(ctx, store, number, report)
+			if err != nil {
+				return updated, err
+			}
+			roomIDs[number] = roomID
+		}
+
+		result, err := repo.UpdateMany(ctx,
+			bson.M{"room": raw, "room_id": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"room_id": roomID, "room": number}})
+		if err != nil {
+			return updated, err
+		}
+		updated += result.Modified
+	}
+	return updated, nil
+}
+
+func findOrCreateRoom(ctx context.Context, store *storage.Store, number string, report *RoomMigrationReport) (primitive.ObjectID, error) {
+	room, err := store.Rooms.FindOne(ctx, bson.M{"number": number})
+	if err == nil {
+		return room.ID, nil
+	}
+	if err != storage.ErrNotFound {
+		return primitive.NilObjectID, err
+	}
+
+	id, err := store.Rooms.Insert(ctx, models.Room{
+		Number:    number,
+		Type:      models.RoomTypeLecture,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	report.CreatedRooms = append(report.CreatedRooms, number)
+	return id, nil
+}
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// historySize сколько последних событий хранится для переподключения по Last-Event-ID
+const historySize = 256
+
+// Event изменение расписания. GroupIDs, TeacherIDs и период From-To описывают,
+// кого и какие даты касается событие: пустое значение - событие касается всех
+type Event struct {
+	ID         uint64               `json:"id"` // Возрастает в пределах запуска сервера
+	Type       string               `json:"type"`
+	OccurredAt time.Time            `json:"occurred_at"`
+	Data       interface{}          `json:"data"`
+	GroupIDs   []primitive.ObjectID `json:"-"`
+	TeacherIDs []primitive.ObjectID `json:"-"`
+	From       time.Time            `json:"-"` // Первая дата уроков события
+	To         time.Time            `json:"-"` // Последняя дата уроков события
+}
+
+// Handler получает события синхронно в момент публикации
+type Handler func(ctx context.Context, event Event)
+
+// Filter условия подписки на поток. Нулевые поля не ограничивают поток
+type Filter struct {
+	GroupID   primitive.ObjectID
+	TeacherID primitive.ObjectID
+	From      time.Time
+	To        time.Time
+	Types     map[string]bool
+}
+
+// Match проверяет, что событие проходит фильтр. Событие без групп, преподавателей
+// или дат (например, изменение пары) проходит соответствующее условие
+func (f Filter) Match(event Event) bool {
+	if len(f.Types) > 0 && !f.Types[event.Type] {
+		return false
+	}
+	if !f.GroupID.IsZero() && len(event.GroupIDs) > 0 && !containsID(event.GroupIDs, f.GroupID) {
+		return false
+	}
+	if !f.TeacherID.IsZero() && len(event.TeacherIDs) > 0 && !containsID(event.TeacherIDs, f.TeacherID) {
+		return false
+	}
+	if !event.From.IsZero() {
+		if !f.To.IsZero() && event.From.After(f.To) {
+			return false
+		}
+		if !f.From.IsZero() && event.To.Before(f.From) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscription подписка на поток событий
+type Subscription struct {
+	C      <-chan Event // Закрывается, если подписчик не успевает читать события
+	events chan Event
+	filter Filter
+}
+
+// Bus шина событий: обработчики изменений публикуют в нее события,
+// а вебхуки и потоки для клиентов получают их
+type Bus struct {
+	mu            sync.Mutex
+	seq           uint64
+	history       []Event
+	handlers      []Handler
+	subscriptions map[*Subscription]bool
+}
+
+// NewBus создает пустую шину
+func NewBus() *Bus {
+	return &Bus{subscriptions: make(map[*Subscription]bool)}
+}
+
+// Handle добавляет обработчик, который получает все события
+func (b *Bus) Handle(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish присваивает событию номер и время и передает его обработчикам и подписчикам
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	b.mu.Lock()
+	b.seq++
+	event.ID = b.seq
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	b.history = append(b.history, event)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+	handlers := append([]Handler(nil), b.handlers...)
+	for subscription := range b.subscriptions {
+		b.send(subscription, event)
+	}
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(ctx, event)
+	}
+}
+
+// Subscribe подписывается на события по фильтру. Если lastID не ноль, подписчик
+// сначала получает пропущенные события с большими номерами, которые еще хранятся
+func (b *Bus) Subscribe(filter Filter, lastID uint64, buffer int) *Subscription {
+	if buffer < historySize {
+		buffer = historySize
+	}
+	events := make(chan Event, buffer)
+	subscription := &Subscription{C: events, events: events, filter: filter}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if lastID > 0 {
+		for _, event := range b.history {
+			if event.ID > lastID {
+				b.send(subscription, event)
+			}
+		}
+	}
+	b.subscriptions[subscription] = true
+	return subscription
+}
+
+// Unsubscribe отменяет подписку
+func (b *Bus) Unsubscribe(subscription *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscriptions[subscription] {
+		delete(b.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+// send передает событие подписчику, не блокируя шину. Подписчик, который не
+// успевает читать, отключается: клиент переподключится с Last-Event-ID
+func (b *Bus) send(subscription *Subscription, event Event) {
+	if !subscription.filter.Match(event) {
+		return
+	}
+	select {
+	case subscription.events <- event:
+	default:
+		if b.subscriptions[subscription] {
+			delete(b.subscriptions, subscription)
+			close(subscription.events)
+		}
+	}
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
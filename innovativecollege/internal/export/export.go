@@ -0,0 +1,210 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Форматы выгрузки
+const (
+	FormatXLSX = "xlsx"
+	FormatCSV  = "csv"
+)
+
+// Ширина столбцов XLSX в символах
+const (
+	minColumnWidth = 8
+	maxColumnWidth = 40
+)
+
+// csvDelimiter русскоязычный Excel открывает CSV с разделителем ";"
+const csvDelimiter = ';'
+
+// Sheet лист выгрузки. Первая строка Rows - заголовок таблицы
+type Sheet struct {
+	Name   string
+	Title  string     // Необязательная строка над таблицей
+	Rows   [][]string // Строки таблицы, первая - заголовок
+	Merges []Merge    // Объединенные ячейки (координаты в Rows)
+}
+
+// Merge объединение ячеек таблицы, строки и столбцы с нуля
+type Merge struct {
+	FromRow, FromCol int
+	ToRow, ToCol     int
+}
+
+// ContentType MIME-тип файла выгрузки
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+// Write записывает листы в выбранном формате
+func Write(w io.Writer, format string, sheets []Sheet) error {
+	if format == FormatCSV {
+		return WriteCSV(w, sheets)
+	}
+	return WriteXLSX(w, sheets)
+}
+
+// WriteXLSX записывает книгу Excel: каждый Sheet на отдельном листе, заголовок таблицы
+// жирным, ширина столбцов по содержимому
+func WriteXLSX(w io.Writer, sheets []Sheet) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	headerStyle, err := file.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true},
+		Fill:      excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#DDEBF7"}},
+		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center", WrapText: true},
+		Border:    borders(),
+	})
+	if err != nil {
+		return err
+	}
+	cellStyle, err := file.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{Vertical: "center", WrapText: true},
+		Border:    borders(),
+	})
+	if err != nil {
+		return err
+	}
+	titleStyle, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		return err
+	}
+
+	for i, sheet := range sheets {
+		name := sheetName(sheet.Name, i)
+		if i == 0 {
+			if err := file.SetSheetName("Sheet1", name); err != nil {
+				return err
+			}
+		} else if _, err := file.NewSheet(name); err != nil {
+			return err
+		}
+
+		// Таблица начинается ниже строки заголовка листа
+		offset := 0
+		if sheet.Title != "" {
+			if err := file.SetCellStr(name, "A1", sheet.Title); err != nil {
+				return err
+			}
+			if err := file.SetCellStyle(name, "A1", "A1", titleStyle); err != nil {
+				return err
+			}
+			offset = 2
+		}
+
+		widths := make(map[int]int)
+		for r, row := range sheet.Rows {
+			for col, value := range row {
+				cell, _ := excelize.CoordinatesToCellName(col+1, r+offset+1)
+				if err := file.SetCellStr(name, cell, value); err != nil {
+					return err
+				}
+				style := cellStyle
+				if r == 0 {
+					style = headerStyle
+				}
+				if err := file.SetCellStyle(name, cell, cell, style); err != nil {
+					return err
+				}
+				widths[col] = max(widths[col], longestLine(value))
+			}
+		}
+
+		for _, merge := range sheet.Merges {
+			from, _ := excelize.CoordinatesToCellName(merge.FromCol+1, merge.FromRow+offset+1)
+			to, _ := excelize.CoordinatesToCellName(merge.ToCol+1, merge.ToRow+offset+1)
+			if err := file.MergeCell(name, from, to); err != nil {
+				return err
+			}
+		}
+
+		columns := make([]int, 0, len(widths))
+		for col := range widths {
+			columns = append(columns, col)
+		}
+		sort.Ints(columns)
+		for _, col := range columns {
+			letter, _ := excelize.ColumnNumberToName(col + 1)
+			width := float64(min(max(widths[col]+2, minColumnWidth), maxColumnWidth))
+			if err := file.SetColWidth(name, letter, letter, width); err != nil {
+				return err
+			}
+		}
+	}
+
+	return file.Write(w)
+}
+
+// WriteCSV записывает листы в один CSV: таблицы разделяются пустой строкой.
+// Файл начинается с BOM, чтобы Excel распознал UTF-8
+func WriteCSV(w io.Writer, sheets []Sheet) error {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = csvDelimiter
+	for i, sheet := range sheets {
+		if i > 0 {
+			if err := writer.Write([]string{}); err != nil {
+				return err
+			}
+		}
+		if sheet.Title != "" {
+			if err := writer.Write([]string{sheet.Title}); err != nil {
+				return err
+			}
+		}
+		if err := writer.WriteAll(sheet.Rows); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func borders() []excelize.Border {
+	var result []excelize.Border
+	for _, side := range []string{"left", "right", "top", "bottom"} {
+		result = append(result, excelize.Border{Type: side, Color: "#999999", Style: 1})
+	}
+	return result
+}
+
+// sheetName имя листа Excel: не длиннее 31 символа и не пустое
+func sheetName(name string, index int) string {
+	if name == "" {
+		name = "Лист" + strconv.Itoa(index+1)
+	}
+	if utf8.RuneCountInString(name) > 31 {
+		name = string([]rune(name)[:31])
+	}
+	return name
+}
+
+// longestLine длина самой длинной строки многострочного значения
+func longestLine(value string) int {
+	longest, current := 0, 0
+	for _, r := range value {
+		if r == '\n' {
+			current = 0
+			continue
+		}
+		current++
+		longest = max(longest, current)
+	}
+	return longest
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"sort"
+	"strings"
+
+	"innovativecollege/internal/models"
+)
+
+// DayNames названия дней недели (индекс - номер дня, 1 - понедельник)
+var DayNames = []string{"", "Понедельник", "Вторник", "Среда", "Четверг", "Пятница", "Суббота", "Воскресенье"}
+
+// MatrixCell одна пара в матрице расписания
+type MatrixCell struct {
+	Group     string
+	DayOfWeek int
+	StartTime string
+	EndTime   string
+	Text      string // Например "ОН 3.1 / Караев / 201"
+}
+
+// ScheduleMatrix раскладывает расписание в бумажный вид колледжа: группы по столбцам,
+// дни и пары по строкам. Время пары пишется как "08:00-09:20", название дня - в первой
+// строке дня (строки дня объединяются). Формат совпадает с форматом импорта
+func ScheduleMatrix(name string, cells []MatrixCell) Sheet {
+	groupSet := make(map[string]bool)
+	times := make(map[int]map[string]bool)
+	texts := make(map[string][]string)
+	for _, cell := range cells {
+		groupSet[cell.Group] = true
+		slot := cell.StartTime + "-" + cell.EndTime
+		if times[cell.DayOfWeek] == nil {
+			times[cell.DayOfWeek] = make(map[string]bool)
+		}
+		times[cell.DayOfWeek][slot] = true
+		key := matrixKey(cell.Group, cell.DayOfWeek, slot)
+		texts[key] = append(texts[key], cell.Text)
+	}
+
+	groups := make([]string, 0, len(groupSet))
+	for group := range groupSet {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	sheet := Sheet{Name: name, Rows: [][]string{append([]string{"День", "Пара"}, groups...)}}
+	for day := 1; day < len(DayNames); day++ {
+		slots := make([]string, 0, len(times[day]))
+		for slot := range times[day] {
+			slots = append(slots, slot)
+		}
+		if len(slots) == 0 {
+			continue
+		}
+		sort.Slice(slots, func(i, j int) bool {
+			a, _ := models.ParseClock(strings.Split(slots[i], "-")[0])
+			b, _ := models.ParseClock(strings.Split(slots[j], "-")[0])
+			return a < b
+		})
+
+		firstRow := len(sheet.Rows)
+		for i, slot := range slots {
+			row := []string{"", slot}
+			if i == 0 {
+				row[0] = DayNames[day]
+			}
+			for _, group := range groups {
+				row = append(row, strings.Join(texts[matrixKey(group, day, slot)], "\n"))
+			}
+			sheet.Rows = append(sheet.Rows, row)
+		}
+		if len(slots) > 1 {
+			sheet.Merges = append(sheet.Merges, Merge{FromRow: firstRow, ToRow: len(sheet.Rows) - 1})
+		}
+	}
+
+	return sheet
+}
+
+func matrixKey(group string, day int, slot string) string {
+	return group + "|" + string(rune('0'+day)) + "|" + slot
+}
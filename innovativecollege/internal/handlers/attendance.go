@@ -0,0 +1,508 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"innovativecollege/internal/audit"
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// attendanceCounts количество отметок по видам и доля посещенных уроков
+type attendanceCounts struct {
+	Present int     `json:"present"`
+	Absent  int     `json:"absent"`
+	Late    int     `json:"late"`
+	Excused int     `json:"excused"`
+	Total   int     `json:"total"`
+	Rate    float64 `json:"rate"` // Процент уроков, на которых студент был (present и late)
+}
+
+func (a *attendanceCounts) add(status string) {
+	switch status {
+	case models.AttendancePresent:
+		a.Present++
+	case models.AttendanceAbsent:
+		a.Absent++
+	case models.AttendanceLate:
+		a.Late++
+	case models.AttendanceExcused:
+		a.Excused++
+	}
+	a.Total++
+	a.Rate = percent(a.Present+a.Late, a.Total)
+}
+
+// attendanceRow строка ведомости урока: студент и его отметка (пусто - не отмечен)
+type attendanceRow struct {
+	Student  models.Student `json:"student"`
+	Status   string         `json:"status,omitempty"`
+	Comment  string         `json:"comment,omitempty"`
+	MarkedBy string         `json:"marked_by,omitempty"`
+}
+
+// subjectAttendance посещаемость по одному предмету
+type subjectAttendance struct {
+	SubjectID primitive.ObjectID `json:"subject_id"`
+	Name      string             `json:"name"`
+	attendanceCounts
+}
+
+// studentAttendance посещаемость одного студента группы
+type studentAttendance struct {
+	Student models.Student `json:"student"`
+	attendanceCounts
+}
+
+// attendanceAlert студент, пропустивший больше допустимой доли уроков предмета
+type attendanceAlert struct {
+	Student       models.Student `json:"student"`
+	Subject       models.Subject `json:"subject"`
+	Total         int            `json:"total"`
+	Missed        int            `json:"missed"`
+	MissedPercent float64        `json:"missed_percent"`
+}
+
+// ========== ПОСЕЩАЕМОСТЬ ==========
+
+// MarkAttendance отмечает посещаемость урока. Можно передать отметки отдельных студентов
+// (marks) и default_status для остальных студентов группы, например "present" для всех,
+// кроме отсутствующих. Повторная отметка заменяет прежнюю
+func (h *Handlers) MarkAttendance(c *gin.Context) {
+	lesson, ok := h.teacherLesson(c, "Посещаемость ведет преподаватель урока")
+	if !ok {
+		return
+	}
+
+	var req models.MarkAttendanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DefaultStatus == "" && len(req.Marks) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите отметки (marks) или default_status"})
+		return
+	}
+
+	if !markableLesson(c, lesson) {
+		return
+	}
+
+	studentFilter, err := h.lessonStudentFilter(c.Request.Context(), lesson)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов группы"})
+		return
+	}
+	students, err := h.store.Students.Find(c.Request.Context(), studentFilter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов группы"})
+		return
+	}
+	inGroup := make(map[primitive.ObjectID]bool, len(students))
+	marks := make(map[primitive.ObjectID]models.AttendanceMark)
+	for _, student := range students {
+		inGroup[student.ID] = true
+		if req.DefaultStatus != "" {
+			marks[student.ID] = models.AttendanceMark{Status: req.DefaultStatus}
+		}
+	}
+	for _, mark := range req.Marks {
+		studentID, err := primitive.ObjectIDFromHex(mark.StudentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID студента: " + mark.StudentID})
+			return
+		}
+		if !inGroup[studentID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Студент не учится в группе урока: " + mark.StudentID})
+			return
+		}
+		marks[studentID] = mark
+	}
+
+	existing, err := h.store.Attendance.Find(c.Request.Context(), bson.M{"lesson_id": lesson.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения посещаемости"})
+		return
+	}
+	byStudent := make(map[primitive.ObjectID]models.Attendance, len(existing))
+	for _, record := range existing {
+		byStudent[record.StudentID] = record
+	}
+
+	markedBy := audit.ActorFrom(c.Request.Context()).Login
+	now := time.Now()
+	var created []models.Attendance
+	for studentID, mark := range marks {
+		record, found := byStudent[studentID]
+		if !found {
+			created = append(created, models.Attendance{
+				LessonID:  lesson.ID,
+				StudentID: studentID,
+				GroupID:   lesson.GroupID,
+				SubjectID: lesson.SubjectID,
+				Date:      truncateToDay(*lesson.Date),
+				Status:    mark.Status,
+				Comment:   mark.Comment,
+				MarkedBy:  markedBy,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+			continue
+		}
+		if record.Status == mark.Status && record.Comment == mark.Comment {
+			continue
+		}
+		_, err := h.store.Attendance.Update(c.Request.Context(), bson.M{"_id": record.ID}, bson.M{"$set": bson.M{
+			"status":     mark.Status,
+			"comment":    mark.Comment,
+			"marked_by":  markedBy,
+			"updated_at": now,
+		}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения посещаемости"})
+			return
+		}
+	}
+	if len(created) > 0 {
+		if _, err := h.store.Attendance.InsertMany(c.Request.Context(), created); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения посещаемости"})
+			return
+		}
+	}
+
+	h.respondLessonAttendance(c, lesson)
+}
+
+// GetLessonAttendance получает ведомость урока: все студенты группы и их отметки
+func (h *Handlers) GetLessonAttendance(c *gin.Context) {
+	lesson, ok := h.teacherLesson(c, "Посещаемость ведет преподаватель урока")
+	if !ok {
+		return
+	}
+
+	h.respondLessonAttendance(c, lesson)
+}
+
+// GetStudentAttendance отчет о посещаемости студента за период (start_date, end_date,
+// по умолчанию текущий семестр) с разбивкой по предметам. Студент видит только свой отчет
+func (h *Handlers) GetStudentAttendance(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID студента"})
+		return
+	}
+	if claims := auth.GetClaims(c); claims != nil && claims.Role == auth.RoleStudent && claims.UserID != id.Hex() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Можно смотреть только свою посещаемость"})
+		return
+	}
+
+	student, err := h.store.Students.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Студент не найден"})
+		return
+	}
+
+	period, records, ok := h.findAttendance(c, bson.M{"student_id": id})
+	if !ok {
+		return
+	}
+
+	var summary attendanceCounts
+	bySubject := make(map[primitive.ObjectID]*attendanceCounts)
+	subjectIDs := make([]primitive.ObjectID, 0)
+	for _, record := range records {
+		summary.add(record.Status)
+		if bySubject[record.SubjectID] == nil {
+			bySubject[record.SubjectID] = &attendanceCounts{}
+			subjectIDs = append(subjectIDs, record.SubjectID)
+		}
+		bySubject[record.SubjectID].add(record.Status)
+	}
+
+	related := h.newRelations()
+	if err := related.subjects.load(c.Request.Context(), subjectIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения предметов"})
+		return
+	}
+	subjects := make([]subjectAttendance, 0, len(bySubject))
+	for _, subjectID := range subjectIDs {
+		item := subjectAttendance{SubjectID: subjectID, attendanceCounts: *bySubject[subjectID]}
+		if subject := related.subjects.get(subjectID); subject != nil {
+			item.Name = subject.Name
+		}
+		subjects = append(subjects, item)
+	}
+	sort.Slice(subjects, func(i, j int) bool { return subjects[i].Name < subjects[j].Name })
+
+	c.JSON(http.StatusOK, gin.H{
+		"student":    student,
+		"period":     period,
+		"summary":    summary,
+		"by_subject": subjects,
+		"records":    records,
+	})
+}
+
+// GetGroupAttendance отчет о посещаемости группы за период по каждому студенту
+// (фильтр subject_id)
+func (h *Handlers) GetGroupAttendance(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID группы"})
+		return
+	}
+
+	group, err := h.store.Groups.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Группа не найдена"})
+		return
+	}
+
+	filter := bson.M{"group_id": id}
+	addObjectIDFilter(filter, "subject_id", c.Query("subject_id"))
+	period, records, ok := h.findAttendance(c, filter)
+	if !ok {
+		return
+	}
+
+	students, err := h.store.Students.Find(c.Request.Context(), bson.M{"group_id": id}, storage.FindOptions{
+		Sort: bson.D{{Key: "last_name", Value: 1}, {Key: "first_name", Value: 1}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов группы"})
+		return
+	}
+
+	var summary attendanceCounts
+	byStudent := make(map[primitive.ObjectID]*attendanceCounts, len(students))
+	for _, record := range records {
+		summary.add(record.Status)
+		if byStudent[record.StudentID] == nil {
+			byStudent[record.StudentID] = &attendanceCounts{}
+		}
+		byStudent[record.StudentID].add(record.Status)
+	}
+
+	items := make([]studentAttendance, 0, len(students))
+	for _, student := range students {
+		item := studentAttendance{Student: student}
+		if counts := byStudent[student.ID]; counts != nil {
+			item.attendanceCounts = *counts
+		}
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group":    group,
+		"period":   period,
+		"summary":  summary,
+		"students": items,
+	})
+}
+
+// GetAttendanceAlerts список студентов, которые пропустили больше threshold процентов
+// отмеченных уроков предмета (по умолчанию ATTENDANCE_ALERT_PERCENT). Пропуски по
+// уважительной причине учитываются только с include_excused=true
+func (h *Handlers) GetAttendanceAlerts(c *gin.Context) {
+	threshold := h.cfg.AttendanceAlertPercent
+	if value := c.Query("threshold"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || parsed > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Порог должен быть числом от 0 до 100"})
+			return
+		}
+		threshold = parsed
+	}
+	includeExcused, _ := strconv.ParseBool(c.Query("include_excused"))
+
+	filter := bson.M{}
+	addObjectIDFilter(filter, "group_id", c.Query("group_id"))
+	addObjectIDFilter(filter, "subject_id", c.Query("subject_id"))
+	period, records, ok := h.findAttendance(c, filter)
+	if !ok {
+		return
+	}
+
+	type key struct{ student, subject primitive.ObjectID }
+	type tally struct{ total, missed int }
+	tallies := make(map[key]*tally)
+	var order []key
+	for _, record := range records {
+		k := key{record.StudentID, record.SubjectID}
+		if tallies[k] == nil {
+			tallies[k] = &tally{}
+			order = append(order, k)
+		}
+		tallies[k].total++
+		if record.Status == models.AttendanceAbsent || (includeExcused && record.Status == models.AttendanceExcused) {
+			tallies[k].missed++
+		}
+	}
+
+	students := newEntityCache(h.store.Students, func(s models.Student) primitive.ObjectID { return s.ID })
+	related := h.newRelations()
+	studentIDs := make([]primitive.ObjectID, 0, len(order))
+	subjectIDs := make([]primitive.ObjectID, 0, len(order))
+	for _, k := range order {
+		if percent(tallies[k].missed, tallies[k].total) > float64(threshold) {
+			studentIDs = append(studentIDs, k.student)
+			subjectIDs = append(subjectIDs, k.subject)
+		}
+	}
+	if err := students.load(c.Request.Context(), studentIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов"})
+		return
+	}
+	if err := related.subjects.load(c.Request.Context(), subjectIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения предметов"})
+		return
+	}
+
+	alerts := []attendanceAlert{}
+	for _, k := range order {
+		missedPercent := percent(tallies[k].missed, tallies[k].total)
+		student := students.get(k.student)
+		if missedPercent <= float64(threshold) || student == nil {
+			continue
+		}
+		alerts = append(alerts, attendanceAlert{
+			Student:       *student,
+			Subject:       *scheduleSubject(related.subjects.get(k.subject), k.subject),
+			Total:         tallies[k].total,
+			Missed:        tallies[k].missed,
+			MissedPercent: missedPercent,
+		})
+	}
+	sort.SliceStable(alerts, func(i, j int) bool { return alerts[i].MissedPercent > alerts[j].MissedPercent })
+
+	c.JSON(http.StatusOK, gin.H{
+		"period":    period,
+		"threshold": threshold,
+		"alerts":    alerts,
+	})
+}
+
+// teacherLesson загружает урок с датой из :id. Преподаватель работает только со своими
+// уроками, иначе получает 403 с сообщением forbidden
+func (h *Handlers) teacherLesson(c *gin.Context, forbidden string) (*models.Lesson, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID урока"})
+		return nil, false
+	}
+
+	lesson, err := h.store.Lessons.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Урок не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска урока"})
+		}
+		return nil, false
+	}
+
+	if claims := auth.GetClaims(c); claims != nil && claims.Role == auth.RoleTeacher && lesson.TeacherID.Hex() != claims.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": forbidden})
+		return nil, false
+	}
+	if lesson.Date == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "У урока нет даты"})
+		return nil, false
+	}
+	return lesson, true
+}
+
+// markableLesson проверяет, что по уроку можно ставить отметки: он уже наступил и не отменен
+func markableLesson(c *gin.Context, lesson *models.Lesson) bool {
+	if !lesson.Active() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Урок отменен или перенесен"})
+		return false
+	}
+	if lesson.Date.After(time.Now().UTC().Truncate(24 * time.Hour)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Урок еще не наступил"})
+		return false
+	}
+	return true
+}
+
+// respondLessonAttendance отвечает ведомостью урока
+func (h *Handlers) respondLessonAttendance(c *gin.Context, lesson *models.Lesson) {
+	studentFilter, err := h.lessonStudentFilter(c.Request.Context(), lesson)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов группы"})
+		return
+	}
+	students, err := h.store.Students.Find(c.Request.Context(), studentFilter, storage.FindOptions{
+		Sort: bson.D{{Key: "last_name", Value: 1}, {Key: "first_name", Value: 1}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов группы"})
+		return
+	}
+
+	records, err := h.store.Attendance.Find(c.Request.Context(), bson.M{"lesson_id": lesson.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения посещаемости"})
+		return
+	}
+	byStudent := make(map[primitive.ObjectID]models.Attendance, len(records))
+	for _, record := range records {
+		byStudent[record.StudentID] = record
+	}
+
+	var summary attendanceCounts
+	rows := make([]attendanceRow, 0, len(students))
+	for _, student := range students {
+		row := attendanceRow{Student: student}
+		if record, ok := byStudent[student.ID]; ok {
+			row.Status = record.Status
+			row.Comment = record.Comment
+			row.MarkedBy = record.MarkedBy
+			summary.add(record.Status)
+		}
+		rows = append(rows, row)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lesson_id": lesson.ID,
+		"group_id":  lesson.GroupID,
+		"date":      lesson.Date,
+		"summary":   summary,
+		"students":  rows,
+	})
+}
+
+// findAttendance загружает отметки по фильтру за период отчета (reportPeriod)
+func (h *Handlers) findAttendance(c *gin.Context, filter bson.M) (statisticsPeriod, []models.Attendance, bool) {
+	period, start, end, ok := h.reportPeriod(c)
+	if !ok {
+		return period, nil, false
+	}
+
+	filter["date"] = bson.M{"$gte": start, "$lt": end.AddDate(0, 0, 1)}
+	records, err := h.store.Attendance.Find(c.Request.Context(), filter, storage.FindOptions{
+		Sort: bson.D{{Key: "date", Value: 1}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения посещаемости"})
+		return period, nil, false
+	}
+	return period, records, true
+}
+
+// percent доля part от total в процентах с одним знаком после запятой
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetAuditLog журнал изменений с фильтрами collection, entity_id, user_id, login, role,
+// action и периодом start_date/end_date. Выдается постранично, новые записи первыми
+func (h *Handlers) GetAuditLog(c *gin.Context) {
+	params, ok := parseListParams(c, []string{"created_at", "collection", "login"}, "-created_at")
+	if !ok {
+		return
+	}
+	if !params.paged {
+		params.paged = true
+		params.options.Limit = params.limit
+	}
+
+	filter := bson.M{}
+	for _, field := range []string{"collection", "user_id", "login", "role", "action"} {
+		if value := c.Query(field); value != "" {
+			filter[field] = value
+		}
+	}
+	if entityID := c.Query("entity_id"); entityID != "" {
+		id, err := primitive.ObjectIDFromHex(entityID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID документа"})
+			return
+		}
+		filter["entity_id"] = id
+	}
+
+	period := bson.M{}
+	if startDate := c.Query("start_date"); startDate != "" {
+		start, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты начала"})
+			return
+		}
+		period["$gte"] = start
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		end, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты окончания"})
+			return
+		}
+		period["$lt"] = end.AddDate(0, 0, 1)
+	}
+	if len(period) > 0 {
+		filter["created_at"] = period
+	}
+
+	entries, total, ok := findPage(c, h.store.AuditLog, filter, params, "Ошибка получения журнала изменений")
+	if !ok {
+		return
+	}
+
+	respondList(c, entries, total, params)
+}
+
+// GetEntityHistory история изменений одного документа от создания до последней правки
+func (h *Handlers) GetEntityHistory(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID документа"})
+		return
+	}
+
+	filter := bson.M{"collection": c.Param("collection"), "entity_id": id}
+	entries, err := h.store.AuditLog.Find(c.Request.Context(), filter, storage.FindOptions{
+		Sort: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения истории изменений"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
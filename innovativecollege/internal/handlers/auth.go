@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Login выдает JWT администратору (логин и пароль), преподавателю или студенту (ИИН)
+func (h *Handlers) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Администратор
+	if req.Login == h.cfg.AdminLogin {
+		if h.cfg.AdminPassword == "" || subtle.ConstantTimeCompare([]byte(req.Password), []byte(h.cfg.AdminPassword)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный логин или пароль"})
+			return
+		}
+
+		h.respondWithToken(c, auth.RoleAdmin, "", "", req.Login, gin.H{"login": req.Login})
+		return
+	}
+
+	// Преподаватель по ИИН
+	teacher, err := h.store.Teachers.FindOne(c.Request.Context(), bson.M{"iin": req.Login})
+	if err == nil {
+		h.respondWithToken(c, auth.RoleTeacher, teacher.ID.Hex(), teacher.IIN, teacher.IIN, teacher)
+		return
+	}
+
+	// Студент по ИИН
+	student, err := h.store.Students.FindOne(c.Request.Context(), bson.M{"iin": req.Login})
+	if err == nil {
+		h.respondWithToken(c, auth.RoleStudent, student.ID.Hex(), student.IIN, student.IIN, student)
+		return
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "Пользователь с таким ИИН не найден"})
+}
+
+// GetCurrentUser возвращает данные текущего токена
+func (h *Handlers) GetCurrentUser(c *gin.Context) {
+	claims := auth.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Требуется авторизация"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"role":    claims.Role,
+		"user_id": claims.UserID,
+		"iin":     claims.IIN,
+		"login":   claims.Subject,
+	})
+}
+
+func (h *Handlers) respondWithToken(c *gin.Context, role, userID, iin, subject string, user interface{}) {
+	token, expiresAt, err := h.auth.Issue(role, userID, iin, subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания токена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_at": expiresAt,
+		"role":       role,
+		"user":       user,
+	})
+}
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Состояния пары в сетке доступности преподавателя
+const (
+	slotAvailable    = "available"     // Можно ставить
+	slotUnavailable  = "unavailable"   // Преподаватель недоступен (жесткое ограничение)
+	slotBusy         = "busy"          // У преподавателя уже есть занятие
+	slotLimitReached = "limit_reached" // Достигнут максимум пар в день (пожелание)
+	slotNotPreferred = "not_preferred" // Не предпочитаемая смена (пожелание)
+)
+
+// availabilitySlot состояние одной пары в сетке доступности
+type availabilitySlot struct {
+	TimeSlotID primitive.ObjectID `json:"time_slot_id"`
+	StartTime  string             `json:"start_time"`
+	EndTime    string             `json:"end_time"`
+	Shift      int                `json:"shift"`
+	Status     string             `json:"status"`
+	Reason     string             `json:"reason,omitempty"`
+}
+
+// availabilityDay сетка доступности на один день
+type availabilityDay struct {
+	DayOfWeek int                `json:"day_of_week"`
+	Date      string             `json:"date,omitempty"` // Только для сетки на конкретную дату
+	Lessons   int                `json:"lessons"`        // Занятий преподавателя в этот день
+	Slots     []availabilitySlot `json:"slots"`
+}
+
+// teacherAvailabilityResponse ограничения преподавателя и сетка пар для календаря
+type teacherAvailabilityResponse struct {
+	TeacherID    primitive.ObjectID         `json:"teacher_id"`
+	Availability models.TeacherAvailability `json:"availability"`
+	Days         []availabilityDay          `json:"days"`
+}
+
+// occupiedTime занятие преподавателя, которое занимает время в сетке
+type occupiedTime struct {
+	StartTime string
+	EndTime   string
+}
+
+// ========== ДОСТУПНОСТЬ ПРЕПОДАВАТЕЛЕЙ ==========
+
+// GetTeacherAvailability возвращает ограничения преподавателя и сетку активных пар:
+// для каждой пары статус available, unavailable, busy, limit_reached или not_preferred.
+// Без параметров сетка строится по недельному расписанию на учебные дни недели,
+// с date (YYYY-MM-DD) - по урокам этой даты
+func (h *Handlers) GetTeacherAvailability(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID преподавателя"})
+		return
+	}
+
+	teacher, err := h.store.Teachers.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Преподаватель не найден"})
+		return
+	}
+
+	var timeSlots []models.TimeSlot
+	if !findAll(c, h.store.TimeSlots, bson.M{"is_active": true}, &timeSlots, "Ошибка получения временных слотов") {
+		return
+	}
+	sort.SliceStable(timeSlots, func(i, j int) bool {
+		a, _ := models.ParseClock(timeSlots[i].StartTime)
+		b, _ := models.ParseClock(timeSlots[j].StartTime)
+		return a < b
+	})
+
+	response := teacherAvailabilityResponse{TeacherID: teacher.ID, Days: []availabilityDay{}}
+	if teacher.Availability != nil {
+		response.Availability = *teacher.Availability
+	}
+	if response.Availability.Unavailable == nil {
+		response.Availability.Unavailable = []models.UnavailableWindow{}
+	}
+
+	if dateParam := c.Query("date"); dateParam != "" {
+		date, err := time.Parse("2006-01-02", dateParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты. Используйте YYYY-MM-DD"})
+			return
+		}
+
+		var lessons []models.Lesson
+		filter := bson.M{
+			"teacher_id": teacher.ID,
+			"date":       bson.M{"$gte": date, "$lt": date.AddDate(0, 0, 1)},
+			"status":     bson.M{"$nin": inactiveLessonStatuses},
+		}
+		if !findAll(c, h.store.Lessons, filter, &lessons, "Ошибка получения уроков") {
+			return
+		}
+		occupied := make([]occupiedTime, 0, len(lessons))
+		for _, lesson := range lessons {
+			occupied = append(occupied, occupiedTime{lesson.StartTime, lesson.EndTime})
+		}
+
+		day := availabilityGrid(response.Availability, models.DayOfWeek(date), timeSlots, occupied)
+		day.Date = date.Format("2006-01-02")
+		response.Days = append(response.Days, day)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	var schedules []models.Schedule
+	if !findAll(c, h.store.Schedules, bson.M{"teacher_id": teacher.ID}, &schedules, "Ошибка получения расписания") {
+		return
+	}
+	occupiedByDay := make(map[int][]occupiedTime)
+	for _, schedule := range schedules {
+		occupiedByDay[schedule.DayOfWeek] = append(occupiedByDay[schedule.DayOfWeek], occupiedTime{schedule.StartTime, schedule.EndTime})
+	}
+	for _, dayOfWeek := range h.cfg.WorkingWeekdays {
+		response.Days = append(response.Days, availabilityGrid(response.Availability, dayOfWeek, timeSlots, occupiedByDay[dayOfWeek]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateTeacherAvailability заменяет ограничения преподавателя
+func (h *Handlers) UpdateTeacherAvailability(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID преподавателя"})
+		return
+	}
+
+	var req models.UpdateTeacherAvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	availability := models.TeacherAvailability{
+		Unavailable:      []models.UnavailableWindow{},
+		PreferredShift:   req.PreferredShift,
+		MaxLessonsPerDay: req.MaxLessonsPerDay,
+	}
+	for _, window := range req.Unavailable {
+		if window.StartTime != "" || window.EndTime != "" {
+			start, ok1 := models.ParseClock(window.StartTime)
+			end, ok2 := models.ParseClock(window.EndTime)
+			if !ok1 || !ok2 || end <= start {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Окно недоступности: укажите start_time и end_time в формате HH:MM (конец позже начала) или не указывайте время для всего дня"})
+				return
+			}
+		}
+		availability.Unavailable = append(availability.Unavailable, models.UnavailableWindow{
+			DayOfWeek: window.DayOfWeek,
+			StartTime: window.StartTime,
+			EndTime:   window.EndTime,
+			Reason:    window.Reason,
+		})
+	}
+
+	result, err := h.store.Teachers.Update(c.Request.Context(), bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"availability": availability,
+		"updated_at":   time.Now(),
+	}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления доступности преподавателя"})
+		return
+	}
+	if result.Matched == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Преподаватель не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, availability)
+}
+
+// availabilityGrid строит сетку пар одного дня недели по ограничениям и занятиям преподавателя
+func availabilityGrid(availability models.TeacherAvailability, dayOfWeek int, timeSlots []models.TimeSlot, occupied []occupiedTime) availabilityDay {
+	day := availabilityDay{DayOfWeek: dayOfWeek, Lessons: len(occupied), Slots: []availabilitySlot{}}
+	limitReached := availability.MaxLessonsPerDay > 0 && len(occupied) >= availability.MaxLessonsPerDay
+
+	for _, slot := range timeSlots {
+		item := availabilitySlot{
+			TimeSlotID: slot.ID,
+			StartTime:  slot.StartTime,
+			EndTime:    slot.EndTime,
+			Shift:      slot.Shift,
+			Status:     slotAvailable,
+		}
+
+		busy := false
+		for _, entry := range occupied {
+			if models.TimesOverlap(slot.StartTime, slot.EndTime, entry.StartTime, entry.EndTime) {
+				busy = true
+				break
+			}
+		}
+
+		switch window := availability.UnavailableAt(dayOfWeek, slot.StartTime, slot.EndTime); {
+		case window != nil:
+			item.Status = slotUnavailable
+			item.Reason = window.Reason
+		case busy:
+			item.Status = slotBusy
+		case limitReached:
+			item.Status = slotLimitReached
+			item.Reason = fmt.Sprintf("Максимум пар в день: %d", availability.MaxLessonsPerDay)
+		case availability.PreferredShift != 0 && slot.Shift != availability.PreferredShift:
+			item.Status = slotNotPreferred
+			item.Reason = fmt.Sprintf("Предпочитает %d смену", availability.PreferredShift)
+		}
+		day.Slots = append(day.Slots, item)
+	}
+	return day
+}
+
+// checkScheduleAvailability проверяет запись расписания по ограничениям преподавателя
+// (см. checkAvailability). Предупреждения записываются в schedule.Warnings
+func (h *Handlers) checkScheduleAvailability(c *gin.Context, schedule *models.Schedule) bool {
+	countDay := func(ctx context.Context) (int64, error) {
+		filter := bson.M{"teacher_id": schedule.TeacherID, "day_of_week": schedule.DayOfWeek}
+		if !schedule.ID.IsZero() {
+			filter["_id"] = bson.M{"$ne": schedule.ID}
+		}
+		return h.store.Schedules.Count(ctx, filter)
+	}
+
+	warnings, stop := h.checkAvailability(c, schedule.TeacherID, schedule.DayOfWeek, schedule.StartTime, schedule.EndTime, schedule.Shift, countDay)
+	schedule.Warnings = warnings
+	return stop
+}
+
+// checkLessonAvailability проверяет урок по ограничениям преподавателя
+// (см. checkAvailability). Уроки без даты не проверяются
+func (h *Handlers) checkLessonAvailability(c *gin.Context, lesson *models.Lesson) bool {
+	if lesson.Date == nil {
+		return false
+	}
+
+	date := truncateToDay(*lesson.Date)
+	countDay := func(ctx context.Context) (int64, error) {
+		filter := bson.M{
+			"teacher_id": lesson.TeacherID,
+			"date":       bson.M{"$gte": date, "$lt": date.AddDate(0, 0, 1)},
+			"status":     bson.M{"$nin": inactiveLessonStatuses},
+		}
+		if !lesson.ID.IsZero() {
+			filter["_id"] = bson.M{"$ne": lesson.ID}
+		}
+		return h.store.Lessons.Count(ctx, filter)
+	}
+
+	warnings, stop := h.checkAvailability(c, lesson.TeacherID, models.DayOfWeek(date), lesson.StartTime, lesson.EndTime, lesson.Shift, countDay)
+	lesson.Warnings = warnings
+	return stop
+}
+
+// checkAvailability проверяет занятие по ограничениям преподавателя. Недоступность
+// отклоняется с 409 (администратор может сохранить с ?force=true), нарушенные пожелания
+// возвращаются как предупреждения. countDay считает другие занятия преподавателя
+// в этот день. Возвращает true, если обработку запроса нужно прекратить
+func (h *Handlers) checkAvailability(c *gin.Context, teacherID primitive.ObjectID, dayOfWeek int, startTime, endTime string, shift int,
+	countDay func(ctx context.Context) (int64, error)) ([]string, bool) {
+	if startTime == "" || endTime == "" {
+		return nil, false
+	}
+
+	teacher, err := h.store.Teachers.FindByID(c.Request.Context(), teacherID)
+	if err == storage.ErrNotFound {
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки доступности преподавателя"})
+		return nil, true
+	}
+
+	window, warnings, err := availabilityIssues(c.Request.Context(), teacher.Availability, dayOfWeek, startTime, endTime, shift, countDay)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки доступности преподавателя"})
+		return nil, true
+	}
+	if window != nil {
+		if !forceRequested(c) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":       "Преподаватель недоступен в это время",
+				"unavailable": window,
+			})
+			return nil, true
+		}
+		warnings = append([]string{forcedUnavailableWarning}, warnings...)
+	}
+	return warnings, false
+}
+
+// forcedUnavailableWarning предупреждение о записи, сохраненной с ?force=true
+// в окно недоступности преподавателя
+const forcedUnavailableWarning = "Сохранено, хотя преподаватель недоступен в это время"
+
+// availabilityIssues сверяет занятие с ограничениями преподавателя: возвращает окно
+// недоступности, в которое оно попадает, и нарушенные пожелания. countDay вызывается,
+// только если у преподавателя задан максимум пар в день
+func availabilityIssues(ctx context.Context, availability *models.TeacherAvailability, dayOfWeek int, startTime, endTime string, shift int,
+	countDay func(ctx context.Context) (int64, error)) (*models.UnavailableWindow, []string, error) {
+	if availability == nil || startTime == "" || endTime == "" {
+		return nil, nil, nil
+	}
+
+	window := availability.UnavailableAt(dayOfWeek, startTime, endTime)
+
+	var warnings []string
+	if shift == 0 {
+		shift = models.DetermineShift(startTime)
+	}
+	if availability.PreferredShift != 0 && shift != 0 && shift != availability.PreferredShift {
+		warnings = append(warnings, fmt.Sprintf("Преподаватель предпочитает %d смену", availability.PreferredShift))
+	}
+
+	if availability.MaxLessonsPerDay > 0 {
+		count, err := countDay(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if int(count)+1 > availability.MaxLessonsPerDay {
+			warnings = append(warnings, fmt.Sprintf("Пар у преподавателя в этот день: %d (желаемый максимум %d)", count+1, availability.MaxLessonsPerDay))
+		}
+	}
+
+	return window, warnings, nil
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"innovativecollege/internal/calendar"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// byStartDate сортировка семестров и событий календаря по дате начала
+var byStartDate = storage.FindOptions{Sort: bson.D{{Key: "start_date", Value: 1}}}
+
+// ========== СЕМЕСТРЫ ==========
+
+// CreateTerm создает новый семестр
+func (h *Handlers) CreateTerm(c *gin.Context) {
+	var req models.CreateTermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, end, ok := parseDateRange(c, req.StartDate, req.EndDate)
+	if !ok {
+		return
+	}
+
+	// Семестры не должны пересекаться
+	overlapping, err := h.store.Terms.Count(c.Request.Context(), bson.M{
+		"start_date": bson.M{"$lte": end},
+		"end_date":   bson.M{"$gte": start},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки семестров"})
+		return
+	}
+	if overlapping > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Семестр пересекается с существующим"})
+		return
+	}
+
+	term := models.Term{
+		Name:      req.Name,
+		StartDate: start,
+		EndDate:   end,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	id, err := h.store.Terms.Insert(c.Request.Context(), term)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания семестра"})
+		return
+	}
+
+	term.ID = id
+	c.JSON(http.StatusCreated, term)
+}
+
+// GetTerms получает все семестры
+func (h *Handlers) GetTerms(c *gin.Context) {
+	terms, err := h.store.Terms.Find(c.Request.Context(), bson.M{}, byStartDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения семестров"})
+		return
+	}
+
+	c.JSON(http.StatusOK, terms)
+}
+
+// GetCurrentTerm получает семестр, в который попадает сегодняшняя дата
+func (h *Handlers) GetCurrentTerm(c *gin.Context) {
+	term, err := h.currentTerm()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения семестра"})
+		return
+	}
+	if term == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Сейчас нет активного семестра"})
+		return
+	}
+
+	c.JSON(http.StatusOK, term)
+}
+
+// UpdateTerm обновляет семестр
+func (h *Handlers) UpdateTerm(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID семестра"})
+		return
+	}
+
+	var req models.UpdateTermRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existingTerm, err := h.store.Terms.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Семестр не найден"})
+		return
+	}
+
+	// Создаем объект для обновления
+	update := bson.M{"updated_at": time.Now()}
+	if req.Name != "" {
+		update["name"] = req.Name
+	}
+
+	startDate := existingTerm.StartDate.Format("2006-01-02")
+	endDate := existingTerm.EndDate.Format("2006-01-02")
+	if req.StartDate != "" {
+		startDate = req.StartDate
+	}
+	if req.EndDate != "" {
+		endDate = req.EndDate
+	}
+	if req.StartDate != "" || req.EndDate != "" {
+		start, end, ok := parseDateRange(c, startDate, endDate)
+		if !ok {
+			return
+		}
+
+		overlapping, err := h.store.Terms.Count(c.Request.Context(), bson.M{
+			"_id":        bson.M{"$ne": id},
+			"start_date": bson.M{"$lte": end},
+			"end_date":   bson.M{"$gte": start},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки семестров"})
+			return
+		}
+		if overlapping > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Семестр пересекается с существующим"})
+			return
+		}
+
+		update["start_date"] = start
+		update["end_date"] = end
+	}
+
+	_, err = h.store.Terms.Update(c.Request.Context(), bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления семестра"})
+		return
+	}
+
+	updatedTerm, err := h.store.Terms.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленного семестра"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedTerm)
+}
+
+// DeleteTerm удаляет семестр
+func (h *Handlers) DeleteTerm(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID семестра"})
+		return
+	}
+
+	deleted, err := h.store.Terms.Delete(c.Request.Context(), bson.M{"_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления семестра"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Семестр не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Семестр успешно удален"})
+}
+
+// ========== КАЛЕНДАРЬ ==========
+
+// CreateCalendarEvent добавляет праздник, каникулы или перенесенный рабочий день
+func (h *Handlers) CreateCalendarEvent(c *gin.Context) {
+	var req models.CreateCalendarEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.EndDate == "" {
+		req.EndDate = req.StartDate
+	}
+	start, end, ok := parseDateRange(c, req.StartDate, req.EndDate)
+	if !ok {
+		return
+	}
+
+	event := models.CalendarEvent{
+		Type:      req.Type,
+		Name:      req.Name,
+		StartDate: start,
+		EndDate:   end,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if req.Type == models.CalendarWorkingDay {
+		event.AsDayOfWeek = req.AsDayOfWeek
+	}
+
+	id, err := h.store.CalendarEvents.Insert(c.Request.Context(), event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания события календаря"})
+		return
+	}
+
+	event.ID = id
+	c.JSON(http.StatusCreated, event)
+}
+
+// GetCalendarEvents получает события календаря (фильтры type, start_date, end_date)
+func (h *Handlers) GetCalendarEvents(c *gin.Context) {
+	filter := bson.M{}
+	if eventType := c.Query("type"); eventType != "" {
+		filter["type"] = eventType
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		if start, err := time.Parse("2006-01-02", startDate); err == nil {
+			filter["end_date"] = bson.M{"$gte": start}
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if end, err := time.Parse("2006-01-02", endDate); err == nil {
+			filter["start_date"] = bson.M{"$lte": end}
+		}
+	}
+
+	events, err := h.store.CalendarEvents.Find(c.Request.Context(), filter, byStartDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения событий календаря"})
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// DeleteCalendarEvent удаляет событие календаря
+func (h *Handlers) DeleteCalendarEvent(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID события"})
+		return
+	}
+
+	deleted, err := h.store.CalendarEvents.Delete(c.Request.Context(), bson.M{"_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления события календаря"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Событие не найдено"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Событие успешно удалено"})
+}
+
+// GetCalendarWeeks получает учебные недели семестра с номерами (term_id или текущий семестр)
+func (h *Handlers) GetCalendarWeeks(c *gin.Context) {
+	cal, err := h.loadCalendar()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки календаря"})
+		return
+	}
+
+	var term *models.Term
+	if termID := c.Query("term_id"); termID != "" {
+		for i := range cal.Terms {
+			if cal.Terms[i].ID.Hex() == termID {
+				term = &cal.Terms[i]
+				break
+			}
+		}
+	} else {
+		term = cal.TermFor(time.Now())
+	}
+
+	if term == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Семестр не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"term":  term,
+		"weeks": cal.Weeks(*term),
+	})
+}
+
+// GetCalendarDays получает описание каждой даты периода: рабочий день, причина выходного, номер недели
+func (h *Handlers) GetCalendarDays(c *gin.Context) {
+	start, end, ok := parseDateRange(c, c.Query("start_date"), c.Query("end_date"))
+	if !ok {
+		return
+	}
+	if end.Sub(start) > maxGenerationDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Период не может превышать один год"})
+		return
+	}
+
+	cal, err := h.loadCalendar()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки календаря"})
+		return
+	}
+
+	days := []calendar.Day{}
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		days = append(days, cal.Describe(date))
+	}
+
+	c.JSON(http.StatusOK, days)
+}
+
+// loadCalendar загружает семестры и события календаря
+func (h *Handlers) loadCalendar() (*calendar.Calendar, error) {
+	terms, err := h.store.Terms.Find(context.Background(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := h.store.CalendarEvents.Find(context.Background(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	return calendar.New(terms, events, h.cfg.WorkingWeekdays), nil
+}
+
+// currentTerm возвращает семестр на сегодняшнюю дату или nil
+func (h *Handlers) currentTerm() (*models.Term, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	term, err := h.store.Terms.FindOne(context.Background(), bson.M{
+		"start_date": bson.M{"$lte": today},
+		"end_date":   bson.M{"$gte": today},
+	})
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return term, nil
+}
+
+// rejectNonWorkingDay отвечает 400, если дата урока приходится на нерабочий день
+// и администратор не запросил force. Возвращает true, если обработку запроса нужно прекратить
+func (h *Handlers) rejectNonWorkingDay(c *gin.Context, date *time.Time) bool {
+	if date == nil || forceRequested(c) {
+		return false
+	}
+
+	cal, err := h.loadCalendar()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки календаря"})
+		return true
+	}
+
+	day := cal.Describe(*date)
+	if day.Working {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": "Дата урока приходится на нерабочий день",
+		"day":   day,
+	})
+	return true
+}
+
+// parseDateRange разбирает пару дат "YYYY-MM-DD" и отвечает 400 при ошибке
+func parseDateRange(c *gin.Context, startDate, endDate string) (time.Time, time.Time, bool) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты начала. Используйте YYYY-MM-DD"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты окончания. Используйте YYYY-MM-DD"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Дата окончания раньше даты начала"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	return start, end, true
+}
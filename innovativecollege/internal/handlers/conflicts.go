@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// findScheduleConflicts ищет записи недельного расписания, пересекающиеся с кандидатом
+// по преподавателю, аудитории или группе в тот же день недели
+func (h *Handlers) findScheduleConflicts(candidate models.Schedule) ([]models.Conflict, error) {
+	if candidate.StartTime == "" || candidate.EndTime == "" {
+		return nil, nil
+	}
+	group, err := h.subgroupOwner(candidate.GroupID, candidate.SubgroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"day_of_week": candidate.DayOfWeek,
+		"$or":         conflictOr(candidate.TeacherID, candidate.RoomID, candidate.Room, candidate.GroupID),
+	}
+	if !candidate.ID.IsZero() {
+		filter["_id"] = bson.M{"$ne": candidate.ID}
+	}
+
+	schedules, err := h.store.Schedules.Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []models.Conflict
+	for _, existing := range schedules {
+		if !models.TimesOverlap(candidate.StartTime, candidate.EndTime, existing.StartTime, existing.EndTime) {
+			continue
+		}
+		for _, conflictType := range conflictTypes(
+			bookedResources{candidate.TeacherID, candidate.RoomID, candidate.Room, candidate.GroupID, candidate.SubgroupID},
+			bookedResources{existing.TeacherID, existing.RoomID, existing.Room, existing.GroupID, existing.SubgroupID}, group) {
+			conflicts = append(conflicts, models.Conflict{
+				Type:       conflictType,
+				Collection: "schedules",
+				ID:         existing.ID.Hex(),
+				Document:   existing,
+			})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// findLessonConflicts ищет уроки, пересекающиеся с кандидатом в ту же дату
+// по преподавателю, аудитории или группе. Уроки без даты или времени не проверяются
+func (h *Handlers) findLessonConflicts(candidate models.Lesson) ([]models.Conflict, error) {
+	if candidate.Date == nil || candidate.StartTime == "" || candidate.EndTime == "" {
+		return nil, nil
+	}
+	group, err := h.subgroupOwner(candidate.GroupID, candidate.SubgroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	date := *candidate.Date
+	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	endOfDay := startOfDay.Add(24 * time.Hour)
+
+	filter := bson.M{
+		"date":   bson.M{"$gte": startOfDay, "$lt": endOfDay},
+		"status": bson.M{"$nin": inactiveLessonStatuses}, // Отмененные и перенесенные уроки время не занимают
+		"$or":    conflictOr(candidate.TeacherID, candidate.RoomID, candidate.Room, candidate.GroupID),
+	}
+	if !candidate.ID.IsZero() {
+		filter["_id"] = bson.M{"$ne": candidate.ID}
+	}
+
+	lessons, err := h.store.Lessons.Find(context.Background(), filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []models.Conflict
+	for _, existing := range lessons {
+		if !models.TimesOverlap(candidate.StartTime, candidate.EndTime, existing.StartTime, existing.EndTime) {
+			continue
+		}
+		for _, conflictType := range conflictTypes(
+			bookedResources{candidate.TeacherID, candidate.RoomID, candidate.Room, candidate.GroupID, candidate.SubgroupID},
+			bookedResources{existing.TeacherID, existing.RoomID, existing.Room, existing.GroupID, existing.SubgroupID}, group) {
+			conflicts = append(conflicts, models.Conflict{
+				Type:       conflictType,
+				Collection: "lessons",
+				ID:         existing.ID.Hex(),
+				Document:   existing,
+			})
+		}
+	}
+
+	return conflicts, nil
+}
+
+// bookedResources ресурсы, которые занимает запись расписания или урок
+type bookedResources struct {
+	TeacherID  primitive.ObjectID
+	RoomID     primitive.ObjectID
+	Room       string // Номер аудитории для записей, созданных до появления коллекции rooms
+	GroupID    primitive.ObjectID
+	SubgroupID primitive.ObjectID // Пусто - вся группа
+}
+
+// conflictOr строит условие поиска записей с тем же преподавателем, аудиторией или группой
+func conflictOr(teacherID, roomID primitive.ObjectID, room string, groupID primitive.ObjectID) []bson.M {
+	or := []bson.M{
+		{"teacher_id": teacherID},
+		{"group_id": groupID},
+	}
+	if !roomID.IsZero() {
+		or = append(or, bson.M{"room_id": roomID})
+	}
+	if room != "" {
+		or = append(or, bson.M{"room": room})
+	}
+	return or
+}
+
+// subgroupOwner загружает группу, если кандидат - занятие подгруппы: по её составу
+// проверяется, пересекаются ли подгруппы. Для занятия всей группы возвращает nil
+func (h *Handlers) subgroupOwner(groupID, subgroupID primitive.ObjectID) (*models.Group, error) {
+	if subgroupID.IsZero() {
+		return nil, nil
+	}
+	group, err := h.store.Groups.FindByID(context.Background(), groupID)
+	if err == storage.ErrNotFound {
+		return nil, nil
+	}
+	return group, err
+}
+
+// conflictTypes возвращает, по каким ресурсам совпадают две записи. group - группа
+// с подгруппами (nil, если кандидат - занятие всей группы)
+func conflictTypes(a, b bookedResources, group *models.Group) []string {
+	var types []string
+	if a.TeacherID == b.TeacherID {
+		types = append(types, models.ConflictTeacher)
+	}
+	if sameRoom(a, b) {
+		types = append(types, models.ConflictRoom)
+	}
+	if a.GroupID == b.GroupID && (group == nil || group.SubgroupsClash(a.SubgroupID, b.SubgroupID)) {
+		types = append(types, models.ConflictGroup)
+	}
+	return types
+}
+
+// sameRoom сравнивает аудитории по ID, а для старых записей без room_id - по номеру
+func sameRoom(a, b bookedResources) bool {
+	if !a.RoomID.IsZero() && !b.RoomID.IsZero() {
+		return a.RoomID == b.RoomID
+	}
+	return a.Room != "" && models.NormalizeRoomNumber(a.Room) == models.NormalizeRoomNumber(b.Room)
+}
+
+// forceRequested проверяет, что администратор явно разрешил сохранить запись с конфликтами (?force=true)
+func forceRequested(c *gin.Context) bool {
+	force, _ := strconv.ParseBool(c.Query("force"))
+	return force && auth.IsAdmin(c)
+}
+
+// rejectConflicts отвечает 409 со списком конфликтов, если они есть и не запрошен force.
+// Возвращает true, если обработку запроса нужно прекратить
+func rejectConflicts(c *gin.Context, conflicts []models.Conflict, err error) bool {
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки конфликтов расписания"})
+		return true
+	}
+	if len(conflicts) == 0 || forceRequested(c) {
+		return false
+	}
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error":     "Преподаватель, аудитория или группа уже заняты в это время",
+		"conflicts": conflicts,
+	})
+	return true
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/events"
+	"innovativecollege/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Параметры потока событий
+const (
+	streamRetry     = 3 * time.Second  // Через сколько браузер переподключается после обрыва
+	streamHeartbeat = 25 * time.Second // Комментарий-пинг, чтобы прокси не закрывали соединение
+)
+
+// ========== ПОТОК СОБЫТИЙ ==========
+
+// IssueEventTicket выдает билет для подключения к потоку событий параметром ticket.
+// Билет действует минуту и годится только для /events/stream, поэтому токен
+// сессии не попадает в URL и журналы
+func (h *Handlers) IssueEventTicket(c *gin.Context) {
+	if h.events == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Поток событий отключен"})
+		return
+	}
+
+	ticket, expiresAt, err := h.auth.IssueTicket(auth.GetClaims(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания билета"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+	})
+}
+
+// StreamEvents поток изменений уроков, расписания и пар (Server-Sent Events).
+// Фильтры group_id, teacher_id, start_date/end_date (даты уроков) и types
+// (через запятую). После обрыва браузер переподключается с заголовком
+// Last-Event-ID и получает пропущенные события
+func (h *Handlers) StreamEvents(c *gin.Context) {
+	if h.events == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Поток событий отключен"})
+		return
+	}
+
+	filter, ok := parseEventFilter(c)
+	if !ok {
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	lastID, _ := strconv.ParseUint(lastEventID, 10, 64)
+
+	subscription := h.events.Subscribe(filter, lastID, 0)
+	defer h.events.Unsubscribe(subscription)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginx не должен буферизовать поток
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetry.Milliseconds())
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case event, open := <-subscription.C:
+			if !open {
+				// Клиент не успевал читать события: он переподключится с Last-Event-ID
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			c.Writer.Flush()
+		}
+	}
+}
+
+// parseEventFilter разбирает фильтры потока. При ошибке отвечает 400
+func parseEventFilter(c *gin.Context) (events.Filter, bool) {
+	var filter events.Filter
+
+	if groupID := c.Query("group_id"); groupID != "" {
+		id, err := primitive.ObjectIDFromHex(groupID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID группы"})
+			return filter, false
+		}
+		filter.GroupID = id
+	}
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		id, err := primitive.ObjectIDFromHex(teacherID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID преподавателя"})
+			return filter, false
+		}
+		filter.TeacherID = id
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		start, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты начала"})
+			return filter, false
+		}
+		filter.From = start
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		end, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты окончания"})
+			return filter, false
+		}
+		filter.To = end
+	}
+	if types := c.Query("types"); types != "" {
+		filter.Types = make(map[string]bool)
+		for _, eventType := range strings.Split(types, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				filter.Types[eventType] = true
+			}
+		}
+	}
+	return filter, true
+}
+
+// publish публикует событие в шину: его получают поток событий и вебхуки
+func (h *Handlers) publish(ctx context.Context, event events.Event) {
+	if h.events != nil {
+		h.events.Publish(ctx, event)
+	}
+}
+
+// publishLesson публикует событие об уроке
+func (h *Handlers) publishLesson(ctx context.Context, eventType string, lesson models.Lesson) {
+	event := events.Event{Type: eventType, Data: gin.H{"lesson": lesson}}
+	describeLesson(&event, lesson)
+	h.publish(ctx, event)
+}
+
+// publishLessonMoved публикует lesson.moved: урок после переноса и его прежнее состояние.
+// Событие получают подписчики и старой, и новой даты
+func (h *Handlers) publishLessonMoved(ctx context.Context, lesson, previous models.Lesson) {
+	event := events.Event{Type: models.EventLessonMoved, Data: gin.H{"lesson": lesson, "previous": previous}}
+	describeLesson(&event, lesson)
+	describeLesson(&event, previous)
+	h.publish(ctx, event)
+}
+
+// publishGeneratedLessons публикует одно событие lessons.generated на запуск генератора
+func (h *Handlers) publishGeneratedLessons(ctx context.Context, start, end time.Time, lessons []models.Lesson) {
+	event := events.Event{Type: models.EventLessonsGenerated}
+	for _, lesson := range lessons {
+		describeLesson(&event, lesson)
+	}
+	event.From, event.To = start, end
+	event.Data = gin.H{
+		"start_date": start.Format("2006-01-02"),
+		"end_date":   end.Format("2006-01-02"),
+		"created":    len(lessons),
+		"group_ids":  event.GroupIDs,
+	}
+	h.publish(ctx, event)
+}
+
+// publishSchedule публикует schedule.updated. action: created, updated, deleted или restored
+func (h *Handlers) publishSchedule(ctx context.Context, action string, schedule models.Schedule) {
+	h.publish(ctx, events.Event{
+		Type:       models.EventScheduleUpdated,
+		Data:       gin.H{"action": action, "schedule": schedule},
+		GroupIDs:   []primitive.ObjectID{schedule.GroupID},
+		TeacherIDs: []primitive.ObjectID{schedule.TeacherID},
+	})
+}
+
+// publishScheduleBatch публикует одно событие schedule.updated после импорта
+// или применения черновика: action imported или replaced
+func (h *Handlers) publishScheduleBatch(ctx context.Context, action string, groupIDs []primitive.ObjectID, created int) {
+	h.publish(ctx, events.Event{
+		Type:     models.EventScheduleUpdated,
+		Data:     gin.H{"action": action, "group_ids": groupIDs, "created": created},
+		GroupIDs: groupIDs,
+	})
+}
+
+// publishTimeSlot публикует событие о паре. Пары общие для всех групп
+func (h *Handlers) publishTimeSlot(ctx context.Context, eventType string, timeSlot models.TimeSlot) {
+	h.publish(ctx, events.Event{Type: eventType, Data: gin.H{"time_slot": timeSlot}})
+}
+
+// describeLesson добавляет к событию группу, преподавателей и дату урока
+func describeLesson(event *events.Event, lesson models.Lesson) {
+	event.GroupIDs = appendUniqueID(event.GroupIDs, lesson.GroupID)
+	event.TeacherIDs = appendUniqueID(event.TeacherIDs, lesson.TeacherID)
+	if !lesson.OriginalTeacherID.IsZero() {
+		event.TeacherIDs = appendUniqueID(event.TeacherIDs, lesson.OriginalTeacherID)
+	}
+	if lesson.Date == nil {
+		return
+	}
+	if event.From.IsZero() || lesson.Date.Before(event.From) {
+		event.From = *lesson.Date
+	}
+	if event.To.IsZero() || lesson.Date.After(event.To) {
+		event.To = *lesson.Date
+	}
+}
+
+func appendUniqueID(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
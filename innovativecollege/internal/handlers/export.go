@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"innovativecollege/internal/export"
+	"innovativecollege/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExportSchedules выгружает недельное расписание матрицей "группы x дни и пары"
+// (?format=xlsx|csv, фильтры group_id, teacher_id, shift)
+func (h *Handlers) ExportSchedules(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{}
+	if id, err := primitive.ObjectIDFromHex(c.Query("group_id")); err == nil {
+		filter["group_id"] = id
+	}
+	if id, err := primitive.ObjectIDFromHex(c.Query("teacher_id")); err == nil {
+		filter["teacher_id"] = id
+	}
+	if shift, err := strconv.Atoi(c.Query("shift")); err == nil && (shift == 1 || shift == 2) {
+		filter["shift"] = shift
+	}
+
+	var schedules []models.Schedule
+	if !findAll(c, h.store.Schedules, filter, &schedules, "Ошибка получения расписания") {
+		return
+	}
+
+	lookups, err := h.loadNameLookups(c.Request.Context(), nil, schedules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения справочников"})
+		return
+	}
+
+	cells := make([]export.MatrixCell, 0, len(schedules))
+	for _, schedule := range schedules {
+		cells = append(cells, export.MatrixCell{
+			Group:     lookups.groupName(schedule.GroupID),
+			DayOfWeek: schedule.DayOfWeek,
+			StartTime: schedule.StartTime,
+			EndTime:   schedule.EndTime,
+			Text:      lookups.subjectCode(schedule.SubjectID) + " / " + lookups.teacherName(schedule.TeacherID) + " / " + schedule.Room,
+		})
+	}
+
+	sheet := export.ScheduleMatrix("Расписание", cells)
+	sheet.Title = "Расписание занятий"
+	respondExport(c, format, "schedule", []export.Sheet{sheet})
+}
+
+// ExportLessons выгружает уроки таблицей с теми же фильтрами, что и GetLessons
+func (h *Handlers) ExportLessons(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	var lessons []models.Lesson
+	if !findAll(c, h.store.Lessons, lessonFilter(c), &lessons, "Ошибка получения уроков") {
+		return
+	}
+
+	sort.SliceStable(lessons, func(i, j int) bool {
+		a, b := lessons[i], lessons[j]
+		if a.Date != nil && b.Date != nil && !a.Date.Equal(*b.Date) {
+			return a.Date.Before(*b.Date)
+		}
+		return a.StartTime < b.StartTime
+	})
+
+	lookups, err := h.loadNameLookups(c.Request.Context(), lessons, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения справочников"})
+		return
+	}
+
+	rows := [][]string{{"Дата", "День", "Время", "Группа", "Код", "Предмет", "Преподаватель", "Аудитория", "Смена", "Описание"}}
+	for _, lesson := range lessons {
+		date, day := "", ""
+		if lesson.Date != nil {
+			date = lesson.Date.Format("02.01.2006")
+			day = export.DayNames[models.DayOfWeek(*lesson.Date)]
+		}
+		shift := ""
+		if lesson.Shift != 0 {
+			shift = strconv.Itoa(lesson.Shift)
+		}
+
+		rows = append(rows, []string{
+			date,
+			day,
+			lesson.StartTime + "-" + lesson.EndTime,
+			lookups.groupLabel(lesson.GroupID, lesson.SubgroupID),
+			lookups.subjectCode(lesson.SubjectID),
+			lookups.subjectName(lesson.SubjectID),
+			lookups.teacherName(lesson.TeacherID),
+			lesson.Room,
+			shift,
+			lesson.Description,
+		})
+	}
+
+	respondExport(c, format, "lessons", []export.Sheet{{Name: "Уроки", Title: "Уроки", Rows: rows}})
+}
+
+// ExportLessonStatistics выгружает статистику уроков с теми же параметрами, что и GetLessonStatistics
+func (h *Handlers) ExportLessonStatistics(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	statistics, ok := h.collectLessonStatistics(c)
+	if !ok {
+		return
+	}
+
+	period := fmt.Sprintf("Статистика уроков за период %s - %s", statistics.Period.StartDate, statistics.Period.EndDate)
+	summary := [][]string{
+		{"Показатель", "Количество"},
+		{"Всего уроков", strconv.FormatInt(statistics.TotalLessons, 10)},
+		{"Первая смена", strconv.FormatInt(statistics.ByShift.FirstShift, 10)},
+		{"Вторая смена", strconv.FormatInt(statistics.ByShift.SecondShift, 10)},
+		{"Запланировано", strconv.Itoa(statistics.ByStatus.Planned)},
+		{"Проведено", strconv.Itoa(statistics.ByStatus.Conducted)},
+		{"Отменено", strconv.Itoa(statistics.ByStatus.Cancelled)},
+		{"Перенесено", strconv.Itoa(statistics.ByStatus.Rescheduled)},
+	}
+
+	byDay := [][]string{{"День недели", "Уроков"}}
+	for day := 1; day < len(export.DayNames); day++ {
+		byDay = append(byDay, []string{export.DayNames[day], strconv.Itoa(statistics.ByDayOfWeek[export.DayNames[day]])})
+	}
+
+	sheets := []export.Sheet{
+		{Name: "Итоги", Title: period, Rows: summary},
+		{Name: "По дням", Title: "По дням недели", Rows: byDay},
+		{Name: "Преподаватели", Title: "Преподаватели с наибольшим числом уроков", Rows: rankedRows("Преподаватель", statistics.TopTeachers)},
+		{Name: "Группы", Title: "Группы с наибольшим числом уроков", Rows: rankedRows("Группа", statistics.TopGroups)},
+		{Name: "Статусы преподавателей", Title: "Уроки преподавателей по статусам", Rows: statusRows("Преподаватель", statistics.ByTeacher)},
+		{Name: "Статусы групп", Title: "Уроки групп по статусам", Rows: statusRows("Группа", statistics.ByGroup)},
+	}
+
+	respondExport(c, format, "statistics", sheets)
+}
+
+func rankedRows(title string, items []rankedItem) [][]string {
+	rows := [][]string{{"№", title, "Уроков"}}
+	for i, item := range items {
+		rows = append(rows, []string{strconv.Itoa(i + 1), item.Name, strconv.Itoa(item.Count)})
+	}
+	return rows
+}
+
+func statusRows(title string, items []statusItem) [][]string {
+	rows := [][]string{{title, "Запланировано", "Проведено", "Отменено", "Перенесено"}}
+	for _, item := range items {
+		rows = append(rows, []string{
+			item.Name,
+			strconv.Itoa(item.Planned),
+			strconv.Itoa(item.Conducted),
+			strconv.Itoa(item.Cancelled),
+			strconv.Itoa(item.Rescheduled),
+		})
+	}
+	return rows
+}
+
+// exportFormat разбирает параметр format (по умолчанию xlsx) и отвечает 400 при ошибке
+func exportFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", export.FormatXLSX)
+	if format != export.FormatXLSX && format != export.FormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат выгрузки (xlsx или csv)"})
+		return "", false
+	}
+	return format, true
+}
+
+// respondExport отдает файл выгрузки с именем вида schedule-2024-09-02.xlsx
+func respondExport(c *gin.Context, format, name string, sheets []export.Sheet) {
+	var buf bytes.Buffer
+	if err := export.Write(&buf, format, sheets); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка формирования файла"})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("2006-01-02"), format)
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, export.ContentType(format), buf.Bytes())
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxGenerationDays ограничивает период генерации одним учебным годом
+const maxGenerationDays = 366
+
+// GenerateLessons создает уроки на каждую учебную дату периода по записям недельного расписания.
+// Повторный вызов не создает дубликатов: даты, на которые урок уже есть, пропускаются.
+// Урок в корзине тоже считается созданным: удаленный администратором урок не появляется
+// снова, а его восстановление не дает дубликата.
+// Выходные, праздники и каникулы берутся из академического календаря
+func (h *Handlers) GenerateLessons(c *gin.Context) {
+	var req models.GenerateLessonsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, end, ok := parseDateRange(c, req.StartDate, req.EndDate)
+	if !ok {
+		return
+	}
+	if end.Sub(start) > maxGenerationDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Период генерации не может превышать один год"})
+		return
+	}
+
+	// Фильтр записей расписания
+	scheduleFilter := bson.M{}
+	if req.GroupID != "" {
+		id, err := primitive.ObjectIDFromHex(req.GroupID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID группы"})
+			return
+		}
+		scheduleFilter["group_id"] = id
+	}
+	if req.TeacherID != "" {
+		id, err := primitive.ObjectIDFromHex(req.TeacherID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID преподавателя"})
+			return
+		}
+		scheduleFilter["teacher_id"] = id
+	}
+
+	schedules, err := h.store.Schedules.Find(c.Request.Context(), scheduleFilter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения расписания"})
+		return
+	}
+
+	// Записи расписания по дням недели
+	byDay := make(map[int][]models.Schedule)
+	for _, schedule := range schedules {
+		byDay[schedule.DayOfWeek] = append(byDay[schedule.DayOfWeek], schedule)
+	}
+
+	// Праздники, каникулы и переносы рабочих дней
+	cal, err := h.loadCalendar()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки календаря"})
+		return
+	}
+
+	// Уже существующие уроки периода
+	existing, err := h.materializedLessons(c.Request.Context(), start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения уроков"})
+		return
+	}
+
+	created := []models.Lesson{}
+	skipped := 0
+	nonWorkingDays := 0
+
+	for date := start; !date.After(end); date = date.AddDate(0, 0, 1) {
+		day := cal.Describe(date)
+		if !day.Working {
+			nonWorkingDays++
+			continue
+		}
+
+		// В перенесенный рабочий день занятия идут по расписанию другого дня недели
+		for _, schedule := range byDay[day.TimetableOf] {
+			if existing.has(schedule, date) {
+				skipped++
+				continue
+			}
+
+			lessonDate := date
+			lesson := models.Lesson{
+				GroupID:     schedule.GroupID,
+				SubgroupID:  schedule.SubgroupID,
+				TeacherID:   schedule.TeacherID,
+				SubjectID:   schedule.SubjectID,
+				RoomID:      schedule.RoomID,
+				Room:        schedule.Room,
+				Date:        &lessonDate,
+				StartTime:   schedule.StartTime,
+				EndTime:     schedule.EndTime,
+				Shift:       schedule.Shift,
+				Description: schedule.Description,
+				ScheduleID:  schedule.ID,
+				Status:      models.LessonPlanned,
+				CreatedAt:   time.Now(),
+				UpdatedAt:   time.Now(),
+			}
+
+			id, err := h.store.Lessons.Insert(c.Request.Context(), lesson)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Ошибка создания урока",
+					"created": len(created),
+				})
+				return
+			}
+
+			lesson.ID = id
+			existing.add(lesson)
+			created = append(created, lesson)
+		}
+	}
+
+	// Одно событие на весь период: подписчикам не нужно по запросу на каждый урок
+	if len(created) > 0 {
+		h.publishGeneratedLessons(c.Request.Context(), start, end, created)
+	}
+
+	// non_working_days - даты периода, на которые уроки не создавались (выходные, праздники, каникулы)
+	c.JSON(http.StatusOK, gin.H{
+		"created":          len(created),
+		"skipped":          skipped,
+		"non_working_days": nonWorkingDays,
+		"lessons":          created,
+	})
+}
+
+// lessonIndex множество уже созданных уроков для проверки идемпотентности
+type lessonIndex struct {
+	bySchedule map[string]bool // schedule_id + дата
+	bySlot     map[string]bool // group_id + subgroup_id + дата + время начала (уроки, созданные вручную)
+}
+
+// materializedLessons уроки периода, включая уроки в корзине
+func (h *Handlers) materializedLessons(ctx context.Context, start, end time.Time) (*lessonIndex, error) {
+	filter := bson.M{
+		"date": bson.M{
+			"$gte": start,
+			"$lt":  end.AddDate(0, 0, 1),
+		},
+	}
+
+	lessons, err := h.store.Lessons.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if bin := storage.BinOf(h.store.Lessons); bin != nil {
+		deleted, err := bin.FindDeleted(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		lessons = append(lessons, deleted...)
+	}
+
+	index := &lessonIndex{bySchedule: make(map[string]bool), bySlot: make(map[string]bool)}
+	for _, lesson := range lessons {
+		index.add(lesson)
+	}
+	return index, nil
+}
+
+func (idx *lessonIndex) add(lesson models.Lesson) {
+	if lesson.Date == nil {
+		return
+	}
+	day := lesson.Date.Format("2006-01-02")
+	if !lesson.ScheduleID.IsZero() {
+		idx.bySchedule[lesson.ScheduleID.Hex()+day] = true
+		return
+	}
+	idx.bySlot[lesson.GroupID.Hex()+lesson.SubgroupID.Hex()+day+lesson.StartTime] = true
+}
+
+func (idx *lessonIndex) has(schedule models.Schedule, date time.Time) bool {
+	day := date.Format("2006-01-02")
+	return idx.bySchedule[schedule.ID.Hex()+day] || idx.bySlot[schedule.GroupID.Hex()+schedule.SubgroupID.Hex()+day+schedule.StartTime]
+}
@@ -0,0 +1,68 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Урок, удаленный в корзину, генерация не создает заново, а восстановление не дает дубликата
+func TestGenerateSkipsLessonsInTrash(t *testing.T) {
+	ctx := context.Background()
+	store := storage.WithSoftDelete(storage.NewMemoryStore(), func(context.Context) string { return "admin" })
+
+	groupID, _ := store.Groups.Insert(ctx, models.Group{Name: "ПО-31", Shift: 1})
+	teacherID, _ := store.Teachers.Insert(ctx, models.Teacher{IIN: "800000000001", FirstName: "Анна", LastName: "Иванова"})
+	subjectID, _ := store.Subjects.Insert(ctx, models.Subject{Name: "Физика", Code: "ФИЗ"})
+	for _, day := range []int{1, 3} {
+		if _, err := store.Schedules.Insert(ctx, models.Schedule{
+			GroupID: groupID, TeacherID: teacherID, SubjectID: subjectID,
+			DayOfWeek: day, StartTime: "08:00", EndTime: "09:20", Shift: 1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	api := newAdminAPI(t, store)
+	generate := func() (created, skipped int) {
+		t.Helper()
+		recorder := api.do(t, http.MethodPost, "/lessons/generate", `{"start_date": "2024-10-14", "end_date": "2024-10-20"}`)
+		var result struct {
+			Created int `json:"created"`
+			Skipped int `json:"skipped"`
+		}
+		if recorder.Code != http.StatusOK || json.Unmarshal(recorder.Body.Bytes(), &result) != nil {
+			t.Fatalf("генерация: статус %d: %s", recorder.Code, recorder.Body.String())
+		}
+		return result.Created, result.Skipped
+	}
+
+	if created, _ := generate(); created != 2 {
+		t.Fatalf("создано %d, ожидали 2", created)
+	}
+	lessons, err := store.Lessons.Find(ctx, bson.M{})
+	if err != nil || len(lessons) != 2 {
+		t.Fatalf("уроки: %d, %v", len(lessons), err)
+	}
+	deletedID := lessons[0].ID
+	if recorder := api.do(t, http.MethodDelete, "/lessons/"+deletedID.Hex(), ""); recorder.Code != http.StatusOK {
+		t.Fatalf("удаление урока: статус %d", recorder.Code)
+	}
+
+	if created, skipped := generate(); created != 0 || skipped != 2 {
+		t.Fatalf("повторная генерация: создано %d, пропущено %d", created, skipped)
+	}
+
+	if recorder := api.do(t, http.MethodPost, "/trash/lessons/"+deletedID.Hex()+"/restore", ""); recorder.Code != http.StatusOK {
+		t.Fatalf("восстановление урока: статус %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if count, _ := store.Lessons.Count(ctx, bson.M{}); count != 2 {
+		t.Fatalf("уроков после восстановления %d, ожидали 2", count)
+	}
+}
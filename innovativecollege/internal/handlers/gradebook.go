@@ -0,0 +1,513 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"innovativecollege/internal/audit"
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// gradeSummary количество оценок, средний балл и средние по видам оценок
+type gradeSummary struct {
+	Count   int                `json:"count"`
+	Average float64            `json:"average"`
+	ByType  map[string]float64 `json:"by_type,omitempty"` // current, midterm, exam
+}
+
+// journalLesson столбец журнала: урок предмета
+type journalLesson struct {
+	ID        primitive.ObjectID `json:"id"`
+	Date      time.Time          `json:"date"`
+	StartTime string             `json:"start_time"`
+	Topic     string             `json:"topic,omitempty"`
+}
+
+// journalRow строка журнала: студент и его оценки по ID урока
+type journalRow struct {
+	Student models.Student            `json:"student"`
+	Grades  map[string][]models.Grade `json:"grades"`
+	Summary gradeSummary              `json:"summary"`
+}
+
+// transcriptSubject оценки студента по одному предмету
+type transcriptSubject struct {
+	Subject models.Subject `json:"subject"`
+	Grades  []models.Grade `json:"grades"`
+	Summary gradeSummary   `json:"summary"`
+}
+
+// termAverages средние баллы студента за семестр по предметам
+type termAverages struct {
+	Student  models.Student          `json:"student"`
+	Subjects map[string]gradeSummary `json:"subjects"` // По ID предмета
+	Average  float64                 `json:"average"`  // Среднее из средних по предметам
+}
+
+// ========== ЖУРНАЛ ОЦЕНОК ==========
+
+// GradeLesson выставляет оценки студентам группы за урок. Преподаватель ставит оценки
+// только на своих уроках. Повторная оценка того же вида заменяет прежнюю
+func (h *Handlers) GradeLesson(c *gin.Context) {
+	lesson, ok := h.teacherLesson(c, "Оценки ставит преподаватель урока")
+	if !ok {
+		return
+	}
+
+	var req models.GradeLessonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !markableLesson(c, lesson) {
+		return
+	}
+
+	studentFilter, err := h.lessonStudentFilter(c.Request.Context(), lesson)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов группы"})
+		return
+	}
+	students, err := h.store.Students.Find(c.Request.Context(), studentFilter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов группы"})
+		return
+	}
+	inGroup := make(map[primitive.ObjectID]bool, len(students))
+	for _, student := range students {
+		inGroup[student.ID] = true
+	}
+
+	existing, err := h.store.Grades.Find(c.Request.Context(), bson.M{"lesson_id": lesson.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения оценок"})
+		return
+	}
+	type gradeKey struct {
+		student primitive.ObjectID
+		kind    string
+	}
+	byKey := make(map[gradeKey]models.Grade, len(existing))
+	for _, grade := range existing {
+		byKey[gradeKey{grade.StudentID, grade.Type}] = grade
+	}
+
+	// Сначала проверяем все оценки, чтобы не сохранить запрос частично
+	marks := make(map[gradeKey]models.GradeMark, len(req.Grades))
+	for _, mark := range req.Grades {
+		studentID, err := primitive.ObjectIDFromHex(mark.StudentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID студента: " + mark.StudentID})
+			return
+		}
+		if !inGroup[studentID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Студент не учится в группе урока: " + mark.StudentID})
+			return
+		}
+		if mark.Value < h.cfg.GradeMin || mark.Value > h.cfg.GradeMax {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Оценка должна быть от %d до %d", h.cfg.GradeMin, h.cfg.GradeMax)})
+			return
+		}
+		if mark.Type == "" {
+			mark.Type = models.GradeCurrent
+		}
+		marks[gradeKey{studentID, mark.Type}] = mark
+	}
+
+	gradedBy := audit.ActorFrom(c.Request.Context()).Login
+	now := time.Now()
+	var created []models.Grade
+	for key, mark := range marks {
+		grade, found := byKey[key]
+		if !found {
+			created = append(created, models.Grade{
+				LessonID:  lesson.ID,
+				StudentID: key.student,
+				GroupID:   lesson.GroupID,
+				SubjectID: lesson.SubjectID,
+				TeacherID: lesson.TeacherID,
+				Date:      truncateToDay(*lesson.Date),
+				Type:      mark.Type,
+				Value:     mark.Value,
+				Comment:   mark.Comment,
+				GradedBy:  gradedBy,
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+			continue
+		}
+		if grade.Value == mark.Value && grade.Comment == mark.Comment {
+			continue
+		}
+		_, err := h.store.Grades.Update(c.Request.Context(), bson.M{"_id": grade.ID}, bson.M{"$set": bson.M{
+			"value":      mark.Value,
+			"comment":    mark.Comment,
+			"teacher_id": lesson.TeacherID,
+			"graded_by":  gradedBy,
+			"updated_at": now,
+		}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения оценок"})
+			return
+		}
+	}
+	if len(created) > 0 {
+		if _, err := h.store.Grades.InsertMany(c.Request.Context(), created); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения оценок"})
+			return
+		}
+	}
+
+	h.respondLessonGrades(c, lesson)
+}
+
+// GetLessonGrades получает оценки за урок
+func (h *Handlers) GetLessonGrades(c *gin.Context) {
+	lesson, ok := h.teacherLesson(c, "Оценки видит преподаватель урока")
+	if !ok {
+		return
+	}
+
+	h.respondLessonGrades(c, lesson)
+}
+
+// DeleteGrade удаляет оценку. Преподаватель удаляет только оценки своих уроков
+func (h *Handlers) DeleteGrade(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID оценки"})
+		return
+	}
+
+	grade, err := h.store.Grades.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Оценка не найдена"})
+		return
+	}
+
+	if claims := auth.GetClaims(c); claims != nil && claims.Role == auth.RoleTeacher && grade.TeacherID.Hex() != claims.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Можно удалять только оценки своих уроков"})
+		return
+	}
+
+	if _, err := h.store.Grades.Delete(c.Request.Context(), bson.M{"_id": id}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления оценки"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Оценка успешно удалена"})
+}
+
+// GetGroupJournal журнал группы по предмету (subject_id обязателен) за период:
+// столбцы - уроки по датам, строки - студенты с оценками и средним баллом
+func (h *Handlers) GetGroupJournal(c *gin.Context) {
+	groupID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID группы"})
+		return
+	}
+	subjectID, err := primitive.ObjectIDFromHex(c.Query("subject_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите предмет (subject_id)"})
+		return
+	}
+
+	group, err := h.store.Groups.FindByID(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Группа не найдена"})
+		return
+	}
+	subject, err := h.store.Subjects.FindByID(c.Request.Context(), subjectID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Предмет не найден"})
+		return
+	}
+
+	period, start, end, ok := h.reportPeriod(c)
+	if !ok {
+		return
+	}
+	dates := bson.M{"$gte": start, "$lt": end.AddDate(0, 0, 1)}
+
+	lessons, err := h.store.Lessons.Find(c.Request.Context(), bson.M{
+		"group_id":   groupID,
+		"subject_id": subjectID,
+		"date":       dates,
+		"status":     bson.M{"$nin": inactiveLessonStatuses},
+	}, storage.FindOptions{Sort: bson.D{{Key: "date", Value: 1}, {Key: "start_time", Value: 1}}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения уроков"})
+		return
+	}
+
+	students, err := h.store.Students.Find(c.Request.Context(), bson.M{"group_id": groupID}, storage.FindOptions{
+		Sort: bson.D{{Key: "last_name", Value: 1}, {Key: "first_name", Value: 1}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов группы"})
+		return
+	}
+
+	grades, err := h.store.Grades.Find(c.Request.Context(), bson.M{
+		"group_id":   groupID,
+		"subject_id": subjectID,
+		"date":       dates,
+	}, storage.FindOptions{Sort: bson.D{{Key: "date", Value: 1}}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения оценок"})
+		return
+	}
+	byStudent := make(map[primitive.ObjectID][]models.Grade)
+	for _, grade := range grades {
+		byStudent[grade.StudentID] = append(byStudent[grade.StudentID], grade)
+	}
+
+	columns := make([]journalLesson, len(lessons))
+	for i, lesson := range lessons {
+		columns[i] = journalLesson{ID: lesson.ID, Date: *lesson.Date, StartTime: lesson.StartTime, Topic: lesson.Topic}
+	}
+
+	rows := make([]journalRow, 0, len(students))
+	for _, student := range students {
+		row := journalRow{Student: student, Grades: make(map[string][]models.Grade)}
+		for _, grade := range byStudent[student.ID] {
+			row.Grades[grade.LessonID.Hex()] = append(row.Grades[grade.LessonID.Hex()], grade)
+		}
+		row.Summary = summarizeGrades(byStudent[student.ID])
+		rows = append(rows, row)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group":    group,
+		"subject":  subject,
+		"period":   period,
+		"lessons":  columns,
+		"students": rows,
+	})
+}
+
+// GetStudentTranscript ведомость оценок студента за период по предметам.
+// Студент видит только свою ведомость
+func (h *Handlers) GetStudentTranscript(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID студента"})
+		return
+	}
+	if claims := auth.GetClaims(c); claims != nil && claims.Role == auth.RoleStudent && claims.UserID != id.Hex() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Можно смотреть только свои оценки"})
+		return
+	}
+
+	student, err := h.store.Students.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Студент не найден"})
+		return
+	}
+
+	period, start, end, ok := h.reportPeriod(c)
+	if !ok {
+		return
+	}
+
+	grades, err := h.store.Grades.Find(c.Request.Context(), bson.M{
+		"student_id": id,
+		"date":       bson.M{"$gte": start, "$lt": end.AddDate(0, 0, 1)},
+	}, storage.FindOptions{Sort: bson.D{{Key: "date", Value: 1}}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения оценок"})
+		return
+	}
+
+	bySubject := make(map[primitive.ObjectID][]models.Grade)
+	var subjectIDs []primitive.ObjectID
+	for _, grade := range grades {
+		if _, ok := bySubject[grade.SubjectID]; !ok {
+			subjectIDs = append(subjectIDs, grade.SubjectID)
+		}
+		bySubject[grade.SubjectID] = append(bySubject[grade.SubjectID], grade)
+	}
+
+	related := h.newRelations()
+	if err := related.subjects.load(c.Request.Context(), subjectIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения предметов"})
+		return
+	}
+
+	subjects := make([]transcriptSubject, 0, len(subjectIDs))
+	for _, subjectID := range subjectIDs {
+		subjects = append(subjects, transcriptSubject{
+			Subject: *scheduleSubject(related.subjects.get(subjectID), subjectID),
+			Grades:  bySubject[subjectID],
+			Summary: summarizeGrades(bySubject[subjectID]),
+		})
+	}
+	sort.Slice(subjects, func(i, j int) bool { return subjects[i].Subject.Name < subjects[j].Subject.Name })
+
+	c.JSON(http.StatusOK, gin.H{
+		"student":  student,
+		"period":   period,
+		"summary":  summarizeGrades(grades),
+		"subjects": subjects,
+	})
+}
+
+// GetTermAverages средние баллы студентов группы (group_id обязателен) по предметам
+// за семестр term_id, по умолчанию текущий
+func (h *Handlers) GetTermAverages(c *gin.Context) {
+	groupID, err := primitive.ObjectIDFromHex(c.Query("group_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите группу (group_id)"})
+		return
+	}
+	group, err := h.store.Groups.FindByID(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Группа не найдена"})
+		return
+	}
+
+	var term *models.Term
+	if termID := c.Query("term_id"); termID != "" {
+		id, err := primitive.ObjectIDFromHex(termID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID семестра"})
+			return
+		}
+		if term, err = h.store.Terms.FindByID(c.Request.Context(), id); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Семестр не найден"})
+			return
+		}
+	} else {
+		if term, err = h.currentTerm(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения семестра"})
+			return
+		}
+		if term == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Сейчас нет активного семестра, укажите term_id"})
+			return
+		}
+	}
+
+	grades, err := h.store.Grades.Find(c.Request.Context(), bson.M{
+		"group_id": groupID,
+		"date":     bson.M{"$gte": term.StartDate, "$lt": term.EndDate.AddDate(0, 0, 1)},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения оценок"})
+		return
+	}
+
+	students, err := h.store.Students.Find(c.Request.Context(), bson.M{"group_id": groupID}, storage.FindOptions{
+		Sort: bson.D{{Key: "last_name", Value: 1}, {Key: "first_name", Value: 1}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов группы"})
+		return
+	}
+
+	type cell struct{ student, subject primitive.ObjectID }
+	byCell := make(map[cell][]models.Grade)
+	var subjectIDs []primitive.ObjectID
+	seenSubjects := make(map[primitive.ObjectID]bool)
+	for _, grade := range grades {
+		byCell[cell{grade.StudentID, grade.SubjectID}] = append(byCell[cell{grade.StudentID, grade.SubjectID}], grade)
+		if !seenSubjects[grade.SubjectID] {
+			seenSubjects[grade.SubjectID] = true
+			subjectIDs = append(subjectIDs, grade.SubjectID)
+		}
+	}
+
+	related := h.newRelations()
+	if err := related.subjects.load(c.Request.Context(), subjectIDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения предметов"})
+		return
+	}
+	subjects := make([]models.Subject, 0, len(subjectIDs))
+	for _, subjectID := range subjectIDs {
+		subjects = append(subjects, *scheduleSubject(related.subjects.get(subjectID), subjectID))
+	}
+	sort.Slice(subjects, func(i, j int) bool { return subjects[i].Name < subjects[j].Name })
+
+	rows := make([]termAverages, 0, len(students))
+	for _, student := range students {
+		row := termAverages{Student: student, Subjects: make(map[string]gradeSummary)}
+		sum, count := 0.0, 0
+		for _, subjectID := range subjectIDs {
+			summary := summarizeGrades(byCell[cell{student.ID, subjectID}])
+			if summary.Count == 0 {
+				continue
+			}
+			row.Subjects[subjectID.Hex()] = summary
+			sum += summary.Average
+			count++
+		}
+		if count > 0 {
+			row.Average = roundAverage(sum / float64(count))
+		}
+		rows = append(rows, row)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"term":     term,
+		"group":    group,
+		"subjects": subjects,
+		"students": rows,
+	})
+}
+
+// respondLessonGrades отвечает оценками урока, упорядоченными по студентам
+func (h *Handlers) respondLessonGrades(c *gin.Context, lesson *models.Lesson) {
+	grades, err := h.store.Grades.Find(c.Request.Context(), bson.M{"lesson_id": lesson.ID}, storage.FindOptions{
+		Sort: bson.D{{Key: "student_id", Value: 1}, {Key: "type", Value: 1}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения оценок"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lesson_id": lesson.ID,
+		"group_id":  lesson.GroupID,
+		"date":      lesson.Date,
+		"grades":    grades,
+	})
+}
+
+// summarizeGrades считает количество оценок, средний балл и средние по видам
+func summarizeGrades(grades []models.Grade) gradeSummary {
+	summary := gradeSummary{Count: len(grades)}
+	if len(grades) == 0 {
+		return summary
+	}
+
+	total := 0
+	sums := make(map[string]int)
+	counts := make(map[string]int)
+	for _, grade := range grades {
+		total += grade.Value
+		sums[grade.Type] += grade.Value
+		counts[grade.Type]++
+	}
+
+	summary.Average = roundAverage(float64(total) / float64(len(grades)))
+	summary.ByType = make(map[string]float64, len(sums))
+	for kind, sum := range sums {
+		summary.ByType[kind] = roundAverage(float64(sum) / float64(counts[kind]))
+	}
+	return summary
+}
+
+// roundAverage округляет средний балл до сотых
+func roundAverage(value float64) float64 {
+	return math.Round(value*100) / 100
+}
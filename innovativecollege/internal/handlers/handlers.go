@@ -1,26 +1,36 @@
 package handlers
 
 import (
-	"context"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/config"
+	"innovativecollege/internal/events"
 	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+	"innovativecollege/internal/webhooks"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type Handlers struct {
-	db *mongo.Database
+	store    *storage.Store
+	cfg      *config.Config
+	auth     *auth.Manager
+	events   *events.Bus
+	webhooks *webhooks.Dispatcher
 }
 
-func New(db *mongo.Database) *Handlers {
-	return &Handlers{db: db}
+// New создает обработчики поверх набора репозиториев: storage.NewMongoStore в приложении
+// или storage.NewMemoryStore в тестах. Изменения расписания публикуются в bus;
+// без bus события не публикуются, без dispatcher недоступна проверка вебхуков
+func New(store *storage.Store, cfg *config.Config, authManager *auth.Manager, bus *events.Bus, dispatcher *webhooks.Dispatcher) *Handlers {
+	return &Handlers{store: store, cfg: cfg, auth: authManager, events: bus, webhooks: dispatcher}
 }
 
 // ========== ГРУППЫ ==========
@@ -36,43 +46,39 @@ func (h *Handlers) CreateGroup(c *gin.Context) {
 	group := models.Group{
 		Name:        req.Name,
 		Description: req.Description,
+		Shift:       req.Shift,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	collection := h.db.Collection("groups")
-	result, err := collection.InsertOne(context.Background(), group)
+	id, err := h.store.Groups.Insert(c.Request.Context(), group)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания группы"})
 		return
 	}
 
-	group.ID = result.InsertedID.(primitive.ObjectID)
+	group.ID = id
 	c.JSON(http.StatusCreated, group)
 }
 
 // GetGroups получает все группы
 func (h *Handlers) GetGroups(c *gin.Context) {
-	collection := h.db.Collection("groups")
-	cursor, err := collection.Find(context.Background(), bson.M{})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения групп"})
+	params, ok := parseListParams(c, []string{"name", "shift", "created_at"}, "name")
+	if !ok {
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var groups []models.Group
-	if err = cursor.All(context.Background(), &groups); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки групп"})
-		return
+	filter := bson.M{}
+	if shift, err := strconv.Atoi(c.Query("shift")); err == nil && (shift == 1 || shift == 2) {
+		filter["shift"] = shift
 	}
 
-	// Если нет групп, возвращаем пустой массив вместо null
-	if groups == nil {
-		groups = []models.Group{}
+	groups, total, ok := findPage(c, h.store.Groups, filter, params, "Ошибка получения групп")
+	if !ok {
+		return
 	}
 
-	c.JSON(http.StatusOK, groups)
+	respondList(c, groups, total, params)
 }
 
 // UpdateGroup обновляет группу
@@ -91,10 +97,7 @@ func (h *Handlers) UpdateGroup(c *gin.Context) {
 	}
 
 	// Проверяем существование группы
-	collection := h.db.Collection("groups")
-	var existingGroup models.Group
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&existingGroup)
-	if err != nil {
+	if _, err := h.store.Groups.FindByID(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Группа не найдена"})
 		return
 	}
@@ -107,17 +110,19 @@ func (h *Handlers) UpdateGroup(c *gin.Context) {
 	if req.Description != "" {
 		update["description"] = req.Description
 	}
+	if req.Shift != nil {
+		update["shift"] = *req.Shift
+	}
 
 	// Обновляем группу
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
+	_, err = h.store.Groups.Update(c.Request.Context(), bson.M{"_id": id}, bson.M{"$set": update})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления группы"})
 		return
 	}
 
 	// Получаем обновленную группу
-	var updatedGroup models.Group
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&updatedGroup)
+	updatedGroup, err := h.store.Groups.FindByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленной группы"})
 		return
@@ -136,25 +141,20 @@ func (h *Handlers) DeleteGroup(c *gin.Context) {
 	}
 
 	// Проверяем существование группы
-	collection := h.db.Collection("groups")
-	var group models.Group
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&group)
-	if err != nil {
+	if _, err := h.store.Groups.FindByID(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Группа не найдена"})
 		return
 	}
 
 	// Проверяем, есть ли студенты в этой группе
-	studentCollection := h.db.Collection("students")
-	studentCount, err := studentCollection.CountDocuments(context.Background(), bson.M{"group_id": id})
+	studentCount, err := h.store.Students.Count(c.Request.Context(), bson.M{"group_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки студентов"})
 		return
 	}
 
 	// Проверяем, есть ли уроки с этой группой
-	lessonCollection := h.db.Collection("lessons")
-	lessonCount, err := lessonCollection.CountDocuments(context.Background(), bson.M{"group_id": id})
+	lessonCount, err := h.store.Lessons.Count(c.Request.Context(), bson.M{"group_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки уроков"})
 		return
@@ -166,7 +166,7 @@ func (h *Handlers) DeleteGroup(c *gin.Context) {
 	}
 
 	// Удаляем группу
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	_, err = h.store.Groups.Delete(c.Request.Context(), bson.M{"_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления группы"})
 		return
@@ -193,39 +193,29 @@ func (h *Handlers) CreateSubject(c *gin.Context) {
 		UpdatedAt:   time.Now(),
 	}
 
-	collection := h.db.Collection("subjects")
-	result, err := collection.InsertOne(context.Background(), subject)
+	id, err := h.store.Subjects.Insert(c.Request.Context(), subject)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания предмета"})
 		return
 	}
 
-	subject.ID = result.InsertedID.(primitive.ObjectID)
+	subject.ID = id
 	c.JSON(http.StatusCreated, subject)
 }
 
 // GetSubjects получает все предметы
 func (h *Handlers) GetSubjects(c *gin.Context) {
-	collection := h.db.Collection("subjects")
-	cursor, err := collection.Find(context.Background(), bson.M{})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения предметов"})
+	params, ok := parseListParams(c, []string{"name", "code", "created_at"}, "name")
+	if !ok {
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var subjects []models.Subject
-	if err = cursor.All(context.Background(), &subjects); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки предметов"})
+	subjects, total, ok := findPage(c, h.store.Subjects, bson.M{}, params, "Ошибка получения предметов")
+	if !ok {
 		return
 	}
 
-	// Если нет предметов, возвращаем пустой массив вместо null
-	if subjects == nil {
-		subjects = []models.Subject{}
-	}
-
-	c.JSON(http.StatusOK, subjects)
+	respondList(c, subjects, total, params)
 }
 
 // UpdateSubject обновляет предмет
@@ -244,10 +234,7 @@ func (h *Handlers) UpdateSubject(c *gin.Context) {
 	}
 
 	// Проверяем существование предмета
-	collection := h.db.Collection("subjects")
-	var existingSubject models.Subject
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&existingSubject)
-	if err != nil {
+	if _, err := h.store.Subjects.FindByID(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Предмет не найден"})
 		return
 	}
@@ -265,15 +252,14 @@ func (h *Handlers) UpdateSubject(c *gin.Context) {
 	}
 
 	// Обновляем предмет
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
+	_, err = h.store.Subjects.Update(c.Request.Context(), bson.M{"_id": id}, bson.M{"$set": update})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления предмета"})
 		return
 	}
 
 	// Получаем обновленный предмет
-	var updatedSubject models.Subject
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&updatedSubject)
+	updatedSubject, err := h.store.Subjects.FindByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленного предмета"})
 		return
@@ -292,17 +278,13 @@ func (h *Handlers) DeleteSubject(c *gin.Context) {
 	}
 
 	// Проверяем существование предмета
-	collection := h.db.Collection("subjects")
-	var existingSubject models.Subject
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&existingSubject)
-	if err != nil {
+	if _, err := h.store.Subjects.FindByID(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Предмет не найден"})
 		return
 	}
 
 	// Проверяем, есть ли уроки с этим предметом
-	lessonCollection := h.db.Collection("lessons")
-	lessonCount, err := lessonCollection.CountDocuments(context.Background(), bson.M{"subject_id": id})
+	lessonCount, err := h.store.Lessons.Count(c.Request.Context(), bson.M{"subject_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки уроков"})
 		return
@@ -314,7 +296,7 @@ func (h *Handlers) DeleteSubject(c *gin.Context) {
 	}
 
 	// Удаляем предмет
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	_, err = h.store.Subjects.Delete(c.Request.Context(), bson.M{"_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления предмета"})
 		return
@@ -340,9 +322,7 @@ func (h *Handlers) CreateStudent(c *gin.Context) {
 		return
 	}
 
-	groupCollection := h.db.Collection("groups")
-	var group models.Group
-	err = groupCollection.FindOne(context.Background(), bson.M{"_id": groupID}).Decode(&group)
+	group, err := h.store.Groups.FindByID(c.Request.Context(), groupID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Группа не найдена"})
 		return
@@ -357,45 +337,39 @@ func (h *Handlers) CreateStudent(c *gin.Context) {
 		UpdatedAt: time.Now(),
 	}
 
-	collection := h.db.Collection("students")
-	result, err := collection.InsertOne(context.Background(), student)
+	id, err := h.store.Students.Insert(c.Request.Context(), student)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания студента"})
 		return
 	}
 
-	student.ID = result.InsertedID.(primitive.ObjectID)
-	student.Group = &group
+	student.ID = id
+	student.Group = group
 	c.JSON(http.StatusCreated, student)
 }
 
 // GetStudents получает всех студентов
 func (h *Handlers) GetStudents(c *gin.Context) {
-	collection := h.db.Collection("students")
-	cursor, err := collection.Find(context.Background(), bson.M{})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения студентов"})
+	params, ok := parseListParams(c, []string{"last_name", "first_name", "iin", "group_id", "created_at"}, "last_name,first_name")
+	if !ok {
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var students []models.Student
-	if err = cursor.All(context.Background(), &students); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки студентов"})
+	filter := bson.M{}
+	addObjectIDFilter(filter, "group_id", c.Query("group_id"))
+
+	students, total, ok := findPage(c, h.store.Students, filter, params, "Ошибка получения студентов")
+	if !ok {
 		return
 	}
 
 	// Загружаем информацию о группах
-	for i := range students {
-		groupCollection := h.db.Collection("groups")
-		var group models.Group
-		err = groupCollection.FindOne(context.Background(), bson.M{"_id": students[i].GroupID}).Decode(&group)
-		if err == nil {
-			students[i].Group = &group
-		}
+	if err := h.newRelations().populateStudents(c.Request.Context(), students); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения групп"})
+		return
 	}
 
-	c.JSON(http.StatusOK, students)
+	respondList(c, students, total, params)
 }
 
 // GetStudentSchedule получает расписание студента по ИИН
@@ -403,37 +377,28 @@ func (h *Handlers) GetStudentSchedule(c *gin.Context) {
 	iin := c.Param("iin")
 
 	// Находим студента по ИИН
-	studentCollection := h.db.Collection("students")
-	var student models.Student
-	err := studentCollection.FindOne(context.Background(), bson.M{"iin": iin}).Decode(&student)
+	student, err := h.store.Students.FindOne(c.Request.Context(), bson.M{"iin": iin})
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Студент не найден"})
 		return
 	}
 
-	// Получаем расписание группы студента
-	scheduleCollection := h.db.Collection("schedules")
-	cursor, err := scheduleCollection.Find(context.Background(), bson.M{"group_id": student.GroupID})
+	// Получаем расписание группы студента без занятий чужих подгрупп
+	filter, err := h.studentLessonFilter(c.Request.Context(), student)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения расписания"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var schedules []models.Schedule
-	if err = cursor.All(context.Background(), &schedules); err != nil {
-		// Если есть ошибка декодирования, возвращаем пустой массив
-		schedules = []models.Schedule{}
+	schedules, err := h.store.Schedules.Find(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения расписания"})
+		return
 	}
 
 	// Загружаем информацию о преподавателях
-	for i := range schedules {
-		teacherCollection := h.db.Collection("teachers")
-		var teacher models.Teacher
-		err = teacherCollection.FindOne(context.Background(), bson.M{"_id": schedules[i].TeacherID}).Decode(&teacher)
-		if err == nil {
-			schedules[i].Teacher = &teacher
-		}
+	if err := h.newRelations().populateScheduleTeachers(c.Request.Context(), schedules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения преподавателей"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -458,9 +423,7 @@ func (h *Handlers) UpdateStudent(c *gin.Context) {
 	}
 
 	// Проверяем существование студента
-	collection := h.db.Collection("students")
-	var existingStudent models.Student
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&existingStudent)
+	existingStudent, err := h.store.Students.FindByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Студент не найден"})
 		return
@@ -474,10 +437,7 @@ func (h *Handlers) UpdateStudent(c *gin.Context) {
 			return
 		}
 
-		groupCollection := h.db.Collection("groups")
-		var group models.Group
-		err = groupCollection.FindOne(context.Background(), bson.M{"_id": groupID}).Decode(&group)
-		if err != nil {
+		if _, err := h.store.Groups.FindByID(c.Request.Context(), groupID); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Группа не найдена"})
 			return
 		}
@@ -500,26 +460,30 @@ func (h *Handlers) UpdateStudent(c *gin.Context) {
 	}
 
 	// Обновляем студента
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
+	_, err = h.store.Students.Update(c.Request.Context(), bson.M{"_id": id}, bson.M{"$set": update})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления студента"})
 		return
 	}
 
+	// При переводе в другую группу студент выходит из подгрупп прежней
+	if groupID, ok := update["group_id"].(primitive.ObjectID); ok && groupID != existingStudent.GroupID {
+		if err := h.removeFromSubgroups(c.Request.Context(), existingStudent.GroupID, id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления подгрупп"})
+			return
+		}
+	}
+
 	// Получаем обновленного студента с информацией о группе
-	var updatedStudent models.Student
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&updatedStudent)
+	updatedStudent, err := h.store.Students.FindByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленного студента"})
 		return
 	}
 
 	// Загружаем информацию о группе
-	groupCollection := h.db.Collection("groups")
-	var group models.Group
-	err = groupCollection.FindOne(context.Background(), bson.M{"_id": updatedStudent.GroupID}).Decode(&group)
-	if err == nil {
-		updatedStudent.Group = &group
+	if group, err := h.store.Groups.FindByID(c.Request.Context(), updatedStudent.GroupID); err == nil {
+		updatedStudent.Group = group
 	}
 
 	c.JSON(http.StatusOK, updatedStudent)
@@ -535,16 +499,13 @@ func (h *Handlers) DeleteStudent(c *gin.Context) {
 	}
 
 	// Проверяем существование студента
-	collection := h.db.Collection("students")
-	var student models.Student
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&student)
-	if err != nil {
+	if _, err := h.store.Students.FindByID(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Студент не найден"})
 		return
 	}
 
 	// Удаляем студента
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	_, err = h.store.Students.Delete(c.Request.Context(), bson.M{"_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления студента"})
 		return
@@ -564,42 +525,38 @@ func (h *Handlers) CreateTeacher(c *gin.Context) {
 	}
 
 	teacher := models.Teacher{
-		IIN:       req.IIN,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Subjects:  req.Subjects,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		IIN:        req.IIN,
+		FirstName:  req.FirstName,
+		LastName:   req.LastName,
+		Subjects:   req.Subjects,
+		WeeklyLoad: req.WeeklyLoad,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}
 
-	collection := h.db.Collection("teachers")
-	result, err := collection.InsertOne(context.Background(), teacher)
+	id, err := h.store.Teachers.Insert(c.Request.Context(), teacher)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания преподавателя"})
 		return
 	}
 
-	teacher.ID = result.InsertedID.(primitive.ObjectID)
+	teacher.ID = id
 	c.JSON(http.StatusCreated, teacher)
 }
 
 // GetTeachers получает всех преподавателей
 func (h *Handlers) GetTeachers(c *gin.Context) {
-	collection := h.db.Collection("teachers")
-	cursor, err := collection.Find(context.Background(), bson.M{})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения преподавателей"})
+	params, ok := parseListParams(c, []string{"last_name", "first_name", "iin", "created_at"}, "last_name,first_name")
+	if !ok {
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var teachers []models.Teacher
-	if err = cursor.All(context.Background(), &teachers); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки преподавателей"})
+	teachers, total, ok := findPage(c, h.store.Teachers, bson.M{}, params, "Ошибка получения преподавателей")
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, teachers)
+	respondList(c, teachers, total, params)
 }
 
 // GetTeacherSchedule получает расписание преподавателя по ИИН
@@ -607,37 +564,23 @@ func (h *Handlers) GetTeacherSchedule(c *gin.Context) {
 	iin := c.Param("iin")
 
 	// Находим преподавателя по ИИН
-	teacherCollection := h.db.Collection("teachers")
-	var teacher models.Teacher
-	err := teacherCollection.FindOne(context.Background(), bson.M{"iin": iin}).Decode(&teacher)
+	teacher, err := h.store.Teachers.FindOne(c.Request.Context(), bson.M{"iin": iin})
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Преподаватель не найден"})
 		return
 	}
 
 	// Получаем расписание преподавателя
-	scheduleCollection := h.db.Collection("schedules")
-	cursor, err := scheduleCollection.Find(context.Background(), bson.M{"teacher_id": teacher.ID})
+	schedules, err := h.store.Schedules.Find(c.Request.Context(), bson.M{"teacher_id": teacher.ID})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения расписания"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var schedules []models.Schedule
-	if err = cursor.All(context.Background(), &schedules); err != nil {
-		// Если есть ошибка декодирования, возвращаем пустой массив
-		schedules = []models.Schedule{}
-	}
 
 	// Загружаем информацию о группах
-	for i := range schedules {
-		groupCollection := h.db.Collection("groups")
-		var group models.Group
-		err = groupCollection.FindOne(context.Background(), bson.M{"_id": schedules[i].GroupID}).Decode(&group)
-		if err == nil {
-			schedules[i].Group = &group
-		}
+	if err := h.newRelations().populateScheduleGroups(c.Request.Context(), schedules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения групп"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -662,10 +605,7 @@ func (h *Handlers) UpdateTeacher(c *gin.Context) {
 	}
 
 	// Проверяем существование преподавателя
-	collection := h.db.Collection("teachers")
-	var existingTeacher models.Teacher
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&existingTeacher)
-	if err != nil {
+	if _, err := h.store.Teachers.FindByID(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Преподаватель не найден"})
 		return
 	}
@@ -684,17 +624,19 @@ func (h *Handlers) UpdateTeacher(c *gin.Context) {
 	if req.Subjects != nil {
 		update["subjects"] = req.Subjects
 	}
+	if req.WeeklyLoad != nil {
+		update["weekly_load"] = *req.WeeklyLoad
+	}
 
 	// Обновляем преподавателя
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
+	_, err = h.store.Teachers.Update(c.Request.Context(), bson.M{"_id": id}, bson.M{"$set": update})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления преподавателя"})
 		return
 	}
 
 	// Получаем обновленного преподавателя
-	var updatedTeacher models.Teacher
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&updatedTeacher)
+	updatedTeacher, err := h.store.Teachers.FindByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленного преподавателя"})
 		return
@@ -713,25 +655,20 @@ func (h *Handlers) DeleteTeacher(c *gin.Context) {
 	}
 
 	// Проверяем существование преподавателя
-	collection := h.db.Collection("teachers")
-	var teacher models.Teacher
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&teacher)
-	if err != nil {
+	if _, err := h.store.Teachers.FindByID(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Преподаватель не найден"})
 		return
 	}
 
 	// Проверяем, есть ли расписания с этим преподавателем
-	scheduleCollection := h.db.Collection("schedules")
-	scheduleCount, err := scheduleCollection.CountDocuments(context.Background(), bson.M{"teacher_id": id})
+	scheduleCount, err := h.store.Schedules.Count(c.Request.Context(), bson.M{"teacher_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки расписаний"})
 		return
 	}
 
 	// Проверяем, есть ли уроки с этим преподавателем
-	lessonCollection := h.db.Collection("lessons")
-	lessonCount, err := lessonCollection.CountDocuments(context.Background(), bson.M{"teacher_id": id})
+	lessonCount, err := h.store.Lessons.Count(c.Request.Context(), bson.M{"teacher_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки уроков"})
 		return
@@ -743,7 +680,7 @@ func (h *Handlers) DeleteTeacher(c *gin.Context) {
 	}
 
 	// Удаляем преподавателя
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	_, err = h.store.Teachers.Delete(c.Request.Context(), bson.M{"_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления преподавателя"})
 		return
@@ -769,9 +706,7 @@ func (h *Handlers) CreateSchedule(c *gin.Context) {
 		return
 	}
 
-	groupCollection := h.db.Collection("groups")
-	var group models.Group
-	err = groupCollection.FindOne(context.Background(), bson.M{"_id": groupID}).Decode(&group)
+	group, err := h.store.Groups.FindByID(c.Request.Context(), groupID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Группа не найдена"})
 		return
@@ -784,9 +719,7 @@ func (h *Handlers) CreateSchedule(c *gin.Context) {
 		return
 	}
 
-	teacherCollection := h.db.Collection("teachers")
-	var teacher models.Teacher
-	err = teacherCollection.FindOne(context.Background(), bson.M{"_id": teacherID}).Decode(&teacher)
+	teacher, err := h.store.Teachers.FindByID(c.Request.Context(), teacherID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Преподаватель не найден"})
 		return
@@ -799,19 +732,35 @@ func (h *Handlers) CreateSchedule(c *gin.Context) {
 		return
 	}
 
-	subjectCollection := h.db.Collection("subjects")
-	var subject models.Subject
-	err = subjectCollection.FindOne(context.Background(), bson.M{"_id": subjectID}).Decode(&subject)
-	if err != nil {
+	if _, err := h.store.Subjects.FindByID(c.Request.Context(), subjectID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Предмет не найден"})
 		return
 	}
 
+	// Проверяем подгруппу
+	subgroupID, ok := h.resolveSubgroup(c, groupID, req.SubgroupID)
+	if !ok {
+		return
+	}
+
+	// Проверяем существование аудитории
+	if req.RoomID == "" && req.Room == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите аудиторию (room_id или room)"})
+		return
+	}
+	room, err := h.resolveRoom(req.RoomID, req.Room)
+	if err != nil {
+		respondRoomError(c, err)
+		return
+	}
+
 	schedule := models.Schedule{
 		GroupID:     groupID,
+		SubgroupID:  subgroupID,
 		TeacherID:   teacherID,
 		SubjectID:   subjectID,
-		Room:        req.Room,
+		RoomID:      room.ID,
+		Room:        room.Number,
 		DayOfWeek:   req.DayOfWeek,
 		StartTime:   req.StartTime,
 		EndTime:     req.EndTime,
@@ -821,78 +770,77 @@ func (h *Handlers) CreateSchedule(c *gin.Context) {
 		UpdatedAt:   time.Now(),
 	}
 
-	collection := h.db.Collection("schedules")
-	result, err := collection.InsertOne(context.Background(), schedule)
+	// Проверяем пересечения с существующим расписанием
+	conflicts, err := h.findScheduleConflicts(schedule)
+	if rejectConflicts(c, conflicts, err) {
+		return
+	}
+
+	// Проверяем доступность и пожелания преподавателя
+	if h.checkScheduleAvailability(c, &schedule) {
+		return
+	}
+
+	id, err := h.store.Schedules.Insert(c.Request.Context(), schedule)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания расписания"})
 		return
 	}
 
-	schedule.ID = result.InsertedID.(primitive.ObjectID)
-	schedule.Group = &group
-	schedule.Teacher = &teacher
+	schedule.ID = id
+	schedule.Group = group
+	schedule.Teacher = teacher
+	h.publishSchedule(c.Request.Context(), "created", schedule)
 	c.JSON(http.StatusCreated, schedule)
 }
 
 // GetSchedules получает все расписания
 func (h *Handlers) GetSchedules(c *gin.Context) {
-	collection := h.db.Collection("schedules")
-	cursor, err := collection.Find(context.Background(), bson.M{})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения расписания"})
+	params, ok := parseListParams(c, []string{"day_of_week", "start_time", "shift", "group_id", "teacher_id", "room", "created_at"}, "day_of_week,start_time")
+	if !ok {
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var schedules []models.Schedule
-	if err = cursor.All(context.Background(), &schedules); err != nil {
-		// Если есть ошибка декодирования, возвращаем пустой массив
-		schedules = []models.Schedule{}
+	schedules, total, ok := findPage(c, h.store.Schedules, scheduleFilter(c), params, "Ошибка получения расписания")
+	if !ok {
+		return
 	}
 
-	// Загружаем информацию о группах и преподавателях
-	for i := range schedules {
-		// Группа
-		groupCollection := h.db.Collection("groups")
-		var group models.Group
-		err = groupCollection.FindOne(context.Background(), bson.M{"_id": schedules[i].GroupID}).Decode(&group)
-		if err == nil {
-			schedules[i].Group = &group
-		}
+	// Загружаем информацию о группах, преподавателях и предметах
+	if err := h.newRelations().populateSchedules(c.Request.Context(), schedules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных расписания"})
+		return
+	}
 
-		// Преподаватель
-		teacherCollection := h.db.Collection("teachers")
-		var teacher models.Teacher
-		err = teacherCollection.FindOne(context.Background(), bson.M{"_id": schedules[i].TeacherID}).Decode(&teacher)
-		if err == nil {
-			schedules[i].Teacher = &teacher
-		}
+	respondList(c, schedules, total, params)
+}
 
-		// Предмет - проверяем, что SubjectID не пустой
-		if !schedules[i].SubjectID.IsZero() {
-			subjectCollection := h.db.Collection("subjects")
-			var subject models.Subject
-			err = subjectCollection.FindOne(context.Background(), bson.M{"_id": schedules[i].SubjectID}).Decode(&subject)
-			if err == nil {
-				schedules[i].Subject = &subject
-			} else {
-				// Если предмет не найден, создаем пустой объект
-				schedules[i].Subject = &models.Subject{
-					ID:   schedules[i].SubjectID,
-					Name: "Предмет не найден",
-					Code: "Н/Д",
-				}
-			}
-		} else {
-			// Если SubjectID пустой, создаем пустой объект
-			schedules[i].Subject = &models.Subject{
-				Name: "Предмет не указан",
-				Code: "Н/Д",
-			}
-		}
+// scheduleFilter строит фильтр расписания по параметрам запроса: group_id, teacher_id,
+// subject_id, room_id, shift, day_of_week
+func scheduleFilter(c *gin.Context) bson.M {
+	filter := bson.M{}
+	addObjectIDFilter(filter, "group_id", c.Query("group_id"))
+	addObjectIDFilter(filter, "teacher_id", c.Query("teacher_id"))
+	addObjectIDFilter(filter, "subject_id", c.Query("subject_id"))
+	addObjectIDFilter(filter, "room_id", c.Query("room_id"))
+
+	if shift, err := strconv.Atoi(c.Query("shift")); err == nil && (shift == 1 || shift == 2) {
+		filter["shift"] = shift
+	}
+	if day, err := strconv.Atoi(c.Query("day_of_week")); err == nil && day >= 1 && day <= 7 {
+		filter["day_of_week"] = day
 	}
+	return filter
+}
 
-	c.JSON(http.StatusOK, schedules)
+// addObjectIDFilter добавляет в фильтр условие по ID, если параметр задан и корректен
+func addObjectIDFilter(filter bson.M, field, value string) {
+	if value == "" {
+		return
+	}
+	if id, err := primitive.ObjectIDFromHex(value); err == nil {
+		filter[field] = id
+	}
 }
 
 // GetSchedulesByDay получает расписание по дню недели
@@ -904,60 +852,16 @@ func (h *Handlers) GetSchedulesByDay(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.Collection("schedules")
-	cursor, err := collection.Find(context.Background(), bson.M{"day_of_week": day})
+	schedules, err := h.store.Schedules.Find(c.Request.Context(), bson.M{"day_of_week": day})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения расписания"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var schedules []models.Schedule
-	if err = cursor.All(context.Background(), &schedules); err != nil {
-		// Если есть ошибка декодирования, возвращаем пустой массив
-		schedules = []models.Schedule{}
-	}
 
-	// Загружаем информацию о группах и преподавателях
-	for i := range schedules {
-		// Группа
-		groupCollection := h.db.Collection("groups")
-		var group models.Group
-		err = groupCollection.FindOne(context.Background(), bson.M{"_id": schedules[i].GroupID}).Decode(&group)
-		if err == nil {
-			schedules[i].Group = &group
-		}
-
-		// Преподаватель
-		teacherCollection := h.db.Collection("teachers")
-		var teacher models.Teacher
-		err = teacherCollection.FindOne(context.Background(), bson.M{"_id": schedules[i].TeacherID}).Decode(&teacher)
-		if err == nil {
-			schedules[i].Teacher = &teacher
-		}
-
-		// Предмет - проверяем, что SubjectID не пустой
-		if !schedules[i].SubjectID.IsZero() {
-			subjectCollection := h.db.Collection("subjects")
-			var subject models.Subject
-			err = subjectCollection.FindOne(context.Background(), bson.M{"_id": schedules[i].SubjectID}).Decode(&subject)
-			if err == nil {
-				schedules[i].Subject = &subject
-			} else {
-				// Если предмет не найден, создаем пустой объект
-				schedules[i].Subject = &models.Subject{
-					ID:   schedules[i].SubjectID,
-					Name: "Предмет не найден",
-					Code: "Н/Д",
-				}
-			}
-		} else {
-			// Если SubjectID пустой, создаем пустой объект
-			schedules[i].Subject = &models.Subject{
-				Name: "Предмет не указан",
-				Code: "Н/Д",
-			}
-		}
+	// Загружаем информацию о группах, преподавателях и предметах
+	if err := h.newRelations().populateSchedules(c.Request.Context(), schedules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных расписания"})
+		return
 	}
 
 	c.JSON(http.StatusOK, schedules)
@@ -979,9 +883,7 @@ func (h *Handlers) UpdateSchedule(c *gin.Context) {
 	}
 
 	// Проверяем существование расписания
-	collection := h.db.Collection("schedules")
-	var existingSchedule models.Schedule
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&existingSchedule)
+	existingSchedule, err := h.store.Schedules.FindByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Расписание не найдено"})
 		return
@@ -995,10 +897,7 @@ func (h *Handlers) UpdateSchedule(c *gin.Context) {
 			return
 		}
 
-		groupCollection := h.db.Collection("groups")
-		var group models.Group
-		err = groupCollection.FindOne(context.Background(), bson.M{"_id": groupID}).Decode(&group)
-		if err != nil {
+		if _, err := h.store.Groups.FindByID(c.Request.Context(), groupID); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Группа не найдена"})
 			return
 		}
@@ -1012,10 +911,7 @@ func (h *Handlers) UpdateSchedule(c *gin.Context) {
 			return
 		}
 
-		teacherCollection := h.db.Collection("teachers")
-		var teacher models.Teacher
-		err = teacherCollection.FindOne(context.Background(), bson.M{"_id": teacherID}).Decode(&teacher)
-		if err != nil {
+		if _, err := h.store.Teachers.FindByID(c.Request.Context(), teacherID); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Преподаватель не найден"})
 			return
 		}
@@ -1029,10 +925,7 @@ func (h *Handlers) UpdateSchedule(c *gin.Context) {
 			return
 		}
 
-		subjectCollection := h.db.Collection("subjects")
-		var subject models.Subject
-		err = subjectCollection.FindOne(context.Background(), bson.M{"_id": subjectID}).Decode(&subject)
-		if err != nil {
+		if _, err := h.store.Subjects.FindByID(c.Request.Context(), subjectID); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Предмет не найден"})
 			return
 		}
@@ -1052,8 +945,14 @@ func (h *Handlers) UpdateSchedule(c *gin.Context) {
 		subjectID, _ := primitive.ObjectIDFromHex(req.SubjectID)
 		update["subject_id"] = subjectID
 	}
-	if req.Room != "" {
-		update["room"] = req.Room
+	if req.RoomID != "" || req.Room != "" {
+		room, err := h.resolveRoom(req.RoomID, req.Room)
+		if err != nil {
+			respondRoomError(c, err)
+			return
+		}
+		update["room_id"] = room.ID
+		update["room"] = room.Number
 	}
 	if req.DayOfWeek != nil {
 		if *req.DayOfWeek < 1 || *req.DayOfWeek > 7 {
@@ -1079,37 +978,73 @@ func (h *Handlers) UpdateSchedule(c *gin.Context) {
 		update["description"] = req.Description
 	}
 
+	// Проверяем пересечения с учетом изменений
+	candidate := *existingSchedule
+	if groupID, ok := update["group_id"].(primitive.ObjectID); ok {
+		candidate.GroupID = groupID
+	}
+	if teacherID, ok := update["teacher_id"].(primitive.ObjectID); ok {
+		candidate.TeacherID = teacherID
+	}
+	if roomID, ok := update["room_id"].(primitive.ObjectID); ok {
+		candidate.RoomID = roomID
+		candidate.Room = update["room"].(string)
+	}
+	if day, ok := update["day_of_week"].(int); ok {
+		candidate.DayOfWeek = day
+	}
+	if startTime, ok := update["start_time"].(string); ok {
+		candidate.StartTime = startTime
+	}
+	if endTime, ok := update["end_time"].(string); ok {
+		candidate.EndTime = endTime
+	}
+	if shift, ok := update["shift"].(int); ok {
+		candidate.Shift = shift
+	}
+	change := bson.M{"$set": update}
+	subgroupID, subgroupChanged, ok := h.updatedSubgroup(c, req.SubgroupID, candidate.GroupID, update["group_id"] != nil)
+	if !ok {
+		return
+	}
+	if subgroupChanged {
+		candidate.SubgroupID = subgroupID
+		subgroupChange(change, subgroupID)
+	}
+	conflicts, err := h.findScheduleConflicts(candidate)
+	if rejectConflicts(c, conflicts, err) {
+		return
+	}
+	if h.checkScheduleAvailability(c, &candidate) {
+		return
+	}
+
 	// Обновляем расписание
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
+	_, err = h.store.Schedules.Update(c.Request.Context(), bson.M{"_id": id}, change)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления расписания"})
 		return
 	}
 
 	// Получаем обновленное расписание с информацией о группе и преподавателе
-	var updatedSchedule models.Schedule
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&updatedSchedule)
+	updatedSchedule, err := h.store.Schedules.FindByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленного расписания"})
 		return
 	}
 
 	// Загружаем информацию о группе
-	groupCollection := h.db.Collection("groups")
-	var group models.Group
-	err = groupCollection.FindOne(context.Background(), bson.M{"_id": updatedSchedule.GroupID}).Decode(&group)
-	if err == nil {
-		updatedSchedule.Group = &group
+	if group, err := h.store.Groups.FindByID(c.Request.Context(), updatedSchedule.GroupID); err == nil {
+		updatedSchedule.Group = group
 	}
 
 	// Загружаем информацию о преподавателе
-	teacherCollection := h.db.Collection("teachers")
-	var teacher models.Teacher
-	err = teacherCollection.FindOne(context.Background(), bson.M{"_id": updatedSchedule.TeacherID}).Decode(&teacher)
-	if err == nil {
-		updatedSchedule.Teacher = &teacher
+	if teacher, err := h.store.Teachers.FindByID(c.Request.Context(), updatedSchedule.TeacherID); err == nil {
+		updatedSchedule.Teacher = teacher
 	}
 
+	h.publishSchedule(c.Request.Context(), "updated", *updatedSchedule)
+	updatedSchedule.Warnings = candidate.Warnings
 	c.JSON(http.StatusOK, updatedSchedule)
 }
 
@@ -1123,21 +1058,21 @@ func (h *Handlers) DeleteSchedule(c *gin.Context) {
 	}
 
 	// Проверяем существование расписания
-	collection := h.db.Collection("schedules")
-	var schedule models.Schedule
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&schedule)
+	schedule, err := h.store.Schedules.FindByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Расписание не найдено"})
 		return
 	}
 
 	// Удаляем расписание
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	_, err = h.store.Schedules.Delete(c.Request.Context(), bson.M{"_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления расписания"})
 		return
 	}
 
+	h.publishSchedule(c.Request.Context(), "deleted", *schedule)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Расписание успешно удалено"})
 }
 
@@ -1170,13 +1105,33 @@ func (h *Handlers) CreateLesson(c *gin.Context) {
 		return
 	}
 
+	// Проверяем подгруппу
+	subgroupID, ok := h.resolveSubgroup(c, groupID, req.SubgroupID)
+	if !ok {
+		return
+	}
+
+	// Проверяем существование аудитории
+	if req.RoomID == "" && req.Room == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите аудиторию (room_id или room)"})
+		return
+	}
+	room, err := h.resolveRoom(req.RoomID, req.Room)
+	if err != nil {
+		respondRoomError(c, err)
+		return
+	}
+
 	// Создаем урок с базовыми данными
 	lesson := models.Lesson{
 		GroupID:     groupID,
+		SubgroupID:  subgroupID,
 		TeacherID:   teacherID,
 		SubjectID:   subjectID,
-		Room:        req.Room,
+		RoomID:      room.ID,
+		Room:        room.Number,
 		Description: req.Description,
+		Status:      models.LessonPlanned,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
@@ -1206,21 +1161,57 @@ func (h *Handlers) CreateLesson(c *gin.Context) {
 		lesson.Shift = req.Shift
 	}
 
-	collection := h.db.Collection("lessons")
-	result, err := collection.InsertOne(context.Background(), lesson)
+	// Урок нельзя поставить на выходной, праздник или каникулы
+	if h.rejectNonWorkingDay(c, lesson.Date) {
+		return
+	}
+
+	// Проверяем пересечения с другими уроками в этот день
+	conflicts, err := h.findLessonConflicts(lesson)
+	if rejectConflicts(c, conflicts, err) {
+		return
+	}
+
+	// Проверяем доступность и пожелания преподавателя
+	if h.checkLessonAvailability(c, &lesson) {
+		return
+	}
+
+	id, err := h.store.Lessons.Insert(c.Request.Context(), lesson)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания урока"})
 		return
 	}
 
-	lesson.ID = result.InsertedID.(primitive.ObjectID)
+	lesson.ID = id
+	h.publishLesson(c.Request.Context(), models.EventLessonCreated, lesson)
 	c.JSON(http.StatusCreated, lesson)
 }
 
 // GetLessons получает все уроки
 func (h *Handlers) GetLessons(c *gin.Context) {
-	collection := h.db.Collection("lessons")
+	params, ok := parseListParams(c, []string{"date", "start_time", "shift", "group_id", "teacher_id", "subject_id", "room", "status", "created_at"}, "date,start_time")
+	if !ok {
+		return
+	}
 
+	lessons, total, ok := findPage(c, h.store.Lessons, lessonFilter(c), params, "Ошибка получения уроков")
+	if !ok {
+		return
+	}
+
+	// Заполняем связанные данные
+	if err := h.newRelations().populateLessons(c.Request.Context(), lessons); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных уроков"})
+		return
+	}
+
+	respondList(c, lessons, total, params)
+}
+
+// lessonFilter строит фильтр уроков по параметрам запроса: date или start_date/end_date,
+// group_id, teacher_id, subject_id, room_id, status, shift
+func lessonFilter(c *gin.Context) bson.M {
 	// Получаем параметры запроса
 	date := c.Query("date")
 	startDate := c.Query("start_date")
@@ -1273,51 +1264,20 @@ func (h *Handlers) GetLessons(c *gin.Context) {
 		}
 	}
 
-	if shift != "" {
-		if shiftNum, err := strconv.Atoi(shift); err == nil && (shiftNum == 1 || shiftNum == 2) {
-			filter["shift"] = shiftNum
-		}
-	}
-
-	cursor, err := collection.Find(context.Background(), filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения уроков"})
-		return
-	}
-	defer cursor.Close(context.Background())
-
-	var lessons []models.Lesson
+	addObjectIDFilter(filter, "subject_id", c.Query("subject_id"))
+	addObjectIDFilter(filter, "room_id", c.Query("room_id"))
 
-	if err = cursor.All(context.Background(), &lessons); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки уроков"})
-		return
+	if status := c.Query("status"); status != "" {
+		filter["status"] = lessonStatusCondition(status)
 	}
 
-	// Заполняем связанные данные
-	for i := range lessons {
-		// Получаем группу
-		groupCollection := h.db.Collection("groups")
-		var group models.Group
-		if err := groupCollection.FindOne(context.Background(), bson.M{"_id": lessons[i].GroupID}).Decode(&group); err == nil {
-			lessons[i].Group = &group
-		}
-
-		// Получаем преподавателя
-		teacherCollection := h.db.Collection("teachers")
-		var teacher models.Teacher
-		if err := teacherCollection.FindOne(context.Background(), bson.M{"_id": lessons[i].TeacherID}).Decode(&teacher); err == nil {
-			lessons[i].Teacher = &teacher
-		}
-
-		// Получаем предмет
-		subjectCollection := h.db.Collection("subjects")
-		var subject models.Subject
-		if err := subjectCollection.FindOne(context.Background(), bson.M{"_id": lessons[i].SubjectID}).Decode(&subject); err == nil {
-			lessons[i].Subject = &subject
+	if shift != "" {
+		if shiftNum, err := strconv.Atoi(shift); err == nil && (shiftNum == 1 || shiftNum == 2) {
+			filter["shift"] = shiftNum
 		}
 	}
 
-	c.JSON(http.StatusOK, lessons)
+	return filter
 }
 
 // GetLessonsByDate получает уроки по конкретной дате
@@ -1341,42 +1301,16 @@ func (h *Handlers) GetLessonsByDate(c *gin.Context) {
 		},
 	}
 
-	collection := h.db.Collection("lessons")
-	cursor, err := collection.Find(context.Background(), filter)
+	lessons, err := h.store.Lessons.Find(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения уроков"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var lessons []models.Lesson
-	if err = cursor.All(context.Background(), &lessons); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки уроков"})
-		return
-	}
 
 	// Заполняем связанные данные
-	for i := range lessons {
-		// Получаем группу
-		groupCollection := h.db.Collection("groups")
-		var group models.Group
-		if err := groupCollection.FindOne(context.Background(), bson.M{"_id": lessons[i].GroupID}).Decode(&group); err == nil {
-			lessons[i].Group = &group
-		}
-
-		// Получаем преподавателя
-		teacherCollection := h.db.Collection("teachers")
-		var teacher models.Teacher
-		if err := teacherCollection.FindOne(context.Background(), bson.M{"_id": lessons[i].TeacherID}).Decode(&teacher); err == nil {
-			lessons[i].Teacher = &teacher
-		}
-
-		// Получаем предмет
-		subjectCollection := h.db.Collection("subjects")
-		var subject models.Subject
-		if err := subjectCollection.FindOne(context.Background(), bson.M{"_id": lessons[i].SubjectID}).Decode(&subject); err == nil {
-			lessons[i].Subject = &subject
-		}
+	if err := h.newRelations().populateLessons(c.Request.Context(), lessons); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных уроков"})
+		return
 	}
 
 	c.JSON(http.StatusOK, lessons)
@@ -1397,13 +1331,10 @@ func (h *Handlers) UpdateLesson(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.Collection("lessons")
-
 	// Проверяем существование урока
-	var existingLesson models.Lesson
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&existingLesson)
+	existingLesson, err := h.store.Lessons.FindByID(c.Request.Context(), id)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == storage.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Урок не найден"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска урока"})
@@ -1411,6 +1342,25 @@ func (h *Handlers) UpdateLesson(c *gin.Context) {
 		return
 	}
 
+	// Преподаватель может менять только описание своих уроков
+	if claims := auth.GetClaims(c); claims != nil && claims.Role == auth.RoleTeacher {
+		if existingLesson.TeacherID.Hex() != claims.UserID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Можно редактировать только свои уроки"})
+			return
+		}
+		if req.GroupID != "" || req.SubgroupID != nil || req.TeacherID != "" || req.SubjectID != "" || req.RoomID != "" || req.Room != "" ||
+			req.Date != "" || req.StartTime != "" || req.EndTime != "" || req.Shift != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Преподаватель может изменять только описание урока"})
+			return
+		}
+	}
+
+	// Отмененный или перенесенный урок больше не занимает время: переносить его нельзя
+	if !existingLesson.Active() && (req.Date != "" || req.StartTime != "" || req.EndTime != "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Урок отменен или перенесен: дату и время изменить нельзя"})
+		return
+	}
+
 	// Подготавливаем обновления
 	update := bson.M{
 		"updated_at": time.Now(),
@@ -1443,8 +1393,14 @@ func (h *Handlers) UpdateLesson(c *gin.Context) {
 		update["subject_id"] = subjectID
 	}
 
-	if req.Room != "" {
-		update["room"] = req.Room
+	if req.RoomID != "" || req.Room != "" {
+		room, err := h.resolveRoom(req.RoomID, req.Room)
+		if err != nil {
+			respondRoomError(c, err)
+			return
+		}
+		update["room_id"] = room.ID
+		update["room"] = room.Number
 	}
 
 	if req.Date != "" {
@@ -1478,21 +1434,75 @@ func (h *Handlers) UpdateLesson(c *gin.Context) {
 		update["description"] = req.Description
 	}
 
+	// Проверяем пересечения с учетом изменений
+	candidate := *existingLesson
+	if groupID, ok := update["group_id"].(primitive.ObjectID); ok {
+		candidate.GroupID = groupID
+	}
+	if teacherID, ok := update["teacher_id"].(primitive.ObjectID); ok {
+		candidate.TeacherID = teacherID
+	}
+	if roomID, ok := update["room_id"].(primitive.ObjectID); ok {
+		candidate.RoomID = roomID
+		candidate.Room = update["room"].(string)
+	}
+	if date, ok := update["date"].(*time.Time); ok {
+		if h.rejectNonWorkingDay(c, date) {
+			return
+		}
+		candidate.Date = date
+	}
+	if startTime, ok := update["start_time"].(string); ok {
+		candidate.StartTime = startTime
+	}
+	if endTime, ok := update["end_time"].(string); ok {
+		candidate.EndTime = endTime
+	}
+	if shift, ok := update["shift"].(int); ok {
+		candidate.Shift = shift
+	}
+	change := bson.M{"$set": update}
+	subgroupID, subgroupChanged, ok := h.updatedSubgroup(c, req.SubgroupID, candidate.GroupID, update["group_id"] != nil)
+	if !ok {
+		return
+	}
+	if subgroupChanged {
+		candidate.SubgroupID = subgroupID
+		subgroupChange(change, subgroupID)
+	}
+	conflicts, err := h.findLessonConflicts(candidate)
+	if rejectConflicts(c, conflicts, err) {
+		return
+	}
+	if h.checkLessonAvailability(c, &candidate) {
+		return
+	}
+
 	// Обновляем урок
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": id}, bson.M{"$set": update})
+	_, err = h.store.Lessons.Update(c.Request.Context(), bson.M{"_id": id}, change)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления урока"})
 		return
 	}
 
 	// Получаем обновленный урок
-	var updatedLesson models.Lesson
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&updatedLesson)
+	updatedLesson, err := h.store.Lessons.FindByID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленного урока"})
 		return
 	}
 
+	_, dateChanged := update["date"]
+	_, startChanged := update["start_time"]
+	_, endChanged := update["end_time"]
+	_, roomChanged := update["room_id"]
+	if dateChanged || startChanged || endChanged || roomChanged {
+		h.publishLessonMoved(c.Request.Context(), *updatedLesson, *existingLesson)
+	} else {
+		h.publishLesson(c.Request.Context(), models.EventLessonUpdated, *updatedLesson)
+	}
+
+	updatedLesson.Warnings = candidate.Warnings
 	c.JSON(http.StatusOK, updatedLesson)
 }
 
@@ -1505,13 +1515,10 @@ func (h *Handlers) DeleteLesson(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.Collection("lessons")
-
 	// Проверяем существование урока
-	var existingLesson models.Lesson
-	err = collection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&existingLesson)
+	lesson, err := h.store.Lessons.FindByID(c.Request.Context(), id)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == storage.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Урок не найден"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска урока"})
@@ -1520,59 +1527,34 @@ func (h *Handlers) DeleteLesson(c *gin.Context) {
 	}
 
 	// Удаляем урок
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": id})
+	_, err = h.store.Lessons.Delete(c.Request.Context(), bson.M{"_id": id})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления урока"})
 		return
 	}
 
+	h.publishLesson(c.Request.Context(), models.EventLessonDeleted, *lesson)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Урок успешно удален"})
 }
 
 // GetAvailableLessons получает уроки без даты и времени (доступные для назначения)
 func (h *Handlers) GetAvailableLessons(c *gin.Context) {
-	collection := h.db.Collection("lessons")
-
 	// Фильтр для уроков без даты (доступные уроки)
 	filter := bson.M{
 		"date": bson.M{"$exists": false},
 	}
 
-	cursor, err := collection.Find(context.Background(), filter)
+	lessons, err := h.store.Lessons.Find(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения доступных уроков"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var lessons []models.Lesson
-	if err = cursor.All(context.Background(), &lessons); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки доступных уроков"})
-		return
-	}
 
 	// Заполняем связанные данные
-	for i := range lessons {
-		// Получаем группу
-		groupCollection := h.db.Collection("groups")
-		var group models.Group
-		if err := groupCollection.FindOne(context.Background(), bson.M{"_id": lessons[i].GroupID}).Decode(&group); err == nil {
-			lessons[i].Group = &group
-		}
-
-		// Получаем преподавателя
-		teacherCollection := h.db.Collection("teachers")
-		var teacher models.Teacher
-		if err := teacherCollection.FindOne(context.Background(), bson.M{"_id": lessons[i].TeacherID}).Decode(&teacher); err == nil {
-			lessons[i].Teacher = &teacher
-		}
-
-		// Получаем предмет
-		subjectCollection := h.db.Collection("subjects")
-		var subject models.Subject
-		if err := subjectCollection.FindOne(context.Background(), bson.M{"_id": lessons[i].SubjectID}).Decode(&subject); err == nil {
-			lessons[i].Subject = &subject
-		}
+	if err := h.newRelations().populateLessons(c.Request.Context(), lessons); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных уроков"})
+		return
 	}
 
 	c.JSON(http.StatusOK, lessons)
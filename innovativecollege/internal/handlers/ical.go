@@ -0,0 +1,588 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/calendar"
+	"innovativecollege/internal/ical"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errFeedNotFound владелец ленты (студент, преподаватель, группа или аудитория) не найден
+var errFeedNotFound = errors.New("feed owner not found")
+
+// feedLookbackDays сколько прошедших дней попадает в ленту, если текущий семестр не задан
+const feedLookbackDays = 30
+
+// GetStudentScheduleICS отдает расписание группы студента в формате iCalendar
+func (h *Handlers) GetStudentScheduleICS(c *gin.Context) {
+	student, err := h.store.Students.FindOne(c.Request.Context(), bson.M{"iin": c.Param("iin")})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Студент не найден"})
+		return
+	}
+
+	h.respondFeed(c, models.FeedStudent, student.ID)
+}
+
+// GetTeacherScheduleICS отдает расписание преподавателя в формате iCalendar
+func (h *Handlers) GetTeacherScheduleICS(c *gin.Context) {
+	teacher, err := h.store.Teachers.FindOne(c.Request.Context(), bson.M{"iin": c.Param("iin")})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Преподаватель не найден"})
+		return
+	}
+
+	h.respondFeed(c, models.FeedTeacher, teacher.ID)
+}
+
+// GetGroupScheduleICS отдает расписание группы в формате iCalendar
+func (h *Handlers) GetGroupScheduleICS(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID группы"})
+		return
+	}
+
+	h.respondFeed(c, models.FeedGroup, id)
+}
+
+// GetRoomScheduleICS отдает занятость аудитории в формате iCalendar
+func (h *Handlers) GetRoomScheduleICS(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аудитории"})
+		return
+	}
+
+	h.respondFeed(c, models.FeedRoom, id)
+}
+
+// GetSubscriptionFeed отдает .ics-ленту по секретному токену подписки (без авторизации)
+func (h *Handlers) GetSubscriptionFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	subscription, err := h.store.CalendarSubscriptions.FindOne(c.Request.Context(), bson.M{"token": token})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Подписка не найдена"})
+		return
+	}
+
+	// Время последнего обращения не критично для ленты: ошибку только записываем в лог
+	now := time.Now()
+	if _, err := h.store.CalendarSubscriptions.Update(c.Request.Context(), bson.M{"_id": subscription.ID}, bson.M{"$set": bson.M{"last_accessed_at": now}}); err != nil {
+		log.Printf("Не удалось обновить время обращения к подписке %s: %v", subscription.ID.Hex(), err)
+	}
+
+	h.respondFeed(c, subscription.FeedType, subscription.FeedID)
+}
+
+// CreateCalendarSubscription создает секретную ссылку на .ics-ленту.
+// Студент может подписаться только на свое расписание, на ленты групп, преподавателей
+// и аудиторий - любой авторизованный пользователь
+func (h *Handlers) CreateCalendarSubscription(c *gin.Context) {
+	var req models.CreateCalendarSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims := auth.GetClaims(c)
+
+	// Без параметров - собственное расписание студента или преподавателя
+	if req.FeedType == "" {
+		switch claims.Role {
+		case auth.RoleStudent:
+			req.FeedType = models.FeedStudent
+		case auth.RoleTeacher:
+			req.FeedType = models.FeedTeacher
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите тип ленты (feed_type) и ID (feed_id)"})
+			return
+		}
+		req.FeedID = claims.UserID
+	}
+
+	feedID, err := primitive.ObjectIDFromHex(req.FeedID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID ленты"})
+		return
+	}
+
+	if req.FeedType == models.FeedStudent && claims.Role != auth.RoleAdmin && claims.UserID != req.FeedID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Можно подписаться только на собственное расписание"})
+		return
+	}
+
+	if _, err := h.feedName(c.Request.Context(), req.FeedType, feedID); err != nil {
+		respondFeedError(c, err)
+		return
+	}
+
+	token, err := newSubscriptionToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания токена подписки"})
+		return
+	}
+
+	subscription := models.CalendarSubscription{
+		Token:     token,
+		FeedType:  req.FeedType,
+		FeedID:    feedID,
+		OwnerRole: claims.Role,
+		OwnerID:   claims.UserID,
+		CreatedAt: time.Now(),
+	}
+
+	id, err := h.store.CalendarSubscriptions.Insert(c.Request.Context(), subscription)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания подписки"})
+		return
+	}
+	subscription.ID = id
+
+	c.JSON(http.StatusCreated, gin.H{
+		"subscription": subscription,
+		"url":          subscriptionURL(c, token),
+	})
+}
+
+// GetCalendarSubscriptions возвращает подписки текущего пользователя (администратору - все)
+func (h *Handlers) GetCalendarSubscriptions(c *gin.Context) {
+	filter := bson.M{}
+	if claims := auth.GetClaims(c); claims.Role != auth.RoleAdmin {
+		filter["owner_role"] = claims.Role
+		filter["owner_id"] = claims.UserID
+	}
+
+	subscriptions := []models.CalendarSubscription{}
+	if !findAll(c, h.store.CalendarSubscriptions, filter, &subscriptions, "Ошибка получения подписок") {
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// DeleteCalendarSubscription отзывает подписку: ссылка перестает работать
+func (h *Handlers) DeleteCalendarSubscription(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID подписки"})
+		return
+	}
+
+	filter := bson.M{"_id": id}
+	if claims := auth.GetClaims(c); claims.Role != auth.RoleAdmin {
+		filter["owner_role"] = claims.Role
+		filter["owner_id"] = claims.UserID
+	}
+
+	deleted, err := h.store.CalendarSubscriptions.Delete(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления подписки"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Подписка не найдена"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Подписка успешно удалена"})
+}
+
+// respondFeed формирует ленту и отдает ее как text/calendar
+func (h *Handlers) respondFeed(c *gin.Context, feedType string, feedID primitive.ObjectID) {
+	feed, err := h.buildFeed(c.Request.Context(), feedType, feedID)
+	if err != nil {
+		respondFeedError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s-%s.ics"`, feedType, feedID.Hex()))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed.Render())
+}
+
+func respondFeedError(c *gin.Context, err error) {
+	if errors.Is(err, errFeedNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Расписание не найдено"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка формирования календаря"})
+}
+
+// buildFeed собирает ленту: уроки с датой - отдельными событиями, недельное расписание -
+// повторяющимися событиями в пределах текущего семестра
+func (h *Handlers) buildFeed(ctx context.Context, feedType string, feedID primitive.ObjectID) (*ical.Calendar, error) {
+	name, err := h.feedName(ctx, feedType, feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := h.feedFilter(ctx, feedType, feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	term, err := h.currentTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	// Уроки семестра, а без семестра - начиная с недавнего прошлого
+	lessonFilter := bson.M{}
+	for key, value := range filter {
+		lessonFilter[key] = value
+	}
+	if term != nil {
+		lessonFilter["date"] = bson.M{"$gte": term.StartDate, "$lt": term.EndDate.AddDate(0, 0, 1)}
+	} else {
+		lessonFilter["date"] = bson.M{"$gte": time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -feedLookbackDays)}
+	}
+
+	lessons, err := h.store.Lessons.Find(ctx, lessonFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedules []models.Schedule
+	if term != nil {
+		if schedules, err = h.store.Schedules.Find(ctx, filter); err != nil {
+			return nil, err
+		}
+	}
+
+	lookups, err := h.loadNameLookups(ctx, lessons, schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &ical.Calendar{Name: name, Timezone: h.cfg.Timezone}
+
+	// Даты, на которые урок уже создан из записи расписания: повторение на эти даты исключаем
+	materialized := make(map[string]bool)
+	for _, lesson := range lessons {
+		if lesson.Date == nil {
+			continue
+		}
+		start, end, ok := clockRange(*lesson.Date, lesson.StartTime, lesson.EndTime)
+		if !ok {
+			continue
+		}
+		if !lesson.ScheduleID.IsZero() {
+			materialized[lesson.ScheduleID.Hex()+lesson.Date.Format("2006-01-02")] = true
+		}
+
+		feed.Events = append(feed.Events, ical.Event{
+			UID:         "lesson-" + lesson.ID.Hex() + "@innovativecollege",
+			Summary:     lookups.summary(lesson.SubjectID),
+			Description: lookups.description(lesson.TeacherID, lesson.GroupID, lesson.SubgroupID, lesson.Description),
+			Location:    lookups.location(lesson.RoomID, lesson.Room),
+			Start:       start,
+			End:         end,
+			Cancelled:   !lesson.Active(), // Календарь покажет отмененный или перенесенный урок зачеркнутым
+		})
+	}
+
+	if term != nil && len(schedules) > 0 {
+		cal, err := h.loadCalendar()
+		if err != nil {
+			return nil, err
+		}
+		for _, schedule := range schedules {
+			event, ok := recurringEvent(schedule, *term, cal, materialized)
+			if !ok {
+				continue
+			}
+			event.Summary = lookups.summary(schedule.SubjectID)
+			event.Description = lookups.description(schedule.TeacherID, schedule.GroupID, schedule.SubgroupID, schedule.Description)
+			event.Location = lookups.location(schedule.RoomID, schedule.Room)
+			feed.Events = append(feed.Events, event)
+		}
+	}
+
+	return feed, nil
+}
+
+// recurringEvent превращает запись недельного расписания в еженедельное событие семестра.
+// Нерабочие дни и даты с уже созданными уроками исключаются, перенесенные рабочие дни добавляются
+func recurringEvent(schedule models.Schedule, term models.Term, cal *calendar.Calendar, materialized map[string]bool) (ical.Event, bool) {
+	event := ical.Event{
+		UID:   "schedule-" + schedule.ID.Hex() + "@innovativecollege",
+		RRule: "FREQ=WEEKLY;UNTIL=" + ical.UntilUTC(term.EndDate),
+	}
+
+	first := true
+	for date := term.StartDate; !date.After(term.EndDate); date = date.AddDate(0, 0, 1) {
+		day := cal.Describe(date)
+		regular := day.DayOfWeek == schedule.DayOfWeek
+		runs := day.Working && day.TimetableOf == schedule.DayOfWeek &&
+			!materialized[schedule.ID.Hex()+date.Format("2006-01-02")]
+
+		if !regular && !runs {
+			continue
+		}
+
+		start, end, ok := clockRange(date, schedule.StartTime, schedule.EndTime)
+		if !ok {
+			return event, false
+		}
+
+		// Первое регулярное повторение задает DTSTART
+		if first && regular {
+			event.Start, event.End = start, end
+			first = false
+			if !runs {
+				event.ExDates = append(event.ExDates, start)
+			}
+			continue
+		}
+
+		switch {
+		case regular && !runs:
+			event.ExDates = append(event.ExDates, start)
+		case !regular && runs:
+			event.RDates = append(event.RDates, start)
+		}
+	}
+
+	return event, !first
+}
+
+// clockRange переводит дату и время "HH:MM" в начало и конец занятия
+func clockRange(date time.Time, startTime, endTime string) (time.Time, time.Time, bool) {
+	startMinutes, ok := models.ParseClock(startTime)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	endMinutes, ok := models.ParseClock(endTime)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return day.Add(time.Duration(startMinutes) * time.Minute), day.Add(time.Duration(endMinutes) * time.Minute), true
+}
+
+// feedName проверяет владельца ленты и возвращает название календаря
+func (h *Handlers) feedName(ctx context.Context, feedType string, feedID primitive.ObjectID) (string, error) {
+	filter := bson.M{"_id": feedID}
+
+	var err error
+	var name string
+	switch feedType {
+	case models.FeedStudent:
+		var student *models.Student
+		if student, err = h.store.Students.FindOne(ctx, filter); err == nil {
+			name = "Расписание: " + student.LastName + " " + student.FirstName
+		}
+	case models.FeedTeacher:
+		var teacher *models.Teacher
+		if teacher, err = h.store.Teachers.FindOne(ctx, filter); err == nil {
+			name = "Расписание: " + teacher.LastName + " " + teacher.FirstName
+		}
+	case models.FeedGroup:
+		var group *models.Group
+		if group, err = h.store.Groups.FindOne(ctx, filter); err == nil {
+			name = "Расписание группы " + group.Name
+		}
+	case models.FeedRoom:
+		var room *models.Room
+		if room, err = h.store.Rooms.FindOne(ctx, filter); err == nil {
+			name = "Аудитория " + room.Number
+		}
+	default:
+		return "", errFeedNotFound
+	}
+
+	if err == storage.ErrNotFound {
+		return "", errFeedNotFound
+	}
+	return name, err
+}
+
+// feedFilter фильтр уроков и расписания для ленты
+func (h *Handlers) feedFilter(ctx context.Context, feedType string, feedID primitive.ObjectID) (bson.M, error) {
+	switch feedType {
+	case models.FeedStudent:
+		// Студент видит расписание своей текущей группы и своих подгрупп
+		student, err := h.store.Students.FindByID(ctx, feedID)
+		if err == storage.ErrNotFound {
+			return nil, errFeedNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return h.studentLessonFilter(ctx, student)
+	case models.FeedTeacher:
+		return bson.M{"teacher_id": feedID}, nil
+	case models.FeedGroup:
+		return bson.M{"group_id": feedID}, nil
+	case models.FeedRoom:
+		return bson.M{"room_id": feedID}, nil
+	}
+	return nil, errFeedNotFound
+}
+
+// nameLookups справочники названий для подписей событий и выгрузок. Документы
+// берутся из кэша relations, поэтому на коллекцию уходит не больше одного запроса
+type nameLookups struct {
+	*relations
+}
+
+// loadNameLookups загружает предметы, преподавателей, группы и аудитории занятий
+func (h *Handlers) loadNameLookups(ctx context.Context, lessons []models.Lesson, schedules []models.Schedule) (*nameLookups, error) {
+	size := len(lessons) + len(schedules)
+	groupIDs := make([]primitive.ObjectID, 0, size)
+	teacherIDs := make([]primitive.ObjectID, 0, size)
+	subjectIDs := make([]primitive.ObjectID, 0, size)
+	roomIDs := make([]primitive.ObjectID, 0, size)
+	for _, lesson := range lessons {
+		groupIDs = append(groupIDs, lesson.GroupID)
+		teacherIDs = append(teacherIDs, lesson.TeacherID)
+		subjectIDs = append(subjectIDs, lesson.SubjectID)
+		roomIDs = append(roomIDs, lesson.RoomID)
+	}
+	for _, schedule := range schedules {
+		groupIDs = append(groupIDs, schedule.GroupID)
+		teacherIDs = append(teacherIDs, schedule.TeacherID)
+		subjectIDs = append(subjectIDs, schedule.SubjectID)
+		roomIDs = append(roomIDs, schedule.RoomID)
+	}
+
+	r := h.newRelations()
+	if err := r.load(ctx, groupIDs, teacherIDs, subjectIDs); err != nil {
+		return nil, err
+	}
+	if err := r.rooms.load(ctx, roomIDs); err != nil {
+		return nil, err
+	}
+	return &nameLookups{relations: r}, nil
+}
+
+// summary заголовок события: код и название предмета, например "ОН 3.1 Математика"
+func (l *nameLookups) summary(subjectID primitive.ObjectID) string {
+	subject := l.subjects.get(subjectID)
+	if subject == nil {
+		return "Занятие"
+	}
+	return strings.TrimSpace(subject.Code + " " + subject.Name)
+}
+
+// description описание события: преподаватель, группа (с подгруппой) и комментарий
+func (l *nameLookups) description(teacherID, groupID, subgroupID primitive.ObjectID, comment string) string {
+	var lines []string
+	if teacher := l.teachers.get(teacherID); teacher != nil {
+		lines = append(lines, "Преподаватель: "+teacher.LastName+" "+teacher.FirstName)
+	}
+	if l.groups.get(groupID) != nil {
+		lines = append(lines, "Группа: "+l.groupLabel(groupID, subgroupID))
+	}
+	if comment != "" {
+		lines = append(lines, comment)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// location место проведения: аудитория и корпус
+func (l *nameLookups) location(roomID primitive.ObjectID, number string) string {
+	room := l.rooms.get(roomID)
+	if room == nil {
+		if number == "" {
+			return ""
+		}
+		return "Аудитория " + number
+	}
+	if room.Building != "" {
+		return "Аудитория " + room.Number + ", " + room.Building
+	}
+	return "Аудитория " + room.Number
+}
+
+// groupName название группы
+func (l *nameLookups) groupName(groupID primitive.ObjectID) string {
+	if group := l.groups.get(groupID); group != nil {
+		return group.Name
+	}
+	return ""
+}
+
+// subgroupName название подгруппы занятия или пустая строка для всей группы
+func (l *nameLookups) subgroupName(groupID, subgroupID primitive.ObjectID) string {
+	if subgroupID.IsZero() {
+		return ""
+	}
+	if group := l.groups.get(groupID); group != nil {
+		if subgroup := group.Subgroup(subgroupID); subgroup != nil {
+			return subgroup.Name
+		}
+	}
+	return ""
+}
+
+// groupLabel группа занятия с подгруппой, например "ПО-31 (1 подгруппа)"
+func (l *nameLookups) groupLabel(groupID, subgroupID primitive.ObjectID) string {
+	if subgroup := l.subgroupName(groupID, subgroupID); subgroup != "" {
+		return l.groupName(groupID) + " (" + subgroup + ")"
+	}
+	return l.groupName(groupID)
+}
+
+// subjectCode код предмета, например "ОН 3.1"
+func (l *nameLookups) subjectCode(subjectID primitive.ObjectID) string {
+	if subject := l.subjects.get(subjectID); subject != nil {
+		return subject.Code
+	}
+	return ""
+}
+
+// subjectName название предмета
+func (l *nameLookups) subjectName(subjectID primitive.ObjectID) string {
+	if subject := l.subjects.get(subjectID); subject != nil {
+		return subject.Name
+	}
+	return ""
+}
+
+// teacherName фамилия и инициал преподавателя: "Караев А."
+func (l *nameLookups) teacherName(teacherID primitive.ObjectID) string {
+	teacher := l.teachers.get(teacherID)
+	if teacher == nil {
+		return ""
+	}
+	if initial := []rune(teacher.FirstName); len(initial) > 0 {
+		return teacher.LastName + " " + string(initial[0]) + "."
+	}
+	return teacher.LastName
+}
+
+// newSubscriptionToken генерирует случайный токен подписки
+func newSubscriptionToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// subscriptionURL полный адрес ленты для календарного приложения
+func subscriptionURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/v1/ical/" + token + ".ics"
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/timetable"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportSchedules загружает недельное расписание из Excel-матрицы (группы по столбцам,
+// дни и пары по строкам). С ?dry_run=true только возвращает отчет: нераспознанные названия
+// и конфликты. Запись выполняется, только если все ячейки распознаны и конфликтов нет.
+// С ?replace=true существующее расписание импортируемых групп заменяется
+func (h *Handlers) ImportSchedules(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	replace, _ := strconv.ParseBool(c.Query("replace"))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Загрузите файл .xlsx в поле file"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось открыть файл"})
+		return
+	}
+	defer file.Close()
+
+	entries, issues, err := timetable.ReadWorkbook(file, c.Query("sheet"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать книгу Excel: " + err.Error()})
+		return
+	}
+	if issues == nil {
+		issues = []timetable.Issue{}
+	}
+
+	// Справочники для сопоставления названий
+	var groups []models.Group
+	var subjects []models.Subject
+	var teachers []models.Teacher
+	var slots []models.TimeSlot
+	var rooms []models.Room
+	if !findAll(c, h.store.Groups, bson.M{}, &groups, "Ошибка получения групп") ||
+		!findAll(c, h.store.Subjects, bson.M{}, &subjects, "Ошибка получения предметов") ||
+		!findAll(c, h.store.Teachers, bson.M{}, &teachers, "Ошибка получения преподавателей") ||
+		!findAll(c, h.store.TimeSlots, bson.M{}, &slots, "Ошибка получения временных слотов") ||
+		!findAll(c, h.store.Rooms, bson.M{}, &rooms, "Ошибка получения аудиторий") {
+		return
+	}
+
+	schedules, resolved, unresolved := timetable.NewResolver(groups, subjects, teachers, slots, rooms).Resolve(entries)
+	clashes := timetable.FindClashes(schedules, resolved)
+	groupIDs := timetable.GroupIDs(schedules)
+
+	// При замене старые записи импортируемых групп конфликтами не считаются
+	replacedIDs := make(map[string]bool)
+	if replace && len(groupIDs) > 0 {
+		var replaced []models.Schedule
+		if !findAll(c, h.store.Schedules, bson.M{"group_id": bson.M{"$in": groupIDs}}, &replaced, "Ошибка получения расписания") {
+			return
+		}
+		for _, schedule := range replaced {
+			replacedIDs[schedule.ID.Hex()] = true
+		}
+	}
+
+	conflicts := []models.Conflict{}
+	for _, schedule := range schedules {
+		found, err := h.findScheduleConflicts(schedule)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки конфликтов расписания"})
+			return
+		}
+		for _, conflict := range found {
+			if !replacedIDs[conflict.ID] {
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+
+	// Ограничения преподавателей проверяются так же, как при создании записи
+	unavailable, err := h.importAvailability(c.Request.Context(), schedules, teachers, replacedIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки доступности преподавателя"})
+		return
+	}
+
+	if schedules == nil {
+		schedules = []models.Schedule{}
+	}
+	report := gin.H{
+		"dry_run":     dryRun,
+		"entries":     len(entries),
+		"resolved":    len(schedules),
+		"issues":      issues,
+		"unresolved":  unresolved,
+		"clashes":     clashes,
+		"conflicts":   conflicts,
+		"unavailable": unavailable,
+		"schedules":   schedules,
+	}
+
+	if dryRun {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	if len(entries) == 0 {
+		report["error"] = "В книге не найдено ни одной пары"
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	if len(issues) > 0 || !unresolved.Empty() || len(clashes) > 0 {
+		report["error"] = "Импорт не выполнен: есть нераспознанные ячейки или пересечения внутри книги"
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	if len(conflicts) > 0 && !forceRequested(c) {
+		report["error"] = "Преподаватель, аудитория или группа уже заняты в это время"
+		c.JSON(http.StatusConflict, report)
+		return
+	}
+	if len(unavailable) > 0 && !forceRequested(c) {
+		report["error"] = "Преподаватель недоступен в это время"
+		c.JSON(http.StatusConflict, report)
+		return
+	}
+	for _, item := range unavailable {
+		schedules[item.Index].Warnings = append([]string{forcedUnavailableWarning}, schedules[item.Index].Warnings...)
+	}
+
+	replacedCount := int64(0)
+	if replace {
+		deleted, err := h.store.Schedules.DeleteMany(c.Request.Context(), bson.M{"group_id": bson.M{"$in": groupIDs}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления старого расписания"})
+			return
+		}
+		replacedCount = deleted
+	}
+
+	now := time.Now()
+	for i := range schedules {
+		schedules[i].CreatedAt = now
+		schedules[i].UpdatedAt = now
+	}
+	ids, err := h.store.Schedules.InsertMany(c.Request.Context(), schedules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения расписания"})
+		return
+	}
+	for i, id := range ids {
+		schedules[i].ID = id
+	}
+
+	action := "imported"
+	if replace {
+		action = "replaced"
+	}
+	h.publishScheduleBatch(c.Request.Context(), action, groupIDs, len(schedules))
+
+	report["created"] = len(schedules)
+	report["replaced"] = replacedCount
+	c.JSON(http.StatusCreated, report)
+}
+
+// importUnavailable запись импорта, попавшая в окно недоступности преподавателя
+type importUnavailable struct {
+	Index     int                      `json:"index"` // Номер записи в schedules отчета
+	GroupID   primitive.ObjectID       `json:"group_id"`
+	TeacherID primitive.ObjectID       `json:"teacher_id"`
+	DayOfWeek int                      `json:"day_of_week"`
+	StartTime string                   `json:"start_time"`
+	EndTime   string                   `json:"end_time"`
+	Window    models.UnavailableWindow `json:"window"`
+}
+
+// importAvailability проверяет записи импорта по ограничениям преподавателей: окна
+// недоступности возвращаются списком, нарушенные пожелания записываются в Warnings.
+// Пары в день считаются по текущему расписанию (без заменяемых записей) и предыдущим
+// записям импорта
+func (h *Handlers) importAvailability(ctx context.Context, schedules []models.Schedule, teachers []models.Teacher,
+	replacedIDs map[string]bool) ([]importUnavailable, error) {
+	availability := make(map[primitive.ObjectID]*models.TeacherAvailability, len(teachers))
+	for _, teacher := range teachers {
+		availability[teacher.ID] = teacher.Availability
+	}
+
+	replaced := make([]primitive.ObjectID, 0, len(replacedIDs))
+	for hex := range replacedIDs {
+		if id, err := primitive.ObjectIDFromHex(hex); err == nil {
+			replaced = append(replaced, id)
+		}
+	}
+
+	unavailable := []importUnavailable{}
+	for i := range schedules {
+		schedule := schedules[i]
+		countDay := func(ctx context.Context) (int64, error) {
+			count, err := h.store.Schedules.Count(ctx, bson.M{
+				"teacher_id":  schedule.TeacherID,
+				"day_of_week": schedule.DayOfWeek,
+				"_id":         bson.M{"$nin": replaced},
+			})
+			for _, earlier := range schedules[:i] {
+				if earlier.TeacherID == schedule.TeacherID && earlier.DayOfWeek == schedule.DayOfWeek {
+					count++
+				}
+			}
+			return count, err
+		}
+
+		window, warnings, err := availabilityIssues(ctx, availability[schedule.TeacherID], schedule.DayOfWeek, schedule.StartTime, schedule.EndTime, schedule.Shift, countDay)
+		if err != nil {
+			return nil, err
+		}
+		schedules[i].Warnings = warnings
+		if window != nil {
+			unavailable = append(unavailable, importUnavailable{
+				Index:     i,
+				GroupID:   schedule.GroupID,
+				TeacherID: schedule.TeacherID,
+				DayOfWeek: schedule.DayOfWeek,
+				StartTime: schedule.StartTime,
+				EndTime:   schedule.EndTime,
+				Window:    *window,
+			})
+		}
+	}
+	return unavailable, nil
+}
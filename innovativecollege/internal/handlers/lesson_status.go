@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CancelLesson отменяет запланированный урок с указанием причины
+func (h *Handlers) CancelLesson(c *gin.Context) {
+	var req models.CancelLessonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lesson, ok := h.lessonForTransition(c, models.LessonCancelled)
+	if !ok {
+		return
+	}
+
+	h.setLessonStatus(c, lesson.ID, models.EventLessonCancelled, bson.M{
+		"status":        models.LessonCancelled,
+		"status_reason": req.Reason,
+	})
+}
+
+// RescheduleLesson переносит урок на новую дату и время. Создается новый запланированный
+// урок со ссылкой на исходный (rescheduled_from_id), исходный получает статус rescheduled
+func (h *Handlers) RescheduleLesson(c *gin.Context) {
+	var req models.RescheduleLessonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lesson, ok := h.lessonForTransition(c, models.LessonRescheduled)
+	if !ok {
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты. Используйте YYYY-MM-DD"})
+		return
+	}
+
+	moved := models.Lesson{
+		GroupID:           lesson.GroupID,
+		SubgroupID:        lesson.SubgroupID,
+		TeacherID:         lesson.TeacherID,
+		SubjectID:         lesson.SubjectID,
+		RoomID:            lesson.RoomID,
+		Room:              lesson.Room,
+		Date:              &date,
+		StartTime:         req.StartTime,
+		EndTime:           req.EndTime,
+		Shift:             models.DetermineShift(req.StartTime),
+		Description:       lesson.Description,
+		OriginalTeacherID: lesson.OriginalTeacherID,
+		Status:            models.LessonPlanned,
+		RescheduledFromID: lesson.ID,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+	if req.RoomID != "" || req.Room != "" {
+		room, err := h.resolveRoom(req.RoomID, req.Room)
+		if err != nil {
+			respondRoomError(c, err)
+			return
+		}
+		moved.RoomID = room.ID
+		moved.Room = room.Number
+	}
+
+	// Новое время проверяется так же, как при создании урока. Исходный урок
+	// в проверке не участвует: после переноса он освобождает свое время
+	if h.rejectNonWorkingDay(c, moved.Date) {
+		return
+	}
+	conflicts, err := h.findLessonConflicts(moved)
+	var otherConflicts []models.Conflict
+	for _, conflict := range conflicts {
+		if conflict.ID != lesson.ID.Hex() {
+			otherConflicts = append(otherConflicts, conflict)
+		}
+	}
+	if rejectConflicts(c, otherConflicts, err) {
+		return
+	}
+	if h.checkLessonAvailability(c, &moved) {
+		return
+	}
+
+	movedID, err := h.store.Lessons.Insert(c.Request.Context(), moved)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания перенесенного урока"})
+		return
+	}
+	moved.ID = movedID
+
+	_, err = h.store.Lessons.Update(c.Request.Context(), bson.M{"_id": lesson.ID}, bson.M{"$set": bson.M{
+		"status":            models.LessonRescheduled,
+		"status_reason":     req.Reason,
+		"rescheduled_to_id": movedID,
+		"updated_at":        time.Now(),
+	}})
+	if err != nil {
+		// Исходный урок остался запланированным: без отката время было бы занято дважды
+		h.discardLesson(c.Request.Context(), movedID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления исходного урока"})
+		return
+	}
+	h.publishLessonMoved(c.Request.Context(), moved, *lesson)
+
+	lessons := []models.Lesson{moved}
+	if err := h.newRelations().populateLessons(c.Request.Context(), lessons); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных урока"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, lessons[0])
+}
+
+// ConductLesson отмечает урок проведенным и сохраняет тему. Преподаватель может
+// отметить только свой урок и только в день урока или позже
+func (h *Handlers) ConductLesson(c *gin.Context) {
+	var req models.ConductLessonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lesson, ok := h.lessonForTransition(c, models.LessonConducted)
+	if !ok {
+		return
+	}
+
+	if claims := auth.GetClaims(c); claims != nil && claims.Role == auth.RoleTeacher && lesson.TeacherID.Hex() != claims.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Можно отмечать только свои уроки"})
+		return
+	}
+
+	if lesson.Date == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "У урока нет даты"})
+		return
+	}
+	if lesson.Date.After(time.Now().UTC().Truncate(24 * time.Hour)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Урок еще не наступил"})
+		return
+	}
+
+	h.setLessonStatus(c, lesson.ID, models.EventLessonUpdated, bson.M{
+		"status": models.LessonConducted,
+		"topic":  req.Topic,
+	})
+}
+
+// discardLesson окончательно удаляет урок, созданный незавершенной операцией,
+// чтобы он не остался ни в расписании, ни в корзине
+func (h *Handlers) discardLesson(ctx context.Context, id primitive.ObjectID) {
+	if _, err := h.store.Lessons.Delete(ctx, bson.M{"_id": id}); err != nil {
+		log.Printf("Не удалось удалить урок %s после ошибки: %v", id.Hex(), err)
+		return
+	}
+	if bin := storage.BinOf(h.store.Lessons); bin != nil {
+		if _, err := bin.Purge(ctx, bson.M{"_id": id}); err != nil {
+			log.Printf("Не удалось очистить урок %s из корзины: %v", id.Hex(), err)
+		}
+	}
+}
+
+// lessonForTransition загружает урок из :id и проверяет, что его можно перевести в статус to
+func (h *Handlers) lessonForTransition(c *gin.Context, to string) (*models.Lesson, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID урока"})
+		return nil, false
+	}
+
+	lesson, err := h.store.Lessons.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Урок не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска урока"})
+		}
+		return nil, false
+	}
+
+	if !lesson.CanTransition(to) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":  "Недопустимый переход статуса урока",
+			"status": lesson.CurrentStatus(),
+			"target": to,
+		})
+		return nil, false
+	}
+	return lesson, true
+}
+
+// setLessonStatus сохраняет новый статус урока, отправляет событие event
+// и отвечает обновленным уроком
+func (h *Handlers) setLessonStatus(c *gin.Context, id primitive.ObjectID, event string, update bson.M) {
+	update["updated_at"] = time.Now()
+	if _, err := h.store.Lessons.Update(c.Request.Context(), bson.M{"_id": id}, bson.M{"$set": update}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления статуса урока"})
+		return
+	}
+
+	lesson, err := h.store.Lessons.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленного урока"})
+		return
+	}
+	h.publishLesson(c.Request.Context(), event, *lesson)
+
+	lessons := []models.Lesson{*lesson}
+	if err := h.newRelations().populateLessons(c.Request.Context(), lessons); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных урока"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lessons[0])
+}
+
+// lessonStatusCondition условие фильтра по статусу урока. Запланированными
+// считаются и уроки без статуса, созданные до его появления
+func lessonStatusCondition(status string) interface{} {
+	if status == models.LessonPlanned {
+		return bson.M{"$in": []interface{}{nil, models.LessonPlanned}}
+	}
+	return status
+}
+
+// inactiveLessonStatuses статусы уроков, которые больше не занимают свое время
+var inactiveLessonStatuses = []string{models.LessonCancelled, models.LessonRescheduled}
@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/config"
+	"innovativecollege/internal/handlers"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/routes"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// failingStatusRepository отказывает в обновлениях, которые ставят уроку статус rescheduled
+type failingStatusRepository struct {
+	storage.Repository[models.Lesson]
+}
+
+func (r failingStatusRepository) Update(ctx context.Context, filter, update bson.M) (storage.UpdateResult, error) {
+	if set, ok := update["$set"].(bson.M); ok && set["status"] == models.LessonRescheduled {
+		return storage.UpdateResult{}, errors.New("хранилище недоступно")
+	}
+	return r.Repository.Update(ctx, filter, update)
+}
+
+// adminAPI роутер поверх заданного хранилища с токеном администратора
+type adminAPI struct {
+	router *gin.Engine
+	token  string
+}
+
+func newAdminAPI(tb testing.TB, store *storage.Store) *adminAPI {
+	tb.Helper()
+	gin.SetMode(gin.TestMode)
+	authManager := auth.NewManager("test-secret", time.Hour)
+	token, _, err := authManager.Issue(auth.RoleAdmin, "admin", "", "admin")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	router := gin.New()
+	routes.SetupRoutes(router, handlers.New(store, config.Load(), authManager, nil, nil), authManager)
+	return &adminAPI{router: router, token: token}
+}
+
+// do выполняет запрос к /api/v1 с JSON-телом body (пустая строка - без тела)
+func (a *adminAPI) do(tb testing.TB, method, path, body string) *httptest.ResponseRecorder {
+	tb.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, "/api/v1"+path, reader)
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	a.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRescheduleRollsBackWhenOriginalUpdateFails(t *testing.T) {
+	ctx := context.Background()
+	memory := storage.NewMemoryStore()
+	memory.Lessons = failingStatusRepository{memory.Lessons}
+	store := storage.WithSoftDelete(memory, func(context.Context) string { return "admin" })
+
+	groupID, _ := store.Groups.Insert(ctx, models.Group{Name: "ПО-31", Shift: 1})
+	teacherID, _ := store.Teachers.Insert(ctx, models.Teacher{IIN: "800000000001", FirstName: "Анна", LastName: "Иванова"})
+	subjectID, _ := store.Subjects.Insert(ctx, models.Subject{Name: "Физика", Code: "ФИЗ"})
+	date := seedDay
+	lessonID, err := store.Lessons.Insert(ctx, models.Lesson{
+		GroupID: groupID, TeacherID: teacherID, SubjectID: subjectID,
+		Date: &date, StartTime: "08:00", EndTime: "09:20", Shift: 1, Status: models.LessonPlanned,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := newAdminAPI(t, store)
+	recorder := api.do(t, http.MethodPost, "/lessons/"+lessonID.Hex()+"/reschedule", `{"date": "2024-10-15", "start_time": "11:00", "end_time": "12:20"}`)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("статус %d, ожидали 500: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Остался только исходный запланированный урок, перенесенный удален и из корзины
+	lessons, err := store.Lessons.Find(ctx, bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lessons) != 1 || lessons[0].ID != lessonID || lessons[0].CurrentStatus() != models.LessonPlanned {
+		t.Fatalf("уроки после ошибки: %+v", lessons)
+	}
+	trashed, err := storage.BinOf(store.Lessons).FindDeleted(ctx, bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 0 {
+		t.Fatalf("перенесенный урок остался в корзине: %+v", trashed)
+	}
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// entityCache кэш документов одной коллекции в пределах запроса. Недостающие
+// документы загружаются одним запросом с $in, повторно не запрашиваются
+type entityCache[T any] struct {
+	repo   storage.Repository[T]
+	id     func(T) primitive.ObjectID
+	items  map[primitive.ObjectID]*T
+	loaded map[primitive.ObjectID]bool
+}
+
+func newEntityCache[T any](repo storage.Repository[T], id func(T) primitive.ObjectID) *entityCache[T] {
+	return &entityCache[T]{
+		repo:   repo,
+		id:     id,
+		items:  make(map[primitive.ObjectID]*T),
+		loaded: make(map[primitive.ObjectID]bool),
+	}
+}
+
+// load загружает документы, которых еще нет в кэше
+func (e *entityCache[T]) load(ctx context.Context, ids []primitive.ObjectID) error {
+	var missing []primitive.ObjectID
+	for _, id := range ids {
+		if id.IsZero() || e.loaded[id] {
+			continue
+		}
+		e.loaded[id] = true
+		missing = append(missing, id)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	docs, err := e.repo.Find(ctx, bson.M{"_id": bson.M{"$in": missing}})
+	if err != nil {
+		for _, id := range missing {
+			delete(e.loaded, id)
+		}
+		return err
+	}
+	for i := range docs {
+		e.items[e.id(docs[i])] = &docs[i]
+	}
+	return nil
+}
+
+// get возвращает документ из кэша или nil, если его нет в базе
+func (e *entityCache[T]) get(id primitive.ObjectID) *T {
+	return e.items[id]
+}
+
+// relations связанные группы, преподаватели, предметы и аудитории для заполнения ответов.
+// Количество запросов не зависит от числа строк: не больше одного на коллекцию
+type relations struct {
+	groups   *entityCache[models.Group]
+	teachers *entityCache[models.Teacher]
+	subjects *entityCache[models.Subject]
+	rooms    *entityCache[models.Room]
+}
+
+func (h *Handlers) newRelations() *relations {
+	return &relations{
+		groups:   newEntityCache(h.store.Groups, func(g models.Group) primitive.ObjectID { return g.ID }),
+		teachers: newEntityCache(h.store.Teachers, func(t models.Teacher) primitive.ObjectID { return t.ID }),
+		subjects: newEntityCache(h.store.Subjects, func(s models.Subject) primitive.ObjectID { return s.ID }),
+		rooms:    newEntityCache(h.store.Rooms, func(r models.Room) primitive.ObjectID { return r.ID }),
+	}
+}
+
+// populateSchedules заполняет группу, преподавателя и предмет в расписании.
+// Если предмет не найден или не указан, подставляется заглушка
+func (r *relations) populateSchedules(ctx context.Context, schedules []models.Schedule) error {
+	groupIDs := make([]primitive.ObjectID, len(schedules))
+	teacherIDs := make([]primitive.ObjectID, len(schedules))
+	subjectIDs := make([]primitive.ObjectID, len(schedules))
+	for i, schedule := range schedules {
+		groupIDs[i] = schedule.GroupID
+		teacherIDs[i] = schedule.TeacherID
+		subjectIDs[i] = schedule.SubjectID
+	}
+	if err := r.load(ctx, groupIDs, teacherIDs, subjectIDs); err != nil {
+		return err
+	}
+
+	for i := range schedules {
+		schedules[i].Group = r.groups.get(schedules[i].GroupID)
+		schedules[i].Teacher = r.teachers.get(schedules[i].TeacherID)
+		schedules[i].Subject = scheduleSubject(r.subjects.get(schedules[i].SubjectID), schedules[i].SubjectID)
+	}
+	return nil
+}
+
+// populateScheduleTeachers заполняет только преподавателей (расписание студента)
+func (r *relations) populateScheduleTeachers(ctx context.Context, schedules []models.Schedule) error {
+	ids := make([]primitive.ObjectID, len(schedules))
+	for i, schedule := range schedules {
+		ids[i] = schedule.TeacherID
+	}
+	if err := r.teachers.load(ctx, ids); err != nil {
+		return err
+	}
+	for i := range schedules {
+		schedules[i].Teacher = r.teachers.get(schedules[i].TeacherID)
+	}
+	return nil
+}
+
+// populateScheduleGroups заполняет только группы (расписание преподавателя)
+func (r *relations) populateScheduleGroups(ctx context.Context, schedules []models.Schedule) error {
+	ids := make([]primitive.ObjectID, len(schedules))
+	for i, schedule := range schedules {
+		ids[i] = schedule.GroupID
+	}
+	if err := r.groups.load(ctx, ids); err != nil {
+		return err
+	}
+	for i := range schedules {
+		schedules[i].Group = r.groups.get(schedules[i].GroupID)
+	}
+	return nil
+}
+
+// populateLessons заполняет группу, преподавателя и предмет в уроках
+func (r *relations) populateLessons(ctx context.Context, lessons []models.Lesson) error {
+	groupIDs := make([]primitive.ObjectID, len(lessons))
+	teacherIDs := make([]primitive.ObjectID, len(lessons))
+	subjectIDs := make([]primitive.ObjectID, len(lessons))
+	for i, lesson := range lessons {
+		groupIDs[i] = lesson.GroupID
+		teacherIDs[i] = lesson.TeacherID
+		subjectIDs[i] = lesson.SubjectID
+	}
+	if err := r.load(ctx, groupIDs, teacherIDs, subjectIDs); err != nil {
+		return err
+	}
+
+	for i := range lessons {
+		lessons[i].Group = r.groups.get(lessons[i].GroupID)
+		lessons[i].Teacher = r.teachers.get(lessons[i].TeacherID)
+		lessons[i].Subject = r.subjects.get(lessons[i].SubjectID)
+	}
+	return nil
+}
+
+// populateStudents заполняет группы студентов
+func (r *relations) populateStudents(ctx context.Context, students []models.Student) error {
+	ids := make([]primitive.ObjectID, len(students))
+	for i, student := range students {
+		ids[i] = student.GroupID
+	}
+	if err := r.groups.load(ctx, ids); err != nil {
+		return err
+	}
+	for i := range students {
+		students[i].Group = r.groups.get(students[i].GroupID)
+	}
+	return nil
+}
+
+// populateSubstitutions заполняет преподавателей, группу и предмет в заменах
+func (r *relations) populateSubstitutions(ctx context.Context, substitutions []models.Substitution) error {
+	groupIDs := make([]primitive.ObjectID, len(substitutions))
+	teacherIDs := make([]primitive.ObjectID, 0, 2*len(substitutions))
+	subjectIDs := make([]primitive.ObjectID, len(substitutions))
+	for i, substitution := range substitutions {
+		groupIDs[i] = substitution.GroupID
+		teacherIDs = append(teacherIDs, substitution.OriginalTeacherID, substitution.SubstituteTeacherID)
+		subjectIDs[i] = substitution.SubjectID
+	}
+	if err := r.load(ctx, groupIDs, teacherIDs, subjectIDs); err != nil {
+		return err
+	}
+
+	for i := range substitutions {
+		substitutions[i].OriginalTeacher = r.teachers.get(substitutions[i].OriginalTeacherID)
+		substitutions[i].SubstituteTeacher = r.teachers.get(substitutions[i].SubstituteTeacherID)
+		substitutions[i].Group = r.groups.get(substitutions[i].GroupID)
+		substitutions[i].Subject = r.subjects.get(substitutions[i].SubjectID)
+	}
+	return nil
+}
+
+// populateAbsences заполняет преподавателей в отсутствиях
+func (r *relations) populateAbsences(ctx context.Context, absences []models.TeacherAbsence) error {
+	ids := make([]primitive.ObjectID, len(absences))
+	for i, absence := range absences {
+		ids[i] = absence.TeacherID
+	}
+	if err := r.teachers.load(ctx, ids); err != nil {
+		return err
+	}
+	for i := range absences {
+		absences[i].Teacher = r.teachers.get(absences[i].TeacherID)
+	}
+	return nil
+}
+
+func (r *relations) load(ctx context.Context, groupIDs, teacherIDs, subjectIDs []primitive.ObjectID) error {
+	if err := r.groups.load(ctx, groupIDs); err != nil {
+		return err
+	}
+	if err := r.teachers.load(ctx, teacherIDs); err != nil {
+		return err
+	}
+	return r.subjects.load(ctx, subjectIDs)
+}
+
+// scheduleSubject предмет для ответа с расписанием: найденный или заглушка
+func scheduleSubject(subject *models.Subject, subjectID primitive.ObjectID) *models.Subject {
+	if subject != nil {
+		return subject
+	}
+	if subjectID.IsZero() {
+		return &models.Subject{
+			Name: "Предмет не указан",
+			Code: "Н/Д",
+		}
+	}
+	return &models.Subject{
+		ID:   subjectID,
+		Name: "Предмет не найден",
+		Code: "Н/Д",
+	}
+}
@@ -0,0 +1,260 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/config"
+	"innovativecollege/internal/handlers"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/routes"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Списки, которые заполняют группы, преподавателей и предметы. Число запросов
+// к хранилищу не должно зависеть от количества строк
+var lookupEndpoints = []struct {
+	path string
+	rows func(size int) int
+}{
+	{"/students", func(size int) int { return size }},
+	{"/schedules", func(size int) int { return size }},
+	{"/schedules/day/1", func(size int) int { return size }},
+	{"/lessons", func(size int) int { return 2 * size }},
+	{"/lessons/date/" + seedDay.Format("2006-01-02"), func(size int) int { return size }},
+	{"/lessons/available", func(size int) int { return size }},
+}
+
+var seedDay = time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC)
+
+// lookupServer роутер поверх хранилища со счетчиком запросов на чтение
+type lookupServer struct {
+	router  *gin.Engine
+	token   string
+	queries int64
+}
+
+func newLookupServer(tb testing.TB, size int) *lookupServer {
+	tb.Helper()
+	gin.SetMode(gin.TestMode)
+
+	server := &lookupServer{}
+	store := countingStore(storage.NewMemoryStore(), &server.queries)
+	if err := seed(store, size); err != nil {
+		tb.Fatal(err)
+	}
+
+	authManager := auth.NewManager("test-secret", time.Hour)
+	token, _, err := authManager.Issue(auth.RoleAdmin, "admin", "", "admin")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	server.token = token
+	server.router = gin.New()
+	routes.SetupRoutes(server.router, handlers.New(store, config.Load(), authManager, nil, nil), authManager)
+	return server
+}
+
+// serve выполняет GET-запрос и возвращает ответ и число запросов к хранилищу
+func (s *lookupServer) serve(tb testing.TB, path string) (*httptest.ResponseRecorder, int64) {
+	tb.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1"+path, nil)
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	recorder := httptest.NewRecorder()
+
+	atomic.StoreInt64(&s.queries, 0)
+	s.router.ServeHTTP(recorder, req)
+	queries := atomic.LoadInt64(&s.queries)
+
+	if recorder.Code != http.StatusOK {
+		tb.Fatalf("GET %s: статус %d: %s", path, recorder.Code, recorder.Body.String())
+	}
+	return recorder, queries
+}
+
+// get выполняет GET-запрос к списку и возвращает строки и число запросов к хранилищу
+func (s *lookupServer) get(tb testing.TB, path string) ([]map[string]interface{}, int64) {
+	tb.Helper()
+	recorder, queries := s.serve(tb, path)
+	var items []map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &items); err != nil {
+		tb.Fatalf("GET %s: %v", path, err)
+	}
+	return items, queries
+}
+
+func TestListQueriesDoNotGrowWithRows(t *testing.T) {
+	small, large := newLookupServer(t, 10), newLookupServer(t, 5000)
+
+	for _, endpoint := range lookupEndpoints {
+		t.Run(endpoint.path, func(t *testing.T) {
+			smallItems, smallQueries := small.get(t, endpoint.path)
+			largeItems, largeQueries := large.get(t, endpoint.path)
+
+			if len(smallItems) != endpoint.rows(10) || len(largeItems) != endpoint.rows(5000) {
+				t.Fatalf("строк %d и %d, ожидали %d и %d", len(smallItems), len(largeItems), endpoint.rows(10), endpoint.rows(5000))
+			}
+			if smallQueries != largeQueries {
+				t.Fatalf("запросов на 10 строк: %d, на 5000: %d", smallQueries, largeQueries)
+			}
+
+			// Связи заполнены у всех строк, в том числе последней
+			last := largeItems[len(largeItems)-1]
+			if last["group"] == nil {
+				t.Fatalf("группа не заполнена: %v", last)
+			}
+			if _, isStudent := last["iin"]; !isStudent && (last["teacher"] == nil || last["subject"] == nil) {
+				t.Fatalf("преподаватель или предмет не заполнены: %v", last)
+			}
+		})
+	}
+}
+
+// Выгрузки и отчеты подписывают строки названиями через loadNameLookups
+func TestExportQueriesDoNotGrowWithRows(t *testing.T) {
+	small, large := newLookupServer(t, 10), newLookupServer(t, 5000)
+
+	for _, path := range []string{
+		"/schedules/export?format=csv",
+		"/lessons/export?format=csv",
+		"/statistics/workload?start_date=2024-10-01&end_date=2024-10-31",
+	} {
+		t.Run(path, func(t *testing.T) {
+			_, smallQueries := small.serve(t, path)
+			recorder, largeQueries := large.serve(t, path)
+			if smallQueries != largeQueries {
+				t.Fatalf("запросов на 10 строк: %d, на 5000: %d", smallQueries, largeQueries)
+			}
+			if recorder.Body.Len() == 0 {
+				t.Fatal("пустой ответ")
+			}
+		})
+	}
+}
+
+func BenchmarkListEndpoints(b *testing.B) {
+	for _, size := range []int{10, 1000, 5000} {
+		server := newLookupServer(b, size)
+		for _, endpoint := range lookupEndpoints {
+			b.Run(fmt.Sprintf("%s/%d", strings.TrimPrefix(endpoint.path, "/"), size), func(b *testing.B) {
+				var queries int64
+				for i := 0; i < b.N; i++ {
+					_, queries = server.get(b, endpoint.path)
+				}
+				b.ReportMetric(float64(queries), "queries/op")
+			})
+		}
+	}
+}
+
+// seed создает по size студентов, записей расписания, уроков с датой и без даты.
+// Групп, преподавателей и предметов - по одному на каждые 10 строк
+func seed(store *storage.Store, size int) error {
+	ctx := context.Background()
+
+	refs := size/10 + 1
+	groupIDs := make([]primitive.ObjectID, refs)
+	teacherIDs := make([]primitive.ObjectID, refs)
+	subjectIDs := make([]primitive.ObjectID, refs)
+	for i := 0; i < refs; i++ {
+		var err error
+		if groupIDs[i], err = store.Groups.Insert(ctx, models.Group{Name: fmt.Sprintf("Группа %d", i), Shift: 1}); err != nil {
+			return err
+		}
+		if teacherIDs[i], err = store.Teachers.Insert(ctx, models.Teacher{IIN: fmt.Sprintf("%012d", i), FirstName: "Имя", LastName: fmt.Sprintf("Фамилия %d", i)}); err != nil {
+			return err
+		}
+		if subjectIDs[i], err = store.Subjects.Insert(ctx, models.Subject{Name: fmt.Sprintf("Предмет %d", i), Code: fmt.Sprintf("П%d", i)}); err != nil {
+			return err
+		}
+	}
+
+	students := make([]models.Student, size)
+	schedules := make([]models.Schedule, size)
+	lessons := make([]models.Lesson, 0, 2*size)
+	for i := 0; i < size; i++ {
+		ref := i % refs
+		students[i] = models.Student{IIN: fmt.Sprintf("9%011d", i), FirstName: "Студент", LastName: fmt.Sprint(i), GroupID: groupIDs[ref]}
+		schedules[i] = models.Schedule{GroupID: groupIDs[ref], TeacherID: teacherIDs[ref], SubjectID: subjectIDs[ref], DayOfWeek: 1, StartTime: "08:00", EndTime: "09:20", Shift: 1}
+		date := seedDay
+		lessons = append(lessons,
+			models.Lesson{GroupID: groupIDs[ref], TeacherID: teacherIDs[ref], SubjectID: subjectIDs[ref], Date: &date, StartTime: "08:00", EndTime: "09:20", Shift: 1},
+			models.Lesson{GroupID: groupIDs[ref], TeacherID: teacherIDs[ref], SubjectID: subjectIDs[ref], Shift: 1},
+		)
+	}
+	if _, err := store.Students.InsertMany(ctx, students); err != nil {
+		return err
+	}
+	if _, err := store.Schedules.InsertMany(ctx, schedules); err != nil {
+		return err
+	}
+	_, err := store.Lessons.InsertMany(ctx, lessons)
+	return err
+}
+
+// countingRepository считает запросы на чтение
+type countingRepository[T any] struct {
+	storage.Repository[T]
+	queries *int64
+}
+
+func (r countingRepository[T]) Find(ctx context.Context, filter bson.M, opts ...storage.FindOptions) ([]T, error) {
+	atomic.AddInt64(r.queries, 1)
+	return r.Repository.Find(ctx, filter, opts...)
+}
+
+func (r countingRepository[T]) FindOne(ctx context.Context, filter bson.M) (*T, error) {
+	atomic.AddInt64(r.queries, 1)
+	return r.Repository.FindOne(ctx, filter)
+}
+
+func (r countingRepository[T]) FindByID(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	atomic.AddInt64(r.queries, 1)
+	return r.Repository.FindByID(ctx, id)
+}
+
+func (r countingRepository[T]) Count(ctx context.Context, filter bson.M) (int64, error) {
+	atomic.AddInt64(r.queries, 1)
+	return r.Repository.Count(ctx, filter)
+}
+
+func counting[T any](repo storage.Repository[T], queries *int64) storage.Repository[T] {
+	return countingRepository[T]{Repository: repo, queries: queries}
+}
+
+func countingStore(store *storage.Store, queries *int64) *storage.Store {
+	return &storage.Store{
+		Groups:                counting(store.Groups, queries),
+		Subjects:              counting(store.Subjects, queries),
+		Students:              counting(store.Students, queries),
+		Teachers:              counting(store.Teachers, queries),
+		Schedules:             counting(store.Schedules, queries),
+		Lessons:               counting(store.Lessons, queries),
+		TimeSlots:             counting(store.TimeSlots, queries),
+		Rooms:                 counting(store.Rooms, queries),
+		Terms:                 counting(store.Terms, queries),
+		CalendarEvents:        counting(store.CalendarEvents, queries),
+		ScheduleDrafts:        counting(store.ScheduleDrafts, queries),
+		CalendarSubscriptions: counting(store.CalendarSubscriptions, queries),
+		TeacherAbsences:       counting(store.TeacherAbsences, queries),
+		Substitutions:         counting(store.Substitutions, queries),
+		Attendance:            counting(store.Attendance, queries),
+		Grades:                counting(store.Grades, queries),
+		Webhooks:              counting(store.Webhooks, queries),
+		WebhookDeliveries:     counting(store.WebhookDeliveries, queries),
+		TelegramChats:         counting(store.TelegramChats, queries),
+		AuditLog:              counting(store.AuditLog, queries),
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// listParams параметры списка из запроса: ?page=2&limit=50&sort=date,-start_time.
+// Без page и limit список возвращается целиком массивом: на массив рассчитаны фронтенды
+// и скрипты, написанные до появления страниц. Это намеренное исключение, описанное
+// в README; общее количество в обоих случаях приходит в заголовке X-Total-Count
+type listParams struct {
+	page    int64
+	limit   int64
+	paged   bool
+	options storage.FindOptions
+}
+
+// pagedList конверт ответа при постраничной выдаче
+type pagedList struct {
+	Items interface{} `json:"items"`
+	Total int64       `json:"total"`
+	Page  int64       `json:"page"`
+	Limit int64       `json:"limit"`
+	Pages int64       `json:"pages"`
+}
+
+// parseListParams разбирает page, limit и sort. sortable - поля, по которым разрешена
+// сортировка, defaultSort - сортировка без параметра sort. При ошибке отвечает 400
+func parseListParams(c *gin.Context, sortable []string, defaultSort string) (listParams, bool) {
+	params := listParams{page: 1, limit: defaultPageSize}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный номер страницы (page >= 1)"})
+			return params, false
+		}
+		params.page = page
+		params.paged = true
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный размер страницы (limit от 1 до " + strconv.Itoa(maxPageSize) + ")"})
+			return params, false
+		}
+		params.limit = limit
+		params.paged = true
+	}
+
+	sortParam := c.DefaultQuery("sort", defaultSort)
+	sort, ok := parseSort(sortParam, sortable)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Неверная сортировка: " + sortParam,
+			"sortable": sortable,
+		})
+		return params, false
+	}
+	params.options.Sort = sort
+
+	if params.paged {
+		params.options.Skip = (params.page - 1) * params.limit
+		params.options.Limit = params.limit
+	}
+	return params, true
+}
+
+// parseSort разбирает список полей через запятую, "-" перед полем - по убыванию.
+// В конец добавляется _id, чтобы порядок страниц был стабильным
+func parseSort(value string, sortable []string) (bson.D, bool) {
+	allowed := make(map[string]bool, len(sortable))
+	for _, field := range sortable {
+		allowed[field] = true
+	}
+
+	sort := bson.D{}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		direction := 1
+		if strings.HasPrefix(field, "-") {
+			direction = -1
+			field = field[1:]
+		}
+		if !allowed[field] {
+			return nil, false
+		}
+		sort = append(sort, bson.E{Key: field, Value: direction})
+	}
+	return append(sort, bson.E{Key: "_id", Value: 1}), true
+}
+
+// findPage загружает страницу документов и общее количество по фильтру.
+// При ошибке отвечает 500 с errorMessage
+func findPage[T any](c *gin.Context, repo storage.Repository[T], filter bson.M, params listParams, errorMessage string) ([]T, int64, bool) {
+	items, err := repo.Find(c.Request.Context(), filter, params.options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errorMessage})
+		return nil, 0, false
+	}
+
+	total := int64(len(items))
+	if params.paged {
+		total, err = repo.Count(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": errorMessage})
+			return nil, 0, false
+		}
+	}
+	return items, total, true
+}
+
+// totalCountHeader общее количество документов по фильтру списка
+const totalCountHeader = "X-Total-Count"
+
+// respondList отвечает массивом или, при постраничной выдаче, конвертом с total
+func respondList(c *gin.Context, items interface{}, total int64, params listParams) {
+	c.Header(totalCountHeader, strconv.FormatInt(total, 10))
+	if !params.paged {
+		c.JSON(http.StatusOK, items)
+		return
+	}
+	c.JSON(http.StatusOK, pagedList{
+		Items: items,
+		Total: total,
+		Page:  params.page,
+		Limit: params.limit,
+		Pages: (total + params.limit - 1) / params.limit,
+	})
+}
@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"innovativecollege/internal/calendar"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/pdf"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Что печатается в ячейке: расписание группы, преподавателя или аудитории
+const (
+	printGroup   = "group"
+	printTeacher = "teacher"
+	printRoom    = "room"
+)
+
+// PrintGroupSchedule печатает расписание группы на неделю (?week=YYYY-MM-DD, любой день недели)
+func (h *Handlers) PrintGroupSchedule(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID группы"})
+		return
+	}
+
+	group, err := h.store.Groups.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Группа не найдена"})
+		return
+	}
+
+	week, ok := h.loadWeek(c)
+	if !ok {
+		return
+	}
+
+	page := week.page("Группа "+group.Name, printGroup, group.Shift, func(groupID, teacherID, roomID primitive.ObjectID) bool {
+		return groupID == group.ID
+	})
+	h.respondPDF(c, "group-"+group.ID.Hex(), []pdf.Page{page})
+}
+
+// PrintTeacherSchedule печатает расписание преподавателя на неделю
+func (h *Handlers) PrintTeacherSchedule(c *gin.Context) {
+	teacher, err := h.store.Teachers.FindOne(c.Request.Context(), bson.M{"iin": c.Param("iin")})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Преподаватель не найден"})
+		return
+	}
+
+	week, ok := h.loadWeek(c)
+	if !ok {
+		return
+	}
+
+	page := week.page("Преподаватель "+teacher.LastName+" "+teacher.FirstName, printTeacher, 0, func(groupID, teacherID, roomID primitive.ObjectID) bool {
+		return teacherID == teacher.ID
+	})
+	h.respondPDF(c, "teacher-"+teacher.IIN, []pdf.Page{page})
+}
+
+// PrintRoomSchedule печатает занятость аудитории на неделю
+func (h *Handlers) PrintRoomSchedule(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аудитории"})
+		return
+	}
+
+	room, err := h.store.Rooms.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Аудитория не найдена"})
+		return
+	}
+
+	week, ok := h.loadWeek(c)
+	if !ok {
+		return
+	}
+
+	page := week.page("Аудитория "+room.Number, printRoom, 0, func(groupID, teacherID, roomID primitive.ObjectID) bool {
+		return roomID == room.ID
+	})
+	h.respondPDF(c, "room-"+room.ID.Hex(), []pdf.Page{page})
+}
+
+// PrintAllGroups печатает расписание всего колледжа на неделю: по странице на группу (?shift=1|2)
+func (h *Handlers) PrintAllGroups(c *gin.Context) {
+	filter := bson.M{}
+	if shift, err := strconv.Atoi(c.Query("shift")); err == nil && (shift == 1 || shift == 2) {
+		filter["shift"] = shift
+	}
+
+	var groups []models.Group
+	if !findAll(c, h.store.Groups, filter, &groups, "Ошибка получения групп") {
+		return
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	week, ok := h.loadWeek(c)
+	if !ok {
+		return
+	}
+
+	pages := make([]pdf.Page, 0, len(groups))
+	for _, group := range groups {
+		groupID := group.ID
+		pages = append(pages, week.page("Группа "+group.Name, printGroup, group.Shift, func(id, teacherID, roomID primitive.ObjectID) bool {
+			return id == groupID
+		}))
+	}
+	h.respondPDF(c, "college-"+week.monday.Format("2006-01-02"), pages)
+}
+
+// printWeek расписание и уроки одной недели всего колледжа
+type printWeek struct {
+	monday    time.Time
+	days      []calendar.Day // Понедельник - воскресенье
+	weekdays  map[int]bool   // Учебные дни недели по умолчанию
+	slots     []models.TimeSlot
+	schedules []models.Schedule
+	lessons   []models.Lesson
+	byID      map[primitive.ObjectID]models.Schedule
+	lookups   *nameLookups
+}
+
+// loadWeek загружает все записи расписания и уроки недели из параметра week (по умолчанию текущей)
+func (h *Handlers) loadWeek(c *gin.Context) (*printWeek, bool) {
+	date := time.Now().UTC()
+	if value := c.Query("week"); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат недели. Используйте YYYY-MM-DD"})
+			return nil, false
+		}
+		date = parsed
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	monday := date.AddDate(0, 0, 1-models.DayOfWeek(date))
+
+	week := &printWeek{monday: monday, weekdays: make(map[int]bool), byID: make(map[primitive.ObjectID]models.Schedule)}
+	for _, day := range h.cfg.WorkingWeekdays {
+		week.weekdays[day] = true
+	}
+
+	if !findAll(c, h.store.TimeSlots, bson.M{"is_active": true}, &week.slots, "Ошибка получения временных слотов") ||
+		!findAll(c, h.store.Schedules, bson.M{}, &week.schedules, "Ошибка получения расписания") ||
+		!findAll(c, h.store.Lessons, bson.M{"date": bson.M{"$gte": monday, "$lt": monday.AddDate(0, 0, 7)}}, &week.lessons, "Ошибка получения уроков") {
+		return nil, false
+	}
+	for _, schedule := range week.schedules {
+		week.byID[schedule.ID] = schedule
+	}
+
+	cal, err := h.loadCalendar()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки календаря"})
+		return nil, false
+	}
+	for i := 0; i < 7; i++ {
+		week.days = append(week.days, cal.Describe(monday.AddDate(0, 0, i)))
+	}
+
+	week.lookups, err = h.loadNameLookups(c.Request.Context(), week.lessons, week.schedules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения справочников"})
+		return nil, false
+	}
+
+	return week, true
+}
+
+// printItem занятие на конкретную дату
+type printItem struct {
+	day     int // Индекс дня недели, 0 - понедельник
+	lesson  models.Lesson
+	changed bool
+}
+
+// page собирает страницу: на учебные дни берется недельное расписание, а если на дату уже
+// созданы уроки - они (с заменами и переносами). shift ограничивает строки слотами смены
+func (w *printWeek) page(title, view string, shift int, match func(groupID, teacherID, roomID primitive.ObjectID) bool) pdf.Page {
+	page := pdf.Page{
+		Title:    title,
+		Subtitle: "Неделя " + w.monday.Format("02.01.2006") + " - " + w.monday.AddDate(0, 0, 6).Format("02.01.2006"),
+	}
+
+	// Записи расписания, по которым на дату уже создан урок, берутся из урока
+	materialized := make(map[string]bool)
+	for _, lesson := range w.lessons {
+		if !lesson.ScheduleID.IsZero() && lesson.Date != nil {
+			materialized[lesson.ScheduleID.Hex()+lesson.Date.Format("2006-01-02")] = true
+		}
+	}
+
+	var items []printItem
+	hasItems := make(map[int]bool)
+	for i, day := range w.days {
+		date := w.monday.AddDate(0, 0, i)
+		for _, lesson := range w.lessons {
+			if lesson.Date == nil || !lesson.Date.Equal(date) || !lesson.Active() || !match(lesson.GroupID, lesson.TeacherID, lesson.RoomID) {
+				continue
+			}
+			items = append(items, printItem{day: i, lesson: lesson, changed: w.changed(lesson, day)})
+			hasItems[i] = true
+		}
+
+		if !day.Working {
+			continue
+		}
+		for _, schedule := range w.schedules {
+			if schedule.DayOfWeek != day.TimetableOf || materialized[schedule.ID.Hex()+day.Date] ||
+				!match(schedule.GroupID, schedule.TeacherID, schedule.RoomID) {
+				continue
+			}
+			items = append(items, printItem{day: i, lesson: models.Lesson{
+				GroupID:   schedule.GroupID,
+				TeacherID: schedule.TeacherID,
+				SubjectID: schedule.SubjectID,
+				RoomID:    schedule.RoomID,
+				Room:      schedule.Room,
+				StartTime: schedule.StartTime,
+				EndTime:   schedule.EndTime,
+			}})
+			hasItems[i] = true
+		}
+	}
+
+	// Столбцы: учебные дни недели и дни, на которые есть занятия (например, рабочая суббота)
+	columnOf := make(map[int]int)
+	for i, day := range w.days {
+		if !w.weekdays[day.DayOfWeek] && !day.Working && !hasItems[i] {
+			continue
+		}
+		columnOf[i] = len(page.Columns)
+		page.Columns = append(page.Columns, pdf.Column{
+			Date: w.monday.AddDate(0, 0, i),
+			Note: dayNote(day),
+		})
+	}
+
+	rows, rowOf := w.rows(shift, items)
+	page.Rows = rows
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].lesson.StartTime < items[j].lesson.StartTime })
+	for _, item := range items {
+		page.Entries = append(page.Entries, pdf.Entry{
+			Column:  columnOf[item.day],
+			Row:     rowOf(item.lesson.StartTime, item.lesson.EndTime),
+			Lines:   w.lines(view, item.lesson),
+			Changed: item.changed,
+		})
+	}
+
+	return page
+}
+
+// rows строки таблицы: активные слоты смены и время занятий, не совпадающее ни с одним слотом
+func (w *printWeek) rows(shift int, items []printItem) ([]pdf.Row, func(start, end string) int) {
+	type row struct {
+		start, end, label string
+	}
+
+	var list []row
+	number := make(map[int]int) // Номер пары в смене
+	for _, slot := range sortedSlots(w.slots) {
+		number[slot.Shift]++
+		if shift != 0 && slot.Shift != shift {
+			continue
+		}
+		label := strconv.Itoa(number[slot.Shift]) + " пара\n" + slot.StartTime + "-" + slot.EndTime
+		list = append(list, row{slot.StartTime, slot.EndTime, label})
+	}
+
+	find := func(start, end string) int {
+		for i, r := range list {
+			if r.start == start {
+				return i
+			}
+		}
+		for i, r := range list {
+			if models.TimesOverlap(start, end, r.start, r.end) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, item := range items {
+		if find(item.lesson.StartTime, item.lesson.EndTime) < 0 {
+			list = append(list, row{item.lesson.StartTime, item.lesson.EndTime, item.lesson.StartTime + "-" + item.lesson.EndTime})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, _ := models.ParseClock(list[i].start)
+		b, _ := models.ParseClock(list[j].start)
+		return a < b
+	})
+
+	rows := make([]pdf.Row, 0, len(list))
+	for _, r := range list {
+		rows = append(rows, pdf.Row{Label: r.label})
+	}
+	return rows, find
+}
+
+// lines текст ячейки: предмет и то, что не следует из заголовка страницы
+func (w *printWeek) lines(view string, lesson models.Lesson) []string {
+	subject := w.lookups.summary(lesson.SubjectID)
+	room := "ауд. " + lesson.Room
+	switch view {
+	case printTeacher:
+		return []string{subject, w.lookups.groupLabel(lesson.GroupID, lesson.SubgroupID), room}
+	case printRoom:
+		return []string{subject, w.lookups.groupLabel(lesson.GroupID, lesson.SubgroupID), w.lookups.teacherName(lesson.TeacherID)}
+	default:
+		if subgroup := w.lookups.subgroupName(lesson.GroupID, lesson.SubgroupID); subgroup != "" {
+			subject += " (" + subgroup + ")"
+		}
+		return []string{subject, w.lookups.teacherName(lesson.TeacherID), room}
+	}
+}
+
+// changed проверяет, отличается ли урок от записи недельного расписания, из которой он создан
+func (w *printWeek) changed(lesson models.Lesson, day calendar.Day) bool {
+	schedule, ok := w.byID[lesson.ScheduleID]
+	if !ok {
+		return true
+	}
+	return schedule.DayOfWeek != day.TimetableOf ||
+		schedule.TeacherID != lesson.TeacherID ||
+		schedule.RoomID != lesson.RoomID ||
+		schedule.StartTime != lesson.StartTime
+}
+
+// dayNote пояснение для нерабочего дня
+func dayNote(day calendar.Day) string {
+	switch day.Reason {
+	case calendar.ReasonHoliday:
+		return "Праздник: " + day.EventName
+	case calendar.ReasonVacation:
+		return "Каникулы: " + day.EventName
+	case calendar.ReasonOutOfTerm:
+		return "Вне семестра"
+	}
+	return ""
+}
+
+func sortedSlots(slots []models.TimeSlot) []models.TimeSlot {
+	sorted := append([]models.TimeSlot(nil), slots...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, _ := models.ParseClock(sorted[i].StartTime)
+		b, _ := models.ParseClock(sorted[j].StartTime)
+		return a < b
+	})
+	return sorted
+}
+
+// respondPDF отдает PDF с расписанием
+func (h *Handlers) respondPDF(c *gin.Context, name string, pages []pdf.Page) {
+	fonts, err := pdf.LoadFonts(h.cfg.PDFFontPath, h.cfg.PDFBoldFontPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки шрифта для PDF"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Render(&buf, fonts, pages); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка формирования PDF"})
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="`+name+`.pdf"`)
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
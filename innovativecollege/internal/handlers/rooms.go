@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"innovativecollege/internal/database"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errRoomNotFound аудитория не найдена по ID или номеру
+var errRoomNotFound = errors.New("аудитория не найдена")
+
+// CreateRoom создает новую аудиторию
+func (h *Handlers) CreateRoom(c *gin.Context) {
+	var req models.CreateRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	number := models.NormalizeRoomNumber(req.Number)
+	if number == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Номер аудитории не может быть пустым"})
+		return
+	}
+
+	// Номер аудитории должен быть уникальным
+	count, err := h.store.Rooms.Count(c.Request.Context(), bson.M{"number": number})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки аудитории"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Аудитория с таким номером уже существует"})
+		return
+	}
+
+	room := models.Room{
+		Number:    number,
+		Building:  req.Building,
+		Floor:     req.Floor,
+		Capacity:  req.Capacity,
+		Type:      req.Type,
+		Equipment: req.Equipment,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	id, err := h.store.Rooms.Insert(c.Request.Context(), room)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания аудитории"})
+		return
+	}
+
+	room.ID = id
+	c.JSON(http.StatusCreated, room)
+}
+
+// GetRooms получает все аудитории
+func (h *Handlers) GetRooms(c *gin.Context) {
+	filter := bson.M{}
+	if roomType := c.Query("type"); roomType != "" {
+		filter["type"] = roomType
+	}
+	if building := c.Query("building"); building != "" {
+		filter["building"] = building
+	}
+
+	rooms, err := h.store.Rooms.Find(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения аудиторий"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rooms)
+}
+
+// GetRoom получает аудиторию по ID
+func (h *Handlers) GetRoom(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аудитории"})
+		return
+	}
+
+	room, err := h.store.Rooms.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Аудитория не найдена"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска аудитории"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, room)
+}
+
+// UpdateRoom обновляет аудиторию
+func (h *Handlers) UpdateRoom(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аудитории"})
+		return
+	}
+
+	var req models.UpdateRoomRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Проверяем существование аудитории
+	existingRoom, err := h.store.Rooms.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Аудитория не найдена"})
+		return
+	}
+
+	// Создаем объект для обновления
+	update := bson.M{"updated_at": time.Now()}
+	numberChanged := false
+	if req.Number != nil {
+		number := models.NormalizeRoomNumber(*req.Number)
+		if number == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Номер аудитории не может быть пустым"})
+			return
+		}
+		if number != existingRoom.Number {
+			count, err := h.store.Rooms.Count(c.Request.Context(), bson.M{"number": number, "_id": bson.M{"$ne": id}})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки аудитории"})
+				return
+			}
+			if count > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Аудитория с таким номером уже существует"})
+				return
+			}
+			numberChanged = true
+		}
+		update["number"] = number
+	}
+	if req.Building != nil {
+		update["building"] = *req.Building
+	}
+	if req.Floor != nil {
+		update["floor"] = *req.Floor
+	}
+	if req.Capacity != nil {
+		update["capacity"] = *req.Capacity
+	}
+	if req.Type != nil {
+		update["type"] = *req.Type
+	}
+	if req.Equipment != nil {
+		update["equipment"] = req.Equipment
+	}
+
+	_, err = h.store.Rooms.Update(c.Request.Context(), bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления аудитории"})
+		return
+	}
+
+	// Обновляем номер аудитории в расписании и уроках
+	if numberChanged {
+		filter := bson.M{"room_id": id}
+		renamed := bson.M{"$set": bson.M{"room": update["number"]}}
+		if _, err = h.store.Schedules.UpdateMany(c.Request.Context(), filter, renamed); err == nil {
+			_, err = h.store.Lessons.UpdateMany(c.Request.Context(), filter, renamed)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления номера аудитории в расписании"})
+			return
+		}
+	}
+
+	// Получаем обновленную аудиторию
+	updatedRoom, err := h.store.Rooms.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленной аудитории"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedRoom)
+}
+
+// DeleteRoom удаляет аудиторию
+func (h *Handlers) DeleteRoom(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID аудитории"})
+		return
+	}
+
+	// Проверяем существование аудитории
+	if _, err := h.store.Rooms.FindByID(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Аудитория не найдена"})
+		return
+	}
+
+	// Проверяем, используется ли аудитория в расписании или уроках
+	scheduleCount, err := h.store.Schedules.Count(c.Request.Context(), bson.M{"room_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки расписаний"})
+		return
+	}
+
+	lessonCount, err := h.store.Lessons.Count(c.Request.Context(), bson.M{"room_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки уроков"})
+		return
+	}
+
+	if scheduleCount > 0 || lessonCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя удалить аудиторию, которая используется в расписании или уроках"})
+		return
+	}
+
+	_, err = h.store.Rooms.Delete(c.Request.Context(), bson.M{"_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления аудитории"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Аудитория успешно удалена"})
+}
+
+// MigrateRooms переносит текстовые номера аудиторий из расписания и уроков в коллекцию rooms
+func (h *Handlers) MigrateRooms(c *gin.Context) {
+	report, err := database.MigrateRooms(c.Request.Context(), h.store)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка миграции аудиторий: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// resolveRoom находит аудиторию по ID или, если ID не указан, по номеру
+func (h *Handlers) resolveRoom(roomID, number string) (*models.Room, error) {
+	filter := bson.M{}
+	if roomID != "" {
+		id, err := primitive.ObjectIDFromHex(roomID)
+		if err != nil {
+			return nil, errRoomNotFound
+		}
+		filter["_id"] = id
+	} else {
+		normalized := models.NormalizeRoomNumber(number)
+		if normalized == "" {
+			return nil, errRoomNotFound
+		}
+		filter["number"] = normalized
+	}
+
+	room, err := h.store.Rooms.FindOne(context.Background(), filter)
+	if err == storage.ErrNotFound {
+		return nil, errRoomNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return room, nil
+}
+
+// respondRoomError отвечает на ошибку resolveRoom
+func respondRoomError(c *gin.Context, err error) {
+	if errors.Is(err, errRoomNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Аудитория не найдена"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска аудитории"})
+}
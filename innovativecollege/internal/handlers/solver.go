@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/solver"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SolveSchedule автоматически составляет недельное расписание и сохраняет его как черновик
+func (h *Handlers) SolveSchedule(c *gin.Context) {
+	var req models.SolveScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	problem := solver.Problem{
+		Groups: make(map[primitive.ObjectID]solver.Group),
+		Days:   req.Days,
+	}
+	if len(problem.Days) == 0 {
+		problem.Days = h.cfg.WorkingWeekdays
+	}
+
+	// Требования: группа, предмет, преподаватель, часы в неделю
+	var groupIDs []primitive.ObjectID
+	teacherIDs := make(map[primitive.ObjectID]bool)
+	subjectIDs := make(map[primitive.ObjectID]bool)
+	for _, requirement := range req.Requirements {
+		groupID, err1 := primitive.ObjectIDFromHex(requirement.GroupID)
+		subjectID, err2 := primitive.ObjectIDFromHex(requirement.SubjectID)
+		teacherID, err3 := primitive.ObjectIDFromHex(requirement.TeacherID)
+		if err1 != nil || err2 != nil || err3 != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID группы, предмета или преподавателя в требованиях"})
+			return
+		}
+
+		if _, exists := problem.Groups[groupID]; !exists {
+			group, ok := h.solverGroup(c, groupID)
+			if !ok {
+				return
+			}
+			problem.Groups[groupID] = group
+			groupIDs = append(groupIDs, groupID)
+		}
+		teacherIDs[teacherID] = true
+		subjectIDs[subjectID] = true
+
+		problem.Requirements = append(problem.Requirements, solver.Requirement{
+			GroupID:   groupID,
+			SubjectID: subjectID,
+			TeacherID: teacherID,
+			Lessons:   (requirement.HoursPerWeek + models.AcademicHoursPerLesson - 1) / models.AcademicHoursPerLesson,
+			RoomType:  requirement.RoomType,
+		})
+	}
+
+	if !allExist(c, h.store.Teachers, teacherIDs, "Преподаватель из требований не найден") ||
+		!allExist(c, h.store.Subjects, subjectIDs, "Предмет из требований не найден") {
+		return
+	}
+
+	// Недоступное время преподавателей решатель не занимает, пожелания учитывает как штраф
+	ids := make([]primitive.ObjectID, 0, len(teacherIDs))
+	for id := range teacherIDs {
+		ids = append(ids, id)
+	}
+	var teachers []models.Teacher
+	if !findAll(c, h.store.Teachers, bson.M{"_id": bson.M{"$in": ids}}, &teachers, "Ошибка получения преподавателей") {
+		return
+	}
+	problem.Teachers = make(map[primitive.ObjectID]models.TeacherAvailability)
+	for _, teacher := range teachers {
+		if teacher.Availability != nil {
+			problem.Teachers[teacher.ID] = *teacher.Availability
+		}
+	}
+
+	// Временные слоты
+	slotFilter := bson.M{"is_active": true}
+	if len(req.TimeSlotIDs) > 0 {
+		ids, ok := parseObjectIDs(c, req.TimeSlotIDs, "Неверный ID временного слота")
+		if !ok {
+			return
+		}
+		slotFilter = bson.M{"_id": bson.M{"$in": ids}}
+	}
+	var timeSlots []models.TimeSlot
+	if !findAll(c, h.store.TimeSlots, slotFilter, &timeSlots, "Ошибка получения временных слотов") {
+		return
+	}
+	for _, slot := range timeSlots {
+		problem.Slots = append(problem.Slots, solver.Slot{
+			ID:        slot.ID,
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+			Shift:     slot.Shift,
+		})
+	}
+
+	// Аудитории
+	roomFilter := bson.M{}
+	if len(req.RoomIDs) > 0 {
+		ids, ok := parseObjectIDs(c, req.RoomIDs, "Неверный ID аудитории")
+		if !ok {
+			return
+		}
+		roomFilter["_id"] = bson.M{"$in": ids}
+	}
+	var rooms []models.Room
+	if !findAll(c, h.store.Rooms, roomFilter, &rooms, "Ошибка получения аудиторий") {
+		return
+	}
+	for _, room := range rooms {
+		problem.Rooms = append(problem.Rooms, solver.Room{
+			ID:       room.ID,
+			Number:   room.Number,
+			Capacity: room.Capacity,
+			Type:     room.Type,
+		})
+	}
+
+	if len(problem.Slots) == 0 || len(problem.Rooms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нет доступных временных слотов или аудиторий"})
+		return
+	}
+
+	// Расписание остальных групп остается на месте и занимает преподавателей и аудитории
+	if !findAll(c, h.store.Schedules, bson.M{"group_id": bson.M{"$nin": groupIDs}}, &problem.Fixed, "Ошибка получения расписания") {
+		return
+	}
+
+	result := solver.Solve(problem)
+
+	draft := models.ScheduleDraft{
+		Name:      req.Name,
+		Status:    models.DraftStatusDraft,
+		GroupIDs:  groupIDs,
+		Schedules: []models.Schedule{},
+		Unplaced:  []models.UnplacedLesson{},
+		Cost:      result.Cost,
+		CreatedAt: time.Now(),
+	}
+	if draft.Name == "" {
+		draft.Name = "Черновик от " + draft.CreatedAt.Format("02.01.2006 15:04")
+	}
+
+	for _, assignment := range result.Assignments {
+		draft.Schedules = append(draft.Schedules, models.Schedule{
+			GroupID:   assignment.Requirement.GroupID,
+			TeacherID: assignment.Requirement.TeacherID,
+			SubjectID: assignment.Requirement.SubjectID,
+			RoomID:    assignment.Room.ID,
+			Room:      assignment.Room.Number,
+			DayOfWeek: assignment.Day,
+			StartTime: assignment.Slot.StartTime,
+			EndTime:   assignment.Slot.EndTime,
+			Shift:     assignment.Slot.Shift,
+		})
+	}
+	for _, unplaced := range result.Unplaced {
+		draft.Unplaced = append(draft.Unplaced, models.UnplacedLesson{
+			GroupID:   unplaced.Requirement.GroupID,
+			SubjectID: unplaced.Requirement.SubjectID,
+			TeacherID: unplaced.Requirement.TeacherID,
+			Reason:    unplaced.Reason,
+		})
+	}
+
+	id, err := h.store.ScheduleDrafts.Insert(c.Request.Context(), draft)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения черновика"})
+		return
+	}
+
+	draft.ID = id
+	c.JSON(http.StatusCreated, draft)
+}
+
+// GetScheduleDrafts получает все черновики расписания
+func (h *Handlers) GetScheduleDrafts(c *gin.Context) {
+	opts := storage.FindOptions{Sort: bson.D{{Key: "created_at", Value: -1}}, Exclude: []string{"schedules"}}
+	drafts, err := h.store.ScheduleDrafts.Find(c.Request.Context(), bson.M{}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения черновиков"})
+		return
+	}
+
+	c.JSON(http.StatusOK, drafts)
+}
+
+// GetScheduleDraft получает черновик расписания по ID
+func (h *Handlers) GetScheduleDraft(c *gin.Context) {
+	draft, ok := h.findDraft(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
+
+// ApplyScheduleDraft заменяет расписание групп черновика его записями
+func (h *Handlers) ApplyScheduleDraft(c *gin.Context) {
+	draft, ok := h.findDraft(c)
+	if !ok {
+		return
+	}
+
+	if draft.Status != models.DraftStatusDraft {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Черновик уже применен"})
+		return
+	}
+
+	// Записи, которые будут заменены, конфликтами не считаются
+	var replaced []models.Schedule
+	if !findAll(c, h.store.Schedules, bson.M{"group_id": bson.M{"$in": draft.GroupIDs}}, &replaced, "Ошибка получения расписания") {
+		return
+	}
+	replacedIDs := make(map[string]bool, len(replaced))
+	for _, schedule := range replaced {
+		replacedIDs[schedule.ID.Hex()] = true
+	}
+
+	// С момента составления черновика расписание других групп могло измениться
+	var conflicts []models.Conflict
+	for _, schedule := range draft.Schedules {
+		found, err := h.findScheduleConflicts(schedule)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки конфликтов расписания"})
+			return
+		}
+		for _, conflict := range found {
+			if !replacedIDs[conflict.ID] {
+				conflicts = append(conflicts, conflict)
+			}
+		}
+	}
+	if rejectConflicts(c, conflicts, nil) {
+		return
+	}
+
+	if _, err := h.store.Schedules.DeleteMany(c.Request.Context(), bson.M{"group_id": bson.M{"$in": draft.GroupIDs}}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления старого расписания"})
+		return
+	}
+
+	now := time.Now()
+	documents := make([]models.Schedule, 0, len(draft.Schedules))
+	for _, schedule := range draft.Schedules {
+		schedule.CreatedAt = now
+		schedule.UpdatedAt = now
+		documents = append(documents, schedule)
+	}
+	if _, err := h.store.Schedules.InsertMany(c.Request.Context(), documents); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения расписания"})
+		return
+	}
+
+	_, err := h.store.ScheduleDrafts.Update(c.Request.Context(), bson.M{"_id": draft.ID},
+		bson.M{"$set": bson.M{"status": models.DraftStatusApplied, "applied_at": now}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления черновика"})
+		return
+	}
+	h.publishScheduleBatch(c.Request.Context(), "replaced", draft.GroupIDs, len(documents))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Черновик применен",
+		"replaced": len(replaced),
+		"created":  len(documents),
+	})
+}
+
+// DeleteScheduleDraft удаляет черновик расписания
+func (h *Handlers) DeleteScheduleDraft(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID черновика"})
+		return
+	}
+
+	deleted, err := h.store.ScheduleDrafts.Delete(c.Request.Context(), bson.M{"_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления черновика"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Черновик не найден"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Черновик успешно удален"})
+}
+
+func (h *Handlers) findDraft(c *gin.Context) (*models.ScheduleDraft, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID черновика"})
+		return nil, false
+	}
+
+	draft, err := h.store.ScheduleDrafts.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Черновик не найден"})
+		return nil, false
+	}
+
+	return draft, true
+}
+
+// solverGroup загружает смену и численность группы
+func (h *Handlers) solverGroup(c *gin.Context, groupID primitive.ObjectID) (solver.Group, bool) {
+	group, err := h.store.Groups.FindByID(c.Request.Context(), groupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Группа не найдена: " + groupID.Hex()})
+		return solver.Group{}, false
+	}
+
+	size, err := h.store.Students.Count(c.Request.Context(), bson.M{"group_id": groupID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка подсчета студентов"})
+		return solver.Group{}, false
+	}
+
+	return solver.Group{ID: groupID, Shift: group.Shift, Size: int(size)}, true
+}
+
+// allExist проверяет, что все документы с указанными ID существуют, и отвечает 400, если нет
+func allExist[T any](c *gin.Context, repo storage.Repository[T], ids map[primitive.ObjectID]bool, errorMessage string) bool {
+	list := make([]primitive.ObjectID, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+
+	count, err := repo.Count(c.Request.Context(), bson.M{"_id": bson.M{"$in": list}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки связанных данных"})
+		return false
+	}
+	if int(count) != len(list) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errorMessage})
+		return false
+	}
+	return true
+}
+
+// findAll загружает все документы репозитория по фильтру и отвечает 500 при ошибке
+func findAll[T any](c *gin.Context, repo storage.Repository[T], filter bson.M, result *[]T, errorMessage string) bool {
+	found, err := repo.Find(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errorMessage})
+		return false
+	}
+
+	*result = found
+	return true
+}
+
+// parseObjectIDs разбирает список ID и отвечает 400 при ошибке
+func parseObjectIDs(c *gin.Context, values []string, errorMessage string) ([]primitive.ObjectID, bool) {
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errorMessage})
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
@@ -1,42 +1,101 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
+	"sort"
 	"time"
 
+	"innovativecollege/internal/models"
+
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// GetLessonStatistics получает статистику уроков
-func (h *Handlers) GetLessonStatistics(c *gin.Context) {
-	// Получаем параметры
-	startDate := c.Query("start_date")
-	endDate := c.Query("end_date")
-	groupID := c.Query("group_id")
-	teacherID := c.Query("teacher_id")
+// statisticsTopSize сколько преподавателей и групп попадает в рейтинг
+const statisticsTopSize = 10
 
-	// Устанавливаем период по умолчанию (последние 30 дней)
-	if startDate == "" {
-		startDate = time.Now().AddDate(0, 0, -30).Format("2006-01-02")
-	}
-	if endDate == "" {
-		endDate = time.Now().Format("2006-01-02")
+// statisticsPeriod период, за который посчитана статистика
+type statisticsPeriod struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// shiftStatistics количество уроков по сменам
+type shiftStatistics struct {
+	FirstShift  int64 `json:"first_shift"`
+	SecondShift int64 `json:"second_shift"`
+}
+
+// rankedItem преподаватель или группа в рейтинге по количеству уроков
+type rankedItem struct {
+	ID    primitive.ObjectID `bson:"_id" json:"_id"`
+	Count int                `bson:"count" json:"count"`
+	Name  string             `bson:"name,omitempty" json:"name,omitempty"`
+}
+
+// statusCounts количество уроков по статусам
+type statusCounts struct {
+	Planned     int `json:"planned"`
+	Conducted   int `json:"conducted"`
+	Cancelled   int `json:"cancelled"`
+	Rescheduled int `json:"rescheduled"`
+}
+
+func (s *statusCounts) add(status string) {
+	switch status {
+	case models.LessonConducted:
+		s.Conducted++
+	case models.LessonCancelled:
+		s.Cancelled++
+	case models.LessonRescheduled:
+		s.Rescheduled++
+	default:
+		s.Planned++
 	}
+}
 
-	// Парсим даты
-	start, err := time.Parse("2006-01-02", startDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты начала"})
+// statusItem преподаватель или группа с количеством уроков по статусам
+type statusItem struct {
+	ID   primitive.ObjectID `json:"_id"`
+	Name string             `json:"name,omitempty"`
+	statusCounts
+}
+
+// lessonStatistics статистика уроков за период. Перенесенные уроки учитываются
+// только в by_status: вместо них в итоги попадает урок на новую дату
+type lessonStatistics struct {
+	Period       statisticsPeriod `json:"period"`
+	TotalLessons int64            `json:"total_lessons"`
+	ByShift      shiftStatistics  `json:"by_shift"`
+	ByDayOfWeek  map[string]int   `json:"by_day_of_week"`
+	TopTeachers  []rankedItem     `json:"top_teachers"`
+	TopGroups    []rankedItem     `json:"top_groups"`
+	ByStatus     statusCounts     `json:"by_status"`
+	ByTeacher    []statusItem     `json:"by_teacher"` // Запланировано, проведено и отменено по преподавателям
+	ByGroup      []statusItem     `json:"by_group"`
+}
+
+// GetLessonStatistics получает статистику уроков
+func (h *Handlers) GetLessonStatistics(c *gin.Context) {
+	statistics, ok := h.collectLessonStatistics(c)
+	if !ok {
 		return
 	}
 
-	end, err := time.Parse("2006-01-02", endDate)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты окончания"})
-		return
+	c.JSON(http.StatusOK, statistics)
+}
+
+// collectLessonStatistics считает статистику уроков по параметрам запроса
+// и отвечает ошибкой, если посчитать не удалось
+func (h *Handlers) collectLessonStatistics(c *gin.Context) (*lessonStatistics, bool) {
+	// Получаем параметры
+	groupID := c.Query("group_id")
+	teacherID := c.Query("teacher_id")
+
+	period, start, end, ok := h.reportPeriod(c)
+	if !ok {
+		return nil, false
 	}
 
 	// Создаем фильтр
@@ -60,118 +119,177 @@ func (h *Handlers) GetLessonStatistics(c *gin.Context) {
 		}
 	}
 
-	collection := h.db.Collection("lessons")
-
-	// Общее количество уроков
-	totalLessons, err := collection.CountDocuments(context.Background(), filter)
+	lessons, err := h.store.Lessons.Find(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка подсчета уроков"})
-		return
+		return nil, false
 	}
 
-	// Уроки по сменам
-	firstShiftFilter := bson.M{}
-	for k, v := range filter {
-		firstShiftFilter[k] = v
+	// Уроки по сменам и дням недели (день недели берется из даты урока)
+	dayNames := []string{"", "Понедельник", "Вторник", "Среда", "Четверг", "Пятница", "Суббота", "Воскресенье"}
+	dayStats := make(map[string]int)
+	for day := 1; day <= 7; day++ {
+		dayStats[dayNames[day]] = 0
 	}
-	firstShiftFilter["shift"] = 1
-	firstShiftCount, _ := collection.CountDocuments(context.Background(), firstShiftFilter)
 
-	secondShiftFilter := bson.M{}
-	for k, v := range filter {
-		secondShiftFilter[k] = v
-	}
-	secondShiftFilter["shift"] = 2
-	secondShiftCount, _ := collection.CountDocuments(context.Background(), secondShiftFilter)
+	var byShift shiftStatistics
+	var byStatus statusCounts
+	var total int64
+	teacherCounts := make(map[primitive.ObjectID]int)
+	groupCounts := make(map[primitive.ObjectID]int)
+	teacherStatuses := make(map[primitive.ObjectID]*statusCounts)
+	groupStatuses := make(map[primitive.ObjectID]*statusCounts)
+	for _, lesson := range lessons {
+		status := lesson.CurrentStatus()
+		byStatus.add(status)
+		countStatus(teacherStatuses, lesson.TeacherID, status)
+		countStatus(groupStatuses, lesson.GroupID, status)
+		if status == models.LessonRescheduled {
+			continue
+		}
 
-	// Уроки по дням недели
-	dayStats := make(map[string]int)
-	for i := 1; i <= 7; i++ {
-		dayFilter := bson.M{}
-		for k, v := range filter {
-			dayFilter[k] = v
+		total++
+		switch lesson.Shift {
+		case 1:
+			byShift.FirstShift++
+		case 2:
+			byShift.SecondShift++
+		}
+		if lesson.Date != nil {
+			dayStats[dayNames[models.DayOfWeek(*lesson.Date)]]++
 		}
-		dayFilter["day_of_week"] = i
-		count, _ := collection.CountDocuments(context.Background(), dayFilter)
-		dayNames := []string{"", "Понедельник", "Вторник", "Среда", "Четверг", "Пятница", "Суббота", "Воскресенье"}
-		dayStats[dayNames[i]] = int(count)
+		teacherCounts[lesson.TeacherID]++
+		groupCounts[lesson.GroupID]++
 	}
 
-	// Топ преподавателей по количеству уроков
-	pipeline := []bson.M{
-		{"$match": filter},
-		{"$group": bson.M{
-			"_id":   "$teacher_id",
-			"count": bson.M{"$sum": 1},
-		}},
-		{"$sort": bson.M{"count": -1}},
-		{"$limit": 10},
+	// Топ преподавателей и групп по количеству уроков
+	topTeachers := topRanked(teacherCounts, statisticsTopSize)
+	topGroups := topRanked(groupCounts, statisticsTopSize)
+
+	// Заполняем имена преподавателей и групп: по одному запросу на коллекцию
+	related := h.newRelations()
+	teacherIDs := make([]primitive.ObjectID, 0, len(teacherStatuses))
+	for id := range teacherStatuses {
+		teacherIDs = append(teacherIDs, id)
+	}
+	groupIDs := make([]primitive.ObjectID, 0, len(groupStatuses))
+	for id := range groupStatuses {
+		groupIDs = append(groupIDs, id)
+	}
+	if err := related.load(c.Request.Context(), groupIDs, teacherIDs, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения названий"})
+		return nil, false
 	}
 
-	cursor, err := collection.Aggregate(context.Background(), pipeline)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка агрегации"})
-		return
+	for i := range topTeachers {
+		if teacher := related.teachers.get(topTeachers[i].ID); teacher != nil {
+			topTeachers[i].Name = teacher.FirstName + " " + teacher.LastName
+		}
 	}
-	defer cursor.Close(context.Background())
 
-	var topTeachers []bson.M
-	cursor.All(context.Background(), &topTeachers)
+	for i := range topGroups {
+		if group := related.groups.get(topGroups[i].ID); group != nil {
+			topGroups[i].Name = group.Name
+		}
+	}
 
-	// Топ групп по количеству уроков
-	groupPipeline := []bson.M{
-		{"$match": filter},
-		{"$group": bson.M{
-			"_id":   "$group_id",
-			"count": bson.M{"$sum": 1},
-		}},
-		{"$sort": bson.M{"count": -1}},
-		{"$limit": 10},
+	byTeacher := statusItems(teacherStatuses, func(id primitive.ObjectID) string {
+		if teacher := related.teachers.get(id); teacher != nil {
+			return teacher.FirstName + " " + teacher.LastName
+		}
+		return ""
+	})
+	byGroup := statusItems(groupStatuses, func(id primitive.ObjectID) string {
+		if group := related.groups.get(id); group != nil {
+			return group.Name
+		}
+		return ""
+	})
+
+	return &lessonStatistics{
+		Period:       period,
+		TotalLessons: total,
+		ByShift:      byShift,
+		ByDayOfWeek:  dayStats,
+		TopTeachers:  topTeachers,
+		TopGroups:    topGroups,
+		ByStatus:     byStatus,
+		ByTeacher:    byTeacher,
+		ByGroup:      byGroup,
+	}, true
+}
+
+func countStatus(counts map[primitive.ObjectID]*statusCounts, id primitive.ObjectID, status string) {
+	if counts[id] == nil {
+		counts[id] = &statusCounts{}
 	}
+	counts[id].add(status)
+}
 
-	groupCursor, err := collection.Aggregate(context.Background(), groupPipeline)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка агрегации групп"})
-		return
+// statusItems переводит счетчики статусов в список, отсортированный по названию
+func statusItems(counts map[primitive.ObjectID]*statusCounts, name func(primitive.ObjectID) string) []statusItem {
+	items := make([]statusItem, 0, len(counts))
+	for id, count := range counts {
+		items = append(items, statusItem{ID: id, Name: name(id), statusCounts: *count})
 	}
-	defer groupCursor.Close(context.Background())
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Name != items[j].Name {
+			return items[i].Name < items[j].Name
+		}
+		return items[i].ID.Hex() < items[j].ID.Hex()
+	})
+	return items
+}
 
-	var topGroups []bson.M
-	groupCursor.All(context.Background(), &topGroups)
+// reportPeriod разбирает период отчета start_date/end_date. По умолчанию берется
+// текущий семестр, а если его нет - последние 30 дней. При ошибке отвечает 400
+func (h *Handlers) reportPeriod(c *gin.Context) (statisticsPeriod, time.Time, time.Time, bool) {
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
 
-	// Заполняем имена преподавателей и групп
-	for i, teacher := range topTeachers {
-		teacherID := teacher["_id"].(primitive.ObjectID)
-		teacherCollection := h.db.Collection("teachers")
-		var teacherDoc bson.M
-		if err := teacherCollection.FindOne(context.Background(), bson.M{"_id": teacherID}).Decode(&teacherDoc); err == nil {
-			topTeachers[i]["name"] = teacherDoc["first_name"].(string) + " " + teacherDoc["last_name"].(string)
+	if startDate == "" && endDate == "" {
+		if term, err := h.currentTerm(); err == nil && term != nil {
+			startDate = term.StartDate.Format("2006-01-02")
+			endDate = term.EndDate.Format("2006-01-02")
 		}
 	}
+	if startDate == "" {
+		startDate = time.Now().AddDate(0, 0, -30).Format("2006-01-02")
+	}
+	if endDate == "" {
+		endDate = time.Now().Format("2006-01-02")
+	}
 
-	for i, group := range topGroups {
-		groupID := group["_id"].(primitive.ObjectID)
-		groupCollection := h.db.Collection("groups")
-		var groupDoc bson.M
-		if err := groupCollection.FindOne(context.Background(), bson.M{"_id": groupID}).Decode(&groupDoc); err == nil {
-			topGroups[i]["name"] = groupDoc["name"].(string)
-		}
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты начала"})
+		return statisticsPeriod{}, time.Time{}, time.Time{}, false
 	}
 
-	statistics := gin.H{
-		"period": gin.H{
-			"start_date": startDate,
-			"end_date":   endDate,
-		},
-		"total_lessons": totalLessons,
-		"by_shift": gin.H{
-			"first_shift":  firstShiftCount,
-			"second_shift": secondShiftCount,
-		},
-		"by_day_of_week": dayStats,
-		"top_teachers":   topTeachers,
-		"top_groups":     topGroups,
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты окончания"})
+		return statisticsPeriod{}, time.Time{}, time.Time{}, false
 	}
 
-	c.JSON(http.StatusOK, statistics)
+	return statisticsPeriod{StartDate: startDate, EndDate: endDate}, start, end, true
+}
+
+// topRanked возвращает limit элементов с наибольшим количеством уроков
+func topRanked(counts map[primitive.ObjectID]int, limit int) []rankedItem {
+	items := make([]rankedItem, 0, len(counts))
+	for id, count := range counts {
+		items = append(items, rankedItem{ID: id, Count: count})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].ID.Hex() < items[j].ID.Hex()
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items
 }
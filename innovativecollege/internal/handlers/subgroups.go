@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ========== ПОДГРУППЫ ==========
+
+// GetSubgroups получает подгруппы группы
+func (h *Handlers) GetSubgroups(c *gin.Context) {
+	group, ok := h.findSubgroupParent(c)
+	if !ok {
+		return
+	}
+
+	subgroups := group.Subgroups
+	if subgroups == nil {
+		subgroups = []models.Subgroup{}
+	}
+	c.JSON(http.StatusOK, subgroups)
+}
+
+// CreateSubgroup создает подгруппу. Студенты должны быть из этой группы
+func (h *Handlers) CreateSubgroup(c *gin.Context) {
+	group, ok := h.findSubgroupParent(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateSubgroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !uniqueSubgroupName(c, group, primitive.NilObjectID, req.Name) {
+		return
+	}
+	studentIDs, ok := h.subgroupStudents(c, group.ID, req.StudentIDs)
+	if !ok {
+		return
+	}
+
+	subgroup := models.Subgroup{
+		ID:         primitive.NewObjectID(),
+		Name:       strings.TrimSpace(req.Name),
+		StudentIDs: studentIDs,
+	}
+	group.Subgroups = append(group.Subgroups, subgroup)
+	if !h.saveSubgroups(c, group) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, subgroup)
+}
+
+// UpdateSubgroup переименовывает подгруппу или заменяет её состав. Новый состав
+// проверяется на пересечения: студент, попавший в подгруппу, не должен оказаться
+// одновременно на её занятии и на занятии другой своей подгруппы (409, ?force=true)
+func (h *Handlers) UpdateSubgroup(c *gin.Context) {
+	group, ok := h.findSubgroupParent(c)
+	if !ok {
+		return
+	}
+	subgroup, ok := findSubgroup(c, group)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateSubgroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != "" {
+		if !uniqueSubgroupName(c, group, subgroup.ID, req.Name) {
+			return
+		}
+		subgroup.Name = strings.TrimSpace(req.Name)
+	}
+	if req.StudentIDs != nil {
+		studentIDs, ok := h.subgroupStudents(c, group.ID, *req.StudentIDs)
+		if !ok {
+			return
+		}
+		subgroup.StudentIDs = studentIDs
+
+		conflicts, err := h.subgroupConflicts(c.Request.Context(), group, subgroup.ID)
+		if rejectConflicts(c, conflicts, err) {
+			return
+		}
+	}
+	if !h.saveSubgroups(c, group) {
+		return
+	}
+
+	c.JSON(http.StatusOK, subgroup)
+}
+
+// DeleteSubgroup удаляет подгруппу, если по ней нет расписания и уроков
+func (h *Handlers) DeleteSubgroup(c *gin.Context) {
+	group, ok := h.findSubgroupParent(c)
+	if !ok {
+		return
+	}
+	subgroup, ok := findSubgroup(c, group)
+	if !ok {
+		return
+	}
+
+	scheduleCount, err := h.store.Schedules.Count(c.Request.Context(), bson.M{"subgroup_id": subgroup.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки расписания"})
+		return
+	}
+	lessonCount, err := h.store.Lessons.Count(c.Request.Context(), bson.M{"subgroup_id": subgroup.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки уроков"})
+		return
+	}
+	if scheduleCount > 0 || lessonCount > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нельзя удалить подгруппу, по которой есть расписание или уроки"})
+		return
+	}
+
+	subgroups := make([]models.Subgroup, 0, len(group.Subgroups))
+	for _, existing := range group.Subgroups {
+		if existing.ID != subgroup.ID {
+			subgroups = append(subgroups, existing)
+		}
+	}
+	group.Subgroups = subgroups
+	if !h.saveSubgroups(c, group) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Подгруппа успешно удалена"})
+}
+
+// subgroupConflicts ищет занятия других подгрупп группы, которые при составе подгрупп
+// из group пересекаются по времени с занятиями подгруппы subgroupID: недельное расписание
+// и предстоящие уроки. Занятия всей группы пересекаются с подгруппами при любом составе
+// и проверяются при их сохранении
+func (h *Handlers) subgroupConflicts(ctx context.Context, group *models.Group, subgroupID primitive.ObjectID) ([]models.Conflict, error) {
+	filter := bson.M{"group_id": group.ID, "subgroup_id": bson.M{"$exists": true}}
+	clash := func(other primitive.ObjectID) bool {
+		return !other.IsZero() && other != subgroupID && group.SubgroupsClash(subgroupID, other)
+	}
+
+	schedules, err := h.store.Schedules.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []models.Conflict
+	for _, own := range schedules {
+		if own.SubgroupID != subgroupID {
+			continue
+		}
+		for _, other := range schedules {
+			if clash(other.SubgroupID) && own.DayOfWeek == other.DayOfWeek &&
+				models.TimesOverlap(own.StartTime, own.EndTime, other.StartTime, other.EndTime) {
+				conflicts = append(conflicts, models.Conflict{
+					Type:       models.ConflictGroup,
+					Collection: "schedules",
+					ID:         other.ID.Hex(),
+					Document:   other,
+				})
+			}
+		}
+	}
+
+	lessonFilter := bson.M{
+		"date":   bson.M{"$gte": time.Now().UTC().Truncate(24 * time.Hour)},
+		"status": bson.M{"$nin": inactiveLessonStatuses},
+	}
+	for key, value := range filter {
+		lessonFilter[key] = value
+	}
+	lessons, err := h.store.Lessons.Find(ctx, lessonFilter)
+	if err != nil {
+		return nil, err
+	}
+	for _, own := range lessons {
+		if own.SubgroupID != subgroupID || own.Date == nil {
+			continue
+		}
+		for _, other := range lessons {
+			if clash(other.SubgroupID) && other.Date != nil && own.Date.Equal(*other.Date) &&
+				models.TimesOverlap(own.StartTime, own.EndTime, other.StartTime, other.EndTime) {
+				conflicts = append(conflicts, models.Conflict{
+					Type:       models.ConflictGroup,
+					Collection: "lessons",
+					ID:         other.ID.Hex(),
+					Document:   other,
+				})
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// findSubgroupParent находит группу из параметра :id. При ошибке отвечает 400 или 404
+func (h *Handlers) findSubgroupParent(c *gin.Context) (*models.Group, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID группы"})
+		return nil, false
+	}
+
+	group, err := h.store.Groups.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Группа не найдена"})
+		return nil, false
+	}
+	return group, true
+}
+
+// findSubgroup находит подгруппу из параметра :subgroup_id. Возвращает указатель
+// на элемент group.Subgroups, чтобы изменения попали в группу
+func findSubgroup(c *gin.Context, group *models.Group) (*models.Subgroup, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("subgroup_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID подгруппы"})
+		return nil, false
+	}
+
+	subgroup := group.Subgroup(id)
+	if subgroup == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Подгруппа не найдена"})
+		return nil, false
+	}
+	return subgroup, true
+}
+
+// uniqueSubgroupName проверяет, что в группе нет другой подгруппы с таким названием
+func uniqueSubgroupName(c *gin.Context, group *models.Group, exceptID primitive.ObjectID, name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите название подгруппы"})
+		return false
+	}
+	for _, existing := range group.Subgroups {
+		if existing.ID != exceptID && strings.EqualFold(existing.Name, name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Подгруппа с таким названием уже есть"})
+			return false
+		}
+	}
+	return true
+}
+
+// subgroupStudents разбирает состав подгруппы и проверяет, что все студенты из группы
+func (h *Handlers) subgroupStudents(c *gin.Context, groupID primitive.ObjectID, values []string) ([]primitive.ObjectID, bool) {
+	ids := []primitive.ObjectID{}
+	seen := make(map[primitive.ObjectID]bool)
+	for _, value := range values {
+		id, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID студента"})
+			return nil, false
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, true
+	}
+
+	count, err := h.store.Students.Count(c.Request.Context(), bson.M{"_id": bson.M{"$in": ids}, "group_id": groupID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки студентов"})
+		return nil, false
+	}
+	if int(count) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "В подгруппу можно добавить только студентов этой группы"})
+		return nil, false
+	}
+	return ids, true
+}
+
+// saveSubgroups сохраняет подгруппы группы
+func (h *Handlers) saveSubgroups(c *gin.Context, group *models.Group) bool {
+	_, err := h.store.Groups.Update(c.Request.Context(), bson.M{"_id": group.ID}, bson.M{"$set": bson.M{
+		"subgroups":  group.Subgroups,
+		"updated_at": time.Now(),
+	}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения подгрупп"})
+		return false
+	}
+	return true
+}
+
+// resolveSubgroup проверяет, что подгруппа из запроса есть в группе. Пустая строка -
+// занятие всей группы. При ошибке отвечает 400
+func (h *Handlers) resolveSubgroup(c *gin.Context, groupID primitive.ObjectID, value string) (primitive.ObjectID, bool) {
+	if value == "" {
+		return primitive.NilObjectID, true
+	}
+
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID подгруппы"})
+		return primitive.NilObjectID, false
+	}
+	group, err := h.store.Groups.FindByID(c.Request.Context(), groupID)
+	if err != nil || group.Subgroup(id) == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Подгруппа не найдена в группе"})
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// removeFromSubgroups убирает студента из подгрупп прежней группы при переводе
+func (h *Handlers) removeFromSubgroups(ctx context.Context, groupID, studentID primitive.ObjectID) error {
+	group, err := h.store.Groups.FindByID(ctx, groupID)
+	if err == storage.ErrNotFound {
+		return nil // Группа удалена: подгрупп у студента больше нет
+	}
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for i := range group.Subgroups {
+		if group.Subgroups[i].HasStudent(studentID) {
+			group.Subgroups[i].StudentIDs = withoutID(group.Subgroups[i].StudentIDs, studentID)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	_, err = h.store.Groups.Update(ctx, bson.M{"_id": group.ID}, bson.M{"$set": bson.M{
+		"subgroups":  group.Subgroups,
+		"updated_at": time.Now(),
+	}})
+	return err
+}
+
+// studentLessonFilter фильтр расписания и уроков студента: занятия его группы,
+// кроме занятий подгрупп, в которые он не входит
+func (h *Handlers) studentLessonFilter(ctx context.Context, student *models.Student) (bson.M, error) {
+	group, err := h.store.Groups.FindByID(ctx, student.GroupID)
+	if err == storage.ErrNotFound {
+		group = &models.Group{ID: student.GroupID} // Группа удалена: подгрупп нет
+	} else if err != nil {
+		return nil, err
+	}
+	return group.StudentLessonFilter(student.ID), nil
+}
+
+// lessonStudentFilter фильтр студентов урока: вся группа или только подгруппа
+func (h *Handlers) lessonStudentFilter(ctx context.Context, lesson *models.Lesson) (bson.M, error) {
+	if lesson.SubgroupID.IsZero() {
+		return models.Group{ID: lesson.GroupID}.LessonStudentFilter(lesson.SubgroupID), nil
+	}
+
+	group, err := h.store.Groups.FindByID(ctx, lesson.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	return group.LessonStudentFilter(lesson.SubgroupID), nil
+}
+
+func withoutID(ids []primitive.ObjectID, id primitive.ObjectID) []primitive.ObjectID {
+	result := make([]primitive.ObjectID, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
+
+// updatedSubgroup разбирает subgroup_id запроса на обновление расписания или урока.
+// Подгруппа проверяется в итоговой группе; при смене группы без subgroup_id занятие
+// становится занятием всей группы. changed=false - подгруппа не меняется
+func (h *Handlers) updatedSubgroup(c *gin.Context, value *string, groupID primitive.ObjectID, groupChanged bool) (id primitive.ObjectID, changed, ok bool) {
+	if value == nil && !groupChanged {
+		return primitive.NilObjectID, false, true
+	}
+
+	subgroupID := ""
+	if value != nil {
+		subgroupID = *value
+	}
+	id, ok = h.resolveSubgroup(c, groupID, subgroupID)
+	return id, ok, ok
+}
+
+// subgroupChange дополняет изменение документа новой подгруппой: пустая удаляется из документа
+func subgroupChange(change bson.M, subgroupID primitive.ObjectID) {
+	if subgroupID.IsZero() {
+		change["$unset"] = bson.M{"subgroup_id": ""}
+		return
+	}
+	change["$set"].(bson.M)["subgroup_id"] = subgroupID
+}
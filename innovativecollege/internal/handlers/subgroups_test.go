@@ -0,0 +1,99 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUpdateSubgroupChecksMembershipConflicts(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+
+	groupID := primitive.NewObjectID()
+	first, _ := store.Students.Insert(ctx, models.Student{IIN: "050000000001", FirstName: "Иван", LastName: "Петров", GroupID: groupID})
+	second, _ := store.Students.Insert(ctx, models.Student{IIN: "050000000002", FirstName: "Мария", LastName: "Ким", GroupID: groupID})
+	third, _ := store.Students.Insert(ctx, models.Student{IIN: "050000000003", FirstName: "Олег", LastName: "Ли", GroupID: groupID})
+	english := models.Subgroup{ID: primitive.NewObjectID(), Name: "Английский", StudentIDs: []primitive.ObjectID{first}}
+	german := models.Subgroup{ID: primitive.NewObjectID(), Name: "Немецкий", StudentIDs: []primitive.ObjectID{second}}
+	if _, err := store.Groups.Insert(ctx, models.Group{ID: groupID, Name: "ПО-31", Shift: 1, Subgroups: []models.Subgroup{english, german}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Подгруппы занимаются одновременно: в расписании по понедельникам и на уроке через неделю
+	date := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 7)
+	for i, subgroup := range []models.Subgroup{english, german} {
+		teacherID := primitive.NewObjectID()
+		if _, err := store.Schedules.Insert(ctx, models.Schedule{
+			GroupID: groupID, SubgroupID: subgroup.ID, TeacherID: teacherID, Room: []string{"101", "102"}[i],
+			DayOfWeek: 1, StartTime: "08:00", EndTime: "09:20", Shift: 1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Lessons.Insert(ctx, models.Lesson{
+			GroupID: groupID, SubgroupID: subgroup.ID, TeacherID: teacherID, Room: []string{"101", "102"}[i],
+			Date: &date, StartTime: "08:00", EndTime: "09:20", Shift: 1, Status: models.LessonPlanned,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	api := newAdminAPI(t, store)
+	path := "/groups/" + groupID.Hex() + "/subgroups/" + german.ID.Hex()
+
+	// Новый студент без других подгрупп пересечений не дает
+	body := `{"student_ids": ["` + second.Hex() + `", "` + third.Hex() + `"]}`
+	if recorder := api.do(t, http.MethodPut, path, body); recorder.Code != http.StatusOK {
+		t.Fatalf("статус %d, ожидали 200: %s", recorder.Code, recorder.Body.String())
+	}
+
+	// Студент английской подгруппы оказался бы на двух занятиях одновременно
+	body = `{"student_ids": ["` + second.Hex() + `", "` + first.Hex() + `"]}`
+	recorder := api.do(t, http.MethodPut, path, body)
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("статус %d, ожидали 409: %s", recorder.Code, recorder.Body.String())
+	}
+	var response struct {
+		Conflicts []models.Conflict `json:"conflicts"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	collections := map[string]int{}
+	for _, conflict := range response.Conflicts {
+		if conflict.Type != models.ConflictGroup {
+			t.Fatalf("тип конфликта %q", conflict.Type)
+		}
+		collections[conflict.Collection]++
+	}
+	if collections["schedules"] != 1 || collections["lessons"] != 1 {
+		t.Fatalf("конфликты: %+v", response.Conflicts)
+	}
+
+	group, err := store.Groups.FindByID(ctx, groupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Subgroup(german.ID).HasStudent(first) {
+		t.Fatal("состав сохранен несмотря на конфликт")
+	}
+
+	// С force состав сохраняется
+	if recorder := api.do(t, http.MethodPut, path+"?force=true", body); recorder.Code != http.StatusOK {
+		t.Fatalf("статус %d с force, ожидали 200: %s", recorder.Code, recorder.Body.String())
+	}
+	group, err = store.Groups.FindByID(ctx, groupID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !group.Subgroup(german.ID).HasStudent(first) {
+		t.Fatal("состав не сохранен с force")
+	}
+}
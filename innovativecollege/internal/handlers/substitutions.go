@@ -0,0 +1,625 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"innovativecollege/internal/audit"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// substituteCandidate преподаватель, который может заменить урок
+type substituteCandidate struct {
+	Teacher        models.Teacher `json:"teacher"`
+	LessonsThatDay int            `json:"lessons_that_day"` // Сколько уроков у него уже есть в этот день
+}
+
+// teacherSubstitutions итоги замен за месяц по одному преподавателю
+type teacherSubstitutions struct {
+	TeacherID   primitive.ObjectID `json:"teacher_id"`
+	Name        string             `json:"name"`
+	Substituted int                `json:"substituted"` // Провел уроков вместо коллег
+	Replaced    int                `json:"replaced"`    // Его уроков провели другие
+	Hours       int                `json:"hours"`       // Академические часы замен, которые он провел
+}
+
+// substitutionReport отчет о заменах за месяц
+type substitutionReport struct {
+	Month         string                 `json:"month"` // "2024-10"
+	Total         int                    `json:"total"`
+	Teachers      []teacherSubstitutions `json:"teachers"`
+	Substitutions []models.Substitution  `json:"substitutions"`
+}
+
+// ========== ОТСУТСТВИЯ ПРЕПОДАВАТЕЛЕЙ ==========
+
+// CreateTeacherAbsence регистрирует отсутствие преподавателя за период
+func (h *Handlers) CreateTeacherAbsence(c *gin.Context) {
+	var req models.CreateTeacherAbsenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	teacherID, err := primitive.ObjectIDFromHex(req.TeacherID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID преподавателя"})
+		return
+	}
+	teacher, err := h.store.Teachers.FindByID(c.Request.Context(), teacherID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Преподаватель не найден"})
+		return
+	}
+
+	start, end, ok := parseDateRange(c, req.StartDate, req.EndDate)
+	if !ok {
+		return
+	}
+
+	// Отсутствия одного преподавателя не должны пересекаться
+	overlapping, err := h.store.TeacherAbsences.Count(c.Request.Context(), bson.M{
+		"teacher_id": teacherID,
+		"start_date": bson.M{"$lte": end},
+		"end_date":   bson.M{"$gte": start},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки отсутствий"})
+		return
+	}
+	if overlapping > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Отсутствие пересекается с уже зарегистрированным"})
+		return
+	}
+
+	absence := models.TeacherAbsence{
+		TeacherID: teacherID,
+		StartDate: start,
+		EndDate:   end,
+		Reason:    req.Reason,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	id, err := h.store.TeacherAbsences.Insert(c.Request.Context(), absence)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка регистрации отсутствия"})
+		return
+	}
+
+	absence.ID = id
+	absence.Teacher = teacher
+	c.JSON(http.StatusCreated, absence)
+}
+
+// GetTeacherAbsences получает отсутствия с фильтрами teacher_id и периодом start_date/end_date
+func (h *Handlers) GetTeacherAbsences(c *gin.Context) {
+	filter := bson.M{}
+	addObjectIDFilter(filter, "teacher_id", c.Query("teacher_id"))
+	if startDate := c.Query("start_date"); startDate != "" {
+		start, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты начала"})
+			return
+		}
+		filter["end_date"] = bson.M{"$gte": start}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		end, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат даты окончания"})
+			return
+		}
+		filter["start_date"] = bson.M{"$lte": end}
+	}
+
+	absences, err := h.store.TeacherAbsences.Find(c.Request.Context(), filter, storage.FindOptions{
+		Sort: bson.D{{Key: "start_date", Value: -1}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения отсутствий"})
+		return
+	}
+
+	if err := h.newRelations().populateAbsences(c.Request.Context(), absences); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения преподавателей"})
+		return
+	}
+
+	c.JSON(http.StatusOK, absences)
+}
+
+// GetAbsenceLessons получает уроки, которые попадают на отсутствие преподавателя,
+// включая уже отданные на замену
+func (h *Handlers) GetAbsenceLessons(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID отсутствия"})
+		return
+	}
+
+	absence, err := h.store.TeacherAbsences.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Отсутствие не найдено"})
+		return
+	}
+
+	lessons, err := h.store.Lessons.Find(c.Request.Context(), bson.M{
+		"date": bson.M{"$gte": absence.StartDate, "$lt": absence.EndDate.AddDate(0, 0, 1)},
+		"$or": []bson.M{
+			{"teacher_id": absence.TeacherID},
+			{"original_teacher_id": absence.TeacherID},
+		},
+	}, storage.FindOptions{Sort: bson.D{{Key: "date", Value: 1}, {Key: "start_time", Value: 1}}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения уроков"})
+		return
+	}
+
+	related := h.newRelations()
+	if err := related.populateLessons(c.Request.Context(), lessons); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных уроков"})
+		return
+	}
+	if err := related.teachers.load(c.Request.Context(), []primitive.ObjectID{absence.TeacherID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения преподавателя"})
+		return
+	}
+	absence.Teacher = related.teachers.get(absence.TeacherID)
+
+	// Урок без замены все еще числится за отсутствующим преподавателем.
+	// Отмененные и перенесенные уроки замены не требуют
+	uncovered := 0
+	for _, lesson := range lessons {
+		if lesson.TeacherID == absence.TeacherID && lesson.Active() {
+			uncovered++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"absence":   absence,
+		"lessons":   lessons,
+		"total":     len(lessons),
+		"uncovered": uncovered,
+	})
+}
+
+// DeleteTeacherAbsence удаляет отсутствие. Назначенные замены остаются в силе
+func (h *Handlers) DeleteTeacherAbsence(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID отсутствия"})
+		return
+	}
+
+	deleted, err := h.store.TeacherAbsences.Delete(c.Request.Context(), bson.M{"_id": id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления отсутствия"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Отсутствие не найдено"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Отсутствие успешно удалено"})
+}
+
+// ========== ЗАМЕНЫ ==========
+
+// GetSubstituteCandidates подбирает замену на урок: преподаватели, которые ведут
+// этот предмет, не отсутствуют, доступны и свободны в это время. Сначала менее загруженные
+func (h *Handlers) GetSubstituteCandidates(c *gin.Context) {
+	lesson, ok := h.substitutionLesson(c)
+	if !ok {
+		return
+	}
+
+	subject, err := h.store.Subjects.FindByID(c.Request.Context(), lesson.SubjectID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Предмет урока не найден"})
+		return
+	}
+
+	teachers, err := h.store.Teachers.Find(c.Request.Context(), bson.M{}, storage.FindOptions{
+		Sort: bson.D{{Key: "last_name", Value: 1}, {Key: "first_name", Value: 1}},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения преподавателей"})
+		return
+	}
+
+	day := truncateToDay(*lesson.Date)
+	absences, err := h.store.TeacherAbsences.Find(c.Request.Context(), bson.M{
+		"start_date": bson.M{"$lte": day},
+		"end_date":   bson.M{"$gte": day},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения отсутствий"})
+		return
+	}
+	absent := make(map[primitive.ObjectID]bool, len(absences))
+	for _, absence := range absences {
+		absent[absence.TeacherID] = true
+	}
+
+	// Один запрос на все уроки дня: по ним считаются занятость и нагрузка
+	dayLessons, err := h.store.Lessons.Find(c.Request.Context(), bson.M{
+		"date": bson.M{"$gte": day, "$lt": day.AddDate(0, 0, 1)},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения уроков"})
+		return
+	}
+	busy := make(map[primitive.ObjectID]bool)
+	load := make(map[primitive.ObjectID]int)
+	for _, other := range dayLessons {
+		if !other.Active() {
+			continue
+		}
+		load[other.TeacherID]++
+		if other.ID != lesson.ID && models.TimesOverlap(lesson.StartTime, lesson.EndTime, other.StartTime, other.EndTime) {
+			busy[other.TeacherID] = true
+		}
+	}
+
+	candidates := []substituteCandidate{}
+	for _, teacher := range teachers {
+		if teacher.ID == lesson.TeacherID || absent[teacher.ID] || busy[teacher.ID] || !teacher.Teaches(*subject) {
+			continue
+		}
+		if teacher.Availability.UnavailableAt(models.DayOfWeek(day), lesson.StartTime, lesson.EndTime) != nil {
+			continue
+		}
+		candidates = append(candidates, substituteCandidate{Teacher: teacher, LessonsThatDay: load[teacher.ID]})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].LessonsThatDay < candidates[j].LessonsThatDay
+	})
+
+	c.JSON(http.StatusOK, candidates)
+}
+
+// ApplySubstitution назначает замену на урок. В уроке сохраняется заменяемый
+// преподаватель (original_teacher_id), а в коллекции substitutions - запись о замене.
+// Преподавателя, который не ведет предмет урока, можно назначить только с ?force=true
+func (h *Handlers) ApplySubstitution(c *gin.Context) {
+	lesson, ok := h.substitutionLesson(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateSubstitutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	substituteID, err := primitive.ObjectIDFromHex(req.SubstituteTeacherID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID преподавателя"})
+		return
+	}
+	substitute, err := h.store.Teachers.FindByID(c.Request.Context(), substituteID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Преподаватель не найден"})
+		return
+	}
+
+	originalID := lesson.TeacherID
+	if !lesson.OriginalTeacherID.IsZero() {
+		originalID = lesson.OriginalTeacherID
+	}
+	if substituteID == lesson.TeacherID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Преподаватель уже ведет этот урок"})
+		return
+	}
+	if substituteID == originalID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Чтобы вернуть урок преподавателю, отмените замену"})
+		return
+	}
+
+	day := truncateToDay(*lesson.Date)
+	absentSubstitute, err := h.store.TeacherAbsences.Count(c.Request.Context(), bson.M{
+		"teacher_id": substituteID,
+		"start_date": bson.M{"$lte": day},
+		"end_date":   bson.M{"$gte": day},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки отсутствий"})
+		return
+	}
+	if absentSubstitute > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Преподаватель отсутствует в этот день"})
+		return
+	}
+
+	if !forceRequested(c) {
+		subject, err := h.store.Subjects.FindByID(c.Request.Context(), lesson.SubjectID)
+		if err != nil || !substitute.Teaches(*subject) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Преподаватель не ведет предмет урока (назначить все равно: ?force=true)"})
+			return
+		}
+	}
+
+	// Замена не должна вести другой урок в это же время
+	candidate := *lesson
+	candidate.TeacherID = substituteID
+	conflicts, err := h.findLessonConflicts(candidate)
+	var teacherConflicts []models.Conflict
+	for _, conflict := range conflicts {
+		if conflict.Type == models.ConflictTeacher {
+			teacherConflicts = append(teacherConflicts, conflict)
+		}
+	}
+	if rejectConflicts(c, teacherConflicts, err) {
+		return
+	}
+	if h.checkLessonAvailability(c, &candidate) {
+		return
+	}
+
+	// Отсутствие, из-за которого нужна замена, если оно зарегистрировано
+	var absenceID primitive.ObjectID
+	if absence, err := h.store.TeacherAbsences.FindOne(c.Request.Context(), bson.M{
+		"teacher_id": originalID,
+		"start_date": bson.M{"$lte": day},
+		"end_date":   bson.M{"$gte": day},
+	}); err == nil {
+		absenceID = absence.ID
+	}
+
+	_, err = h.store.Lessons.Update(c.Request.Context(), bson.M{"_id": lesson.ID}, bson.M{"$set": bson.M{
+		"teacher_id":          substituteID,
+		"original_teacher_id": originalID,
+		"updated_at":          time.Now(),
+	}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка назначения замены"})
+		return
+	}
+	substituted := *lesson
+	substituted.TeacherID = substituteID
+	substituted.OriginalTeacherID = originalID
+	h.publishLesson(c.Request.Context(), models.EventLessonUpdated, substituted)
+
+	substitution := models.Substitution{
+		LessonID:            lesson.ID,
+		AbsenceID:           absenceID,
+		OriginalTeacherID:   originalID,
+		SubstituteTeacherID: substituteID,
+		GroupID:             lesson.GroupID,
+		SubjectID:           lesson.SubjectID,
+		Date:                day,
+		StartTime:           lesson.StartTime,
+		EndTime:             lesson.EndTime,
+		Reason:              req.Reason,
+		CreatedBy:           audit.ActorFrom(c.Request.Context()).Login,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+
+	// Повторная замена того же урока обновляет существующую запись
+	existing, err := h.store.Substitutions.FindOne(c.Request.Context(), bson.M{"lesson_id": lesson.ID})
+	switch {
+	case err == nil:
+		substitution.ID = existing.ID
+		substitution.CreatedAt = existing.CreatedAt
+		_, err = h.store.Substitutions.Update(c.Request.Context(), bson.M{"_id": existing.ID}, bson.M{"$set": bson.M{
+			"absence_id":            absenceID,
+			"substitute_teacher_id": substituteID,
+			"reason":                req.Reason,
+			"created_by":            substitution.CreatedBy,
+			"updated_at":            substitution.UpdatedAt,
+		}})
+	case err == storage.ErrNotFound:
+		substitution.ID, err = h.store.Substitutions.Insert(c.Request.Context(), substitution)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка сохранения замены"})
+		return
+	}
+
+	substitutions := []models.Substitution{substitution}
+	if err := h.newRelations().populateSubstitutions(c.Request.Context(), substitutions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных замены"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, substitutions[0])
+}
+
+// CancelSubstitution отменяет замену и возвращает урок заменяемому преподавателю
+func (h *Handlers) CancelSubstitution(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID замены"})
+		return
+	}
+
+	substitution, err := h.store.Substitutions.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Замена не найдена"})
+		return
+	}
+
+	// Урок мог быть удален или снова изменен вручную: возвращаем только свою замену
+	returned, err := h.store.Lessons.Update(c.Request.Context(),
+		bson.M{"_id": substitution.LessonID, "teacher_id": substitution.SubstituteTeacherID},
+		bson.M{
+			"$set":   bson.M{"teacher_id": substitution.OriginalTeacherID, "updated_at": time.Now()},
+			"$unset": bson.M{"original_teacher_id": ""},
+		},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка возврата урока преподавателю"})
+		return
+	}
+	if returned.Matched > 0 {
+		if lesson, err := h.store.Lessons.FindByID(c.Request.Context(), substitution.LessonID); err == nil {
+			h.publishLesson(c.Request.Context(), models.EventLessonUpdated, *lesson)
+		}
+	}
+
+	if _, err := h.store.Substitutions.Delete(c.Request.Context(), bson.M{"_id": id}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления замены"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Замена отменена"})
+}
+
+// GetSubstitutions получает замены с фильтрами teacher_id (заменяемый или замена),
+// group_id, month или start_date/end_date
+func (h *Handlers) GetSubstitutions(c *gin.Context) {
+	params, ok := parseListParams(c, []string{"date", "start_time", "created_at"}, "date,start_time")
+	if !ok {
+		return
+	}
+
+	filter := bson.M{}
+	addObjectIDFilter(filter, "group_id", c.Query("group_id"))
+	if teacherID, err := primitive.ObjectIDFromHex(c.Query("teacher_id")); err == nil {
+		filter["$or"] = []bson.M{
+			{"original_teacher_id": teacherID},
+			{"substitute_teacher_id": teacherID},
+		}
+	}
+	if month := c.Query("month"); month != "" {
+		start, end, ok := parseMonth(c, month)
+		if !ok {
+			return
+		}
+		filter["date"] = bson.M{"$gte": start, "$lt": end}
+	} else if c.Query("start_date") != "" && c.Query("end_date") != "" {
+		start, end, ok := parseDateRange(c, c.Query("start_date"), c.Query("end_date"))
+		if !ok {
+			return
+		}
+		filter["date"] = bson.M{"$gte": start, "$lt": end.AddDate(0, 0, 1)}
+	}
+
+	substitutions, total, ok := findPage(c, h.store.Substitutions, filter, params, "Ошибка получения замен")
+	if !ok {
+		return
+	}
+
+	if err := h.newRelations().populateSubstitutions(c.Request.Context(), substitutions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных замен"})
+		return
+	}
+
+	respondList(c, substitutions, total, params)
+}
+
+// GetSubstitutionReport отчет о заменах за месяц (?month=2024-10, по умолчанию текущий):
+// сколько уроков каждый преподаватель провел за коллег и сколько его уроков провели другие
+func (h *Handlers) GetSubstitutionReport(c *gin.Context) {
+	month := c.DefaultQuery("month", time.Now().Format("2006-01"))
+	start, end, ok := parseMonth(c, month)
+	if !ok {
+		return
+	}
+
+	substitutions, err := h.store.Substitutions.Find(c.Request.Context(),
+		bson.M{"date": bson.M{"$gte": start, "$lt": end}},
+		storage.FindOptions{Sort: bson.D{{Key: "date", Value: 1}, {Key: "start_time", Value: 1}}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения замен"})
+		return
+	}
+
+	related := h.newRelations()
+	if err := related.populateSubstitutions(c.Request.Context(), substitutions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения связанных данных замен"})
+		return
+	}
+
+	byTeacher := make(map[primitive.ObjectID]*teacherSubstitutions)
+	teacherTotals := func(id primitive.ObjectID) *teacherSubstitutions {
+		if totals, ok := byTeacher[id]; ok {
+			return totals
+		}
+		totals := &teacherSubstitutions{TeacherID: id}
+		if teacher := related.teachers.get(id); teacher != nil {
+			totals.Name = teacher.LastName + " " + teacher.FirstName
+		}
+		byTeacher[id] = totals
+		return totals
+	}
+	for _, substitution := range substitutions {
+		substitute := teacherTotals(substitution.SubstituteTeacherID)
+		substitute.Substituted++
+		substitute.Hours += models.AcademicHoursPerLesson
+		teacherTotals(substitution.OriginalTeacherID).Replaced++
+	}
+
+	teachers := make([]teacherSubstitutions, 0, len(byTeacher))
+	for _, totals := range byTeacher {
+		teachers = append(teachers, *totals)
+	}
+	sort.Slice(teachers, func(i, j int) bool {
+		if teachers[i].Substituted != teachers[j].Substituted {
+			return teachers[i].Substituted > teachers[j].Substituted
+		}
+		return teachers[i].Name < teachers[j].Name
+	})
+
+	c.JSON(http.StatusOK, substitutionReport{
+		Month:         month,
+		Total:         len(substitutions),
+		Teachers:      teachers,
+		Substitutions: substitutions,
+	})
+}
+
+// substitutionLesson загружает урок из :id и проверяет, что он запланирован на дату и время
+func (h *Handlers) substitutionLesson(c *gin.Context) (*models.Lesson, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID урока"})
+		return nil, false
+	}
+
+	lesson, err := h.store.Lessons.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Урок не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска урока"})
+		}
+		return nil, false
+	}
+
+	if lesson.Date == nil || lesson.StartTime == "" || lesson.EndTime == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Замена возможна только для урока с датой и временем"})
+		return nil, false
+	}
+	if lesson.CurrentStatus() != models.LessonPlanned {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Замена возможна только для запланированного урока"})
+		return nil, false
+	}
+	return lesson, true
+}
+
+// parseMonth разбирает месяц "2024-10" и возвращает его первый день и первый день следующего
+func parseMonth(c *gin.Context, value string) (time.Time, time.Time, bool) {
+	start, err := time.Parse("2006-01", value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный формат месяца. Используйте YYYY-MM"})
+		return time.Time{}, time.Time{}, false
+	}
+	return start, start.AddDate(0, 1, 0), true
+}
+
+// truncateToDay отбрасывает время, оставляя полночь того же дня
+func truncateToDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
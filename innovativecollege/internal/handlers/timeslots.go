@@ -1,17 +1,16 @@
 package handlers
 
 import (
-	"context"
 	"net/http"
 	"strconv"
 	"time"
 
 	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // CreateTimeSlot создает новый временной слот
@@ -37,21 +36,19 @@ func (h *Handlers) CreateTimeSlot(c *gin.Context) {
 		UpdatedAt: time.Now(),
 	}
 
-	collection := h.db.Collection("time_slots")
-	result, err := collection.InsertOne(context.Background(), timeSlot)
+	id, err := h.store.TimeSlots.Insert(c.Request.Context(), timeSlot)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания временного слота"})
 		return
 	}
 
-	timeSlot.ID = result.InsertedID.(primitive.ObjectID)
+	timeSlot.ID = id
+	h.publishTimeSlot(c.Request.Context(), models.EventTimeSlotCreated, timeSlot)
 	c.JSON(http.StatusCreated, timeSlot)
 }
 
 // GetTimeSlots получает все временные слоты
 func (h *Handlers) GetTimeSlots(c *gin.Context) {
-	collection := h.db.Collection("time_slots")
-
 	// Получаем параметры фильтрации
 	shift := c.Query("shift")
 	isActive := c.Query("is_active")
@@ -68,18 +65,11 @@ func (h *Handlers) GetTimeSlots(c *gin.Context) {
 		}
 	}
 
-	cursor, err := collection.Find(context.Background(), filter)
+	timeSlots, err := h.store.TimeSlots.Find(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения временных слотов"})
 		return
 	}
-	defer cursor.Close(context.Background())
-
-	var timeSlots []models.TimeSlot
-	if err = cursor.All(context.Background(), &timeSlots); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обработки временных слотов"})
-		return
-	}
 
 	c.JSON(http.StatusOK, timeSlots)
 }
@@ -93,11 +83,9 @@ func (h *Handlers) GetTimeSlot(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.Collection("time_slots")
-	var timeSlot models.TimeSlot
-	err = collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&timeSlot)
+	timeSlot, err := h.store.TimeSlots.FindByID(c.Request.Context(), objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == storage.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Временной слот не найден"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска временного слота"})
@@ -123,13 +111,10 @@ func (h *Handlers) UpdateTimeSlot(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.Collection("time_slots")
-
 	// Проверяем существование временного слота
-	var existingTimeSlot models.TimeSlot
-	err = collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&existingTimeSlot)
+	existingTimeSlot, err := h.store.TimeSlots.FindByID(c.Request.Context(), objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == storage.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Временной слот не найден"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска временного слота"})
@@ -168,20 +153,20 @@ func (h *Handlers) UpdateTimeSlot(c *gin.Context) {
 		update["label"] = startTime + "-" + endTime
 	}
 
-	_, err = collection.UpdateOne(context.Background(), bson.M{"_id": objectID}, bson.M{"$set": update})
+	_, err = h.store.TimeSlots.Update(c.Request.Context(), bson.M{"_id": objectID}, bson.M{"$set": update})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления временного слота"})
 		return
 	}
 
 	// Получаем обновленный временной слот
-	var updatedTimeSlot models.TimeSlot
-	err = collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&updatedTimeSlot)
+	updatedTimeSlot, err := h.store.TimeSlots.FindByID(c.Request.Context(), objectID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленного временного слота"})
 		return
 	}
 
+	h.publishTimeSlot(c.Request.Context(), models.EventTimeSlotUpdated, *updatedTimeSlot)
 	c.JSON(http.StatusOK, updatedTimeSlot)
 }
 
@@ -194,13 +179,10 @@ func (h *Handlers) DeleteTimeSlot(c *gin.Context) {
 		return
 	}
 
-	collection := h.db.Collection("time_slots")
-
 	// Проверяем существование временного слота
-	var existingTimeSlot models.TimeSlot
-	err = collection.FindOne(context.Background(), bson.M{"_id": objectID}).Decode(&existingTimeSlot)
+	existingTimeSlot, err := h.store.TimeSlots.FindByID(c.Request.Context(), objectID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == storage.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Временной слот не найден"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска временного слота"})
@@ -209,8 +191,7 @@ func (h *Handlers) DeleteTimeSlot(c *gin.Context) {
 	}
 
 	// Проверяем, есть ли уроки в этом временном слоте
-	lessonCollection := h.db.Collection("lessons")
-	lessonCount, err := lessonCollection.CountDocuments(context.Background(), bson.M{
+	lessonCount, err := h.store.Lessons.Count(c.Request.Context(), bson.M{
 		"start_time": existingTimeSlot.StartTime,
 		"end_time":   existingTimeSlot.EndTime,
 	})
@@ -225,11 +206,13 @@ func (h *Handlers) DeleteTimeSlot(c *gin.Context) {
 	}
 
 	// Удаляем временной слот
-	_, err = collection.DeleteOne(context.Background(), bson.M{"_id": objectID})
+	_, err = h.store.TimeSlots.Delete(c.Request.Context(), bson.M{"_id": objectID})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления временного слота"})
 		return
 	}
 
+	h.publishTimeSlot(c.Request.Context(), models.EventTimeSlotDeleted, *existingTimeSlot)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Временной слот успешно удален"})
 }
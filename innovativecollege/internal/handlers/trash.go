@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"innovativecollege/internal/export"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// trashItem документ в корзине
+type trashItem struct {
+	Collection string             `json:"collection"`
+	ID         primitive.ObjectID `json:"id"`
+	Title      string             `json:"title"` // Название для списка: группа, ФИО, предмет и дата
+	DeletedAt  *time.Time         `json:"deleted_at"`
+	DeletedBy  string             `json:"deleted_by,omitempty"`
+	Document   interface{}        `json:"document"`
+}
+
+// trashBin корзина одной коллекции
+type trashBin interface {
+	list(ctx context.Context) ([]trashItem, error)
+	restore(c *gin.Context, id primitive.ObjectID)
+	purge(ctx context.Context, id primitive.ObjectID) (int64, error)
+}
+
+// typedBin корзина коллекции документов типа T
+type typedBin[T any] struct {
+	collection string
+	bin        storage.Bin[T]
+	// describe возвращает ID, название, время и автора удаления
+	describe func(doc T) (primitive.ObjectID, string, *time.Time, string)
+	// validate проверяет, что документ можно вернуть: связанные документы существуют,
+	// нет дублей и пересечений. При ошибке отвечает сам и возвращает false
+	validate func(c *gin.Context, doc T) bool
+	// restored вызывается после восстановления, например чтобы опубликовать событие
+	restored func(ctx context.Context, doc T)
+}
+
+func (b typedBin[T]) list(ctx context.Context) ([]trashItem, error) {
+	docs, err := b.bin.FindDeleted(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]trashItem, len(docs))
+	for i, doc := range docs {
+		id, title, deletedAt, deletedBy := b.describe(doc)
+		items[i] = trashItem{
+			Collection: b.collection,
+			ID:         id,
+			Title:      title,
+			DeletedAt:  deletedAt,
+			DeletedBy:  deletedBy,
+			Document:   doc,
+		}
+	}
+	return items, nil
+}
+
+func (b typedBin[T]) restore(c *gin.Context, id primitive.ObjectID) {
+	doc, err := b.bin.FindDeletedByID(c.Request.Context(), id)
+	if err == storage.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Документ не найден в корзине"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения документа из корзины"})
+		return
+	}
+
+	if b.validate != nil && !b.validate(c, *doc) {
+		return
+	}
+
+	if err := b.bin.Restore(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка восстановления документа"})
+		return
+	}
+	if b.restored != nil {
+		b.restored(c.Request.Context(), *doc)
+	}
+
+	_, title, _, _ := b.describe(*doc)
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Документ восстановлен",
+		"collection": b.collection,
+		"id":         id,
+		"title":      title,
+	})
+}
+
+func (b typedBin[T]) purge(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	return b.bin.Purge(ctx, bson.M{"_id": id})
+}
+
+// trashBins корзины коллекций, в которых удаление обратимо
+func (h *Handlers) trashBins() map[string]trashBin {
+	bins := map[string]trashBin{}
+	add := func(collection string, bin trashBin, ok bool) {
+		if ok {
+			bins[collection] = bin
+		}
+	}
+
+	groups := storage.BinOf(h.store.Groups)
+	add("groups", typedBin[models.Group]{
+		collection: "groups",
+		bin:        groups,
+		describe: func(g models.Group) (primitive.ObjectID, string, *time.Time, string) {
+			return g.ID, g.Name, g.DeletedAt, g.DeletedBy
+		},
+	}, groups != nil)
+
+	subjects := storage.BinOf(h.store.Subjects)
+	add("subjects", typedBin[models.Subject]{
+		collection: "subjects",
+		bin:        subjects,
+		describe: func(s models.Subject) (primitive.ObjectID, string, *time.Time, string) {
+			return s.ID, strings.TrimSpace(s.Code + " " + s.Name), s.DeletedAt, s.DeletedBy
+		},
+	}, subjects != nil)
+
+	students := storage.BinOf(h.store.Students)
+	add("students", typedBin[models.Student]{
+		collection: "students",
+		bin:        students,
+		describe: func(s models.Student) (primitive.ObjectID, string, *time.Time, string) {
+			return s.ID, s.LastName + " " + s.FirstName, s.DeletedAt, s.DeletedBy
+		},
+		validate: h.validateStudentRestore,
+	}, students != nil)
+
+	teachers := storage.BinOf(h.store.Teachers)
+	add("teachers", typedBin[models.Teacher]{
+		collection: "teachers",
+		bin:        teachers,
+		describe: func(t models.Teacher) (primitive.ObjectID, string, *time.Time, string) {
+			return t.ID, t.LastName + " " + t.FirstName, t.DeletedAt, t.DeletedBy
+		},
+		validate: h.validateTeacherRestore,
+	}, teachers != nil)
+
+	schedules := storage.BinOf(h.store.Schedules)
+	add("schedules", typedBin[models.Schedule]{
+		collection: "schedules",
+		bin:        schedules,
+		describe: func(s models.Schedule) (primitive.ObjectID, string, *time.Time, string) {
+			title := s.StartTime + "-" + s.EndTime
+			if s.DayOfWeek >= 1 && s.DayOfWeek <= 7 {
+				title = export.DayNames[s.DayOfWeek] + " " + title
+			}
+			return s.ID, title, s.DeletedAt, s.DeletedBy
+		},
+		validate: h.validateScheduleRestore,
+		restored: func(ctx context.Context, s models.Schedule) {
+			h.publishSchedule(ctx, "restored", s)
+		},
+	}, schedules != nil)
+
+	lessons := storage.BinOf(h.store.Lessons)
+	add("lessons", typedBin[models.Lesson]{
+		collection: "lessons",
+		bin:        lessons,
+		describe: func(l models.Lesson) (primitive.ObjectID, string, *time.Time, string) {
+			title := "Урок без даты"
+			if l.Date != nil {
+				title = l.Date.Format("02.01.2006") + " " + l.StartTime + "-" + l.EndTime
+			}
+			return l.ID, title, l.DeletedAt, l.DeletedBy
+		},
+		validate: h.validateLessonRestore,
+		restored: func(ctx context.Context, l models.Lesson) {
+			h.publishLesson(ctx, models.EventLessonCreated, l)
+		},
+	}, lessons != nil)
+
+	timeSlots := storage.BinOf(h.store.TimeSlots)
+	add("time_slots", typedBin[models.TimeSlot]{
+		collection: "time_slots",
+		bin:        timeSlots,
+		describe: func(s models.TimeSlot) (primitive.ObjectID, string, *time.Time, string) {
+			return s.ID, s.Label, s.DeletedAt, s.DeletedBy
+		},
+		restored: func(ctx context.Context, s models.TimeSlot) {
+			h.publishTimeSlot(ctx, models.EventTimeSlotCreated, s)
+		},
+	}, timeSlots != nil)
+
+	rooms := storage.BinOf(h.store.Rooms)
+	add("rooms", typedBin[models.Room]{
+		collection: "rooms",
+		bin:        rooms,
+		describe: func(r models.Room) (primitive.ObjectID, string, *time.Time, string) {
+			return r.ID, "Аудитория " + r.Number, r.DeletedAt, r.DeletedBy
+		},
+		validate: h.validateRoomRestore,
+	}, rooms != nil)
+
+	terms := storage.BinOf(h.store.Terms)
+	add("terms", typedBin[models.Term]{
+		collection: "terms",
+		bin:        terms,
+		describe: func(t models.Term) (primitive.ObjectID, string, *time.Time, string) {
+			return t.ID, t.Name, t.DeletedAt, t.DeletedBy
+		},
+		validate: h.validateTermRestore,
+	}, terms != nil)
+
+	events := storage.BinOf(h.store.CalendarEvents)
+	add("calendar_events", typedBin[models.CalendarEvent]{
+		collection: "calendar_events",
+		bin:        events,
+		describe: func(e models.CalendarEvent) (primitive.ObjectID, string, *time.Time, string) {
+			return e.ID, e.Name, e.DeletedAt, e.DeletedBy
+		},
+	}, events != nil)
+
+	return bins
+}
+
+// GetTrash список удаленных документов, последние удаленные первыми.
+// ?collection=lessons ограничивает список одной коллекцией
+func (h *Handlers) GetTrash(c *gin.Context) {
+	bins := h.trashBins()
+	if collection := c.Query("collection"); collection != "" {
+		bin, ok := bins[collection]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Коллекция не поддерживает корзину"})
+			return
+		}
+		bins = map[string]trashBin{collection: bin}
+	}
+
+	items := []trashItem{}
+	for _, bin := range bins {
+		found, err := bin.list(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения корзины"})
+			return
+		}
+		items = append(items, found...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i].DeletedAt, items[j].DeletedAt
+		if a == nil || b == nil || a.Equal(*b) {
+			return items[i].ID.Hex() > items[j].ID.Hex()
+		}
+		return a.After(*b)
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":          items,
+		"retention_days": h.cfg.TrashRetentionDays,
+	})
+}
+
+// RestoreFromTrash восстанавливает документ из корзины. Перед восстановлением заново
+// проверяются ссылки на группы, преподавателей, предметы и аудитории, уникальность
+// и пересечения в расписании (пересечения можно игнорировать через ?force=true)
+func (h *Handlers) RestoreFromTrash(c *gin.Context) {
+	bin, id, ok := h.trashTarget(c)
+	if !ok {
+		return
+	}
+	bin.restore(c, id)
+}
+
+// PurgeFromTrash удаляет документ из корзины окончательно
+func (h *Handlers) PurgeFromTrash(c *gin.Context) {
+	bin, id, ok := h.trashTarget(c)
+	if !ok {
+		return
+	}
+
+	purged, err := bin.purge(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления документа"})
+		return
+	}
+	if purged == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Документ не найден в корзине"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Документ удален окончательно"})
+}
+
+func (h *Handlers) trashTarget(c *gin.Context) (trashBin, primitive.ObjectID, bool) {
+	bin, ok := h.trashBins()[c.Param("collection")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Коллекция не поддерживает корзину"})
+		return nil, primitive.NilObjectID, false
+	}
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID документа"})
+		return nil, primitive.NilObjectID, false
+	}
+	return bin, id, true
+}
+
+// ========== ПРОВЕРКИ ПРИ ВОССТАНОВЛЕНИИ ==========
+
+// requireActive проверяет, что связанный документ существует и не находится в корзине
+func requireActive[T any](c *gin.Context, repo storage.Repository[T], id primitive.ObjectID, errorMessage string) bool {
+	return allExist(c, repo, map[primitive.ObjectID]bool{id: true}, errorMessage)
+}
+
+// requireUnique проверяет, что среди активных документов нет дубля по фильтру
+func requireUnique[T any](c *gin.Context, repo storage.Repository[T], filter bson.M, errorMessage string) bool {
+	count, err := repo.Count(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки связанных данных"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errorMessage})
+		return false
+	}
+	return true
+}
+
+func (h *Handlers) validateStudentRestore(c *gin.Context, student models.Student) bool {
+	return requireActive(c, h.store.Groups, student.GroupID, "Группа студента удалена: сначала восстановите группу") &&
+		requireUnique(c, h.store.Students, bson.M{"iin": student.IIN}, "Студент с таким ИИН уже существует")
+}
+
+func (h *Handlers) validateTeacherRestore(c *gin.Context, teacher models.Teacher) bool {
+	return requireUnique(c, h.store.Teachers, bson.M{"iin": teacher.IIN}, "Преподаватель с таким ИИН уже существует")
+}
+
+func (h *Handlers) validateRoomRestore(c *gin.Context, room models.Room) bool {
+	return requireUnique(c, h.store.Rooms, bson.M{"number": room.Number}, "Аудитория с таким номером уже существует")
+}
+
+func (h *Handlers) validateTermRestore(c *gin.Context, term models.Term) bool {
+	return requireUnique(c, h.store.Terms, bson.M{
+		"start_date": bson.M{"$lte": term.EndDate},
+		"end_date":   bson.M{"$gte": term.StartDate},
+	}, "Семестр пересекается с существующим")
+}
+
+func (h *Handlers) validateScheduleRestore(c *gin.Context, schedule models.Schedule) bool {
+	if !h.requireScheduleReferences(c, schedule.GroupID, schedule.TeacherID, schedule.SubjectID, schedule.RoomID) {
+		return false
+	}
+	conflicts, err := h.findScheduleConflicts(schedule)
+	return !rejectConflicts(c, conflicts, err)
+}
+
+func (h *Handlers) validateLessonRestore(c *gin.Context, lesson models.Lesson) bool {
+	if !h.requireScheduleReferences(c, lesson.GroupID, lesson.TeacherID, lesson.SubjectID, lesson.RoomID) {
+		return false
+	}
+	if h.rejectNonWorkingDay(c, lesson.Date) {
+		return false
+	}
+	conflicts, err := h.findLessonConflicts(lesson)
+	return !rejectConflicts(c, conflicts, err)
+}
+
+// requireScheduleReferences проверяет группу, преподавателя, предмет и аудиторию записи
+func (h *Handlers) requireScheduleReferences(c *gin.Context, groupID, teacherID, subjectID, roomID primitive.ObjectID) bool {
+	if !requireActive(c, h.store.Groups, groupID, "Группа удалена: сначала восстановите группу") ||
+		!requireActive(c, h.store.Teachers, teacherID, "Преподаватель удален: сначала восстановите преподавателя") {
+		return false
+	}
+	if !subjectID.IsZero() && !requireActive(c, h.store.Subjects, subjectID, "Предмет удален: сначала восстановите предмет") {
+		return false
+	}
+	if !roomID.IsZero() && !requireActive(c, h.store.Rooms, roomID, "Аудитория удалена: сначала восстановите аудиторию") {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"innovativecollege/internal/events"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ========== ВЕБХУКИ ==========
+
+// CreateWebhook создает подписку на события расписания. Ключ подписи
+// возвращается только в ответе на создание
+func (h *Handlers) CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := newSubscriptionToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка генерации ключа подписи"})
+			return
+		}
+		secret = generated
+	}
+
+	webhook := models.Webhook{
+		URL:         req.URL,
+		Events:      uniqueStrings(req.Events),
+		Secret:      secret,
+		Description: req.Description,
+		IsActive:    true,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	id, err := h.store.Webhooks.Insert(c.Request.Context(), webhook)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка создания вебхука"})
+		return
+	}
+
+	webhook.ID = id
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// GetWebhooks получает все вебхуки (без ключей подписи)
+func (h *Handlers) GetWebhooks(c *gin.Context) {
+	webhooks, err := h.store.Webhooks.Find(c.Request.Context(), bson.M{}, storage.FindOptions{
+		Sort:    bson.D{{Key: "created_at", Value: 1}},
+		Exclude: []string{"secret"},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения вебхуков"})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhook получает вебхук по ID (без ключа подписи)
+func (h *Handlers) GetWebhook(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	webhook.Secret = ""
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook обновляет адрес, события, описание или включает и выключает вебхук
+func (h *Handlers) UpdateWebhook(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	if req.URL != "" {
+		update["url"] = req.URL
+	}
+	if len(req.Events) > 0 {
+		update["events"] = uniqueStrings(req.Events)
+	}
+	if req.Description != nil {
+		update["description"] = *req.Description
+	}
+	if req.IsActive != nil {
+		update["is_active"] = *req.IsActive
+	}
+
+	if _, err := h.store.Webhooks.Update(c.Request.Context(), bson.M{"_id": webhook.ID}, bson.M{"$set": update}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления вебхука"})
+		return
+	}
+
+	updated, err := h.store.Webhooks.FindByID(c.Request.Context(), webhook.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения обновленного вебхука"})
+		return
+	}
+
+	updated.Secret = ""
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteWebhook удаляет вебхук вместе с журналом его доставок
+func (h *Handlers) DeleteWebhook(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.store.Webhooks.Delete(c.Request.Context(), bson.M{"_id": webhook.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления вебхука"})
+		return
+	}
+	if _, err := h.store.WebhookDeliveries.DeleteMany(c.Request.Context(), bson.M{"webhook_id": webhook.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка удаления журнала доставок"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Вебхук успешно удален"})
+}
+
+// PingWebhook ставит в очередь проверочное событие ping, чтобы проверить адрес и подпись
+func (h *Handlers) PingWebhook(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+	if h.webhooks == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Отправка вебхуков отключена"})
+		return
+	}
+
+	delivery, err := h.webhooks.Enqueue(c.Request.Context(), *webhook, events.Event{
+		Type: models.EventPing,
+		Data: gin.H{"webhook_id": webhook.ID},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка постановки события в очередь"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// GetWebhookDeliveries журнал доставок вебхука с попытками, новые первыми.
+// Фильтры status (pending, delivered, failed) и event
+func (h *Handlers) GetWebhookDeliveries(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	params, ok := parseListParams(c, []string{"created_at", "event", "status"}, "-created_at")
+	if !ok {
+		return
+	}
+	if !params.paged {
+		params.paged = true
+		params.options.Limit = params.limit
+	}
+
+	filter := bson.M{"webhook_id": webhook.ID}
+	for _, field := range []string{"status", "event"} {
+		if value := c.Query(field); value != "" {
+			filter[field] = value
+		}
+	}
+
+	deliveries, total, ok := findPage(c, h.store.WebhookDeliveries, filter, params, "Ошибка получения журнала доставок")
+	if !ok {
+		return
+	}
+
+	respondList(c, deliveries, total, params)
+}
+
+// RetryWebhookDelivery повторно отправляет доставку, например после того, как
+// получатель исправил ошибку. Прежние попытки сохраняются в журнале
+func (h *Handlers) RetryWebhookDelivery(c *gin.Context) {
+	webhook, ok := h.findWebhook(c)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := primitive.ObjectIDFromHex(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID доставки"})
+		return
+	}
+
+	now := time.Now()
+	result, err := h.store.WebhookDeliveries.Update(c.Request.Context(),
+		bson.M{"_id": deliveryID, "webhook_id": webhook.ID},
+		bson.M{"$set": bson.M{"status": models.DeliveryPending, "next_attempt_at": now, "updated_at": now}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка обновления доставки"})
+		return
+	}
+	if result.Matched == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Доставка не найдена"})
+		return
+	}
+	if h.webhooks != nil {
+		h.webhooks.Wake()
+	}
+
+	delivery, err := h.store.WebhookDeliveries.FindByID(c.Request.Context(), deliveryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения доставки"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// findWebhook загружает вебхук из :id
+func (h *Handlers) findWebhook(c *gin.Context) (*models.Webhook, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID вебхука"})
+		return nil, false
+	}
+
+	webhook, err := h.store.Webhooks.FindByID(c.Request.Context(), id)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Вебхук не найден"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка поиска вебхука"})
+		}
+		return nil, false
+	}
+	return webhook, true
+}
+
+// uniqueStrings убирает повторы, сохраняя порядок
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
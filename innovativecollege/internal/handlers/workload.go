@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/export"
+	"innovativecollege/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// workloadItem часы преподавателя по одному предмету в одной группе
+type workloadItem struct {
+	SubjectID         primitive.ObjectID `json:"subject_id"`
+	Subject           string             `json:"subject"`
+	GroupID           primitive.ObjectID `json:"group_id"`
+	Group             string             `json:"group"`
+	Lessons           int                `json:"lessons"`
+	RegularHours      float64            `json:"regular_hours"`
+	SubstitutionHours float64            `json:"substitution_hours"` // Уроки, проведенные за другого преподавателя
+	TotalHours        float64            `json:"total_hours"`
+
+	regularMinutes      int
+	substitutionMinutes int
+}
+
+// teacherWorkload нагрузка преподавателя за период
+type teacherWorkload struct {
+	TeacherID         primitive.ObjectID `json:"teacher_id"`
+	Name              string             `json:"name"`
+	WeeklyLoad        int                `json:"weekly_load"`    // Нагрузка по договору в неделю
+	ContractHours     float64            `json:"contract_hours"` // Нагрузка по договору за рабочие дни периода
+	Lessons           int                `json:"lessons"`
+	RegularHours      float64            `json:"regular_hours"`
+	SubstitutionHours float64            `json:"substitution_hours"`
+	TotalHours        float64            `json:"total_hours"`
+	Difference        float64            `json:"difference"`             // Всего минус договор: больше нуля - переработка
+	LoadPercent       float64            `json:"load_percent,omitempty"` // Выполнение договорной нагрузки, %
+	UnmarkedHours     float64            `json:"unmarked_hours"`         // Прошедшие уроки без отметки о проведении
+	Items             []workloadItem     `json:"items"`
+
+	unmarkedMinutes int
+}
+
+// workloadReport отчет о нагрузке преподавателей за период
+type workloadReport struct {
+	Period              statisticsPeriod  `json:"period"`
+	WorkingDays         int               `json:"working_days"`
+	AcademicHourMinutes int               `json:"academic_hour_minutes"`
+	RegularHours        float64           `json:"regular_hours"`
+	SubstitutionHours   float64           `json:"substitution_hours"`
+	TotalHours          float64           `json:"total_hours"`
+	Teachers            []teacherWorkload `json:"teachers"`
+}
+
+// GetWorkloadReport нагрузка преподавателей за период для бухгалтерии: академические
+// часы проведенных уроков по предметам и группам, отдельно часы замен, и сравнение
+// с нагрузкой по договору. Период: month=2024-10 или start_date/end_date
+// (по умолчанию текущий семестр). Преподаватель видит только свою нагрузку
+func (h *Handlers) GetWorkloadReport(c *gin.Context) {
+	report, ok := h.collectWorkload(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ExportWorkloadReport выгружает отчет о нагрузке (?format=xlsx|csv) с теми же параметрами
+func (h *Handlers) ExportWorkloadReport(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	report, ok := h.collectWorkload(c)
+	if !ok {
+		return
+	}
+
+	summary := [][]string{{"Преподаватель", "Нагрузка в неделю", "По договору", "Основные часы", "Замены", "Всего", "Разница", "Выполнение, %", "Не отмечено"}}
+	details := [][]string{{"Преподаватель", "Предмет", "Группа", "Уроков", "Основные часы", "Замены", "Всего"}}
+	for _, teacher := range report.Teachers {
+		summary = append(summary, []string{
+			teacher.Name,
+			strconv.Itoa(teacher.WeeklyLoad),
+			formatHours(teacher.ContractHours),
+			formatHours(teacher.RegularHours),
+			formatHours(teacher.SubstitutionHours),
+			formatHours(teacher.TotalHours),
+			formatHours(teacher.Difference),
+			formatHours(teacher.LoadPercent),
+			formatHours(teacher.UnmarkedHours),
+		})
+		for _, item := range teacher.Items {
+			details = append(details, []string{
+				teacher.Name,
+				item.Subject,
+				item.Group,
+				strconv.Itoa(item.Lessons),
+				formatHours(item.RegularHours),
+				formatHours(item.SubstitutionHours),
+				formatHours(item.TotalHours),
+			})
+		}
+	}
+	summary = append(summary, []string{"Итого", "", "", formatHours(report.RegularHours), formatHours(report.SubstitutionHours), formatHours(report.TotalHours), "", "", ""})
+
+	title := fmt.Sprintf("Нагрузка преподавателей за период %s - %s (рабочих дней: %d, академический час - %d минут)",
+		report.Period.StartDate, report.Period.EndDate, report.WorkingDays, report.AcademicHourMinutes)
+	sheets := []export.Sheet{
+		{Name: "Итоги", Title: title, Rows: summary},
+		{Name: "По предметам", Title: "Часы по предметам и группам", Rows: details},
+	}
+
+	respondExport(c, format, "workload", sheets)
+}
+
+// collectWorkload считает нагрузку. Учитываются проведенные уроки; отмененные
+// и перенесенные не оплачиваются, а прошедшие уроки без отметки о проведении
+// показываются отдельно, чтобы их можно было проверить. При ошибке отвечает сам
+func (h *Handlers) collectWorkload(c *gin.Context) (*workloadReport, bool) {
+	period, start, end, ok := h.workloadPeriod(c)
+	if !ok {
+		return nil, false
+	}
+
+	teacherFilter := bson.M{}
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		id, err := primitive.ObjectIDFromHex(teacherID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неверный ID преподавателя"})
+			return nil, false
+		}
+		teacherFilter["_id"] = id
+	}
+	if claims := auth.GetClaims(c); claims.Role == auth.RoleTeacher {
+		id, _ := primitive.ObjectIDFromHex(claims.UserID)
+		if current, ok := teacherFilter["_id"]; ok && current != id {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Можно смотреть только свою нагрузку"})
+			return nil, false
+		}
+		teacherFilter["_id"] = id
+	}
+
+	var teachers []models.Teacher
+	if !findAll(c, h.store.Teachers, teacherFilter, &teachers, "Ошибка получения преподавателей") {
+		return nil, false
+	}
+
+	lessonFilter := bson.M{"date": bson.M{"$gte": start, "$lte": end}}
+	if id, ok := teacherFilter["_id"]; ok {
+		lessonFilter["teacher_id"] = id
+	}
+	var lessons []models.Lesson
+	if !findAll(c, h.store.Lessons, lessonFilter, &lessons, "Ошибка получения уроков") {
+		return nil, false
+	}
+
+	lookups, err := h.loadNameLookups(c.Request.Context(), lessons, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка получения справочников"})
+		return nil, false
+	}
+
+	cal, err := h.loadCalendar()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка загрузки календаря"})
+		return nil, false
+	}
+	workingDays := 0
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if cal.IsWorkingDay(day) {
+			workingDays++
+		}
+	}
+
+	byTeacher := make(map[primitive.ObjectID]*teacherWorkload, len(teachers))
+	items := make(map[primitive.ObjectID]map[string]*workloadItem)
+	for _, teacher := range teachers {
+		byTeacher[teacher.ID] = &teacherWorkload{
+			TeacherID:  teacher.ID,
+			Name:       teacher.LastName + " " + teacher.FirstName,
+			WeeklyLoad: teacher.WeeklyLoad,
+		}
+		items[teacher.ID] = make(map[string]*workloadItem)
+	}
+
+	today := truncateToDay(time.Now().UTC())
+	for _, lesson := range lessons {
+		workload, ok := byTeacher[lesson.TeacherID]
+		if !ok {
+			continue
+		}
+
+		minutes := lesson.DurationMinutes()
+		switch lesson.CurrentStatus() {
+		case models.LessonConducted:
+		case models.LessonPlanned:
+			if lesson.Date.Before(today) {
+				workload.unmarkedMinutes += minutes
+			}
+			continue
+		default:
+			continue
+		}
+
+		key := lesson.SubjectID.Hex() + lesson.GroupID.Hex()
+		item, ok := items[lesson.TeacherID][key]
+		if !ok {
+			item = &workloadItem{
+				SubjectID: lesson.SubjectID,
+				Subject:   lookups.subjectName(lesson.SubjectID),
+				GroupID:   lesson.GroupID,
+				Group:     lookups.groupName(lesson.GroupID),
+			}
+			items[lesson.TeacherID][key] = item
+		}
+		item.Lessons++
+		if lesson.OriginalTeacherID.IsZero() {
+			item.regularMinutes += minutes
+		} else {
+			item.substitutionMinutes += minutes
+		}
+	}
+
+	report := &workloadReport{
+		Period:              period,
+		WorkingDays:         workingDays,
+		AcademicHourMinutes: models.AcademicHourMinutes,
+		Teachers:            []teacherWorkload{},
+	}
+	var regularMinutes, substitutionMinutes int
+	for id, workload := range byTeacher {
+		var teacherRegular, teacherSubstitution int
+		workload.Items = make([]workloadItem, 0, len(items[id]))
+		for _, item := range items[id] {
+			item.RegularHours = academicHours(item.regularMinutes)
+			item.SubstitutionHours = academicHours(item.substitutionMinutes)
+			item.TotalHours = academicHours(item.regularMinutes + item.substitutionMinutes)
+			workload.Lessons += item.Lessons
+			teacherRegular += item.regularMinutes
+			teacherSubstitution += item.substitutionMinutes
+			workload.Items = append(workload.Items, *item)
+		}
+		sort.Slice(workload.Items, func(i, j int) bool {
+			a, b := workload.Items[i], workload.Items[j]
+			if a.Subject != b.Subject {
+				return a.Subject < b.Subject
+			}
+			return a.Group < b.Group
+		})
+
+		// Преподаватели без договорной нагрузки и без уроков в отчет не попадают
+		if workload.WeeklyLoad == 0 && workload.Lessons == 0 && workload.unmarkedMinutes == 0 {
+			continue
+		}
+
+		workload.RegularHours = academicHours(teacherRegular)
+		workload.SubstitutionHours = academicHours(teacherSubstitution)
+		workload.TotalHours = academicHours(teacherRegular + teacherSubstitution)
+		workload.UnmarkedHours = academicHours(workload.unmarkedMinutes)
+		if len(h.cfg.WorkingWeekdays) > 0 {
+			contract := float64(workload.WeeklyLoad) * float64(workingDays) / float64(len(h.cfg.WorkingWeekdays))
+			workload.ContractHours = roundHours(contract)
+			if contract > 0 {
+				workload.LoadPercent = math.Round(workload.TotalHours*1000/contract) / 10
+			}
+		}
+		workload.Difference = roundHours(workload.TotalHours - workload.ContractHours)
+
+		regularMinutes += teacherRegular
+		substitutionMinutes += teacherSubstitution
+		report.Teachers = append(report.Teachers, *workload)
+	}
+
+	sort.Slice(report.Teachers, func(i, j int) bool {
+		return report.Teachers[i].Name < report.Teachers[j].Name
+	})
+	report.RegularHours = academicHours(regularMinutes)
+	report.SubstitutionHours = academicHours(substitutionMinutes)
+	report.TotalHours = academicHours(regularMinutes + substitutionMinutes)
+
+	return report, true
+}
+
+// workloadPeriod период отчета: месяц (month=2024-10) или start_date/end_date
+func (h *Handlers) workloadPeriod(c *gin.Context) (statisticsPeriod, time.Time, time.Time, bool) {
+	month := c.Query("month")
+	if month == "" {
+		period, start, end, ok := h.reportPeriod(c)
+		if ok && end.Before(start) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Дата окончания раньше даты начала"})
+			return statisticsPeriod{}, time.Time{}, time.Time{}, false
+		}
+		return period, start, end, ok
+	}
+
+	start, next, ok := parseMonth(c, month)
+	if !ok {
+		return statisticsPeriod{}, time.Time{}, time.Time{}, false
+	}
+	end := next.AddDate(0, 0, -1)
+	return statisticsPeriod{StartDate: start.Format("2006-01-02"), EndDate: end.Format("2006-01-02")}, start, end, true
+}
+
+// academicHours переводит минуты в академические часы с точностью до сотых
+func academicHours(minutes int) float64 {
+	return roundHours(float64(minutes) / models.AcademicHourMinutes)
+}
+
+func roundHours(hours float64) float64 {
+	return math.Round(hours*100) / 100
+}
+
+// formatHours число для выгрузки: с запятой, как ожидает русскоязычный Excel
+func formatHours(value float64) string {
+	return strings.Replace(strconv.FormatFloat(value, 'f', -1, 64), ".", ",", 1)
+}
@@ -0,0 +1,149 @@
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	// База часовых поясов встроена в бинарник: в alpine-образе ее нет
+	_ "time/tzdata"
+)
+
+// maxLineLength максимальная длина строки в октетах (RFC 5545, 3.1)
+const maxLineLength = 75
+
+// Event событие календаря (VEVENT). Время хранится как настенное время в часовом поясе календаря
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	RRule       string      // Правило повторения, например "FREQ=WEEKLY;UNTIL=20251231T235959Z"
+	ExDates     []time.Time // Исключенные повторения
+	RDates      []time.Time // Дополнительные повторения (перенесенные рабочие дни)
+	Cancelled   bool        // Событие отменено (STATUS:CANCELLED)
+}
+
+// Calendar календарь (VCALENDAR) с событиями
+type Calendar struct {
+	Name     string
+	Timezone string // Идентификатор IANA, например "Asia/Almaty"
+	Events   []Event
+}
+
+// Render формирует содержимое .ics файла
+func (cal *Calendar) Render() []byte {
+	var buf bytes.Buffer
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	writeLine(&buf, "BEGIN:VCALENDAR")
+	writeLine(&buf, "VERSION:2.0")
+	writeLine(&buf, "PRODID:-//Innovative College//Schedule//RU")
+	writeLine(&buf, "CALSCALE:GREGORIAN")
+	writeLine(&buf, "METHOD:PUBLISH")
+	writeLine(&buf, "X-WR-CALNAME:"+escape(cal.Name))
+	if cal.Timezone != "" {
+		writeLine(&buf, "X-WR-TIMEZONE:"+cal.Timezone)
+		cal.writeTimezone(&buf)
+	}
+
+	for _, event := range cal.Events {
+		writeLine(&buf, "BEGIN:VEVENT")
+		writeLine(&buf, "UID:"+event.UID)
+		writeLine(&buf, "DTSTAMP:"+stamp)
+		writeLine(&buf, "DTSTART"+cal.dateTime(event.Start))
+		writeLine(&buf, "DTEND"+cal.dateTime(event.End))
+		if event.RRule != "" {
+			writeLine(&buf, "RRULE:"+event.RRule)
+		}
+		for _, date := range event.ExDates {
+			writeLine(&buf, "EXDATE"+cal.dateTime(date))
+		}
+		for _, date := range event.RDates {
+			writeLine(&buf, "RDATE"+cal.dateTime(date))
+		}
+		writeLine(&buf, "SUMMARY:"+escape(event.Summary))
+		if event.Cancelled {
+			writeLine(&buf, "STATUS:CANCELLED")
+		}
+		if event.Location != "" {
+			writeLine(&buf, "LOCATION:"+escape(event.Location))
+		}
+		if event.Description != "" {
+			writeLine(&buf, "DESCRIPTION:"+escape(event.Description))
+		}
+		writeLine(&buf, "END:VEVENT")
+	}
+
+	writeLine(&buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// dateTime форматирует значение DTSTART/DTEND вместе с параметром TZID
+func (cal *Calendar) dateTime(t time.Time) string {
+	value := t.Format("20060102T150405")
+	if cal.Timezone == "" {
+		return ":" + value
+	}
+	return ";TZID=" + cal.Timezone + ":" + value
+}
+
+// writeTimezone описывает часовой пояс календаря. Пояса колледжа не переходят на летнее время,
+// поэтому достаточно одного STANDARD блока с текущим смещением
+func (cal *Calendar) writeTimezone(buf *bytes.Buffer) {
+	location, err := time.LoadLocation(cal.Timezone)
+	if err != nil {
+		return
+	}
+	_, offset := time.Now().In(location).Zone()
+
+	writeLine(buf, "BEGIN:VTIMEZONE")
+	writeLine(buf, "TZID:"+cal.Timezone)
+	writeLine(buf, "BEGIN:STANDARD")
+	writeLine(buf, "DTSTART:19700101T000000")
+	writeLine(buf, "TZOFFSETFROM:"+formatOffset(offset))
+	writeLine(buf, "TZOFFSETTO:"+formatOffset(offset))
+	writeLine(buf, "END:STANDARD")
+	writeLine(buf, "END:VTIMEZONE")
+}
+
+// UntilUTC значение UNTIL для правила повторения: конец указанного дня
+func UntilUTC(date time.Time) string {
+	return date.Format("20060102") + "T235959Z"
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// escape экранирует текстовое значение (RFC 5545, 3.3.11)
+func escape(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(value)
+}
+
+// writeLine записывает строку, перенося ее по 75 октетов без разрыва UTF-8 символов
+func writeLine(buf *bytes.Buffer, line string) {
+	limit := maxLineLength
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		buf.WriteString(line[:cut])
+		buf.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineLength - 1
+	}
+	buf.WriteString(line)
+	buf.WriteString("\r\n")
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"innovativecollege/internal/storage"
+)
+
+// RunTrashPurge раз в interval окончательно удаляет документы, которые пролежали
+// в корзине дольше retentionDays дней. Работает до отмены ctx
+func RunTrashPurge(ctx context.Context, store *storage.Store, retentionDays int, interval time.Duration) {
+	if retentionDays <= 0 || interval <= 0 {
+		log.Println("Очистка корзины отключена")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		PurgeTrash(ctx, store, retentionDays)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeTrash один проход очистки корзины
+func PurgeTrash(ctx context.Context, store *storage.Store, retentionDays int) {
+	before := time.Now().AddDate(0, 0, -retentionDays)
+	purged, err := store.PurgeDeleted(ctx, before)
+	if err != nil {
+		log.Println("Ошибка очистки корзины:", err)
+	}
+	for collection, count := range purged {
+		log.Printf("Очистка корзины: %s - удалено %d", collection, count)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -13,8 +14,98 @@ type Group struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name        string             `bson:"name" json:"name"`
 	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Shift       int                `bson:"shift,omitempty" json:"shift,omitempty"`         // Смена группы: 1 или 2 (0 - не задана)
+	Subgroups   []Subgroup         `bson:"subgroups,omitempty" json:"subgroups,omitempty"` // Подгруппы для раздельных занятий
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt   *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy   string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// Subgroup подгруппа для раздельных занятий (лабораторные, иностранный язык).
+// Группа может делиться по-разному для разных предметов, поэтому студент может
+// входить в несколько подгрупп
+type Subgroup struct {
+	ID         primitive.ObjectID   `bson:"_id" json:"id"`
+	Name       string               `bson:"name" json:"name"` // Например: "1 подгруппа", "Английский A"
+	StudentIDs []primitive.ObjectID `bson:"student_ids" json:"student_ids"`
+}
+
+// Subgroup возвращает подгруппу по ID или nil
+func (g Group) Subgroup(id primitive.ObjectID) *Subgroup {
+	for i := range g.Subgroups {
+		if g.Subgroups[i].ID == id {
+			return &g.Subgroups[i]
+		}
+	}
+	return nil
+}
+
+// HasStudent проверяет, что студент входит в подгруппу
+func (s Subgroup) HasStudent(studentID primitive.ObjectID) bool {
+	for _, id := range s.StudentIDs {
+		if id == studentID {
+			return true
+		}
+	}
+	return false
+}
+
+// ForeignSubgroupIDs подгруппы группы, в которые студент не входит: их занятия
+// не показываются в расписании студента
+func (g Group) ForeignSubgroupIDs(studentID primitive.ObjectID) []primitive.ObjectID {
+	var ids []primitive.ObjectID
+	for _, subgroup := range g.Subgroups {
+		if !subgroup.HasStudent(studentID) {
+			ids = append(ids, subgroup.ID)
+		}
+	}
+	return ids
+}
+
+// StudentLessonFilter фильтр расписания и уроков студента группы: занятия всей группы
+// и подгрупп, в которые он входит
+func (g Group) StudentLessonFilter(studentID primitive.ObjectID) bson.M {
+	filter := bson.M{"group_id": g.ID}
+	if foreign := g.ForeignSubgroupIDs(studentID); len(foreign) > 0 {
+		filter["subgroup_id"] = bson.M{"$nin": foreign}
+	}
+	return filter
+}
+
+// LessonStudentFilter фильтр студентов занятия: вся группа или только подгруппа.
+// Если подгруппы уже нет, студентов у занятия нет
+func (g Group) LessonStudentFilter(subgroupID primitive.ObjectID) bson.M {
+	filter := bson.M{"group_id": g.ID}
+	if subgroupID.IsZero() {
+		return filter
+	}
+
+	studentIDs := []primitive.ObjectID{}
+	if subgroup := g.Subgroup(subgroupID); subgroup != nil {
+		studentIDs = subgroup.StudentIDs
+	}
+	filter["_id"] = bson.M{"$in": studentIDs}
+	return filter
+}
+
+// SubgroupsClash проверяет, что два занятия группы нельзя вести одновременно:
+// занятие всей группы пересекается с любым, занятия разных подгрупп - только
+// если у подгрупп есть общие студенты
+func (g Group) SubgroupsClash(a, b primitive.ObjectID) bool {
+	if a.IsZero() || b.IsZero() || a == b {
+		return true
+	}
+	first, second := g.Subgroup(a), g.Subgroup(b)
+	if first == nil || second == nil {
+		return true
+	}
+	for _, studentID := range first.StudentIDs {
+		if second.HasStudent(studentID) {
+			return true
+		}
+	}
+	return false
 }
 
 // Subject представляет предмет
@@ -25,6 +116,8 @@ type Subject struct {
 	Description string             `bson:"description,omitempty" json:"description,omitempty"`
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt   *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy   string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
 }
 
 // Student представляет студента
@@ -37,17 +130,85 @@ type Student struct {
 	Group     *Group             `bson:"group,omitempty" json:"group,omitempty"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
 }
 
 // Teacher представляет преподавателя
 type Teacher struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	IIN          string               `bson:"iin" json:"iin"` // ИИН для входа
+	FirstName    string               `bson:"first_name" json:"first_name"`
+	LastName     string               `bson:"last_name" json:"last_name"`
+	Subjects     []string             `bson:"subjects" json:"subjects"`                             // Предметы которые ведет
+	WeeklyLoad   int                  `bson:"weekly_load,omitempty" json:"weekly_load,omitempty"`   // Нагрузка по договору, академических часов в неделю
+	Availability *TeacherAvailability `bson:"availability,omitempty" json:"availability,omitempty"` // Когда преподаватель может вести занятия
+	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time            `bson:"updated_at" json:"updated_at"`
+	DeletedAt    *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy    string               `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// TeacherAvailability ограничения преподавателя (например, совместителя).
+// Недоступность - жесткое ограничение, смена и число пар в день - пожелания
+type TeacherAvailability struct {
+	Unavailable      []UnavailableWindow `bson:"unavailable,omitempty" json:"unavailable"`
+	PreferredShift   int                 `bson:"preferred_shift,omitempty" json:"preferred_shift,omitempty"`         // 1 или 2, 0 - без предпочтений
+	MaxLessonsPerDay int                 `bson:"max_lessons_per_day,omitempty" json:"max_lessons_per_day,omitempty"` // 0 - без ограничения
+}
+
+// UnavailableWindow время, когда преподаватель не может вести занятия
+type UnavailableWindow struct {
+	DayOfWeek int    `bson:"day_of_week" json:"day_of_week"`                   // 1-7 (понедельник-воскресенье)
+	StartTime string `bson:"start_time,omitempty" json:"start_time,omitempty"` // Пусто - весь день
+	EndTime   string `bson:"end_time,omitempty" json:"end_time,omitempty"`
+	Reason    string `bson:"reason,omitempty" json:"reason,omitempty"`
+}
+
+// Covers проверяет, что окно недоступности пересекается с занятием в день недели
+func (w UnavailableWindow) Covers(dayOfWeek int, startTime, endTime string) bool {
+	if w.DayOfWeek != dayOfWeek {
+		return false
+	}
+	if w.StartTime == "" {
+		return true
+	}
+	return TimesOverlap(w.StartTime, w.EndTime, startTime, endTime)
+}
+
+// UnavailableAt возвращает окно недоступности, в которое попадает занятие, или nil
+func (a *TeacherAvailability) UnavailableAt(dayOfWeek int, startTime, endTime string) *UnavailableWindow {
+	if a == nil {
+		return nil
+	}
+	for i := range a.Unavailable {
+		if a.Unavailable[i].Covers(dayOfWeek, startTime, endTime) {
+			return &a.Unavailable[i]
+		}
+	}
+	return nil
+}
+
+// Типы аудиторий
+const (
+	RoomTypeLecture     = "lecture"      // Лекционная
+	RoomTypeComputerLab = "computer_lab" // Компьютерный класс
+	RoomTypeGym         = "gym"          // Спортзал
+)
+
+// Room представляет аудиторию
+type Room struct {
 	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	IIN       string             `bson:"iin" json:"iin"` // ИИН для входа
-	FirstName string             `bson:"first_name" json:"first_name"`
-	LastName  string             `bson:"last_name" json:"last_name"`
-	Subjects  []string           `bson:"subjects" json:"subjects"` // Предметы которые ведет
+	Number    string             `bson:"number" json:"number"` // Например: 201, 508
+	Building  string             `bson:"building,omitempty" json:"building,omitempty"`
+	Floor     int                `bson:"floor,omitempty" json:"floor,omitempty"`
+	Capacity  int                `bson:"capacity" json:"capacity"`
+	Type      string             `bson:"type" json:"type"` // lecture, computer_lab, gym
+	Equipment []string           `bson:"equipment,omitempty" json:"equipment,omitempty"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
 }
 
 // Schedule представляет расписание
@@ -55,43 +216,112 @@ type Schedule struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	GroupID     primitive.ObjectID `bson:"group_id" json:"group_id"`
 	Group       *Group             `bson:"group,omitempty" json:"group,omitempty"`
+	SubgroupID  primitive.ObjectID `bson:"subgroup_id,omitempty" json:"subgroup_id,omitempty"` // Подгруппа, если занятие не для всей группы
 	TeacherID   primitive.ObjectID `bson:"teacher_id" json:"teacher_id"`
 	Teacher     *Teacher           `bson:"teacher,omitempty" json:"teacher,omitempty"`
 	SubjectID   primitive.ObjectID `bson:"subject_id" json:"subject_id"`
 	Subject     *Subject           `bson:"subject,omitempty" json:"subject,omitempty"`
-	Room        string             `bson:"room" json:"room"`
+	RoomID      primitive.ObjectID `bson:"room_id,omitempty" json:"room_id,omitempty"`
+	Room        string             `bson:"room" json:"room"`               // Номер аудитории (копия Room.Number)
 	DayOfWeek   int                `bson:"day_of_week" json:"day_of_week"` // 1-7 (понедельник-воскресенье)
 	StartTime   string             `bson:"start_time" json:"start_time"`   // "12:40"
 	EndTime     string             `bson:"end_time" json:"end_time"`       // "14:00"
 	Shift       int                `bson:"shift" json:"shift"`             // 1 или 2 смена
 	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Warnings    []string           `bson:"-" json:"warnings,omitempty"` // Нарушенные пожелания преподавателя (только в ответе на сохранение)
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt   *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy   string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
 }
 
 // Lesson представляет урок в календаре
 type Lesson struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	GroupID     primitive.ObjectID `bson:"group_id" json:"group_id"`
-	Group       *Group             `bson:"group,omitempty" json:"group,omitempty"`
-	TeacherID   primitive.ObjectID `bson:"teacher_id" json:"teacher_id"`
-	Teacher     *Teacher           `bson:"teacher,omitempty" json:"teacher,omitempty"`
-	SubjectID   primitive.ObjectID `bson:"subject_id" json:"subject_id"`
-	Subject     *Subject           `bson:"subject,omitempty" json:"subject,omitempty"`
-	Room        string             `bson:"room" json:"room"`
-	Date        *time.Time         `bson:"date,omitempty" json:"date,omitempty"`             // Конкретная дата урока
-	StartTime   string             `bson:"start_time,omitempty" json:"start_time,omitempty"` // "12:40"
-	EndTime     string             `bson:"end_time,omitempty" json:"end_time,omitempty"`     // "14:00"
-	Shift       int                `bson:"shift,omitempty" json:"shift,omitempty"`           // 1 или 2 смена (автоматически определяется)
-	Description string             `bson:"description,omitempty" json:"description,omitempty"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	GroupID           primitive.ObjectID `bson:"group_id" json:"group_id"`
+	Group             *Group             `bson:"group,omitempty" json:"group,omitempty"`
+	SubgroupID        primitive.ObjectID `bson:"subgroup_id,omitempty" json:"subgroup_id,omitempty"` // Подгруппа, если урок не для всей группы
+	TeacherID         primitive.ObjectID `bson:"teacher_id" json:"teacher_id"`
+	Teacher           *Teacher           `bson:"teacher,omitempty" json:"teacher,omitempty"`
+	SubjectID         primitive.ObjectID `bson:"subject_id" json:"subject_id"`
+	Subject           *Subject           `bson:"subject,omitempty" json:"subject,omitempty"`
+	RoomID            primitive.ObjectID `bson:"room_id,omitempty" json:"room_id,omitempty"`
+	Room              string             `bson:"room" json:"room"`                                 // Номер аудитории (копия Room.Number)
+	Date              *time.Time         `bson:"date,omitempty" json:"date,omitempty"`             // Конкретная дата урока
+	StartTime         string             `bson:"start_time,omitempty" json:"start_time,omitempty"` // "12:40"
+	EndTime           string             `bson:"end_time,omitempty" json:"end_time,omitempty"`     // "14:00"
+	Shift             int                `bson:"shift,omitempty" json:"shift,omitempty"`           // 1 или 2 смена (автоматически определяется)
+	Description       string             `bson:"description,omitempty" json:"description,omitempty"`
+	ScheduleID        primitive.ObjectID `bson:"schedule_id,omitempty" json:"schedule_id,omitempty"`                 // Запись расписания, из которой создан урок
+	OriginalTeacherID primitive.ObjectID `bson:"original_teacher_id,omitempty" json:"original_teacher_id,omitempty"` // Заменяемый преподаватель, если урок ведет замена
+	Status            string             `bson:"status,omitempty" json:"status,omitempty"`                           // planned, conducted, cancelled, rescheduled (пусто - planned)
+	Topic             string             `bson:"topic,omitempty" json:"topic,omitempty"`                             // Тема проведенного урока
+	StatusReason      string             `bson:"status_reason,omitempty" json:"status_reason,omitempty"`             // Причина отмены или переноса
+	RescheduledFromID primitive.ObjectID `bson:"rescheduled_from_id,omitempty" json:"rescheduled_from_id,omitempty"` // Исходный урок, если этот создан переносом
+	RescheduledToID   primitive.ObjectID `bson:"rescheduled_to_id,omitempty" json:"rescheduled_to_id,omitempty"`     // Урок, на который перенесен этот
+	Warnings          []string           `bson:"-" json:"warnings,omitempty"`                                        // Нарушенные пожелания преподавателя (только в ответе на сохранение)
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt         *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy         string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// Статусы урока
+const (
+	LessonPlanned     = "planned"     // Запланирован
+	LessonConducted   = "conducted"   // Проведен
+	LessonCancelled   = "cancelled"   // Отменен
+	LessonRescheduled = "rescheduled" // Перенесен на другую дату или время
+)
+
+// lessonTransitions допустимые переходы между статусами урока.
+// Проведенный урок можно отметить повторно, чтобы исправить тему
+var lessonTransitions = map[string][]string{
+	LessonPlanned:   {LessonConducted, LessonCancelled, LessonRescheduled},
+	LessonConducted: {LessonConducted},
+}
+
+// CurrentStatus статус урока. Уроки, созданные до появления статусов, считаются запланированными
+func (l Lesson) CurrentStatus() string {
+	if l.Status == "" {
+		return LessonPlanned
+	}
+	return l.Status
+}
+
+// Active проверяет, что урок занимает свое время: он не отменен и не перенесен
+func (l Lesson) Active() bool {
+	status := l.CurrentStatus()
+	return status == LessonPlanned || status == LessonConducted
+}
+
+// CanTransition проверяет, можно ли перевести урок в статус to
+func (l Lesson) CanTransition(to string) bool {
+	for _, allowed := range lessonTransitions[l.CurrentStatus()] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
 }
 
 // CreateGroupRequest запрос на создание группы
 type CreateGroupRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description,omitempty"`
+	Shift       int    `json:"shift,omitempty" binding:"omitempty,min=1,max=2"`
+}
+
+// CreateSubgroupRequest запрос на создание подгруппы
+type CreateSubgroupRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	StudentIDs []string `json:"student_ids"`
+}
+
+// UpdateSubgroupRequest запрос на обновление подгруппы. student_ids заменяет состав целиком
+type UpdateSubgroupRequest struct {
+	Name       string    `json:"name,omitempty"`
+	StudentIDs *[]string `json:"student_ids,omitempty"`
 }
 
 // CreateSubjectRequest запрос на создание предмета
@@ -111,18 +341,21 @@ type CreateStudentRequest struct {
 
 // CreateTeacherRequest запрос на создание преподавателя
 type CreateTeacherRequest struct {
-	IIN       string   `json:"iin" binding:"required"`
-	FirstName string   `json:"first_name" binding:"required"`
-	LastName  string   `json:"last_name" binding:"required"`
-	Subjects  []string `json:"subjects"`
+	IIN        string   `json:"iin" binding:"required"`
+	FirstName  string   `json:"first_name" binding:"required"`
+	LastName   string   `json:"last_name" binding:"required"`
+	Subjects   []string `json:"subjects"`
+	WeeklyLoad int      `json:"weekly_load" binding:"min=0"`
 }
 
 // CreateScheduleRequest запрос на создание расписания
 type CreateScheduleRequest struct {
 	GroupID     string `json:"group_id" binding:"required"`
+	SubgroupID  string `json:"subgroup_id,omitempty"` // Подгруппа группы, пусто - вся группа
 	TeacherID   string `json:"teacher_id" binding:"required"`
 	SubjectID   string `json:"subject_id" binding:"required"`
-	Room        string `json:"room" binding:"required"`
+	RoomID      string `json:"room_id,omitempty"` // ID аудитории (приоритетнее номера)
+	Room        string `json:"room,omitempty"`    // Номер аудитории, если ID не указан
 	DayOfWeek   int    `json:"day_of_week" binding:"required,min=1,max=7"`
 	StartTime   string `json:"start_time" binding:"required"`
 	EndTime     string `json:"end_time" binding:"required"`
@@ -134,6 +367,7 @@ type CreateScheduleRequest struct {
 type UpdateGroupRequest struct {
 	Name        string `json:"name,omitempty"`
 	Description string `json:"description,omitempty"`
+	Shift       *int   `json:"shift,omitempty" binding:"omitempty,min=0,max=2"` // 0 снимает привязку к смене
 }
 
 // UpdateSubjectRequest запрос на обновление предмета
@@ -153,31 +387,51 @@ type UpdateStudentRequest struct {
 
 // UpdateTeacherRequest запрос на обновление преподавателя
 type UpdateTeacherRequest struct {
-	IIN       string   `json:"iin,omitempty"`
-	FirstName string   `json:"first_name,omitempty"`
-	LastName  string   `json:"last_name,omitempty"`
-	Subjects  []string `json:"subjects,omitempty"`
+	IIN        string   `json:"iin,omitempty"`
+	FirstName  string   `json:"first_name,omitempty"`
+	LastName   string   `json:"last_name,omitempty"`
+	Subjects   []string `json:"subjects,omitempty"`
+	WeeklyLoad *int     `json:"weekly_load,omitempty" binding:"omitempty,min=0"`
+}
+
+// UpdateTeacherAvailabilityRequest запрос на замену ограничений преподавателя
+type UpdateTeacherAvailabilityRequest struct {
+	Unavailable      []UnavailableWindowRequest `json:"unavailable" binding:"dive"`
+	PreferredShift   int                        `json:"preferred_shift" binding:"omitempty,oneof=1 2"`
+	MaxLessonsPerDay int                        `json:"max_lessons_per_day" binding:"min=0"`
+}
+
+// UnavailableWindowRequest окно недоступности в запросе. Без времени - весь день
+type UnavailableWindowRequest struct {
+	DayOfWeek int    `json:"day_of_week" binding:"required,min=1,max=7"`
+	StartTime string `json:"start_time,omitempty"` // "08:00"
+	EndTime   string `json:"end_time,omitempty"`   // "12:30"
+	Reason    string `json:"reason,omitempty"`
 }
 
 // UpdateScheduleRequest запрос на обновление расписания
 type UpdateScheduleRequest struct {
-	GroupID     string `json:"group_id,omitempty"`
-	TeacherID   string `json:"teacher_id,omitempty"`
-	SubjectID   string `json:"subject_id,omitempty"`
-	Room        string `json:"room,omitempty"`
-	DayOfWeek   *int   `json:"day_of_week,omitempty"` // Указатель для проверки на 0
-	StartTime   string `json:"start_time,omitempty"`
-	EndTime     string `json:"end_time,omitempty"`
-	Shift       *int   `json:"shift,omitempty"` // Указатель для проверки на 0
-	Description string `json:"description,omitempty"`
+	GroupID     string  `json:"group_id,omitempty"`
+	SubgroupID  *string `json:"subgroup_id,omitempty"` // Пустая строка - вся группа. При смене группы без subgroup_id подгруппа сбрасывается
+	TeacherID   string  `json:"teacher_id,omitempty"`
+	SubjectID   string  `json:"subject_id,omitempty"`
+	RoomID      string  `json:"room_id,omitempty"`
+	Room        string  `json:"room,omitempty"`
+	DayOfWeek   *int    `json:"day_of_week,omitempty"` // Указатель для проверки на 0
+	StartTime   string  `json:"start_time,omitempty"`
+	EndTime     string  `json:"end_time,omitempty"`
+	Shift       *int    `json:"shift,omitempty"` // Указатель для проверки на 0
+	Description string  `json:"description,omitempty"`
 }
 
 // CreateLessonRequest запрос на создание урока
 type CreateLessonRequest struct {
 	GroupID     string `json:"group_id" binding:"required"`
+	SubgroupID  string `json:"subgroup_id,omitempty"` // Подгруппа группы, пусто - вся группа
 	TeacherID   string `json:"teacher_id" binding:"required"`
 	SubjectID   string `json:"subject_id" binding:"required"`
-	Room        string `json:"room" binding:"required"`
+	RoomID      string `json:"room_id,omitempty"`    // ID аудитории (приоритетнее номера)
+	Room        string `json:"room,omitempty"`       // Номер аудитории, если ID не указан
 	Date        string `json:"date,omitempty"`       // "2024-01-15" - необязательное поле
 	StartTime   string `json:"start_time,omitempty"` // "12:40" - необязательное поле
 	EndTime     string `json:"end_time,omitempty"`   // "14:00" - необязательное поле
@@ -187,52 +441,84 @@ type CreateLessonRequest struct {
 
 // UpdateLessonRequest запрос на обновление урока
 type UpdateLessonRequest struct {
-	GroupID     string `json:"group_id,omitempty"`
-	TeacherID   string `json:"teacher_id,omitempty"`
-	SubjectID   string `json:"subject_id,omitempty"`
-	Room        string `json:"room,omitempty"`
-	Date        string `json:"date,omitempty"`       // "2024-01-15"
-	StartTime   string `json:"start_time,omitempty"` // "12:40"
-	EndTime     string `json:"end_time,omitempty"`   // "14:00"
-	Shift       *int   `json:"shift,omitempty"`      // 1 или 2 смена (указатель для проверки на 0)
-	Description string `json:"description,omitempty"`
+	GroupID     string  `json:"group_id,omitempty"`
+	SubgroupID  *string `json:"subgroup_id,omitempty"` // Пустая строка - вся группа. При смене группы без subgroup_id подгруппа сбрасывается
+	TeacherID   string  `json:"teacher_id,omitempty"`
+	SubjectID   string  `json:"subject_id,omitempty"`
+	RoomID      string  `json:"room_id,omitempty"`
+	Room        string  `json:"room,omitempty"`
+	Date        string  `json:"date,omitempty"`       // "2024-01-15"
+	StartTime   string  `json:"start_time,omitempty"` // "12:40"
+	EndTime     string  `json:"end_time,omitempty"`   // "14:00"
+	Shift       *int    `json:"shift,omitempty"`      // 1 или 2 смена (указатель для проверки на 0)
+	Description string  `json:"description,omitempty"`
 }
 
 // DetermineShift определяет смену по времени начала урока
 // Первая смена: 8:00-12:30, Вторая смена: 12:40-17:00
 func DetermineShift(startTime string) int {
-	if startTime == "" {
+	// Конвертируем в минуты для удобства сравнения
+	totalMinutes, ok := ParseClock(startTime)
+	if !ok {
 		return 0
 	}
 
+	// Первая смена: 8:00 (480 мин) - 12:30 (750 мин)
+	// Вторая смена: 12:40 (760 мин) - 17:00 (1020 мин)
+	if totalMinutes >= 480 && totalMinutes <= 750 {
+		return 1 // Первая смена
+	} else if totalMinutes >= 760 && totalMinutes <= 1020 {
+		return 2 // Вторая смена
+	}
+
+	return 0 // Неопределенная смена
+}
+
+// ParseClock переводит время "HH:MM" в минуты от начала суток
+func ParseClock(value string) (int, bool) {
+	if value == "" {
+		return 0, false
+	}
+
 	// Парсим время в формате "HH:MM"
-	parts := strings.Split(startTime, ":")
+	parts := strings.Split(strings.TrimSpace(value), ":")
 	if len(parts) != 2 {
-		return 0
+		return 0, false
 	}
 
 	hour, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return 0
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, false
 	}
 
 	minute, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return 0
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, false
 	}
 
-	// Конвертируем в минуты для удобства сравнения
-	totalMinutes := hour*60 + minute
+	return hour*60 + minute, true
+}
 
-	// Первая смена: 8:00 (480 мин) - 12:30 (750 мин)
-	// Вторая смена: 12:40 (760 мин) - 17:00 (1020 мин)
-	if totalMinutes >= 480 && totalMinutes <= 750 {
-		return 1 // Первая смена
-	} else if totalMinutes >= 760 && totalMinutes <= 1020 {
-		return 2 // Вторая смена
+// DayOfWeek возвращает день недели в формате расписания: 1 - понедельник, 7 - воскресенье
+func DayOfWeek(date time.Time) int {
+	if date.Weekday() == time.Sunday {
+		return 7
 	}
+	return int(date.Weekday())
+}
 
-	return 0 // Неопределенная смена
+// TimesOverlap проверяет пересечение интервалов [aStart, aEnd) и [bStart, bEnd)
+// Интервалы, которые только касаются друг друга (14:00-15:20 и 15:20-16:40), не пересекаются
+func TimesOverlap(aStart, aEnd, bStart, bEnd string) bool {
+	as, ok1 := ParseClock(aStart)
+	ae, ok2 := ParseClock(aEnd)
+	bs, ok3 := ParseClock(bStart)
+	be, ok4 := ParseClock(bEnd)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return false
+	}
+
+	return as < be && bs < ae
 }
 
 // TimeSlot представляет временной слот в расписании
@@ -245,6 +531,8 @@ type TimeSlot struct {
 	IsActive  bool               `bson:"is_active" json:"is_active"`   // Активен ли слот
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
 }
 
 // CreateTimeSlotRequest запрос на создание временного слота
@@ -264,3 +552,511 @@ type UpdateTimeSlotRequest struct {
 	Label     *string `json:"label,omitempty"`
 	IsActive  *bool   `json:"is_active,omitempty"`
 }
+
+// LoginRequest запрос на вход в систему
+type LoginRequest struct {
+	Login    string `json:"login" binding:"required"` // Логин администратора или ИИН
+	Password string `json:"password,omitempty"`       // Только для администратора
+}
+
+// Типы конфликтов расписания
+const (
+	ConflictTeacher = "teacher"
+	ConflictRoom    = "room"
+	ConflictGroup   = "group"
+)
+
+// Conflict описывает пересечение с уже существующей записью расписания или урока
+type Conflict struct {
+	Type       string      `json:"type"`       // teacher, room или group
+	Collection string      `json:"collection"` // schedules или lessons
+	ID         string      `json:"id"`
+	Document   interface{} `json:"document"`
+}
+
+// CreateRoomRequest запрос на создание аудитории
+type CreateRoomRequest struct {
+	Number    string   `json:"number" binding:"required"`
+	Building  string   `json:"building,omitempty"`
+	Floor     int      `json:"floor,omitempty"`
+	Capacity  int      `json:"capacity" binding:"min=0"`
+	Type      string   `json:"type" binding:"required,oneof=lecture computer_lab gym"`
+	Equipment []string `json:"equipment,omitempty"`
+}
+
+// UpdateRoomRequest запрос на обновление аудитории
+type UpdateRoomRequest struct {
+	Number    *string  `json:"number,omitempty"`
+	Building  *string  `json:"building,omitempty"`
+	Floor     *int     `json:"floor,omitempty"`
+	Capacity  *int     `json:"capacity,omitempty" binding:"omitempty,min=0"`
+	Type      *string  `json:"type,omitempty" binding:"omitempty,oneof=lecture computer_lab gym"`
+	Equipment []string `json:"equipment,omitempty"`
+}
+
+// roomNumberPrefixes префиксы, которые пишут перед номером аудитории
+var roomNumberPrefixes = []string{"аудитория", "кабинет", "ауд.", "каб.", "ауд", "каб"}
+
+// NormalizeRoomNumber приводит номер аудитории к единому виду: "каб. 201 " -> "201"
+func NormalizeRoomNumber(value string) string {
+	number := strings.ToLower(strings.TrimSpace(value))
+	for _, prefix := range roomNumberPrefixes {
+		if strings.HasPrefix(number, prefix) {
+			number = strings.TrimSpace(strings.TrimPrefix(number, prefix))
+			break
+		}
+	}
+	return strings.Join(strings.Fields(number), " ")
+}
+
+// GenerateLessonsRequest запрос на создание уроков из недельного расписания
+type GenerateLessonsRequest struct {
+	StartDate string `json:"start_date" binding:"required"` // "2024-09-02"
+	EndDate   string `json:"end_date" binding:"required"`   // "2024-12-28"
+	GroupID   string `json:"group_id,omitempty"`
+	TeacherID string `json:"teacher_id,omitempty"`
+}
+
+// Term учебный семестр
+type Term struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`             // Например: "1 семестр 2024-2025"
+	StartDate time.Time          `bson:"start_date" json:"start_date"` // Первый учебный день
+	EndDate   time.Time          `bson:"end_date" json:"end_date"`     // Последний учебный день
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// Типы событий академического календаря
+const (
+	CalendarHoliday    = "holiday"     // Праздничный день
+	CalendarWorkingDay = "working_day" // Перенесенный рабочий день (например, суббота)
+	CalendarVacation   = "vacation"    // Каникулы
+)
+
+// CalendarEvent праздник, каникулы или перенесенный рабочий день
+type CalendarEvent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type        string             `bson:"type" json:"type"` // holiday, working_day, vacation
+	Name        string             `bson:"name" json:"name"`
+	StartDate   time.Time          `bson:"start_date" json:"start_date"`
+	EndDate     time.Time          `bson:"end_date" json:"end_date"`                                 // Для одного дня совпадает с StartDate
+	AsDayOfWeek int                `bson:"as_day_of_week,omitempty" json:"as_day_of_week,omitempty"` // По расписанию какого дня работаем в перенесенный день
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt   *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // Когда перенесен в корзину
+	DeletedBy   string             `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// CreateTermRequest запрос на создание семестра
+type CreateTermRequest struct {
+	Name      string `json:"name" binding:"required"`
+	StartDate string `json:"start_date" binding:"required"` // "2024-09-02"
+	EndDate   string `json:"end_date" binding:"required"`   // "2024-12-28"
+}
+
+// UpdateTermRequest запрос на обновление семестра
+type UpdateTermRequest struct {
+	Name      string `json:"name,omitempty"`
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+// CreateCalendarEventRequest запрос на создание события календаря
+type CreateCalendarEventRequest struct {
+	Type        string `json:"type" binding:"required,oneof=holiday working_day vacation"`
+	Name        string `json:"name" binding:"required"`
+	StartDate   string `json:"start_date" binding:"required"` // "2024-12-16"
+	EndDate     string `json:"end_date,omitempty"`            // По умолчанию равна start_date
+	AsDayOfWeek int    `json:"as_day_of_week,omitempty" binding:"omitempty,min=1,max=7"`
+}
+
+// Статусы черновика расписания
+const (
+	DraftStatusDraft   = "draft"
+	DraftStatusApplied = "applied"
+)
+
+// ScheduleDraft черновик недельного расписания, составленный автоматически
+type ScheduleDraft struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name      string               `bson:"name" json:"name"`
+	Status    string               `bson:"status" json:"status"` // draft или applied
+	GroupIDs  []primitive.ObjectID `bson:"group_ids" json:"group_ids"`
+	Schedules []Schedule           `bson:"schedules" json:"schedules"`
+	Unplaced  []UnplacedLesson     `bson:"unplaced" json:"unplaced"`
+	Cost      int                  `bson:"cost" json:"cost"` // Штраф мягких ограничений: окна и неравномерная нагрузка
+	CreatedAt time.Time            `bson:"created_at" json:"created_at"`
+	AppliedAt *time.Time           `bson:"applied_at,omitempty" json:"applied_at,omitempty"`
+}
+
+// UnplacedLesson занятие, которое не удалось поставить в расписание
+type UnplacedLesson struct {
+	GroupID   primitive.ObjectID `bson:"group_id" json:"group_id"`
+	SubjectID primitive.ObjectID `bson:"subject_id" json:"subject_id"`
+	TeacherID primitive.ObjectID `bson:"teacher_id" json:"teacher_id"`
+	Reason    string             `bson:"reason" json:"reason"`
+}
+
+// WeeklyRequirement сколько часов в неделю группа изучает предмет у преподавателя
+type WeeklyRequirement struct {
+	GroupID      string `json:"group_id" binding:"required"`
+	SubjectID    string `json:"subject_id" binding:"required"`
+	TeacherID    string `json:"teacher_id" binding:"required"`
+	HoursPerWeek int    `json:"hours_per_week" binding:"required,min=1"` // Академические часы (одна пара = 2 часа)
+	RoomType     string `json:"room_type,omitempty" binding:"omitempty,oneof=lecture computer_lab gym"`
+}
+
+// SolveScheduleRequest запрос на автоматическое составление расписания
+type SolveScheduleRequest struct {
+	Name         string              `json:"name,omitempty"`
+	Requirements []WeeklyRequirement `json:"requirements" binding:"required,min=1,dive"`
+	TimeSlotIDs  []string            `json:"time_slot_ids,omitempty"` // По умолчанию все активные слоты
+	RoomIDs      []string            `json:"room_ids,omitempty"`      // По умолчанию все аудитории
+	Days         []int               `json:"days,omitempty"`          // По умолчанию учебные дни недели
+}
+
+// AcademicHoursPerLesson количество академических часов в одной паре
+const AcademicHoursPerLesson = 2
+
+// AcademicHourMinutes длительность академического часа: пара 80 минут - 2 часа
+const AcademicHourMinutes = 40
+
+// DurationMinutes длительность урока по StartTime и EndTime. Если время не задано
+// или неверно, урок считается обычной парой
+func (l Lesson) DurationMinutes() int {
+	start, okStart := ParseClock(l.StartTime)
+	end, okEnd := ParseClock(l.EndTime)
+	if !okStart || !okEnd || end <= start {
+		return AcademicHoursPerLesson * AcademicHourMinutes
+	}
+	return end - start
+}
+
+// Типы .ics-лент расписания
+const (
+	FeedStudent = "student"
+	FeedTeacher = "teacher"
+	FeedGroup   = "group"
+	FeedRoom    = "room"
+)
+
+// CalendarSubscription секретная ссылка на .ics-ленту, которую календарные приложения
+// опрашивают без авторизации
+type CalendarSubscription struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Token          string             `bson:"token" json:"token"`
+	FeedType       string             `bson:"feed_type" json:"feed_type"` // student, teacher, group, room
+	FeedID         primitive.ObjectID `bson:"feed_id" json:"feed_id"`
+	OwnerRole      string             `bson:"owner_role" json:"owner_role"` // Кто создал подписку
+	OwnerID        string             `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	LastAccessedAt *time.Time         `bson:"last_accessed_at,omitempty" json:"last_accessed_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CreateCalendarSubscriptionRequest запрос на создание подписки. Без параметров
+// студент или преподаватель получает ссылку на собственное расписание
+type CreateCalendarSubscriptionRequest struct {
+	FeedType string `json:"feed_type,omitempty" binding:"omitempty,oneof=student teacher group room"`
+	FeedID   string `json:"feed_id,omitempty"`
+}
+
+// CancelLessonRequest запрос на отмену урока
+type CancelLessonRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// RescheduleLessonRequest запрос на перенос урока. Без аудитории урок остается в прежней
+type RescheduleLessonRequest struct {
+	Date      string `json:"date" binding:"required"`       // "2024-10-21"
+	StartTime string `json:"start_time" binding:"required"` // "12:40"
+	EndTime   string `json:"end_time" binding:"required"`   // "14:00"
+	RoomID    string `json:"room_id,omitempty"`
+	Room      string `json:"room,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ConductLessonRequest запрос на отметку урока проведенным
+type ConductLessonRequest struct {
+	Topic string `json:"topic" binding:"required"`
+}
+
+// Отметки посещаемости
+const (
+	AttendancePresent = "present" // Присутствовал
+	AttendanceAbsent  = "absent"  // Отсутствовал
+	AttendanceLate    = "late"    // Опоздал
+	AttendanceExcused = "excused" // Отсутствовал по уважительной причине
+)
+
+// Attendance отметка посещаемости студента на уроке
+type Attendance struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LessonID  primitive.ObjectID `bson:"lesson_id" json:"lesson_id"`
+	StudentID primitive.ObjectID `bson:"student_id" json:"student_id"`
+	Student   *Student           `bson:"student,omitempty" json:"student,omitempty"`
+	GroupID   primitive.ObjectID `bson:"group_id" json:"group_id"`     // Копия из урока для отчетов
+	SubjectID primitive.ObjectID `bson:"subject_id" json:"subject_id"` // Копия из урока для отчетов
+	Date      time.Time          `bson:"date" json:"date"`             // Дата урока
+	Status    string             `bson:"status" json:"status"`         // present, absent, late, excused
+	Comment   string             `bson:"comment,omitempty" json:"comment,omitempty"`
+	MarkedBy  string             `bson:"marked_by" json:"marked_by"` // Логин того, кто поставил отметку
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// AttendanceMark отметка одного студента
+type AttendanceMark struct {
+	StudentID string `json:"student_id" binding:"required"`
+	Status    string `json:"status" binding:"required,oneof=present absent late excused"`
+	Comment   string `json:"comment,omitempty"`
+}
+
+// MarkAttendanceRequest запрос на отметку посещаемости урока. DefaultStatus
+// ставится всем студентам группы, которых нет в Marks
+type MarkAttendanceRequest struct {
+	DefaultStatus string           `json:"default_status,omitempty" binding:"omitempty,oneof=present absent late excused"`
+	Marks         []AttendanceMark `json:"marks,omitempty" binding:"dive"`
+}
+
+// Виды оценок
+const (
+	GradeCurrent = "current" // Текущая
+	GradeMidterm = "midterm" // Рубежный контроль
+	GradeExam    = "exam"    // Экзамен
+)
+
+// Grade оценка студента за урок
+type Grade struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LessonID  primitive.ObjectID `bson:"lesson_id" json:"lesson_id"`
+	StudentID primitive.ObjectID `bson:"student_id" json:"student_id"`
+	GroupID   primitive.ObjectID `bson:"group_id" json:"group_id"`     // Копия из урока для журнала
+	SubjectID primitive.ObjectID `bson:"subject_id" json:"subject_id"` // Копия из урока для журнала
+	TeacherID primitive.ObjectID `bson:"teacher_id" json:"teacher_id"` // Кто поставил оценку
+	Date      time.Time          `bson:"date" json:"date"`             // Дата урока
+	Type      string             `bson:"type" json:"type"`             // current, midterm, exam
+	Value     int                `bson:"value" json:"value"`
+	Comment   string             `bson:"comment,omitempty" json:"comment,omitempty"`
+	GradedBy  string             `bson:"graded_by" json:"graded_by"` // Логин того, кто поставил оценку
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// GradeMark оценка одного студента в запросе
+type GradeMark struct {
+	StudentID string `json:"student_id" binding:"required"`
+	Value     int    `json:"value"`
+	Type      string `json:"type,omitempty" binding:"omitempty,oneof=current midterm exam"` // По умолчанию current
+	Comment   string `json:"comment,omitempty"`
+}
+
+// GradeLessonRequest запрос на выставление оценок за урок. У студента может быть
+// одна оценка каждого вида за урок: повторная заменяет прежнюю
+type GradeLessonRequest struct {
+	Grades []GradeMark `json:"grades" binding:"required,min=1,dive"`
+}
+
+// TeacherAbsence отсутствие преподавателя (больничный, командировка) за период
+type TeacherAbsence struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TeacherID primitive.ObjectID `bson:"teacher_id" json:"teacher_id"`
+	Teacher   *Teacher           `bson:"teacher,omitempty" json:"teacher,omitempty"`
+	StartDate time.Time          `bson:"start_date" json:"start_date"` // Первый день отсутствия
+	EndDate   time.Time          `bson:"end_date" json:"end_date"`     // Последний день отсутствия
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CreateTeacherAbsenceRequest запрос на регистрацию отсутствия преподавателя
+type CreateTeacherAbsenceRequest struct {
+	TeacherID string `json:"teacher_id" binding:"required"`
+	StartDate string `json:"start_date" binding:"required"` // "2024-10-14"
+	EndDate   string `json:"end_date" binding:"required"`   // "2024-10-18"
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Substitution замена преподавателя на одном уроке
+type Substitution struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LessonID            primitive.ObjectID `bson:"lesson_id" json:"lesson_id"`
+	AbsenceID           primitive.ObjectID `bson:"absence_id,omitempty" json:"absence_id,omitempty"` // Отсутствие, из-за которого нужна замена
+	OriginalTeacherID   primitive.ObjectID `bson:"original_teacher_id" json:"original_teacher_id"`
+	OriginalTeacher     *Teacher           `bson:"original_teacher,omitempty" json:"original_teacher,omitempty"`
+	SubstituteTeacherID primitive.ObjectID `bson:"substitute_teacher_id" json:"substitute_teacher_id"`
+	SubstituteTeacher   *Teacher           `bson:"substitute_teacher,omitempty" json:"substitute_teacher,omitempty"`
+	GroupID             primitive.ObjectID `bson:"group_id" json:"group_id"`
+	Group               *Group             `bson:"group,omitempty" json:"group,omitempty"`
+	SubjectID           primitive.ObjectID `bson:"subject_id" json:"subject_id"`
+	Subject             *Subject           `bson:"subject,omitempty" json:"subject,omitempty"`
+	Date                time.Time          `bson:"date" json:"date"`
+	StartTime           string             `bson:"start_time" json:"start_time"`
+	EndTime             string             `bson:"end_time" json:"end_time"`
+	Reason              string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedBy           string             `bson:"created_by" json:"created_by"` // Логин того, кто назначил замену
+	CreatedAt           time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// CreateSubstitutionRequest запрос на назначение замены на урок
+type CreateSubstitutionRequest struct {
+	SubstituteTeacherID string `json:"substitute_teacher_id" binding:"required"`
+	Reason              string `json:"reason,omitempty"`
+}
+
+// Teaches проверяет, что предмет есть в списке предметов преподавателя.
+// Предмет в списке может быть записан названием, кодом или ID
+func (t Teacher) Teaches(subject Subject) bool {
+	for _, value := range t.Subjects {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+		if value == strings.ToLower(strings.TrimSpace(subject.Name)) ||
+			value == strings.ToLower(strings.TrimSpace(subject.Code)) ||
+			value == subject.ID.Hex() {
+			return true
+		}
+	}
+	return false
+}
+
+// События изменения расписания: их получают поток событий и вебхуки
+const (
+	EventLessonCreated    = "lesson.created"
+	EventLessonUpdated    = "lesson.updated" // Сменился преподаватель, тема или описание урока
+	EventLessonMoved      = "lesson.moved"   // Урок перенесен на другое время, дату или в другую аудиторию
+	EventLessonCancelled  = "lesson.cancelled"
+	EventLessonDeleted    = "lesson.deleted"
+	EventLessonsGenerated = "lessons.generated" // Уроки созданы генератором по расписанию
+	EventScheduleUpdated  = "schedule.updated"
+	EventTimeSlotCreated  = "time_slot.created"
+	EventTimeSlotUpdated  = "time_slot.updated"
+	EventTimeSlotDeleted  = "time_slot.deleted"
+	EventPing             = "ping" // Проверочная доставка
+)
+
+// Статусы доставки вебхука
+const (
+	DeliveryPending   = "pending" // Ждет первой или повторной попытки
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // Попытки закончились
+)
+
+// Webhook подписка внешней системы (сайт, экраны) на изменения расписания
+type Webhook struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL         string             `bson:"url" json:"url"`
+	Events      []string           `bson:"events" json:"events"`
+	Secret      string             `bson:"secret" json:"secret,omitempty"` // Ключ подписи, показывается только при создании
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	IsActive    bool               `bson:"is_active" json:"is_active"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Subscribed проверяет, что вебхук подписан на событие
+func (w Webhook) Subscribed(event string) bool {
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhookRequest запрос на создание вебхука. Без secret ключ подписи генерируется
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,url"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=lesson.created lesson.updated lesson.moved lesson.cancelled lesson.deleted lessons.generated schedule.updated time_slot.created time_slot.updated time_slot.deleted"`
+	Secret      string   `json:"secret,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// UpdateWebhookRequest запрос на обновление вебхука
+type UpdateWebhookRequest struct {
+	URL         string   `json:"url,omitempty" binding:"omitempty,url"`
+	Events      []string `json:"events,omitempty" binding:"omitempty,min=1,dive,oneof=lesson.created lesson.updated lesson.moved lesson.cancelled lesson.deleted lessons.generated schedule.updated time_slot.created time_slot.updated time_slot.deleted"`
+	Description *string  `json:"description,omitempty"`
+	IsActive    *bool    `json:"is_active,omitempty"`
+}
+
+// WebhookDelivery доставка одного события одному вебхуку со всеми попытками
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WebhookID     primitive.ObjectID `bson:"webhook_id" json:"webhook_id"`
+	Event         string             `bson:"event" json:"event"`
+	Payload       string             `bson:"payload" json:"payload"` // Тело запроса: повторные попытки отправляют его без изменений
+	Status        string             `bson:"status" json:"status"`   // pending, delivered, failed
+	Attempts      []DeliveryAttempt  `bson:"attempts" json:"attempts"`
+	NextAttemptAt *time.Time         `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// DeliveryAttempt одна попытка доставки
+type DeliveryAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"` // Ответ получателя
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMs int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+// TelegramChat чат Telegram, привязанный к студенту или преподавателю по ИИН.
+// В один чат приходит расписание и уведомления одного пользователя
+type TelegramChat struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ChatID    int64              `bson:"chat_id" json:"chat_id"`
+	Role      string             `bson:"role" json:"role"`         // student или teacher
+	OwnerID   primitive.ObjectID `bson:"owner_id" json:"owner_id"` // ID студента или преподавателя
+	IIN       string             `bson:"iin" json:"iin"`
+	Username  string             `bson:"username,omitempty" json:"username,omitempty"`
+	LinkedAt  time.Time          `bson:"linked_at" json:"linked_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Действия в журнале изменений
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"  // Перенос в корзину или окончательное удаление
+	AuditRestore = "restore" // Восстановление из корзины
+)
+
+// AuditEntry запись журнала изменений: кто, когда и как изменил документ
+type AuditEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Collection string             `bson:"collection" json:"collection"` // Например: lessons, schedules
+	EntityID   primitive.ObjectID `bson:"entity_id" json:"entity_id"`
+	Action     string             `bson:"action" json:"action"` // create, update, delete, restore
+	UserID     string             `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Role       string             `bson:"role" json:"role"`   // admin, teacher, student или system
+	Login      string             `bson:"login" json:"login"` // Логин администратора или ИИН
+	Before     primitive.M        `bson:"before,omitempty" json:"before,omitempty"`
+	After      primitive.M        `bson:"after,omitempty" json:"after,omitempty"`
+	Changes    []AuditChange      `bson:"changes,omitempty" json:"changes,omitempty"` // Только для update
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// AuditChange изменение одного поля
+type AuditChange struct {
+	Field  string      `bson:"field" json:"field"`
+	Before interface{} `bson:"before,omitempty" json:"before,omitempty"`
+	After  interface{} `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+// UnmarshalBSON читает вложенные документы значений как bson.M, чтобы они
+// выводились в JSON объектами, а не списками ключей
+func (c *AuditChange) UnmarshalBSON(data []byte) error {
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	c.Field, _ = doc["field"].(string)
+	c.Before = doc["before"]
+	c.After = doc["after"]
+	return nil
+}
@@ -0,0 +1,223 @@
+package pdf
+
+import (
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Шрифты DejaVu Sans Condensed поддерживают русскую и казахскую кириллицу
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	defaultRegular []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	defaultBold []byte
+)
+
+// fontFamily имя семейства шрифтов внутри документа
+const fontFamily = "Timetable"
+
+// Размеры страницы A4 (альбомная ориентация) в миллиметрах
+const (
+	pageMargin   = 10.0
+	titleHeight  = 9.0
+	headerHeight = 10.0
+	slotWidth    = 24.0
+	lineHeight   = 3.6
+	cellPadding  = 1.2
+	footerHeight = 6.0
+)
+
+// Fonts шрифты для печати расписания
+type Fonts struct {
+	Regular []byte
+	Bold    []byte
+}
+
+// LoadFonts загружает шрифты из файлов. Если путь не указан, используется встроенный DejaVu Sans
+func LoadFonts(regularPath, boldPath string) (Fonts, error) {
+	fonts := Fonts{Regular: defaultRegular, Bold: defaultBold}
+	if regularPath != "" {
+		data, err := os.ReadFile(regularPath)
+		if err != nil {
+			return fonts, err
+		}
+		fonts.Regular = data
+		fonts.Bold = data
+	}
+	if boldPath != "" {
+		data, err := os.ReadFile(boldPath)
+		if err != nil {
+			return fonts, err
+		}
+		fonts.Bold = data
+	}
+	return fonts, nil
+}
+
+// Column день недели в таблице
+type Column struct {
+	Date time.Time
+	Note string // Например, "Праздник: День Республики"
+}
+
+// Row строка таблицы - временной слот
+type Row struct {
+	Label string // Подпись слота, например "1 пара\n08:00-09:20"
+}
+
+// Entry занятие в ячейке таблицы
+type Entry struct {
+	Column  int
+	Row     int
+	Lines   []string // Первая строка печатается жирным
+	Changed bool     // Урок отличается от недельного расписания (замена, перенос)
+}
+
+// Page страница с расписанием на неделю
+type Page struct {
+	Title    string // "Группа ПО-31"
+	Subtitle string // "Неделя 02.09.2024 - 08.09.2024"
+	Columns  []Column
+	Rows     []Row
+	Entries  []Entry
+}
+
+// dayNames названия дней недели (индекс - time.Weekday)
+var dayNames = []string{"Воскресенье", "Понедельник", "Вторник", "Среда", "Четверг", "Пятница", "Суббота"}
+
+// Render рисует расписание на листах A4 в альбомной ориентации: по странице на Page
+func Render(w io.Writer, fonts Fonts, pages []Page) error {
+	doc := fpdf.New("L", "mm", "A4", "")
+	doc.SetMargins(pageMargin, pageMargin, pageMargin)
+	doc.SetAutoPageBreak(false, pageMargin)
+	doc.AddUTF8FontFromBytes(fontFamily, "", fonts.Regular)
+	doc.AddUTF8FontFromBytes(fontFamily, "B", fonts.Bold)
+	doc.SetTitle("Расписание занятий", true)
+
+	printed := time.Now().Format("02.01.2006 15:04")
+	for _, page := range pages {
+		renderPage(doc, page, printed)
+	}
+	if len(pages) == 0 {
+		doc.AddPage()
+	}
+
+	if err := doc.Error(); err != nil {
+		return err
+	}
+	return doc.Output(w)
+}
+
+func renderPage(doc *fpdf.Fpdf, page Page, printed string) {
+	doc.AddPage()
+	pageWidth, pageHeight := doc.GetPageSize()
+	tableWidth := pageWidth - 2*pageMargin
+
+	// Заголовок
+	doc.SetFont(fontFamily, "B", 14)
+	doc.CellFormat(tableWidth*0.6, titleHeight, page.Title, "", 0, "L", false, 0, "")
+	doc.SetFont(fontFamily, "", 10)
+	doc.CellFormat(tableWidth*0.4, titleHeight, page.Subtitle, "", 1, "R", false, 0, "")
+
+	if len(page.Columns) == 0 {
+		return
+	}
+
+	top := doc.GetY() + 2
+	columnWidth := (tableWidth - slotWidth) / float64(len(page.Columns))
+	rowHeight := 0.0
+	if len(page.Rows) > 0 {
+		rowHeight = (pageHeight - top - headerHeight - footerHeight - pageMargin) / float64(len(page.Rows))
+	}
+
+	// Шапка: дни недели с датами
+	doc.SetFillColor(221, 235, 247)
+	doc.SetFont(fontFamily, "B", 9)
+	doc.Rect(pageMargin, top, slotWidth, headerHeight, "FD")
+	for i, column := range page.Columns {
+		x := pageMargin + slotWidth + float64(i)*columnWidth
+		doc.Rect(x, top, columnWidth, headerHeight, "FD")
+		header := dayNames[column.Date.Weekday()] + ", " + column.Date.Format("02.01")
+		writeLines(doc, x, top+1, columnWidth, headerHeight, []string{header}, "C")
+	}
+
+	// Строки: временные слоты
+	for r, row := range page.Rows {
+		y := top + headerHeight + float64(r)*rowHeight
+		doc.SetFont(fontFamily, "B", 8)
+		doc.SetFillColor(242, 242, 242)
+		doc.Rect(pageMargin, y, slotWidth, rowHeight, "FD")
+		writeLines(doc, pageMargin, y+cellPadding, slotWidth, rowHeight, strings.Split(row.Label, "\n"), "C")
+
+		for i := range page.Columns {
+			doc.Rect(pageMargin+slotWidth+float64(i)*columnWidth, y, columnWidth, rowHeight, "D")
+		}
+	}
+
+	// Нерабочие дни закрашиваются, в первой ячейке - причина
+	for i, column := range page.Columns {
+		if column.Note == "" || len(page.Rows) == 0 {
+			continue
+		}
+		x := pageMargin + slotWidth + float64(i)*columnWidth
+		doc.SetFillColor(250, 226, 213)
+		doc.Rect(x, top+headerHeight, columnWidth, rowHeight*float64(len(page.Rows)), "FD")
+		doc.SetFont(fontFamily, "", 8)
+		writeLines(doc, x, top+headerHeight+cellPadding, columnWidth, rowHeight, doc.SplitText(column.Note, columnWidth), "C")
+	}
+
+	// Занятия
+	occupied := make(map[[2]int]float64) // Сколько места уже занято в ячейке
+	for _, entry := range page.Entries {
+		if entry.Column >= len(page.Columns) || entry.Row >= len(page.Rows) {
+			continue
+		}
+		key := [2]int{entry.Column, entry.Row}
+		x := pageMargin + slotWidth + float64(entry.Column)*columnWidth
+		y := top + headerHeight + float64(entry.Row)*rowHeight + cellPadding + occupied[key]
+		available := rowHeight - cellPadding - occupied[key]
+		if available <= lineHeight {
+			continue
+		}
+
+		if entry.Changed {
+			doc.SetTextColor(192, 0, 0)
+		}
+		used := 0.0
+		for i, line := range entry.Lines {
+			style := ""
+			if i == 0 {
+				style = "B"
+			}
+			doc.SetFont(fontFamily, style, 8)
+			wrapped := doc.SplitText(line, columnWidth)
+			used += writeLines(doc, x, y+used, columnWidth, available-used, wrapped, "L")
+		}
+		doc.SetTextColor(0, 0, 0)
+		occupied[key] += used + cellPadding
+	}
+
+	// Подвал
+	doc.SetFont(fontFamily, "", 7)
+	doc.SetXY(pageMargin, pageHeight-pageMargin-footerHeight+2)
+	doc.CellFormat(0, 4, "Красным отмечены изменения относительно недельного расписания. Напечатано "+printed, "", 0, "L", false, 0, "")
+}
+
+// writeLines печатает строки в пределах высоты ячейки и возвращает занятую высоту
+func writeLines(doc *fpdf.Fpdf, x, y, width, height float64, lines []string, align string) float64 {
+	used := 0.0
+	for _, line := range lines {
+		if used+lineHeight > height {
+			break
+		}
+		doc.SetXY(x, y+used)
+		doc.CellFormat(width, lineHeight, line, "", 0, align, false, 0, "")
+		used += lineHeight
+	}
+	return used
+}
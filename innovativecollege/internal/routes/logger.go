@@ -0,0 +1,46 @@
+package routes
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sensitiveParams параметры запроса, значения которых не пишутся в журнал
+var sensitiveParams = []string{"ticket", "access_token"}
+
+// Logger журнал запросов в формате gin.Default, но без билетов и токенов в URL
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			redactQuery(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// redactQuery заменяет значения sensitiveParams в строке запроса. Если строку
+// не удалось разобрать, она отбрасывается целиком
+func redactQuery(path string) string {
+	base, rawQuery, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return base
+	}
+	for _, name := range sensitiveParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+		}
+	}
+	return base + "?" + query.Encode()
+}
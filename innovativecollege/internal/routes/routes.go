@@ -1,17 +1,20 @@
 package routes
 
 import (
+	"innovativecollege/internal/audit"
+	"innovativecollege/internal/auth"
 	"innovativecollege/internal/handlers"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, h *handlers.Handlers) {
+func SetupRoutes(r *gin.Engine, h *handlers.Handlers, authManager *auth.Manager) {
 	// CORS middleware
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID")
+		c.Header("Access-Control-Expose-Headers", "X-Total-Count")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -21,59 +24,187 @@ func SetupRoutes(r *gin.Engine, h *handlers.Handlers) {
 		c.Next()
 	})
 
+	// Публичные маршруты
+	public := r.Group("/api/v1")
+	{
+		public.POST("/auth/login", h.Login)
+		public.GET("/ical/:token", h.GetSubscriptionFeed)
+	}
+
+	// Поток событий: кроме Bearer токена принимает билет в параметре ticket
+	stream := r.Group("/api/v1")
+	stream.Use(auth.StreamMiddleware(authManager), audit.Middleware())
+	{
+		stream.GET("/events/stream", h.StreamEvents)
+	}
+
+	// Роли: администратор может всё, преподаватель редактирует свои уроки, студент только читает
+	admin := auth.RequireRoles(auth.RoleAdmin)
+	lessonEditors := auth.RequireRoles(auth.RoleAdmin, auth.RoleTeacher)
+
 	// API routes
 	api := r.Group("/api/v1")
+	api.Use(auth.Middleware(authManager), audit.Middleware())
 	{
+		// Текущий пользователь
+		api.GET("/auth/me", h.GetCurrentUser)
+
 		// Группы
-		api.POST("/groups", h.CreateGroup)
+		api.POST("/groups", admin, h.CreateGroup)
 		api.GET("/groups", h.GetGroups)
-		api.PUT("/groups/:id", h.UpdateGroup)
-		api.DELETE("/groups/:id", h.DeleteGroup)
+		api.GET("/groups/:id/schedule.ics", h.GetGroupScheduleICS)
+		api.GET("/groups/:id/schedule.pdf", h.PrintGroupSchedule)
+		api.PUT("/groups/:id", admin, h.UpdateGroup)
+		api.DELETE("/groups/:id", admin, h.DeleteGroup)
+		api.GET("/groups/:id/subgroups", h.GetSubgroups)
+		api.POST("/groups/:id/subgroups", admin, h.CreateSubgroup)
+		api.PUT("/groups/:id/subgroups/:subgroup_id", admin, h.UpdateSubgroup)
+		api.DELETE("/groups/:id/subgroups/:subgroup_id", admin, h.DeleteSubgroup)
 
 		// Предметы
-		api.POST("/subjects", h.CreateSubject)
+		api.POST("/subjects", admin, h.CreateSubject)
 		api.GET("/subjects", h.GetSubjects)
-		api.PUT("/subjects/:id", h.UpdateSubject)
-		api.DELETE("/subjects/:id", h.DeleteSubject)
+		api.PUT("/subjects/:id", admin, h.UpdateSubject)
+		api.DELETE("/subjects/:id", admin, h.DeleteSubject)
 
 		// Студенты
-		api.POST("/students", h.CreateStudent)
+		api.POST("/students", admin, h.CreateStudent)
 		api.GET("/students", h.GetStudents)
 		api.GET("/students/:iin/schedule", h.GetStudentSchedule)
-		api.PUT("/students/:id", h.UpdateStudent)
-		api.DELETE("/students/:id", h.DeleteStudent)
+		api.GET("/students/:iin/schedule.ics", h.GetStudentScheduleICS)
+		api.PUT("/students/:id", admin, h.UpdateStudent)
+		api.DELETE("/students/:id", admin, h.DeleteStudent)
 
 		// Преподаватели
-		api.POST("/teachers", h.CreateTeacher)
+		api.POST("/teachers", admin, h.CreateTeacher)
 		api.GET("/teachers", h.GetTeachers)
 		api.GET("/teachers/:iin/schedule", h.GetTeacherSchedule)
-		api.PUT("/teachers/:id", h.UpdateTeacher)
-		api.DELETE("/teachers/:id", h.DeleteTeacher)
+		api.GET("/teachers/:iin/schedule.ics", h.GetTeacherScheduleICS)
+		api.GET("/teachers/:iin/schedule.pdf", h.PrintTeacherSchedule)
+		api.PUT("/teachers/:id", admin, h.UpdateTeacher)
+		api.DELETE("/teachers/:id", admin, h.DeleteTeacher)
+
+		// Доступность преподавателей
+		api.GET("/availability/teachers/:id", h.GetTeacherAvailability)
+		api.PUT("/availability/teachers/:id", admin, h.UpdateTeacherAvailability)
 
 		// Расписание
-		api.POST("/schedules", h.CreateSchedule)
+		api.POST("/schedules", admin, h.CreateSchedule)
 		api.GET("/schedules", h.GetSchedules)
 		api.GET("/schedules/day/:day", h.GetSchedulesByDay)
-		api.PUT("/schedules/:id", h.UpdateSchedule)
-		api.DELETE("/schedules/:id", h.DeleteSchedule)
+		api.GET("/schedules/export", h.ExportSchedules)
+		api.GET("/schedules/print.pdf", h.PrintAllGroups)
+		api.POST("/schedules/import", admin, h.ImportSchedules)
+		api.PUT("/schedules/:id", admin, h.UpdateSchedule)
+		api.DELETE("/schedules/:id", admin, h.DeleteSchedule)
 
 		// Календарь (Уроки)
-		api.POST("/lessons", h.CreateLesson)
+		api.POST("/lessons", admin, h.CreateLesson)
 		api.GET("/lessons", h.GetLessons)
+		api.POST("/lessons/generate", admin, h.GenerateLessons)
 		api.GET("/lessons/available", h.GetAvailableLessons)
+		api.GET("/lessons/export", h.ExportLessons)
 		api.GET("/lessons/date/:date", h.GetLessonsByDate)
-		api.PUT("/lessons/:id", h.UpdateLesson)
-		api.DELETE("/lessons/:id", h.DeleteLesson)
+		api.PUT("/lessons/:id", lessonEditors, h.UpdateLesson)
+		api.POST("/lessons/:id/cancel", admin, h.CancelLesson)
+		api.POST("/lessons/:id/reschedule", admin, h.RescheduleLesson)
+		api.POST("/lessons/:id/conduct", lessonEditors, h.ConductLesson)
+		api.GET("/lessons/:id/attendance", lessonEditors, h.GetLessonAttendance)
+		api.POST("/lessons/:id/attendance", lessonEditors, h.MarkAttendance)
+		api.GET("/lessons/:id/grades", lessonEditors, h.GetLessonGrades)
+		api.POST("/lessons/:id/grades", lessonEditors, h.GradeLesson)
+		api.DELETE("/lessons/:id", admin, h.DeleteLesson)
+
+		// Отсутствия преподавателей и замены
+		api.POST("/absences", admin, h.CreateTeacherAbsence)
+		api.GET("/absences", admin, h.GetTeacherAbsences)
+		api.GET("/absences/:id/lessons", admin, h.GetAbsenceLessons)
+		api.DELETE("/absences/:id", admin, h.DeleteTeacherAbsence)
+		api.GET("/lessons/:id/substitutes", admin, h.GetSubstituteCandidates)
+		api.POST("/lessons/:id/substitution", admin, h.ApplySubstitution)
+		api.GET("/substitutions", h.GetSubstitutions)
+		api.GET("/substitutions/report", admin, h.GetSubstitutionReport)
+		api.DELETE("/substitutions/:id", admin, h.CancelSubstitution)
+
+		// Посещаемость
+		api.GET("/attendance/students/:id", h.GetStudentAttendance)
+		api.GET("/attendance/groups/:id", lessonEditors, h.GetGroupAttendance)
+		api.GET("/attendance/alerts", lessonEditors, h.GetAttendanceAlerts)
+
+		// Журнал оценок
+		api.GET("/gradebook/groups/:id", lessonEditors, h.GetGroupJournal)
+		api.GET("/gradebook/students/:id", h.GetStudentTranscript)
+		api.GET("/gradebook/averages", lessonEditors, h.GetTermAverages)
+		api.DELETE("/grades/:id", lessonEditors, h.DeleteGrade)
+
+		// Вебхуки
+		api.POST("/webhooks", admin, h.CreateWebhook)
+		api.GET("/webhooks", admin, h.GetWebhooks)
+		api.GET("/webhooks/:id", admin, h.GetWebhook)
+		api.PUT("/webhooks/:id", admin, h.UpdateWebhook)
+		api.DELETE("/webhooks/:id", admin, h.DeleteWebhook)
+		api.POST("/webhooks/:id/ping", admin, h.PingWebhook)
+		api.GET("/webhooks/:id/deliveries", admin, h.GetWebhookDeliveries)
+		api.POST("/webhooks/:id/deliveries/:delivery_id/retry", admin, h.RetryWebhookDelivery)
+
+		// Билет на поток событий
+		api.POST("/events/ticket", h.IssueEventTicket)
 
 		// Временные слоты
-		api.POST("/time-slots", h.CreateTimeSlot)
+		api.POST("/time-slots", admin, h.CreateTimeSlot)
 		api.GET("/time-slots", h.GetTimeSlots)
 		api.GET("/time-slots/:id", h.GetTimeSlot)
-		api.PUT("/time-slots/:id", h.UpdateTimeSlot)
-		api.DELETE("/time-slots/:id", h.DeleteTimeSlot)
+		api.PUT("/time-slots/:id", admin, h.UpdateTimeSlot)
+		api.DELETE("/time-slots/:id", admin, h.DeleteTimeSlot)
+
+		// Автоматическое составление расписания
+		api.POST("/solver/drafts", admin, h.SolveSchedule)
+		api.GET("/solver/drafts", admin, h.GetScheduleDrafts)
+		api.GET("/solver/drafts/:id", admin, h.GetScheduleDraft)
+		api.POST("/solver/drafts/:id/apply", admin, h.ApplyScheduleDraft)
+		api.DELETE("/solver/drafts/:id", admin, h.DeleteScheduleDraft)
+
+		// Аудитории
+		api.POST("/rooms", admin, h.CreateRoom)
+		api.GET("/rooms", h.GetRooms)
+		api.POST("/rooms/migrate", admin, h.MigrateRooms)
+		api.GET("/rooms/:id", h.GetRoom)
+		api.GET("/rooms/:id/schedule.ics", h.GetRoomScheduleICS)
+		api.GET("/rooms/:id/schedule.pdf", h.PrintRoomSchedule)
+		api.PUT("/rooms/:id", admin, h.UpdateRoom)
+		api.DELETE("/rooms/:id", admin, h.DeleteRoom)
+
+		// Академический календарь
+		api.POST("/terms", admin, h.CreateTerm)
+		api.GET("/terms", h.GetTerms)
+		api.GET("/terms/current", h.GetCurrentTerm)
+		api.PUT("/terms/:id", admin, h.UpdateTerm)
+		api.DELETE("/terms/:id", admin, h.DeleteTerm)
+		api.POST("/calendar/events", admin, h.CreateCalendarEvent)
+		api.GET("/calendar/events", h.GetCalendarEvents)
+		api.DELETE("/calendar/events/:id", admin, h.DeleteCalendarEvent)
+		api.GET("/calendar/weeks", h.GetCalendarWeeks)
+		api.GET("/calendar/days", h.GetCalendarDays)
+
+		// Подписки на .ics-ленты расписания
+		api.POST("/calendar/subscriptions", h.CreateCalendarSubscription)
+		api.GET("/calendar/subscriptions", h.GetCalendarSubscriptions)
+		api.DELETE("/calendar/subscriptions/:id", h.DeleteCalendarSubscription)
 
 		// Статистика
 		api.GET("/statistics/lessons", h.GetLessonStatistics)
+		api.GET("/statistics/lessons/export", h.ExportLessonStatistics)
+		api.GET("/statistics/workload", lessonEditors, h.GetWorkloadReport)
+		api.GET("/statistics/workload/export", lessonEditors, h.ExportWorkloadReport)
+
+		// Журнал изменений
+		api.GET("/audit", admin, h.GetAuditLog)
+		api.GET("/audit/:collection/:id", admin, h.GetEntityHistory)
+
+		// Корзина
+		api.GET("/trash", admin, h.GetTrash)
+		api.POST("/trash/:collection/:id/restore", admin, h.RestoreFromTrash)
+		api.DELETE("/trash/:collection/:id", admin, h.PurgeFromTrash)
 	}
 
 	// Health check
@@ -0,0 +1,488 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/config"
+	"innovativecollege/internal/events"
+	"innovativecollege/internal/handlers"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+	"innovativecollege/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// testAPI HTTP API поверх хранилища в памяти
+type testAPI struct {
+	t     *testing.T
+	srv   *httptest.Server
+	admin string
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Load()
+	cfg.AdminLogin = "admin"
+	cfg.AdminPassword = "secret"
+	authManager := auth.NewManager("test-secret", time.Hour)
+	store := storage.NewMemoryStore()
+	dispatcher := webhooks.NewDispatcher(store, http.DefaultClient, 1, time.Second)
+
+	r := gin.New()
+	SetupRoutes(r, handlers.New(store, cfg, authManager, events.NewBus(), dispatcher), authManager)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	api := &testAPI{t: t, srv: srv}
+	api.admin = api.login("admin", "secret")
+	return api
+}
+
+// do выполняет запрос к /api/v1 и раскладывает JSON ответа в out, если он не nil
+func (a *testAPI) do(method, path, token string, body, out interface{}) int {
+	a.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, a.srv.URL+"/api/v1"+path, reader)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			a.t.Fatalf("%s %s: разбор ответа %q: %v", method, path, data, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// create создает документ от имени администратора и возвращает его id
+func (a *testAPI) create(path string, body interface{}) string {
+	a.t.Helper()
+	var created struct {
+		ID    string `json:"id"`
+		Error string `json:"error"`
+	}
+	if code := a.do(http.MethodPost, path, a.admin, body, &created); code != http.StatusCreated {
+		a.t.Fatalf("POST %s: код %d, ошибка %q", path, code, created.Error)
+	}
+	return created.ID
+}
+
+// totalCount заголовок X-Total-Count ответа на GET-запрос администратора
+func (a *testAPI) totalCount(path string) string {
+	a.t.Helper()
+	req, err := http.NewRequest(http.MethodGet, a.srv.URL+"/api/v1"+path, nil)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.admin)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.Header.Get("X-Total-Count")
+}
+
+func (a *testAPI) login(login, password string) string {
+	a.t.Helper()
+	var resp struct {
+		Token string `json:"token"`
+	}
+	body := models.LoginRequest{Login: login, Password: password}
+	if code := a.do(http.MethodPost, "/auth/login", "", body, &resp); code != http.StatusOK {
+		a.t.Fatalf("вход %s: код %d", login, code)
+	}
+	return resp.Token
+}
+
+func TestGroupCRUD(t *testing.T) {
+	api := newTestAPI(t)
+
+	id := api.create("/groups", gin.H{"name": "ПО-31", "shift": 1})
+
+	var groups []models.Group
+	if code := api.do(http.MethodGet, "/groups", api.admin, nil, &groups); code != http.StatusOK {
+		t.Fatalf("список групп: код %d", code)
+	}
+	if len(groups) != 1 || groups[0].Name != "ПО-31" {
+		t.Fatalf("список групп: %+v", groups)
+	}
+
+	if code := api.do(http.MethodPut, "/groups/"+id, api.admin, gin.H{"name": "ПО-32"}, nil); code != http.StatusOK {
+		t.Fatalf("обновление группы: код %d", code)
+	}
+	api.do(http.MethodGet, "/groups", api.admin, nil, &groups)
+	if len(groups) != 1 || groups[0].Name != "ПО-32" {
+		t.Fatalf("группа не обновилась: %+v", groups)
+	}
+
+	if code := api.do(http.MethodDelete, "/groups/"+id, api.admin, nil, nil); code != http.StatusOK {
+		t.Fatalf("удаление группы: код %d", code)
+	}
+	api.do(http.MethodGet, "/groups", api.admin, nil, &groups)
+	if len(groups) != 0 {
+		t.Fatalf("группа не удалилась: %+v", groups)
+	}
+
+	if code := api.do(http.MethodPut, "/groups/not-an-id", api.admin, gin.H{"name": "X"}, nil); code != http.StatusBadRequest {
+		t.Fatalf("неверный id: код %d, ожидали 400", code)
+	}
+}
+
+func TestScheduleConflicts(t *testing.T) {
+	api := newTestAPI(t)
+
+	subjectID := api.create("/subjects", gin.H{"name": "Математика", "code": "МАТ"})
+	teacherID := api.create("/teachers", gin.H{"iin": "800000000001", "first_name": "Анна", "last_name": "Иванова", "subjects": []string{"МАТ"}})
+	firstGroup := api.create("/groups", gin.H{"name": "ПО-31", "shift": 1})
+	secondGroup := api.create("/groups", gin.H{"name": "ПО-32", "shift": 1})
+	firstRoom := api.create("/rooms", gin.H{"number": "101", "type": "lecture", "capacity": 30})
+	secondRoom := api.create("/rooms", gin.H{"number": "102", "type": "lecture", "capacity": 30})
+
+	schedule := func(groupID, roomID string) gin.H {
+		return gin.H{
+			"group_id": groupID, "teacher_id": teacherID, "subject_id": subjectID, "room_id": roomID,
+			"day_of_week": 3, "start_time": "08:00", "end_time": "09:20", "shift": 1,
+		}
+	}
+	api.create("/schedules", schedule(firstGroup, firstRoom))
+
+	// Тот же преподаватель в то же время у другой группы
+	var conflict struct {
+		Conflicts []models.Conflict `json:"conflicts"`
+	}
+	if code := api.do(http.MethodPost, "/schedules", api.admin, schedule(secondGroup, secondRoom), &conflict); code != http.StatusConflict {
+		t.Fatalf("пересечение преподавателя: код %d, ожидали 409", code)
+	}
+	if len(conflict.Conflicts) != 1 || conflict.Conflicts[0].Type != models.ConflictTeacher {
+		t.Fatalf("ожидали один конфликт преподавателя: %+v", conflict.Conflicts)
+	}
+
+	// Та же аудитория и группа - конфликты по обоим ресурсам
+	conflict.Conflicts = nil
+	if code := api.do(http.MethodPost, "/schedules", api.admin, schedule(firstGroup, firstRoom), &conflict); code != http.StatusConflict {
+		t.Fatalf("повторная запись: код %d, ожидали 409", code)
+	}
+	if len(conflict.Conflicts) != 3 {
+		t.Fatalf("ожидали конфликты преподавателя, аудитории и группы: %+v", conflict.Conflicts)
+	}
+
+	// Администратор может сохранить запись принудительно
+	if code := api.do(http.MethodPost, "/schedules?force=true", api.admin, schedule(secondGroup, secondRoom), nil); code != http.StatusCreated {
+		t.Fatalf("принудительное сохранение: код %d", code)
+	}
+}
+
+func TestRoleGuards(t *testing.T) {
+	api := newTestAPI(t)
+
+	groupID := api.create("/groups", gin.H{"name": "ПО-31", "shift": 1})
+	api.create("/students", gin.H{"iin": "900000000001", "first_name": "Иван", "last_name": "Петров", "group_id": groupID})
+	api.create("/teachers", gin.H{"iin": "800000000001", "first_name": "Анна", "last_name": "Иванова"})
+	student := api.login("900000000001", "")
+	teacher := api.login("800000000001", "")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{"без токена", http.MethodGet, "/groups", "", http.StatusUnauthorized},
+		{"поддельный токен", http.MethodGet, "/groups", "not-a-token", http.StatusUnauthorized},
+		{"студент читает", http.MethodGet, "/groups", student, http.StatusOK},
+		{"студент создает группу", http.MethodPost, "/groups", student, http.StatusForbidden},
+		{"преподаватель создает группу", http.MethodPost, "/groups", teacher, http.StatusForbidden},
+		{"студент удаляет группу", http.MethodDelete, "/groups/" + groupID, student, http.StatusForbidden},
+		{"студент смотрит журнал изменений", http.MethodGet, "/audit", student, http.StatusForbidden},
+		{"преподаватель смотрит журнал изменений", http.MethodGet, "/audit", teacher, http.StatusForbidden},
+		{"администратор создает группу", http.MethodPost, "/groups", api.admin, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := api.do(tt.method, tt.path, tt.token, gin.H{"name": "ПО-33", "shift": 1}, nil); code != tt.want {
+				t.Fatalf("%s %s: код %d, ожидали %d", tt.method, tt.path, code, tt.want)
+			}
+		})
+	}
+
+	if code := api.do(http.MethodPost, "/auth/login", "", gin.H{"login": "admin", "password": "wrong"}, nil); code != http.StatusUnauthorized {
+		t.Fatalf("неверный пароль: код %d, ожидали 401", code)
+	}
+}
+
+func TestPagingEnvelope(t *testing.T) {
+	api := newTestAPI(t)
+	for _, name := range []string{"В-1", "А-1", "Б-1"} {
+		api.create("/groups", gin.H{"name": name, "shift": 1})
+	}
+	api.create("/groups", gin.H{"name": "Г-2", "shift": 2})
+
+	// Без page и limit - весь массив (исключение для старых клиентов), по умолчанию по названию
+	var groups []models.Group
+	api.do(http.MethodGet, "/groups?shift=1", api.admin, nil, &groups)
+	if len(groups) != 3 || groups[0].Name != "А-1" || groups[2].Name != "В-1" {
+		t.Fatalf("список без страниц: %+v", groups)
+	}
+	if total := api.totalCount("/groups?shift=2"); total != "1" {
+		t.Fatalf("X-Total-Count без страниц: %q", total)
+	}
+	if total := api.totalCount("/groups?shift=1&limit=1"); total != "3" {
+		t.Fatalf("X-Total-Count страницы: %q", total)
+	}
+
+	var page struct {
+		Items []models.Group `json:"items"`
+		Total int64          `json:"total"`
+		Page  int64          `json:"page"`
+		Limit int64          `json:"limit"`
+		Pages int64          `json:"pages"`
+	}
+	if code := api.do(http.MethodGet, "/groups?shift=1&page=2&limit=2&sort=-name", api.admin, nil, &page); code != http.StatusOK {
+		t.Fatalf("страница: код %d", code)
+	}
+	if page.Total != 3 || page.Page != 2 || page.Limit != 2 || page.Pages != 2 {
+		t.Fatalf("конверт: %+v", page)
+	}
+	if len(page.Items) != 1 || page.Items[0].Name != "А-1" {
+		t.Fatalf("вторая страница по убыванию: %+v", page.Items)
+	}
+
+	for _, query := range []string{"?sort=unknown", "?limit=0", "?page=0"} {
+		if code := api.do(http.MethodGet, "/groups"+query, api.admin, nil, nil); code != http.StatusBadRequest {
+			t.Fatalf("GET /groups%s: код %d, ожидали 400", query, code)
+		}
+	}
+}
+
+func TestTeacherUnavailability(t *testing.T) {
+	api := newTestAPI(t)
+
+	subjectID := api.create("/subjects", gin.H{"name": "Математика", "code": "МАТ"})
+	teacherID := api.create("/teachers", gin.H{"iin": "800000000001", "first_name": "Анна", "last_name": "Иванова"})
+	groupID := api.create("/groups", gin.H{"name": "ПО-31", "shift": 1})
+	api.create("/rooms", gin.H{"number": "101", "type": "lecture", "capacity": 30})
+	api.create("/time-slots", gin.H{"start_time": "08:00", "end_time": "09:20", "shift": 1, "is_active": true})
+
+	// По вторникам преподаватель недоступен весь день
+	availability := gin.H{"unavailable": []gin.H{{"day_of_week": 2, "reason": "методический день"}}}
+	if code := api.do(http.MethodPut, "/availability/teachers/"+teacherID, api.admin, availability, nil); code != http.StatusOK {
+		t.Fatalf("ограничения преподавателя: код %d", code)
+	}
+
+	// Перенос урока
+	{
+		lessonID := api.create("/lessons", gin.H{
+			"group_id": groupID, "teacher_id": teacherID, "subject_id": subjectID,
+			"room": "101", "date": "2024-10-14", "start_time": "08:00", "end_time": "09:20",
+		})
+		tuesday := gin.H{"date": "2024-10-15", "start_time": "08:00", "end_time": "09:20"}
+
+		var rejected struct {
+			Unavailable *models.UnavailableWindow `json:"unavailable"`
+		}
+		if code := api.do(http.MethodPost, "/lessons/"+lessonID+"/reschedule", api.admin, tuesday, &rejected); code != http.StatusConflict {
+			t.Fatalf("перенос в недоступный день: код %d, ожидали 409", code)
+		}
+		if rejected.Unavailable == nil || rejected.Unavailable.DayOfWeek != 2 {
+			t.Fatalf("окно недоступности: %+v", rejected.Unavailable)
+		}
+
+		var moved models.Lesson
+		if code := api.do(http.MethodPost, "/lessons/"+lessonID+"/reschedule?force=true", api.admin, tuesday, &moved); code != http.StatusCreated {
+			t.Fatalf("принудительный перенос: код %d", code)
+		}
+		if len(moved.Warnings) == 0 {
+			t.Fatalf("принудительный перенос без предупреждения: %+v", moved)
+		}
+	}
+
+	// Импорт расписания из Excel
+	{
+		workbook := excelize.NewFile()
+		rows := [][]interface{}{
+			{"День", "Пара", "ПО-31"},
+			{"Понедельник", "08:00-09:20", "МАТ / Иванова / 101"},
+			{"Вторник", "08:00-09:20", "МАТ / Иванова / 101"},
+		}
+		for i, row := range rows {
+			if err := workbook.SetSheetRow("Sheet1", fmt.Sprintf("A%d", i+1), &row); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		importXLSX := func(query string, out interface{}) int {
+			t.Helper()
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, err := form.CreateFormFile("file", "raspisanie.xlsx")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := workbook.Write(part); err != nil {
+				t.Fatal(err)
+			}
+			form.Close()
+
+			req, _ := http.NewRequest(http.MethodPost, api.srv.URL+"/api/v1/schedules/import"+query, &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			req.Header.Set("Authorization", "Bearer "+api.admin)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatal(err)
+			}
+			return resp.StatusCode
+		}
+
+		var report struct {
+			Resolved    int `json:"resolved"`
+			Unavailable []struct {
+				Index     int `json:"index"`
+				DayOfWeek int `json:"day_of_week"`
+			} `json:"unavailable"`
+			Created int `json:"created"`
+		}
+		if code := importXLSX("?dry_run=true", &report); code != http.StatusOK {
+			t.Fatalf("пробный импорт: код %d", code)
+		}
+		if report.Resolved != 2 || len(report.Unavailable) != 1 || report.Unavailable[0].DayOfWeek != 2 {
+			t.Fatalf("отчет пробного импорта: %+v", report)
+		}
+
+		if code := importXLSX("", &report); code != http.StatusConflict {
+			t.Fatalf("импорт в недоступный день: код %d, ожидали 409", code)
+		}
+		var schedules []models.Schedule
+		api.do(http.MethodGet, "/schedules", api.admin, nil, &schedules)
+		if len(schedules) != 0 {
+			t.Fatalf("отклоненный импорт записал расписание: %+v", schedules)
+		}
+
+		if code := importXLSX("?force=true", &report); code != http.StatusCreated || report.Created != 2 {
+			t.Fatalf("принудительный импорт: код %d, отчет %+v", code, report)
+		}
+	}
+}
+
+func TestEventStreamAuth(t *testing.T) {
+	api := newTestAPI(t)
+
+	api.create("/teachers", gin.H{"iin": "800000000001", "first_name": "Анна", "last_name": "Иванова"})
+	teacher := api.login("800000000001", "")
+
+	var issued struct {
+		Ticket string `json:"ticket"`
+	}
+	if code := api.do(http.MethodPost, "/events/ticket", teacher, nil, &issued); code != http.StatusOK || issued.Ticket == "" {
+		t.Fatalf("билет: код %d, %+v", code, issued)
+	}
+	if code := api.do(http.MethodPost, "/events/ticket", "", nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("билет без токена: код %d, ожидали 401", code)
+	}
+
+	// stream открывает поток и сразу закрывает его, возвращает код ответа
+	stream := func(path, token string) int {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, api.srv.URL+"/api/v1"+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"билет в параметре", "/events/stream?ticket=" + issued.Ticket, "", http.StatusOK},
+		{"токен в заголовке", "/events/stream", teacher, http.StatusOK},
+		{"без авторизации", "/events/stream", "", http.StatusUnauthorized},
+		{"токен сессии вместо билета", "/events/stream?ticket=" + teacher, "", http.StatusUnauthorized},
+		{"токен сессии в access_token", "/events/stream?access_token=" + teacher, "", http.StatusUnauthorized},
+		{"билет на другом маршруте", "/groups?ticket=" + issued.Ticket, "", http.StatusUnauthorized},
+		{"токен в access_token на другом маршруте", "/groups?access_token=" + teacher, "", http.StatusUnauthorized},
+		{"билет вместо токена в заголовке", "/groups", issued.Ticket, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if code := stream(tt.path, tt.token); code != tt.want {
+			t.Errorf("%s: код %d, ожидали %d", tt.name, code, tt.want)
+		}
+	}
+}
+
+func TestLoggerRedactsTickets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = &buf
+	t.Cleanup(func() { gin.DefaultWriter = defaultWriter })
+
+	r := gin.New()
+	r.Use(Logger())
+	r.GET("/api/v1/events/stream", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/events/stream?ticket=secret-ticket&access_token=secret-token&group_id=42", nil))
+
+	logged := buf.String()
+	if strings.Contains(logged, "secret-") {
+		t.Fatalf("в журнал попал билет или токен: %s", logged)
+	}
+	if !strings.Contains(logged, "group_id=42") || !strings.Contains(logged, "ticket=REDACTED") {
+		t.Fatalf("журнал без параметров запроса: %s", logged)
+	}
+}
@@ -0,0 +1,459 @@
+package solver
+
+import (
+	"sort"
+
+	"innovativecollege/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Веса мягких ограничений
+const (
+	gapWeight             = 10 // Окно в расписании группы или преподавателя
+	groupLoadWeight       = 3  // Квадрат количества пар группы за день (равномерная нагрузка)
+	teacherLoadWeight     = 1  // Квадрат количества пар преподавателя за день
+	subjectRepeatWeight   = 4  // Повтор предмета у группы в один день
+	shiftPreferenceWeight = 6  // Занятие не в предпочитаемую смену преподавателя
+	dailyLimitWeight      = 40 // Каждая пара сверх желаемого максимума преподавателя в день
+)
+
+// maxImprovementPasses ограничивает число проходов локального улучшения
+const maxImprovementPasses = 20
+
+// Requirement сколько пар в неделю группа должна заниматься предметом у преподавателя
+type Requirement struct {
+	GroupID   primitive.ObjectID
+	SubjectID primitive.ObjectID
+	TeacherID primitive.ObjectID
+	Lessons   int    // Пар в неделю
+	RoomType  string // Необязательный тип аудитории (например, computer_lab)
+}
+
+// Group параметры группы, влияющие на расписание
+type Group struct {
+	ID    primitive.ObjectID
+	Shift int // 0 - любая смена
+	Size  int // Количество студентов
+}
+
+// Slot временной слот (пара)
+type Slot struct {
+	ID        primitive.ObjectID
+	StartTime string
+	EndTime   string
+	Shift     int
+}
+
+// Room аудитория
+type Room struct {
+	ID       primitive.ObjectID
+	Number   string
+	Capacity int // 0 - вместимость не указана
+	Type     string
+}
+
+// Problem входные данные для составления расписания
+type Problem struct {
+	Requirements []Requirement
+	Groups       map[primitive.ObjectID]Group
+	Slots        []Slot
+	Rooms        []Room
+	Days         []int                                             // Учебные дни недели (1-7)
+	Fixed        []models.Schedule                                 // Уже существующие занятия, которые нельзя двигать
+	Teachers     map[primitive.ObjectID]models.TeacherAvailability // Ограничения преподавателей, у которых они заданы
+}
+
+// Assignment размещенное занятие
+type Assignment struct {
+	Requirement Requirement
+	Day         int
+	Slot        Slot
+	Room        Room
+}
+
+// Unplaced занятие, которое не удалось разместить
+type Unplaced struct {
+	Requirement Requirement
+	Reason      string
+}
+
+// Result результат работы решателя
+type Result struct {
+	Assignments []Assignment
+	Unplaced    []Unplaced
+	Cost        int // Суммарный штраф мягких ограничений (меньше - лучше)
+}
+
+// position место занятия в сетке: день, индекс слота и аудитория
+type position struct {
+	day  int
+	slot int
+	room int
+}
+
+// occupancyKey занятость ресурса в конкретный день и слот
+type occupancyKey struct {
+	id   primitive.ObjectID
+	day  int
+	slot int
+}
+
+// dayKey ресурс в конкретный день
+type dayKey struct {
+	id  primitive.ObjectID
+	day int
+}
+
+// state текущее состояние сетки
+type state struct {
+	problem     *Problem
+	teacherBusy map[occupancyKey]bool
+	groupBusy   map[occupancyKey]bool
+	roomBusy    map[occupancyKey]bool
+	groupDay    map[dayKey][]int // Индексы занятых слотов группы в день
+	teacherDay  map[dayKey][]int
+	fixedDay    map[dayKey]int                        // Сколько существующих занятий у преподавателя в день
+	subjectDay  map[dayKey]map[primitive.ObjectID]int // Сколько раз предмет стоит у группы в день
+}
+
+// Solve составляет расписание без пересечений: сначала жадно размещает самые
+// ограниченные занятия, затем улучшает результат перестановками по мягким ограничениям
+func Solve(problem Problem) Result {
+	sort.SliceStable(problem.Slots, func(i, j int) bool {
+		a, _ := models.ParseClock(problem.Slots[i].StartTime)
+		b, _ := models.ParseClock(problem.Slots[j].StartTime)
+		return a < b
+	})
+	sort.SliceStable(problem.Rooms, func(i, j int) bool {
+		return problem.Rooms[i].Capacity < problem.Rooms[j].Capacity
+	})
+
+	s := &state{
+		problem:     &problem,
+		teacherBusy: make(map[occupancyKey]bool),
+		groupBusy:   make(map[occupancyKey]bool),
+		roomBusy:    make(map[occupancyKey]bool),
+		groupDay:    make(map[dayKey][]int),
+		teacherDay:  make(map[dayKey][]int),
+		fixedDay:    make(map[dayKey]int),
+		subjectDay:  make(map[dayKey]map[primitive.ObjectID]int),
+	}
+	s.reserveFixed()
+
+	// Разворачиваем требования в отдельные занятия
+	var units []Requirement
+	for _, req := range problem.Requirements {
+		for i := 0; i < req.Lessons; i++ {
+			units = append(units, req)
+		}
+	}
+
+	// Самые ограниченные занятия размещаем первыми
+	difficulty := s.difficulty(units)
+	sort.SliceStable(units, func(i, j int) bool {
+		return difficulty[units[i]] > difficulty[units[j]]
+	})
+
+	result := Result{}
+	placed := make([]position, 0, len(units))
+	placedUnits := make([]Requirement, 0, len(units))
+
+	for _, unit := range units {
+		pos, reason := s.best(unit)
+		if reason != "" {
+			result.Unplaced = append(result.Unplaced, Unplaced{Requirement: unit, Reason: reason})
+			continue
+		}
+		s.place(unit, pos)
+		placed = append(placed, pos)
+		placedUnits = append(placedUnits, unit)
+	}
+
+	// Локальное улучшение: переносим занятие туда, где штраф меньше
+	for pass := 0; pass < maxImprovementPasses; pass++ {
+		improved := false
+		for i, unit := range placedUnits {
+			current := placed[i]
+			before := s.placementCost(unit, current, true)
+			s.remove(unit, current)
+
+			pos, _ := s.best(unit)
+			if s.placementCost(unit, pos, false) < before {
+				current = pos
+				improved = true
+			}
+			s.place(unit, current)
+			placed[i] = current
+		}
+		if !improved {
+			break
+		}
+	}
+
+	for i, unit := range placedUnits {
+		pos := placed[i]
+		result.Assignments = append(result.Assignments, Assignment{
+			Requirement: unit,
+			Day:         pos.day,
+			Slot:        problem.Slots[pos.slot],
+			Room:        problem.Rooms[pos.room],
+		})
+	}
+	result.Cost = s.totalCost()
+
+	return result
+}
+
+// reserveFixed отмечает занятость ресурсов существующими занятиями
+// и время, когда преподаватели недоступны
+func (s *state) reserveFixed() {
+	for teacherID, availability := range s.problem.Teachers {
+		for _, day := range s.problem.Days {
+			for slotIndex, slot := range s.problem.Slots {
+				if availability.UnavailableAt(day, slot.StartTime, slot.EndTime) != nil {
+					s.teacherBusy[occupancyKey{teacherID, day, slotIndex}] = true
+				}
+			}
+		}
+	}
+
+	for _, fixed := range s.problem.Fixed {
+		s.fixedDay[dayKey{fixed.TeacherID, fixed.DayOfWeek}]++
+		for slotIndex, slot := range s.problem.Slots {
+			if !models.TimesOverlap(fixed.StartTime, fixed.EndTime, slot.StartTime, slot.EndTime) {
+				continue
+			}
+			s.teacherBusy[occupancyKey{fixed.TeacherID, fixed.DayOfWeek, slotIndex}] = true
+			s.groupBusy[occupancyKey{fixed.GroupID, fixed.DayOfWeek, slotIndex}] = true
+			if !fixed.RoomID.IsZero() {
+				s.roomBusy[occupancyKey{fixed.RoomID, fixed.DayOfWeek, slotIndex}] = true
+			}
+		}
+	}
+}
+
+// difficulty оценивает, насколько трудно разместить занятие: чем больше нагрузка
+// преподавателя и группы и чем меньше подходящих аудиторий, тем раньше его ставим
+func (s *state) difficulty(units []Requirement) map[Requirement]int {
+	teacherLoad := make(map[primitive.ObjectID]int)
+	groupLoad := make(map[primitive.ObjectID]int)
+	for _, unit := range units {
+		teacherLoad[unit.TeacherID]++
+		groupLoad[unit.GroupID]++
+	}
+
+	result := make(map[Requirement]int)
+	for _, unit := range units {
+		suitableRooms := 0
+		for _, room := range s.problem.Rooms {
+			if s.roomFits(unit, room) {
+				suitableRooms++
+			}
+		}
+		result[unit] = teacherLoad[unit.TeacherID] + groupLoad[unit.GroupID] + len(s.problem.Rooms) - suitableRooms
+	}
+	return result
+}
+
+// best находит допустимую позицию с минимальным штрафом или причину, почему её нет
+func (s *state) best(unit Requirement) (position, string) {
+	group := s.problem.Groups[unit.GroupID]
+	bestPos := position{}
+	bestCost := -1
+	reason := "Нет свободного времени у группы и преподавателя"
+
+	hasRoom := false
+	for _, room := range s.problem.Rooms {
+		if s.roomFits(unit, room) {
+			hasRoom = true
+			break
+		}
+	}
+	if !hasRoom {
+		return bestPos, "Нет аудитории подходящего типа и вместимости"
+	}
+
+	shiftMatches := false
+	for _, day := range s.problem.Days {
+		for slotIndex, slot := range s.problem.Slots {
+			if group.Shift != 0 && slot.Shift != group.Shift {
+				continue
+			}
+			shiftMatches = true
+
+			if s.teacherBusy[occupancyKey{unit.TeacherID, day, slotIndex}] ||
+				s.groupBusy[occupancyKey{unit.GroupID, day, slotIndex}] {
+				continue
+			}
+
+			// Самая маленькая свободная аудитория, в которую помещается группа.
+			// Специальные аудитории (компьютерные классы, спортзал) оставляем тем, кому они нужны
+			roomIndex := -1
+			for i, room := range s.problem.Rooms {
+				if !s.roomFits(unit, room) || s.roomBusy[occupancyKey{room.ID, day, slotIndex}] {
+					continue
+				}
+				if roomIndex < 0 || (isSpecialRoom(unit, s.problem.Rooms[roomIndex]) && !isSpecialRoom(unit, room)) {
+					roomIndex = i
+				}
+			}
+			if roomIndex < 0 {
+				reason = "Нет свободной аудитории в свободное время группы и преподавателя"
+				continue
+			}
+
+			pos := position{day: day, slot: slotIndex, room: roomIndex}
+			cost := s.placementCost(unit, pos, false)
+			if bestCost < 0 || cost < bestCost {
+				bestCost = cost
+				bestPos = pos
+			}
+		}
+	}
+
+	if !shiftMatches {
+		return bestPos, "Нет временных слотов в смене группы"
+	}
+	if bestCost < 0 {
+		return bestPos, reason
+	}
+	return bestPos, ""
+}
+
+// roomFits проверяет тип и вместимость аудитории
+func (s *state) roomFits(unit Requirement, room Room) bool {
+	if unit.RoomType != "" && room.Type != unit.RoomType {
+		return false
+	}
+	size := s.problem.Groups[unit.GroupID].Size
+	return room.Capacity == 0 || room.Capacity >= size
+}
+
+// isSpecialRoom проверяет, что аудитория специального типа, хотя занятию он не нужен
+func isSpecialRoom(unit Requirement, room Room) bool {
+	return unit.RoomType == "" && room.Type != "" && room.Type != models.RoomTypeLecture
+}
+
+// placementCost прирост штрафа от размещения занятия в позиции.
+// placed=true означает, что занятие уже стоит в этой позиции
+func (s *state) placementCost(unit Requirement, pos position, placed bool) int {
+	groupKey := dayKey{unit.GroupID, pos.day}
+	teacherKey := dayKey{unit.TeacherID, pos.day}
+
+	groupSlots := s.groupDay[groupKey]
+	teacherSlots := s.teacherDay[teacherKey]
+	repeats := s.subjectDay[groupKey][unit.SubjectID]
+	if placed {
+		groupSlots = without(groupSlots, pos.slot)
+		teacherSlots = without(teacherSlots, pos.slot)
+		repeats--
+	}
+
+	cost := dayCost(append(append([]int{}, groupSlots...), pos.slot), groupLoadWeight) - dayCost(groupSlots, groupLoadWeight)
+	cost += dayCost(append(append([]int{}, teacherSlots...), pos.slot), teacherLoadWeight) - dayCost(teacherSlots, teacherLoadWeight)
+	cost += repeats * subjectRepeatWeight
+	cost += s.preferenceCost(unit.TeacherID, pos.day, pos.slot, len(teacherSlots))
+	return cost
+}
+
+// preferenceCost штраф за нарушение пожеланий преподавателя при постановке
+// занятия в слот, если у него в этот день уже стоит placedBefore занятий решателя
+func (s *state) preferenceCost(teacherID primitive.ObjectID, day, slot, placedBefore int) int {
+	availability, ok := s.problem.Teachers[teacherID]
+	if !ok {
+		return 0
+	}
+
+	cost := 0
+	if availability.PreferredShift != 0 && s.problem.Slots[slot].Shift != availability.PreferredShift {
+		cost += shiftPreferenceWeight
+	}
+	if availability.MaxLessonsPerDay > 0 && s.fixedDay[dayKey{teacherID, day}]+placedBefore+1 > availability.MaxLessonsPerDay {
+		cost += dailyLimitWeight
+	}
+	return cost
+}
+
+func (s *state) place(unit Requirement, pos position) {
+	room := s.problem.Rooms[pos.room]
+	s.teacherBusy[occupancyKey{unit.TeacherID, pos.day, pos.slot}] = true
+	s.groupBusy[occupancyKey{unit.GroupID, pos.day, pos.slot}] = true
+	s.roomBusy[occupancyKey{room.ID, pos.day, pos.slot}] = true
+
+	groupKey := dayKey{unit.GroupID, pos.day}
+	s.groupDay[groupKey] = append(s.groupDay[groupKey], pos.slot)
+	teacherKey := dayKey{unit.TeacherID, pos.day}
+	s.teacherDay[teacherKey] = append(s.teacherDay[teacherKey], pos.slot)
+	if s.subjectDay[groupKey] == nil {
+		s.subjectDay[groupKey] = make(map[primitive.ObjectID]int)
+	}
+	s.subjectDay[groupKey][unit.SubjectID]++
+}
+
+func (s *state) remove(unit Requirement, pos position) {
+	room := s.problem.Rooms[pos.room]
+	delete(s.teacherBusy, occupancyKey{unit.TeacherID, pos.day, pos.slot})
+	delete(s.groupBusy, occupancyKey{unit.GroupID, pos.day, pos.slot})
+	delete(s.roomBusy, occupancyKey{room.ID, pos.day, pos.slot})
+
+	groupKey := dayKey{unit.GroupID, pos.day}
+	s.groupDay[groupKey] = without(s.groupDay[groupKey], pos.slot)
+	teacherKey := dayKey{unit.TeacherID, pos.day}
+	s.teacherDay[teacherKey] = without(s.teacherDay[teacherKey], pos.slot)
+	s.subjectDay[groupKey][unit.SubjectID]--
+}
+
+// totalCost суммарный штраф текущего расписания
+func (s *state) totalCost() int {
+	total := 0
+	for _, slots := range s.groupDay {
+		total += dayCost(slots, groupLoadWeight)
+	}
+	for key, slots := range s.teacherDay {
+		total += dayCost(slots, teacherLoadWeight)
+		for i, slot := range slots {
+			total += s.preferenceCost(key.id, key.day, slot, i)
+		}
+	}
+	for _, subjects := range s.subjectDay {
+		for _, count := range subjects {
+			if count > 1 {
+				total += (count - 1) * subjectRepeatWeight
+			}
+		}
+	}
+	return total
+}
+
+// dayCost штраф за один день: окна между парами и квадрат нагрузки
+func dayCost(slots []int, loadWeight int) int {
+	if len(slots) == 0 {
+		return 0
+	}
+	first, last := slots[0], slots[0]
+	for _, slot := range slots {
+		if slot < first {
+			first = slot
+		}
+		if slot > last {
+			last = slot
+		}
+	}
+	gaps := last - first + 1 - len(slots)
+	return gaps*gapWeight + len(slots)*len(slots)*loadWeight
+}
+
+// without возвращает копию среза без одного вхождения значения
+func without(values []int, value int) []int {
+	result := make([]int, 0, len(values))
+	removed := false
+	for _, v := range values {
+		if v == value && !removed {
+			removed = true
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,330 @@
+package solver
+
+import (
+	"sort"
+	"testing"
+
+	"innovativecollege/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	firstShift = []Slot{
+		{ID: primitive.NewObjectID(), StartTime: "08:00", EndTime: "09:20", Shift: 1},
+		{ID: primitive.NewObjectID(), StartTime: "09:30", EndTime: "10:50", Shift: 1},
+		{ID: primitive.NewObjectID(), StartTime: "11:00", EndTime: "12:20", Shift: 1},
+		{ID: primitive.NewObjectID(), StartTime: "12:30", EndTime: "13:50", Shift: 1},
+	}
+	secondShift = []Slot{
+		{ID: primitive.NewObjectID(), StartTime: "14:00", EndTime: "15:20", Shift: 2},
+		{ID: primitive.NewObjectID(), StartTime: "15:30", EndTime: "16:50", Shift: 2},
+	}
+)
+
+func slots() []Slot {
+	return append(append([]Slot{}, firstShift...), secondShift...)
+}
+
+func groups(list ...Group) map[primitive.ObjectID]Group {
+	result := make(map[primitive.ObjectID]Group, len(list))
+	for _, group := range list {
+		result[group.ID] = group
+	}
+	return result
+}
+
+func TestSolve(t *testing.T) {
+	big := Group{ID: primitive.NewObjectID(), Shift: 1, Size: 25}
+	small := Group{ID: primitive.NewObjectID(), Shift: 2, Size: 10}
+	anyShift := Group{ID: primitive.NewObjectID(), Size: 12}
+	sharedTeacher, labTeacher, otherTeacher := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	math, programming, history := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+
+	smallRoom := Room{ID: primitive.NewObjectID(), Number: "101", Capacity: 15, Type: models.RoomTypeLecture}
+	bigRoom := Room{ID: primitive.NewObjectID(), Number: "201", Capacity: 30, Type: models.RoomTypeLecture}
+	lab := Room{ID: primitive.NewObjectID(), Number: "301", Capacity: 30, Type: "computer_lab"}
+
+	tests := []struct {
+		name     string
+		problem  Problem
+		unplaced map[string]int // причина - сколько занятий не размещено
+	}{
+		{
+			name: "две группы с общим преподавателем и лабораторией",
+			problem: Problem{
+				Requirements: []Requirement{
+					{GroupID: big.ID, SubjectID: math, TeacherID: sharedTeacher, Lessons: 4},
+					{GroupID: small.ID, SubjectID: math, TeacherID: sharedTeacher, Lessons: 3},
+					{GroupID: big.ID, SubjectID: programming, TeacherID: labTeacher, Lessons: 3, RoomType: "computer_lab"},
+					{GroupID: anyShift.ID, SubjectID: history, TeacherID: otherTeacher, Lessons: 4},
+				},
+				Groups: groups(big, small, anyShift),
+				Slots:  slots(),
+				Rooms:  []Room{lab, bigRoom, smallRoom},
+				Days:   []int{1, 2, 3},
+			},
+		},
+		{
+			name: "сетка заполнена полностью",
+			problem: Problem{
+				Requirements: []Requirement{
+					{GroupID: big.ID, SubjectID: math, TeacherID: sharedTeacher, Lessons: 2},
+					{GroupID: big.ID, SubjectID: history, TeacherID: otherTeacher, Lessons: 2},
+				},
+				Groups: groups(big),
+				Slots:  slots(),
+				Rooms:  []Room{bigRoom},
+				Days:   []int{1},
+			},
+		},
+		{
+			name: "существующие занятия и недоступность преподавателя",
+			problem: Problem{
+				Requirements: []Requirement{
+					{GroupID: big.ID, SubjectID: math, TeacherID: sharedTeacher, Lessons: 2},
+				},
+				Groups: groups(big),
+				Slots:  slots(),
+				Rooms:  []Room{bigRoom},
+				Days:   []int{1, 2, 3},
+				Fixed: []models.Schedule{
+					{GroupID: big.ID, TeacherID: otherTeacher, RoomID: smallRoom.ID, DayOfWeek: 1, StartTime: "08:00", EndTime: "13:50"},
+					{GroupID: small.ID, TeacherID: labTeacher, RoomID: bigRoom.ID, DayOfWeek: 3, StartTime: "08:00", EndTime: "10:50"},
+				},
+				Teachers: map[primitive.ObjectID]models.TeacherAvailability{
+					sharedTeacher: {Unavailable: []models.UnavailableWindow{{DayOfWeek: 2}}},
+				},
+			},
+		},
+		{
+			name: "занятий больше, чем слотов",
+			problem: Problem{
+				Requirements: []Requirement{
+					{GroupID: big.ID, SubjectID: math, TeacherID: sharedTeacher, Lessons: 5},
+				},
+				Groups: groups(big),
+				Slots:  slots(),
+				Rooms:  []Room{bigRoom},
+				Days:   []int{1},
+			},
+			unplaced: map[string]int{"Нет свободного времени у группы и преподавателя": 1},
+		},
+		{
+			name: "нет аудитории нужного типа",
+			problem: Problem{
+				Requirements: []Requirement{
+					{GroupID: big.ID, SubjectID: programming, TeacherID: labTeacher, Lessons: 2, RoomType: "computer_lab"},
+					{GroupID: big.ID, SubjectID: math, TeacherID: sharedTeacher, Lessons: 1},
+				},
+				Groups: groups(big),
+				Slots:  slots(),
+				Rooms:  []Room{bigRoom},
+				Days:   []int{1},
+			},
+			unplaced: map[string]int{"Нет аудитории подходящего типа и вместимости": 2},
+		},
+		{
+			name: "группа не помещается ни в одну аудиторию",
+			problem: Problem{
+				Requirements: []Requirement{
+					{GroupID: big.ID, SubjectID: math, TeacherID: sharedTeacher, Lessons: 1},
+				},
+				Groups: groups(big),
+				Slots:  slots(),
+				Rooms:  []Room{smallRoom},
+				Days:   []int{1},
+			},
+			unplaced: map[string]int{"Нет аудитории подходящего типа и вместимости": 1},
+		},
+		{
+			name: "нет слотов в смене группы",
+			problem: Problem{
+				Requirements: []Requirement{
+					{GroupID: small.ID, SubjectID: math, TeacherID: sharedTeacher, Lessons: 1},
+				},
+				Groups: groups(small),
+				Slots:  append([]Slot{}, firstShift...),
+				Rooms:  []Room{bigRoom},
+				Days:   []int{1},
+			},
+			unplaced: map[string]int{"Нет временных слотов в смене группы": 1},
+		},
+		{
+			name: "все аудитории заняты",
+			problem: Problem{
+				Requirements: []Requirement{
+					{GroupID: big.ID, SubjectID: math, TeacherID: sharedTeacher, Lessons: 1},
+				},
+				Groups: groups(big),
+				Slots:  append([]Slot{}, firstShift...),
+				Rooms:  []Room{bigRoom},
+				Days:   []int{1},
+				Fixed: []models.Schedule{
+					{GroupID: small.ID, TeacherID: otherTeacher, RoomID: bigRoom.ID, DayOfWeek: 1, StartTime: "08:00", EndTime: "13:50"},
+				},
+			},
+			unplaced: map[string]int{"Нет свободной аудитории в свободное время группы и преподавателя": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Solve(tt.problem)
+			checkHardConstraints(t, tt.problem, result)
+
+			reasons := make(map[string]int)
+			for _, unplaced := range result.Unplaced {
+				reasons[unplaced.Reason]++
+			}
+			if len(reasons) != len(tt.unplaced) {
+				t.Fatalf("не размещено: %v, ожидали %v", reasons, tt.unplaced)
+			}
+			for reason, count := range tt.unplaced {
+				if reasons[reason] != count {
+					t.Fatalf("не размещено: %v, ожидали %v", reasons, tt.unplaced)
+				}
+			}
+
+			// Каждое занятие либо размещено, либо попало в список неразмещенных
+			total := 0
+			for _, req := range tt.problem.Requirements {
+				total += req.Lessons
+			}
+			if len(result.Assignments)+len(result.Unplaced) != total {
+				t.Fatalf("размещено %d и не размещено %d из %d", len(result.Assignments), len(result.Unplaced), total)
+			}
+		})
+	}
+}
+
+func TestSolveReportsUnplacedRequirement(t *testing.T) {
+	group := Group{ID: primitive.NewObjectID(), Shift: 1, Size: 20}
+	room := Room{ID: primitive.NewObjectID(), Number: "101", Capacity: 30}
+	placeable := Requirement{GroupID: group.ID, SubjectID: primitive.NewObjectID(), TeacherID: primitive.NewObjectID(), Lessons: 1}
+	needsGym := Requirement{GroupID: group.ID, SubjectID: primitive.NewObjectID(), TeacherID: primitive.NewObjectID(), Lessons: 1, RoomType: "gym"}
+
+	result := Solve(Problem{
+		Requirements: []Requirement{placeable, needsGym},
+		Groups:       groups(group),
+		Slots:        slots(),
+		Rooms:        []Room{room},
+		Days:         []int{1},
+	})
+
+	if len(result.Unplaced) != 1 || result.Unplaced[0].Requirement != needsGym {
+		t.Fatalf("не размещено: %+v, ожидали занятие в спортзале", result.Unplaced)
+	}
+	if len(result.Assignments) != 1 || result.Assignments[0].Requirement != placeable {
+		t.Fatalf("размещено: %+v", result.Assignments)
+	}
+}
+
+func TestSolveAvoidsGaps(t *testing.T) {
+	group := Group{ID: primitive.NewObjectID(), Shift: 1, Size: 20}
+	teacher := primitive.NewObjectID()
+	room := Room{ID: primitive.NewObjectID(), Number: "101", Capacity: 30}
+
+	// Две пары группы в один день ставятся подряд, без окна
+	problem := Problem{
+		Requirements: []Requirement{{GroupID: group.ID, SubjectID: primitive.NewObjectID(), TeacherID: teacher, Lessons: 2}},
+		Groups:       groups(group),
+		Slots:        slots(),
+		Rooms:        []Room{room},
+		Days:         []int{1},
+	}
+	result := Solve(problem)
+	checkHardConstraints(t, problem, result)
+
+	var starts []string
+	for _, assignment := range result.Assignments {
+		starts = append(starts, assignment.Slot.StartTime)
+	}
+	sort.Strings(starts)
+	if len(starts) != 2 || starts[0] != "08:00" || starts[1] != "09:30" {
+		t.Fatalf("пары группы: %v, ожидали две подряд с начала дня", starts)
+	}
+}
+
+func TestSolvePrefersTeacherShift(t *testing.T) {
+	group := Group{ID: primitive.NewObjectID(), Size: 20}
+	teacher := primitive.NewObjectID()
+	room := Room{ID: primitive.NewObjectID(), Number: "101", Capacity: 30}
+
+	problem := Problem{
+		Requirements: []Requirement{{GroupID: group.ID, SubjectID: primitive.NewObjectID(), TeacherID: teacher, Lessons: 2}},
+		Groups:       groups(group),
+		Slots:        slots(),
+		Rooms:        []Room{room},
+		Days:         []int{1, 2},
+		Teachers:     map[primitive.ObjectID]models.TeacherAvailability{teacher: {PreferredShift: 2, MaxLessonsPerDay: 1}},
+	}
+	result := Solve(problem)
+	checkHardConstraints(t, problem, result)
+
+	days := make(map[int]bool)
+	for _, assignment := range result.Assignments {
+		if assignment.Slot.Shift != 2 {
+			t.Fatalf("пара в %s, ожидали вторую смену", assignment.Slot.StartTime)
+		}
+		days[assignment.Day] = true
+	}
+	if len(days) != 2 {
+		t.Fatalf("пары по дням: %v, ожидали не больше одной в день", days)
+	}
+}
+
+// checkHardConstraints проверяет результат на пересечения, смену, аудитории и недоступность
+func checkHardConstraints(t *testing.T, problem Problem, result Result) {
+	t.Helper()
+
+	type booking struct {
+		id    primitive.ObjectID
+		day   int
+		start string
+		end   string
+		label string
+	}
+	var bookings []booking
+	for _, fixed := range problem.Fixed {
+		bookings = append(bookings,
+			booking{fixed.TeacherID, fixed.DayOfWeek, fixed.StartTime, fixed.EndTime, "преподаватель"},
+			booking{fixed.GroupID, fixed.DayOfWeek, fixed.StartTime, fixed.EndTime, "группа"},
+			booking{fixed.RoomID, fixed.DayOfWeek, fixed.StartTime, fixed.EndTime, "аудитория"},
+		)
+	}
+
+	for _, assignment := range result.Assignments {
+		req := assignment.Requirement
+		slot := assignment.Slot
+		group := problem.Groups[req.GroupID]
+
+		if group.Shift != 0 && slot.Shift != group.Shift {
+			t.Errorf("группа смены %d поставлена в слот смены %d", group.Shift, slot.Shift)
+		}
+		if assignment.Room.Capacity != 0 && assignment.Room.Capacity < group.Size {
+			t.Errorf("группа из %d человек в аудитории на %d мест", group.Size, assignment.Room.Capacity)
+		}
+		if req.RoomType != "" && assignment.Room.Type != req.RoomType {
+			t.Errorf("занятию нужна аудитория %s, поставлено в %s", req.RoomType, assignment.Room.Type)
+		}
+		if availability, ok := problem.Teachers[req.TeacherID]; ok {
+			if availability.UnavailableAt(assignment.Day, slot.StartTime, slot.EndTime) != nil {
+				t.Errorf("преподаватель недоступен в день %d в %s", assignment.Day, slot.StartTime)
+			}
+		}
+
+		for _, next := range []booking{
+			{req.TeacherID, assignment.Day, slot.StartTime, slot.EndTime, "преподаватель"},
+			{req.GroupID, assignment.Day, slot.StartTime, slot.EndTime, "группа"},
+			{assignment.Room.ID, assignment.Day, slot.StartTime, slot.EndTime, "аудитория"},
+		} {
+			for _, existing := range bookings {
+				if existing.id == next.id && existing.day == next.day && models.TimesOverlap(existing.start, existing.end, next.start, next.end) {
+					t.Errorf("%s занят дважды в день %d в %s", next.label, next.day, next.start)
+				}
+			}
+			bookings = append(bookings, next)
+		}
+	}
+}
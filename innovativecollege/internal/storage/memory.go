@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"innovativecollege/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NewMemoryStore создает пустое хранилище в памяти (для тестов и локального запуска без MongoDB)
+func NewMemoryStore() *Store {
+	return &Store{
+		Groups:                NewMemoryRepository[models.Group](),
+		Subjects:              NewMemoryRepository[models.Subject](),
+		Students:              NewMemoryRepository[models.Student](),
+		Teachers:              NewMemoryRepository[models.Teacher](),
+		Schedules:             NewMemoryRepository[models.Schedule](),
+		Lessons:               NewMemoryRepository[models.Lesson](),
+		TimeSlots:             NewMemoryRepository[models.TimeSlot](),
+		Rooms:                 NewMemoryRepository[models.Room](),
+		Terms:                 NewMemoryRepository[models.Term](),
+		CalendarEvents:        NewMemoryRepository[models.CalendarEvent](),
+		ScheduleDrafts:        NewMemoryRepository[models.ScheduleDraft](),
+		CalendarSubscriptions: NewMemoryRepository[models.CalendarSubscription](),
+		TeacherAbsences:       NewMemoryRepository[models.TeacherAbsence](),
+		Substitutions:         NewMemoryRepository[models.Substitution](),
+		Attendance:            NewMemoryRepository[models.Attendance](),
+		Grades:                NewMemoryRepository[models.Grade](),
+		Webhooks:              NewMemoryRepository[models.Webhook](),
+		WebhookDeliveries:     NewMemoryRepository[models.WebhookDelivery](),
+		TelegramChats:         NewMemoryRepository[models.TelegramChat](),
+		AuditLog:              NewMemoryRepository[models.AuditEntry](),
+	}
+}
+
+// memoryRepository хранит документы в том виде, в каком их сохранила бы MongoDB (bson.D),
+// поэтому фильтры, сортировка и обновления работают по тем же bson-тегам моделей
+type memoryRepository[T any] struct {
+	mu   sync.RWMutex
+	docs []bson.D // В порядке вставки
+}
+
+// NewMemoryRepository создает пустой репозиторий в памяти
+func NewMemoryRepository[T any]() Repository[T] {
+	return &memoryRepository[T]{}
+}
+
+func (r *memoryRepository[T]) Find(ctx context.Context, filter bson.M, opts ...FindOptions) ([]T, error) {
+	r.mu.RLock()
+	matched, err := r.filter(filter)
+	r.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var options FindOptions
+	for _, opt := range opts {
+		if opt.Sort != nil {
+			options.Sort = opt.Sort
+		}
+		if opt.Skip > 0 {
+			options.Skip = opt.Skip
+		}
+		if opt.Limit > 0 {
+			options.Limit = opt.Limit
+		}
+		options.Exclude = append(options.Exclude, opt.Exclude...)
+	}
+
+	if len(options.Sort) > 0 {
+		sort.SliceStable(matched, func(i, j int) bool {
+			return sortLess(matched[i], matched[j], options.Sort)
+		})
+	}
+	if options.Skip > 0 {
+		if options.Skip >= int64(len(matched)) {
+			matched = nil
+		} else {
+			matched = matched[options.Skip:]
+		}
+	}
+	if options.Limit > 0 && options.Limit < int64(len(matched)) {
+		matched = matched[:options.Limit]
+	}
+
+	result := make([]T, 0, len(matched))
+	for _, doc := range matched {
+		for _, field := range options.Exclude {
+			doc = unsetPath(doc, field)
+		}
+		item, err := decode[T](doc)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *item)
+	}
+	return result, nil
+}
+
+func (r *memoryRepository[T]) FindOne(ctx context.Context, filter bson.M) (*T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, doc := range r.docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return decode[T](doc)
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryRepository[T]) FindByID(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	return r.FindOne(ctx, bson.M{"_id": id})
+}
+
+func (r *memoryRepository[T]) Count(ctx context.Context, filter bson.M) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched, err := r.filter(filter)
+	return int64(len(matched)), err
+}
+
+func (r *memoryRepository[T]) Insert(ctx context.Context, doc T) (primitive.ObjectID, error) {
+	ids, err := r.InsertMany(ctx, []T{doc})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return ids[0], nil
+}
+
+func (r *memoryRepository[T]) InsertMany(ctx context.Context, docs []T) ([]primitive.ObjectID, error) {
+	stored := make([]bson.D, 0, len(docs))
+	ids := make([]primitive.ObjectID, 0, len(docs))
+	for _, doc := range docs {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		var d bson.D
+		if err := bson.Unmarshal(data, &d); err != nil {
+			return nil, err
+		}
+
+		id, ok := lookupField(d, "_id")
+		objectID, isObjectID := id.(primitive.ObjectID)
+		if !ok || (isObjectID && objectID.IsZero()) {
+			objectID = primitive.NewObjectID()
+			d = append(bson.D{{Key: "_id", Value: objectID}}, unsetPath(d, "_id")...)
+		}
+		stored = append(stored, d)
+		ids = append(ids, objectID)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.docs = append(r.docs, stored...)
+	return ids, nil
+}
+
+func (r *memoryRepository[T]) Update(ctx context.Context, filter, update bson.M) (UpdateResult, error) {
+	return r.update(filter, update, false)
+}
+
+func (r *memoryRepository[T]) UpdateMany(ctx context.Context, filter, update bson.M) (UpdateResult, error) {
+	return r.update(filter, update, true)
+}
+
+func (r *memoryRepository[T]) Delete(ctx context.Context, filter bson.M) (int64, error) {
+	return r.delete(filter, false)
+}
+
+func (r *memoryRepository[T]) DeleteMany(ctx context.Context, filter bson.M) (int64, error) {
+	return r.delete(filter, true)
+}
+
+// filter возвращает документы по фильтру. Вызывается под блокировкой
+func (r *memoryRepository[T]) filter(filter bson.M) ([]bson.D, error) {
+	result := []bson.D{}
+	for _, doc := range r.docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, doc)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryRepository[T]) update(filter, update bson.M, many bool) (UpdateResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result UpdateResult
+	for i, doc := range r.docs {
+		ok, err := matches(doc, filter)
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			continue
+		}
+
+		updated, err := applyUpdate(doc, update)
+		if err != nil {
+			return result, err
+		}
+		// Проверяем, что документ по-прежнему читается в модель
+		if _, err := decode[T](updated); err != nil {
+			return result, err
+		}
+
+		result.Matched++
+		before, _ := bson.Marshal(doc)
+		after, _ := bson.Marshal(updated)
+		if !bytes.Equal(before, after) {
+			result.Modified++
+			r.docs[i] = updated
+		}
+		if !many {
+			break
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryRepository[T]) delete(filter bson.M, many bool) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	kept := make([]bson.D, 0, len(r.docs))
+	for _, doc := range r.docs {
+		if many || deleted == 0 {
+			ok, err := matches(doc, filter)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				deleted++
+				continue
+			}
+		}
+		kept = append(kept, doc)
+	}
+	r.docs = kept
+	return deleted, nil
+}
+
+// decode читает документ в модель
+func decode[T any](doc bson.D) (*T, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var item T
+	if err := bson.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
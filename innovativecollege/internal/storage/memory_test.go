@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"innovativecollege/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func lessonsFixture(t *testing.T) (Repository[models.Lesson], []models.Lesson) {
+	t.Helper()
+	day := func(d int) *time.Time {
+		date := time.Date(2024, 10, d, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+	group, otherGroup := primitive.NewObjectID(), primitive.NewObjectID()
+	teacher, otherTeacher := primitive.NewObjectID(), primitive.NewObjectID()
+	subgroup := primitive.NewObjectID()
+
+	lessons := []models.Lesson{
+		{ID: primitive.NewObjectID(), GroupID: group, TeacherID: teacher, Date: day(14), StartTime: "08:00"},
+		{ID: primitive.NewObjectID(), GroupID: group, TeacherID: otherTeacher, Date: day(15), StartTime: "09:30", Status: models.LessonCancelled},
+		{ID: primitive.NewObjectID(), GroupID: group, SubgroupID: subgroup, TeacherID: teacher, Date: day(16), StartTime: "08:00", Status: models.LessonConducted},
+		{ID: primitive.NewObjectID(), GroupID: otherGroup, TeacherID: otherTeacher, Date: day(21), StartTime: "11:00"},
+	}
+
+	repo := NewMemoryRepository[models.Lesson]()
+	if _, err := repo.InsertMany(context.Background(), lessons); err != nil {
+		t.Fatal(err)
+	}
+	return repo, lessons
+}
+
+// Фильтры в том виде, в котором их строят обработчики. Ожидания - поведение MongoDB
+func TestMemoryFind(t *testing.T) {
+	repo, lessons := lessonsFixture(t)
+	weekStart := time.Date(2024, 10, 14, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter bson.M
+		want   []int // индексы в lessons
+	}{
+		{"равенство", bson.M{"group_id": lessons[0].GroupID}, []int{0, 1, 2}},
+		{"диапазон дат", bson.M{"date": bson.M{"$gte": weekStart, "$lt": weekStart.AddDate(0, 0, 7)}}, []int{0, 1, 2}},
+		{"$in", bson.M{"_id": bson.M{"$in": []primitive.ObjectID{lessons[1].ID, lessons[3].ID}}}, []int{1, 3}},
+		{"$nin совпадает с отсутствующим полем", bson.M{"status": bson.M{"$nin": []string{models.LessonCancelled, models.LessonRescheduled}}}, []int{0, 2, 3}},
+		{"$nin по подгруппе", bson.M{"group_id": lessons[0].GroupID, "subgroup_id": bson.M{"$nin": []primitive.ObjectID{lessons[2].SubgroupID}}}, []int{0, 1}},
+		{"$ne совпадает с отсутствующим полем", bson.M{"status": bson.M{"$ne": models.LessonConducted}}, []int{0, 1, 3}},
+		{"$exists", bson.M{"subgroup_id": bson.M{"$exists": true}}, []int{2}},
+		{"$or", bson.M{"$or": []bson.M{{"teacher_id": lessons[0].TeacherID}, {"start_time": "11:00"}}}, []int{0, 2, 3}},
+		{"$and со сравнением строк", bson.M{"$and": []bson.M{{"start_time": bson.M{"$lt": "09:30"}}, {"group_id": lessons[0].GroupID}}}, []int{0, 2}},
+		{"ничего не найдено", bson.M{"group_id": primitive.NewObjectID()}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := repo.Find(context.Background(), tt.filter, FindOptions{Sort: bson.D{{Key: "date", Value: 1}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found) != len(tt.want) {
+				t.Fatalf("найдено %d, ожидали %d", len(found), len(tt.want))
+			}
+			for i, index := range tt.want {
+				if found[i].ID != lessons[index].ID {
+					t.Fatalf("позиция %d: %s, ожидали урок %d", i, found[i].ID.Hex(), index)
+				}
+			}
+
+			count, err := repo.Count(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != int64(len(tt.want)) {
+				t.Fatalf("Count = %d, ожидали %d", count, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestMemoryFindOptions(t *testing.T) {
+	repo, lessons := lessonsFixture(t)
+
+	found, err := repo.Find(context.Background(), bson.M{}, FindOptions{
+		Sort:  bson.D{{Key: "start_time", Value: -1}, {Key: "date", Value: 1}},
+		Skip:  1,
+		Limit: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// По убыванию времени: 11:00, 09:30, 08:00 (14-е), 08:00 (16-е)
+	if len(found) != 2 || found[0].ID != lessons[1].ID || found[1].ID != lessons[0].ID {
+		t.Fatalf("сортировка и страница: %+v", found)
+	}
+}
+
+func TestMemoryUpdate(t *testing.T) {
+	ctx := context.Background()
+	repo, lessons := lessonsFixture(t)
+
+	result, err := repo.Update(ctx, bson.M{"_id": lessons[2].ID}, bson.M{
+		"$set":   bson.M{"status": models.LessonPlanned, "topic": "Интегралы"},
+		"$unset": bson.M{"subgroup_id": ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 1 || result.Modified != 1 {
+		t.Fatalf("результат обновления: %+v", result)
+	}
+
+	lesson, err := repo.FindByID(ctx, lessons[2].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lesson.Status != models.LessonPlanned || lesson.Topic != "Интегралы" || !lesson.SubgroupID.IsZero() {
+		t.Fatalf("урок после обновления: %+v", lesson)
+	}
+
+	// Повторная запись тех же значений находит документ, но не меняет его
+	result, err = repo.Update(ctx, bson.M{"_id": lessons[2].ID}, bson.M{"$set": bson.M{"topic": "Интегралы"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 1 || result.Modified != 0 {
+		t.Fatalf("повторное обновление: %+v", result)
+	}
+
+	result, err = repo.UpdateMany(ctx, bson.M{"group_id": lessons[0].GroupID}, bson.M{"$set": bson.M{"room": "101"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched != 3 {
+		t.Fatalf("UpdateMany нашел %d, ожидали 3", result.Matched)
+	}
+}
+
+func TestMemoryNotFoundAndDelete(t *testing.T) {
+	ctx := context.Background()
+	repo, lessons := lessonsFixture(t)
+
+	if _, err := repo.FindByID(ctx, primitive.NewObjectID()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("FindByID неизвестного id: %v, ожидали ErrNotFound", err)
+	}
+
+	deleted, err := repo.DeleteMany(ctx, bson.M{"group_id": lessons[0].GroupID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 3 {
+		t.Fatalf("удалено %d, ожидали 3", deleted)
+	}
+	if count, _ := repo.Count(ctx, bson.M{}); count != 1 {
+		t.Fatalf("осталось %d, ожидали 1", count)
+	}
+
+	if _, err := repo.Find(ctx, bson.M{"date": bson.M{"$near": 1}}); err == nil {
+		t.Fatal("неподдерживаемый оператор должен вернуть ошибку")
+	}
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"context"
+
+	"innovativecollege/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NewMongoStore создает репозитории поверх коллекций MongoDB
+func NewMongoStore(db *mongo.Database) *Store {
+	return &Store{
+		Groups:                NewMongoRepository[models.Group](db.Collection("groups")),
+		Subjects:              NewMongoRepository[models.Subject](db.Collection("subjects")),
+		Students:              NewMongoRepository[models.Student](db.Collection("students")),
+		Teachers:              NewMongoRepository[models.Teacher](db.Collection("teachers")),
+		Schedules:             NewMongoRepository[models.Schedule](db.Collection("schedules")),
+		Lessons:               NewMongoRepository[models.Lesson](db.Collection("lessons")),
+		TimeSlots:             NewMongoRepository[models.TimeSlot](db.Collection("time_slots")),
+		Rooms:                 NewMongoRepository[models.Room](db.Collection("rooms")),
+		Terms:                 NewMongoRepository[models.Term](db.Collection("terms")),
+		CalendarEvents:        NewMongoRepository[models.CalendarEvent](db.Collection("calendar_events")),
+		ScheduleDrafts:        NewMongoRepository[models.ScheduleDraft](db.Collection("schedule_drafts")),
+		CalendarSubscriptions: NewMongoRepository[models.CalendarSubscription](db.Collection("calendar_subscriptions")),
+		TeacherAbsences:       NewMongoRepository[models.TeacherAbsence](db.Collection("teacher_absences")),
+		Substitutions:         NewMongoRepository[models.Substitution](db.Collection("substitutions")),
+		Attendance:            NewMongoRepository[models.Attendance](db.Collection("attendance")),
+		Grades:                NewMongoRepository[models.Grade](db.Collection("grades")),
+		Webhooks:              NewMongoRepository[models.Webhook](db.Collection("webhooks")),
+		WebhookDeliveries:     NewMongoRepository[models.WebhookDelivery](db.Collection("webhook_deliveries")),
+		TelegramChats:         NewMongoRepository[models.TelegramChat](db.Collection("telegram_chats")),
+		AuditLog:              NewMongoRepository[models.AuditEntry](db.Collection("audit_log")),
+	}
+}
+
+type mongoRepository[T any] struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRepository создает репозиторий для коллекции MongoDB
+func NewMongoRepository[T any](collection *mongo.Collection) Repository[T] {
+	return &mongoRepository[T]{collection: collection}
+}
+
+func (r *mongoRepository[T]) Find(ctx context.Context, filter bson.M, opts ...FindOptions) ([]T, error) {
+	findOptions := options.Find()
+	for _, opt := range opts {
+		if opt.Sort != nil {
+			findOptions.SetSort(opt.Sort)
+		}
+		if opt.Skip > 0 {
+			findOptions.SetSkip(opt.Skip)
+		}
+		if opt.Limit > 0 {
+			findOptions.SetLimit(opt.Limit)
+		}
+		if len(opt.Exclude) > 0 {
+			projection := bson.M{}
+			for _, field := range opt.Exclude {
+				projection[field] = 0
+			}
+			findOptions.SetProjection(projection)
+		}
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result := []T{}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *mongoRepository[T]) FindOne(ctx context.Context, filter bson.M) (*T, error) {
+	var doc T
+	if err := r.collection.FindOne(ctx, filter).Decode(&doc); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *mongoRepository[T]) FindByID(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	return r.FindOne(ctx, bson.M{"_id": id})
+}
+
+func (r *mongoRepository[T]) Count(ctx context.Context, filter bson.M) (int64, error) {
+	return r.collection.CountDocuments(ctx, filter)
+}
+
+func (r *mongoRepository[T]) Insert(ctx context.Context, doc T) (primitive.ObjectID, error) {
+	result, err := r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	id, _ := result.InsertedID.(primitive.ObjectID)
+	return id, nil
+}
+
+func (r *mongoRepository[T]) InsertMany(ctx context.Context, docs []T) ([]primitive.ObjectID, error) {
+	if len(docs) == 0 {
+		return []primitive.ObjectID{}, nil
+	}
+
+	documents := make([]interface{}, len(docs))
+	for i := range docs {
+		documents[i] = docs[i]
+	}
+	result, err := r.collection.InsertMany(ctx, documents)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, len(result.InsertedIDs))
+	for i, id := range result.InsertedIDs {
+		ids[i], _ = id.(primitive.ObjectID)
+	}
+	return ids, nil
+}
+
+func (r *mongoRepository[T]) Update(ctx context.Context, filter, update bson.M) (UpdateResult, error) {
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	return UpdateResult{Matched: result.MatchedCount, Modified: result.ModifiedCount}, nil
+}
+
+func (r *mongoRepository[T]) UpdateMany(ctx context.Context, filter, update bson.M) (UpdateResult, error) {
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	return UpdateResult{Matched: result.MatchedCount, Modified: result.ModifiedCount}, nil
+}
+
+func (r *mongoRepository[T]) Delete(ctx context.Context, filter bson.M) (int64, error) {
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *mongoRepository[T]) DeleteMany(ctx context.Context, filter bson.M) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
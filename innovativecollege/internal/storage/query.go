@@ -0,0 +1,478 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Подмножество языка запросов MongoDB для in-memory хранилища: равенство (в том числе
+// с элементом массива), $eq, $ne, $gt, $gte, $lt, $lte, $in, $nin, $exists, $regex,
+// $or, $and, $nor и точечные пути к вложенным полям
+
+// matches проверяет документ на соответствие фильтру
+func matches(doc bson.D, filter bson.M) (bool, error) {
+	for key, condition := range filter {
+		var ok bool
+		var err error
+		switch key {
+		case "$or", "$and", "$nor":
+			ok, err = matchLogical(doc, key, condition)
+		default:
+			if strings.HasPrefix(key, "$") {
+				return false, fmt.Errorf("неподдерживаемый оператор %s", key)
+			}
+			value, found := lookupField(doc, key)
+			ok, err = matchCondition(value, found, normalize(condition))
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchLogical(doc bson.D, operator string, condition interface{}) (bool, error) {
+	clauses, ok := normalize(condition).(primitive.A)
+	if !ok {
+		return false, fmt.Errorf("%s ожидает массив условий", operator)
+	}
+
+	for _, clause := range clauses {
+		ok, err := matches(doc, toM(clause))
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case operator == "$or" && ok:
+			return true, nil
+		case operator == "$and" && !ok:
+			return false, nil
+		case operator == "$nor" && ok:
+			return false, nil
+		}
+	}
+	return operator != "$or", nil
+}
+
+// matchCondition проверяет значение поля: condition - значение для сравнения или документ с операторами
+func matchCondition(value interface{}, found bool, condition interface{}) (bool, error) {
+	operators, ok := condition.(primitive.D)
+	if !ok || len(operators) == 0 || !strings.HasPrefix(operators[0].Key, "$") {
+		return matchEqual(value, found, condition), nil
+	}
+
+	for _, operator := range operators {
+		var ok bool
+		switch operator.Key {
+		case "$eq":
+			ok = matchEqual(value, found, operator.Value)
+		case "$ne":
+			ok = !matchEqual(value, found, operator.Value)
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = matchCompare(value, operator.Key, operator.Value)
+		case "$in", "$nin":
+			list, isList := operator.Value.(primitive.A)
+			if !isList {
+				return false, fmt.Errorf("%s ожидает массив", operator.Key)
+			}
+			for _, item := range list {
+				if matchEqual(value, found, item) {
+					ok = true
+					break
+				}
+			}
+			if operator.Key == "$nin" {
+				ok = !ok
+			}
+		case "$exists":
+			ok = found == truthy(operator.Value)
+		case "$regex":
+			pattern, err := regexPattern(operator.Value, operators)
+			if err != nil {
+				return false, err
+			}
+			ok = matchRegex(value, pattern)
+		case "$options":
+			continue // Учитывается вместе с $regex
+		default:
+			return false, fmt.Errorf("неподдерживаемый оператор %s", operator.Key)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchEqual равенство по правилам MongoDB: null совпадает с отсутствующим полем,
+// массив совпадает, если равен целиком или содержит значение
+func matchEqual(value interface{}, found bool, expected interface{}) bool {
+	if expected == nil {
+		return !found || value == nil
+	}
+	if !found {
+		return false
+	}
+	if equal(value, expected) {
+		return true
+	}
+	if list, ok := value.(primitive.A); ok {
+		for _, item := range list {
+			if equal(item, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchCompare(value interface{}, operator string, expected interface{}) bool {
+	candidates := []interface{}{value}
+	if list, ok := value.(primitive.A); ok {
+		candidates = list
+	}
+
+	for _, candidate := range candidates {
+		result, ok := compare(candidate, expected)
+		if !ok {
+			continue
+		}
+		switch {
+		case operator == "$gt" && result > 0,
+			operator == "$gte" && result >= 0,
+			operator == "$lt" && result < 0,
+			operator == "$lte" && result <= 0:
+			return true
+		}
+	}
+	return false
+}
+
+func regexPattern(value interface{}, operators primitive.D) (*regexp.Regexp, error) {
+	var pattern, flags string
+	switch v := value.(type) {
+	case string:
+		pattern = v
+	case primitive.Regex:
+		pattern, flags = v.Pattern, v.Options
+	default:
+		return nil, fmt.Errorf("$regex ожидает строку")
+	}
+	for _, operator := range operators {
+		if operator.Key == "$options" {
+			flags, _ = operator.Value.(string)
+		}
+	}
+
+	prefix := ""
+	for _, flag := range flags {
+		if strings.ContainsRune("imsx", flag) {
+			prefix += string(flag)
+		}
+	}
+	if prefix != "" {
+		pattern = "(?" + prefix + ")" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+func matchRegex(value interface{}, pattern *regexp.Regexp) bool {
+	if s, ok := value.(string); ok {
+		return pattern.MatchString(s)
+	}
+	if list, ok := value.(primitive.A); ok {
+		for _, item := range list {
+			if s, ok := item.(string); ok && pattern.MatchString(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookupField возвращает значение по точечному пути. Для массивов документов
+// собирает значения из всех элементов, как это делает MongoDB
+func lookupField(doc bson.D, path string) (interface{}, bool) {
+	key, rest, nested := strings.Cut(path, ".")
+	for _, element := range doc {
+		if element.Key != key {
+			continue
+		}
+		if !nested {
+			return element.Value, true
+		}
+		switch v := element.Value.(type) {
+		case primitive.D:
+			return lookupField(v, rest)
+		case primitive.A:
+			var values primitive.A
+			for _, item := range v {
+				if d, ok := item.(primitive.D); ok {
+					if value, found := lookupField(d, rest); found {
+						values = append(values, value)
+					}
+				}
+			}
+			return values, len(values) > 0
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// applyUpdate применяет $set, $unset и $inc и возвращает новый документ
+func applyUpdate(doc bson.D, update bson.M) (bson.D, error) {
+	result := copyDoc(doc)
+	for operator, fields := range update {
+		values, ok := normalize(fields).(primitive.D)
+		if !ok {
+			return nil, fmt.Errorf("%s ожидает документ", operator)
+		}
+
+		for _, field := range values {
+			if field.Key == "_id" {
+				continue
+			}
+			switch operator {
+			case "$set":
+				result = setPath(result, field.Key, field.Value)
+			case "$unset":
+				result = unsetPath(result, field.Key)
+			case "$inc":
+				current, _ := lookupField(result, field.Key)
+				sum, err := add(current, field.Value)
+				if err != nil {
+					return nil, err
+				}
+				result = setPath(result, field.Key, sum)
+			default:
+				return nil, fmt.Errorf("неподдерживаемый оператор обновления %s", operator)
+			}
+		}
+	}
+	return result, nil
+}
+
+// setPath возвращает копию документа с установленным значением по точечному пути
+func setPath(doc bson.D, path string, value interface{}) bson.D {
+	key, rest, nested := strings.Cut(path, ".")
+	result := copyDoc(doc)
+	for i, element := range result {
+		if element.Key != key {
+			continue
+		}
+		if !nested {
+			result[i].Value = value
+		} else {
+			child, _ := element.Value.(primitive.D)
+			result[i].Value = setPath(child, rest, value)
+		}
+		return result
+	}
+
+	if nested {
+		value = setPath(bson.D{}, rest, value)
+	}
+	return append(result, bson.E{Key: key, Value: value})
+}
+
+// unsetPath возвращает копию документа без поля
+func unsetPath(doc bson.D, path string) bson.D {
+	key, rest, nested := strings.Cut(path, ".")
+	result := make(bson.D, 0, len(doc))
+	for _, element := range doc {
+		if element.Key == key {
+			if !nested {
+				continue
+			}
+			if child, ok := element.Value.(primitive.D); ok {
+				element.Value = unsetPath(child, rest)
+			}
+		}
+		result = append(result, element)
+	}
+	return result
+}
+
+func copyDoc(doc bson.D) bson.D {
+	result := make(bson.D, len(doc))
+	copy(result, doc)
+	return result
+}
+
+// sortLess сравнивает документы по ключам сортировки (1 - по возрастанию, -1 - по убыванию)
+func sortLess(a, b bson.D, keys bson.D) bool {
+	for _, key := range keys {
+		left, _ := lookupField(a, key.Key)
+		right, _ := lookupField(b, key.Key)
+		result := sortCompare(left, right)
+		if result == 0 {
+			continue
+		}
+		if direction, _ := toFloat(normalize(key.Value)); direction < 0 {
+			return result > 0
+		}
+		return result < 0
+	}
+	return false
+}
+
+// sortCompare порядок значений разных типов как в MongoDB: null, числа, строки,
+// документы, массивы, ObjectID, bool, даты
+func sortCompare(a, b interface{}) int {
+	if result, ok := compare(a, b); ok {
+		return result
+	}
+	return typeRank(a) - typeRank(b)
+}
+
+func typeRank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case int32, int64, float64:
+		return 1
+	case string:
+		return 2
+	case primitive.D:
+		return 3
+	case primitive.A:
+		return 4
+	case primitive.ObjectID:
+		return 5
+	case bool:
+		return 6
+	case primitive.DateTime:
+		return 7
+	}
+	return 8
+}
+
+// compare сравнивает значения одного типа. ok = false, если значения несравнимы
+func compare(a, b interface{}) (int, bool) {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	}
+
+	switch x := a.(type) {
+	case nil:
+		if b == nil {
+			return 0, true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case primitive.DateTime:
+		if y, ok := b.(primitive.DateTime); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	case primitive.ObjectID:
+		if y, ok := b.(primitive.ObjectID); ok {
+			return bytes.Compare(x[:], y[:]), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case !x:
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, false
+}
+
+func equal(a, b interface{}) bool {
+	if result, ok := compare(a, b); ok {
+		return result == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func add(current, delta interface{}) (interface{}, error) {
+	if current == nil {
+		return delta, nil
+	}
+	x, ok1 := toFloat(current)
+	y, ok2 := toFloat(delta)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("$inc применим только к числам")
+	}
+
+	_, currentFloat := current.(float64)
+	_, deltaFloat := delta.(float64)
+	if currentFloat || deltaFloat {
+		return x + y, nil
+	}
+	return int64(x + y), nil
+}
+
+func truthy(value interface{}) bool {
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	if f, ok := toFloat(value); ok {
+		return f != 0
+	}
+	return value != nil
+}
+
+func toM(value interface{}) bson.M {
+	result := bson.M{}
+	if d, ok := value.(primitive.D); ok {
+		for _, element := range d {
+			result[element.Key] = element.Value
+		}
+	}
+	return result
+}
+
+// normalize приводит значение из фильтра к типам, в которых документы хранятся после
+// bson-кодирования: time.Time -> DateTime, int -> int32/int64, срезы -> primitive.A, bson.M -> primitive.D
+func normalize(value interface{}) interface{} {
+	data, err := bson.Marshal(bson.D{{Key: "v", Value: value}})
+	if err != nil {
+		return value
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil || len(doc) == 0 {
+		return value
+	}
+	return doc[0].Value
+}
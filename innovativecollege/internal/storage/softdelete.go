@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// notDeleted условие для документов, которые не находятся в корзине
+var notDeleted = bson.M{"deleted_at": bson.M{"$exists": false}}
+
+// inTrash условие для документов в корзине
+var inTrash = bson.M{"deleted_at": bson.M{"$exists": true}}
+
+// Bin корзина коллекции: удаленные документы можно найти, восстановить или удалить окончательно
+type Bin[T any] interface {
+	// FindDeleted возвращает документы из корзины по фильтру
+	FindDeleted(ctx context.Context, filter bson.M, opts ...FindOptions) ([]T, error)
+	// FindDeletedByID возвращает документ из корзины или ErrNotFound
+	FindDeletedByID(ctx context.Context, id primitive.ObjectID) (*T, error)
+	// Restore убирает пометку об удалении или возвращает ErrNotFound
+	Restore(ctx context.Context, id primitive.ObjectID) error
+	// Purge окончательно удаляет документы из корзины по фильтру
+	Purge(ctx context.Context, filter bson.M) (int64, error)
+}
+
+// BinOf возвращает корзину репозитория или nil, если удаление в нем окончательное
+func BinOf[T any](repo Repository[T]) Bin[T] {
+	bin, _ := repo.(Bin[T])
+	return bin
+}
+
+// softDeleteRepository вместо удаления помечает документы полями deleted_at и deleted_by.
+// Чтение и обновление не видят помеченные документы
+type softDeleteRepository[T any] struct {
+	inner     Repository[T]
+	deletedBy func(ctx context.Context) string
+}
+
+// NewSoftDeleteRepository оборачивает репозиторий корзиной. deletedBy возвращает
+// логин пользователя, выполняющего удаление
+func NewSoftDeleteRepository[T any](inner Repository[T], deletedBy func(ctx context.Context) string) Repository[T] {
+	return &softDeleteRepository[T]{inner: inner, deletedBy: deletedBy}
+}
+
+// WithSoftDelete включает корзину для справочников, расписания и уроков.
+// Черновики, подписки и журнал изменений удаляются окончательно
+func WithSoftDelete(store *Store, deletedBy func(ctx context.Context) string) *Store {
+	wrapped := *store
+	wrapped.Groups = NewSoftDeleteRepository(store.Groups, deletedBy)
+	wrapped.Subjects = NewSoftDeleteRepository(store.Subjects, deletedBy)
+	wrapped.Students = NewSoftDeleteRepository(store.Students, deletedBy)
+	wrapped.Teachers = NewSoftDeleteRepository(store.Teachers, deletedBy)
+	wrapped.Schedules = NewSoftDeleteRepository(store.Schedules, deletedBy)
+	wrapped.Lessons = NewSoftDeleteRepository(store.Lessons, deletedBy)
+	wrapped.TimeSlots = NewSoftDeleteRepository(store.TimeSlots, deletedBy)
+	wrapped.Rooms = NewSoftDeleteRepository(store.Rooms, deletedBy)
+	wrapped.Terms = NewSoftDeleteRepository(store.Terms, deletedBy)
+	wrapped.CalendarEvents = NewSoftDeleteRepository(store.CalendarEvents, deletedBy)
+	return &wrapped
+}
+
+// PurgeDeleted окончательно удаляет документы, которые находятся в корзине дольше,
+// чем до момента before. Возвращает количество удаленных по коллекциям
+func (s *Store) PurgeDeleted(ctx context.Context, before time.Time) (map[string]int64, error) {
+	filter := bson.M{"deleted_at": bson.M{"$lt": before}}
+	purged := make(map[string]int64)
+	for _, step := range []struct {
+		collection string
+		purge      func() (int64, error)
+	}{
+		{"groups", func() (int64, error) { return purgeBin(ctx, s.Groups, filter) }},
+		{"subjects", func() (int64, error) { return purgeBin(ctx, s.Subjects, filter) }},
+		{"students", func() (int64, error) { return purgeBin(ctx, s.Students, filter) }},
+		{"teachers", func() (int64, error) { return purgeBin(ctx, s.Teachers, filter) }},
+		{"schedules", func() (int64, error) { return purgeBin(ctx, s.Schedules, filter) }},
+		{"lessons", func() (int64, error) { return purgeBin(ctx, s.Lessons, filter) }},
+		{"time_slots", func() (int64, error) { return purgeBin(ctx, s.TimeSlots, filter) }},
+		{"rooms", func() (int64, error) { return purgeBin(ctx, s.Rooms, filter) }},
+		{"terms", func() (int64, error) { return purgeBin(ctx, s.Terms, filter) }},
+		{"calendar_events", func() (int64, error) { return purgeBin(ctx, s.CalendarEvents, filter) }},
+	} {
+		count, err := step.purge()
+		if err != nil {
+			return purged, err
+		}
+		if count > 0 {
+			purged[step.collection] = count
+		}
+	}
+	return purged, nil
+}
+
+func purgeBin[T any](ctx context.Context, repo Repository[T], filter bson.M) (int64, error) {
+	bin := BinOf(repo)
+	if bin == nil {
+		return 0, nil
+	}
+	return bin.Purge(ctx, filter)
+}
+
+func withCondition(filter, condition bson.M) bson.M {
+	if len(filter) == 0 {
+		return condition
+	}
+	return bson.M{"$and": bson.A{filter, condition}}
+}
+
+func (r *softDeleteRepository[T]) Find(ctx context.Context, filter bson.M, opts ...FindOptions) ([]T, error) {
+	return r.inner.Find(ctx, withCondition(filter, notDeleted), opts...)
+}
+
+func (r *softDeleteRepository[T]) FindOne(ctx context.Context, filter bson.M) (*T, error) {
+	return r.inner.FindOne(ctx, withCondition(filter, notDeleted))
+}
+
+func (r *softDeleteRepository[T]) FindByID(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	return r.inner.FindOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": false}})
+}
+
+func (r *softDeleteRepository[T]) Count(ctx context.Context, filter bson.M) (int64, error) {
+	return r.inner.Count(ctx, withCondition(filter, notDeleted))
+}
+
+func (r *softDeleteRepository[T]) Insert(ctx context.Context, doc T) (primitive.ObjectID, error) {
+	return r.inner.Insert(ctx, doc)
+}
+
+func (r *softDeleteRepository[T]) InsertMany(ctx context.Context, docs []T) ([]primitive.ObjectID, error) {
+	return r.inner.InsertMany(ctx, docs)
+}
+
+func (r *softDeleteRepository[T]) Update(ctx context.Context, filter, update bson.M) (UpdateResult, error) {
+	return r.inner.Update(ctx, withCondition(filter, notDeleted), update)
+}
+
+func (r *softDeleteRepository[T]) UpdateMany(ctx context.Context, filter, update bson.M) (UpdateResult, error) {
+	return r.inner.UpdateMany(ctx, withCondition(filter, notDeleted), update)
+}
+
+// Delete помечает первый документ по фильтру удаленным
+func (r *softDeleteRepository[T]) Delete(ctx context.Context, filter bson.M) (int64, error) {
+	result, err := r.inner.Update(ctx, withCondition(filter, notDeleted), r.markDeleted(ctx))
+	return result.Matched, err
+}
+
+// DeleteMany помечает удаленными все документы по фильтру
+func (r *softDeleteRepository[T]) DeleteMany(ctx context.Context, filter bson.M) (int64, error) {
+	result, err := r.inner.UpdateMany(ctx, withCondition(filter, notDeleted), r.markDeleted(ctx))
+	return result.Matched, err
+}
+
+func (r *softDeleteRepository[T]) FindDeleted(ctx context.Context, filter bson.M, opts ...FindOptions) ([]T, error) {
+	return r.inner.Find(ctx, withCondition(filter, inTrash), opts...)
+}
+
+func (r *softDeleteRepository[T]) FindDeletedByID(ctx context.Context, id primitive.ObjectID) (*T, error) {
+	return r.inner.FindOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}})
+}
+
+func (r *softDeleteRepository[T]) Restore(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.inner.Update(ctx,
+		bson.M{"_id": id, "deleted_at": bson.M{"$exists": true}},
+		bson.M{
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if result.Matched == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *softDeleteRepository[T]) Purge(ctx context.Context, filter bson.M) (int64, error) {
+	return r.inner.DeleteMany(ctx, withCondition(filter, inTrash))
+}
+
+func (r *softDeleteRepository[T]) markDeleted(ctx context.Context) bson.M {
+	return bson.M{"$set": bson.M{
+		"deleted_at": time.Now(),
+		"deleted_by": r.deletedBy(ctx),
+	}}
+}
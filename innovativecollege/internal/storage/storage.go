@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"errors"
+
+	"innovativecollege/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrNotFound документ не найден
+var ErrNotFound = errors.New("документ не найден")
+
+// Repository доступ к коллекции документов типа T.
+// Фильтры и обновления записываются в синтаксисе MongoDB (bson.M): так обработчики
+// одинаково работают с MongoDB и с in-memory хранилищем в тестах
+type Repository[T any] interface {
+	// Find возвращает документы по фильтру (пустой срез, если ничего не найдено)
+	Find(ctx context.Context, filter bson.M, opts ...FindOptions) ([]T, error)
+	// FindOne возвращает первый документ по фильтру или ErrNotFound
+	FindOne(ctx context.Context, filter bson.M) (*T, error)
+	// FindByID возвращает документ по _id или ErrNotFound
+	FindByID(ctx context.Context, id primitive.ObjectID) (*T, error)
+	// Count считает документы по фильтру
+	Count(ctx context.Context, filter bson.M) (int64, error)
+	// Insert сохраняет документ и возвращает его _id
+	Insert(ctx context.Context, doc T) (primitive.ObjectID, error)
+	// InsertMany сохраняет документы и возвращает их _id в том же порядке
+	InsertMany(ctx context.Context, docs []T) ([]primitive.ObjectID, error)
+	// Update применяет обновление ($set, $unset, $inc) к первому документу по фильтру
+	Update(ctx context.Context, filter, update bson.M) (UpdateResult, error)
+	// UpdateMany применяет обновление ко всем документам по фильтру
+	UpdateMany(ctx context.Context, filter, update bson.M) (UpdateResult, error)
+	// Delete удаляет первый документ по фильтру и возвращает число удаленных
+	Delete(ctx context.Context, filter bson.M) (int64, error)
+	// DeleteMany удаляет все документы по фильтру и возвращает их число
+	DeleteMany(ctx context.Context, filter bson.M) (int64, error)
+}
+
+// FindOptions сортировка, пагинация и исключение полей для Find
+type FindOptions struct {
+	Sort    bson.D // Например, bson.D{{Key: "start_date", Value: 1}}
+	Skip    int64
+	Limit   int64    // 0 - без ограничения
+	Exclude []string // Поля, которые не нужно загружать
+}
+
+// UpdateResult результат обновления
+type UpdateResult struct {
+	Matched  int64
+	Modified int64
+}
+
+// Store набор репозиториев приложения
+type Store struct {
+	Groups                Repository[models.Group]
+	Subjects              Repository[models.Subject]
+	Students              Repository[models.Student]
+	Teachers              Repository[models.Teacher]
+	Schedules             Repository[models.Schedule]
+	Lessons               Repository[models.Lesson]
+	TimeSlots             Repository[models.TimeSlot]
+	Rooms                 Repository[models.Room]
+	Terms                 Repository[models.Term]
+	CalendarEvents        Repository[models.CalendarEvent]
+	ScheduleDrafts        Repository[models.ScheduleDraft]
+	CalendarSubscriptions Repository[models.CalendarSubscription]
+	TeacherAbsences       Repository[models.TeacherAbsence]
+	Substitutions         Repository[models.Substitution]
+	Attendance            Repository[models.Attendance]
+	Grades                Repository[models.Grade]
+	Webhooks              Repository[models.Webhook]
+	WebhookDeliveries     Repository[models.WebhookDelivery]
+	TelegramChats         Repository[models.TelegramChat]
+	AuditLog              Repository[models.AuditEntry]
+}
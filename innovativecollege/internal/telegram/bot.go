@@ -0,0 +1,441 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/events"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// notificationQueue сколько событий ждут отправки уведомлений
+const notificationQueue = 256
+
+// retryDelay пауза после ошибки getUpdates
+const retryDelay = 5 * time.Second
+
+const helpText = `Команды:
+/today - расписание на сегодня
+/tomorrow - расписание на завтра
+/week - расписание на 7 дней
+/next - следующий урок
+/link <ИИН> - привязать чат к студенту или преподавателю
+/unlink - отвязать чат
+
+Об отмене и переносе уроков бот сообщит сам.`
+
+// errNotLinked чат не привязан к студенту или преподавателю
+var errNotLinked = errors.New("чат не привязан")
+
+// Bot отвечает на команды в Telegram и присылает уведомления об отмене и переносе уроков.
+// Чат привязывается к студенту или преподавателю по ИИН, как при входе в приложение
+type Bot struct {
+	store         *storage.Store
+	client        Client
+	location      *time.Location
+	pollTimeout   time.Duration
+	notifications chan events.Event
+}
+
+// NewBot создает бота. location - часовой пояс колледжа: по нему определяются
+// "сегодня" и следующий урок
+func NewBot(store *storage.Store, client Client, location *time.Location, pollTimeout time.Duration) *Bot {
+	return &Bot{
+		store:         store,
+		client:        client,
+		location:      location,
+		pollTimeout:   pollTimeout,
+		notifications: make(chan events.Event, notificationQueue),
+	}
+}
+
+// Run получает сообщения long polling и рассылает уведомления. Работает до отмены ctx
+func (b *Bot) Run(ctx context.Context) {
+	go b.runNotifications(ctx)
+
+	var offset int64
+	for {
+		updates, err := b.client.GetUpdates(ctx, offset, b.pollTimeout)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Println("Ошибка получения сообщений Telegram:", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		for _, update := range updates {
+			offset = update.UpdateID + 1
+			b.HandleUpdate(ctx, update)
+		}
+	}
+}
+
+// HandleUpdate отвечает на одно сообщение
+func (b *Bot) HandleUpdate(ctx context.Context, update Update) {
+	if update.Message == nil || update.Message.Text == "" {
+		return
+	}
+
+	text := b.reply(ctx, *update.Message)
+	if err := b.client.SendMessage(ctx, update.Message.Chat.ID, text); err != nil {
+		log.Printf("Ошибка отправки сообщения в чат %d: %v", update.Message.Chat.ID, err)
+	}
+}
+
+// reply выполняет команду и возвращает текст ответа
+func (b *Bot) reply(ctx context.Context, message Message) string {
+	fields := strings.Fields(message.Text)
+	if len(fields) == 0 {
+		return helpText
+	}
+	// В группах команда приходит с именем бота: /today@college_bot
+	command, _, _ := strings.Cut(strings.ToLower(fields[0]), "@")
+	args := fields[1:]
+
+	switch command {
+	case "/start":
+		if len(args) > 0 {
+			return b.link(ctx, message, args[0])
+		}
+		return "Здравствуйте! Я присылаю расписание колледжа.\n" +
+			"Чтобы начать, отправьте /link и свой ИИН, например: /link 990101300123\n\n" + helpText
+	case "/help":
+		return helpText
+	case "/link":
+		if len(args) == 0 {
+			return "Укажите ИИН: /link 990101300123"
+		}
+		return b.link(ctx, message, args[0])
+	case "/unlink":
+		return b.unlink(ctx, message.Chat.ID)
+	case "/today", "/tomorrow", "/week", "/next":
+		chat, err := b.findChat(ctx, message.Chat.ID)
+		if err == errNotLinked {
+			return "Сначала привяжите чат: /link <ИИН>"
+		}
+		if err != nil {
+			log.Println("Ошибка поиска чата Telegram:", err)
+			return "Не удалось получить расписание, попробуйте позже"
+		}
+
+		text, err := b.schedule(ctx, chat, command)
+		if err != nil {
+			log.Println("Ошибка получения расписания для Telegram:", err)
+			return "Не удалось получить расписание, попробуйте позже"
+		}
+		return text
+	}
+
+	return "Неизвестная команда.\n\n" + helpText
+}
+
+// link привязывает чат к преподавателю или студенту с таким ИИН.
+// Чат, привязанный раньше, переходит к новому пользователю
+func (b *Bot) link(ctx context.Context, message Message, iin string) string {
+	chat := models.TelegramChat{ChatID: message.Chat.ID, IIN: iin, LinkedAt: time.Now(), UpdatedAt: time.Now()}
+	if message.From != nil {
+		chat.Username = message.From.Username
+	}
+
+	var greeting string
+	teacher, err := b.store.Teachers.FindOne(ctx, bson.M{"iin": iin})
+	switch {
+	case err == nil:
+		chat.Role, chat.OwnerID = auth.RoleTeacher, teacher.ID
+		greeting = fmt.Sprintf("Чат привязан: %s %s, преподаватель.", teacher.LastName, teacher.FirstName)
+	case err == storage.ErrNotFound:
+		student, err := b.store.Students.FindOne(ctx, bson.M{"iin": iin})
+		if err == storage.ErrNotFound {
+			return "Студент или преподаватель с таким ИИН не найден"
+		}
+		if err != nil {
+			log.Println("Ошибка поиска студента для Telegram:", err)
+			return "Не удалось привязать чат, попробуйте позже"
+		}
+		chat.Role, chat.OwnerID = auth.RoleStudent, student.ID
+		greeting = fmt.Sprintf("Чат привязан: %s %s, студент.", student.LastName, student.FirstName)
+	default:
+		log.Println("Ошибка поиска преподавателя для Telegram:", err)
+		return "Не удалось привязать чат, попробуйте позже"
+	}
+
+	if err := b.saveChat(ctx, chat); err != nil {
+		log.Println("Ошибка сохранения чата Telegram:", err)
+		return "Не удалось привязать чат, попробуйте позже"
+	}
+
+	return greeting + "\n\n" + helpText
+}
+
+// saveChat сохраняет привязку: обновляет запись чата или создает новую
+func (b *Bot) saveChat(ctx context.Context, chat models.TelegramChat) error {
+	result, err := b.store.TelegramChats.Update(ctx, bson.M{"chat_id": chat.ChatID}, bson.M{"$set": bson.M{
+		"role":       chat.Role,
+		"owner_id":   chat.OwnerID,
+		"iin":        chat.IIN,
+		"username":   chat.Username,
+		"linked_at":  chat.LinkedAt,
+		"updated_at": chat.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.Matched > 0 {
+		return nil
+	}
+
+	_, err = b.store.TelegramChats.Insert(ctx, chat)
+	return err
+}
+
+// unlink отвязывает чат: расписание и уведомления перестают приходить
+func (b *Bot) unlink(ctx context.Context, chatID int64) string {
+	deleted, err := b.store.TelegramChats.Delete(ctx, bson.M{"chat_id": chatID})
+	if err != nil {
+		log.Println("Ошибка отвязки чата Telegram:", err)
+		return "Не удалось отвязать чат, попробуйте позже"
+	}
+	if deleted == 0 {
+		return "Чат не привязан"
+	}
+	return "Чат отвязан. Уведомления больше не будут приходить"
+}
+
+// findChat возвращает привязку чата или errNotLinked
+func (b *Bot) findChat(ctx context.Context, chatID int64) (*models.TelegramChat, error) {
+	chat, err := b.store.TelegramChats.FindOne(ctx, bson.M{"chat_id": chatID})
+	if err == storage.ErrNotFound {
+		return nil, errNotLinked
+	}
+	return chat, err
+}
+
+// schedule отвечает на команды расписания
+func (b *Bot) schedule(ctx context.Context, chat *models.TelegramChat, command string) (string, error) {
+	filter, err := b.lessonFilter(ctx, chat)
+	if err == errNotLinked {
+		return "Студент или преподаватель, к которому привязан чат, удален. Привяжите чат заново: /link <ИИН>", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().In(b.location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch command {
+	case "/today":
+		return b.days(ctx, chat, filter, today, today, "Сегодня уроков нет")
+	case "/tomorrow":
+		tomorrow := today.AddDate(0, 0, 1)
+		return b.days(ctx, chat, filter, tomorrow, tomorrow, "Завтра уроков нет")
+	case "/week":
+		return b.days(ctx, chat, filter, today, today.AddDate(0, 0, 6), "В ближайшие 7 дней уроков нет")
+	}
+	return b.next(ctx, chat, filter, today, now.Format("15:04"))
+}
+
+// lessonFilter уроки пользователя чата: студенту - уроки его текущей группы
+// без уроков подгрупп, в которые он не входит
+func (b *Bot) lessonFilter(ctx context.Context, chat *models.TelegramChat) (bson.M, error) {
+	if chat.Role == auth.RoleTeacher {
+		if _, err := b.store.Teachers.FindByID(ctx, chat.OwnerID); err != nil {
+			if err == storage.ErrNotFound {
+				return nil, errNotLinked
+			}
+			return nil, err
+		}
+		return bson.M{"teacher_id": chat.OwnerID}, nil
+	}
+
+	student, err := b.store.Students.FindByID(ctx, chat.OwnerID)
+	if err == storage.ErrNotFound {
+		return nil, errNotLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := b.store.Groups.FindByID(ctx, student.GroupID)
+	if err == storage.ErrNotFound {
+		group = &models.Group{ID: student.GroupID}
+	} else if err != nil {
+		return nil, err
+	}
+	return group.StudentLessonFilter(student.ID), nil
+}
+
+// findLessons уроки с from по to включительно. Перенесенные уроки не показываются:
+// вместо них есть уроки на новую дату
+func (b *Bot) findLessons(ctx context.Context, filter bson.M, from, to time.Time) ([]models.Lesson, error) {
+	query := bson.M{
+		"date":   bson.M{"$gte": from, "$lte": to},
+		"status": bson.M{"$ne": models.LessonRescheduled},
+	}
+	for key, value := range filter {
+		query[key] = value
+	}
+
+	return b.store.Lessons.Find(ctx, query, storage.FindOptions{
+		Sort: bson.D{{Key: "date", Value: 1}, {Key: "start_time", Value: 1}},
+	})
+}
+
+// days расписание по дням периода
+func (b *Bot) days(ctx context.Context, chat *models.TelegramChat, filter bson.M, from, to time.Time, empty string) (string, error) {
+	lessons, err := b.findLessons(ctx, filter, from, to)
+	if err != nil {
+		return "", err
+	}
+	if len(lessons) == 0 {
+		return empty, nil
+	}
+
+	names, err := loadNames(ctx, b.store, lessons)
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	var day time.Time
+	for _, lesson := range lessons {
+		if !lesson.Date.Equal(day) {
+			day = *lesson.Date
+			if text.Len() > 0 {
+				text.WriteString("\n")
+			}
+			text.WriteString(formatDay(day) + "\n")
+		}
+		text.WriteString(names.lessonLine(lesson, chat.Role) + "\n")
+	}
+	return strings.TrimSpace(text.String()), nil
+}
+
+// next ближайший урок, который еще не начался, в пределах двух недель
+func (b *Bot) next(ctx context.Context, chat *models.TelegramChat, filter bson.M, today time.Time, clock string) (string, error) {
+	lessons, err := b.findLessons(ctx, filter, today, today.AddDate(0, 0, 14))
+	if err != nil {
+		return "", err
+	}
+
+	for _, lesson := range lessons {
+		if lesson.Status == models.LessonCancelled {
+			continue
+		}
+		if lesson.Date.Equal(today) && lesson.StartTime <= clock {
+			continue
+		}
+
+		names, err := loadNames(ctx, b.store, []models.Lesson{lesson})
+		if err != nil {
+			return "", err
+		}
+		return "Следующий урок: " + formatDay(*lesson.Date) + "\n" + names.lessonLine(lesson, chat.Role), nil
+	}
+	return "В ближайшие две недели уроков нет", nil
+}
+
+// weekdays названия дней недели, начиная с воскресенья, как time.Weekday
+var weekdays = []string{"Воскресенье", "Понедельник", "Вторник", "Среда", "Четверг", "Пятница", "Суббота"}
+
+// formatDay заголовок дня: "Понедельник, 20.10"
+func formatDay(day time.Time) string {
+	return weekdays[day.Weekday()] + ", " + day.Format("02.01")
+}
+
+// names названия предметов, групп и преподавателей для текста сообщений
+type names struct {
+	subjects map[primitive.ObjectID]string
+	groups   map[primitive.ObjectID]string
+	teachers map[primitive.ObjectID]string
+}
+
+// loadNames загружает названия одним запросом на коллекцию
+func loadNames(ctx context.Context, store *storage.Store, lessons []models.Lesson) (*names, error) {
+	var subjectIDs, groupIDs, teacherIDs []primitive.ObjectID
+	for _, lesson := range lessons {
+		subjectIDs = append(subjectIDs, lesson.SubjectID)
+		groupIDs = append(groupIDs, lesson.GroupID)
+		teacherIDs = append(teacherIDs, lesson.TeacherID)
+	}
+
+	n := &names{
+		subjects: make(map[primitive.ObjectID]string),
+		groups:   make(map[primitive.ObjectID]string),
+		teachers: make(map[primitive.ObjectID]string),
+	}
+
+	subjects, err := store.Subjects.Find(ctx, bson.M{"_id": bson.M{"$in": subjectIDs}})
+	if err != nil {
+		return nil, err
+	}
+	for _, subject := range subjects {
+		n.subjects[subject.ID] = subject.Name
+	}
+
+	groups, err := store.Groups.Find(ctx, bson.M{"_id": bson.M{"$in": groupIDs}})
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		n.groups[group.ID] = group.Name
+	}
+
+	teachers, err := store.Teachers.Find(ctx, bson.M{"_id": bson.M{"$in": teacherIDs}})
+	if err != nil {
+		return nil, err
+	}
+	for _, teacher := range teachers {
+		name := teacher.LastName
+		if initial := []rune(teacher.FirstName); len(initial) > 0 {
+			name += " " + string(initial[0]) + "."
+		}
+		n.teachers[teacher.ID] = name
+	}
+
+	return n, nil
+}
+
+// lessonLine строка урока: студенту - с преподавателем, преподавателю - с группой
+func (n *names) lessonLine(lesson models.Lesson, role string) string {
+	parts := []string{n.subject(lesson)}
+	if role == auth.RoleTeacher {
+		parts = append(parts, n.groups[lesson.GroupID])
+	} else if teacher := n.teachers[lesson.TeacherID]; teacher != "" {
+		if !lesson.OriginalTeacherID.IsZero() {
+			teacher += " (замена)"
+		}
+		parts = append(parts, teacher)
+	}
+	if lesson.Room != "" {
+		parts = append(parts, "ауд. "+lesson.Room)
+	}
+
+	line := lesson.StartTime + "–" + lesson.EndTime + " " + strings.Join(parts, ", ")
+	if lesson.Status == models.LessonCancelled {
+		line += " — отменен"
+	}
+	return line
+}
+
+func (n *names) subject(lesson models.Lesson) string {
+	if name := n.subjects[lesson.SubjectID]; name != "" {
+		return name
+	}
+	return "Урок"
+}
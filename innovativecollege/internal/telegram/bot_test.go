@@ -0,0 +1,365 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"innovativecollege/internal/events"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sentMessage сообщение, отправленное ботом
+type sentMessage struct {
+	chatID int64
+	text   string
+}
+
+// fakeClient локальная замена Bot API: отдает заранее заданные обновления
+// и запоминает отправленные сообщения
+type fakeClient struct {
+	mu      sync.Mutex
+	updates []Update
+	sent    []sentMessage
+	notify  chan struct{}
+}
+
+func newFakeClient(updates ...Update) *fakeClient {
+	return &fakeClient{updates: updates, notify: make(chan struct{}, 16)}
+}
+
+func (f *fakeClient) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	f.mu.Lock()
+	var pending []Update
+	for _, update := range f.updates {
+		if update.UpdateID >= offset {
+			pending = append(pending, update)
+		}
+	}
+	f.mu.Unlock()
+	if len(pending) > 0 {
+		return pending, nil
+	}
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (f *fakeClient) SendMessage(ctx context.Context, chatID int64, text string) error {
+	f.mu.Lock()
+	f.sent = append(f.sent, sentMessage{chatID: chatID, text: text})
+	f.mu.Unlock()
+	select {
+	case f.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// last последнее сообщение в чат
+func (f *fakeClient) last(t *testing.T, chatID int64) string {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.sent) - 1; i >= 0; i-- {
+		if f.sent[i].chatID == chatID {
+			return f.sent[i].text
+		}
+	}
+	t.Fatalf("в чат %d ничего не отправлено", chatID)
+	return ""
+}
+
+func (f *fakeClient) count(chatID int64) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, message := range f.sent {
+		if message.chatID == chatID {
+			n++
+		}
+	}
+	return n
+}
+
+func message(updateID, chatID int64, text string) Update {
+	return Update{UpdateID: updateID, Message: &Message{MessageID: updateID, Chat: Chat{ID: chatID}, Text: text}}
+}
+
+// fixture группа с подгруппой, студенты, преподаватель и уроки на сегодня
+type fixture struct {
+	store    *storage.Store
+	group    models.Group
+	member   models.Student // Входит в подгруппу
+	outsider models.Student // Не входит в подгруппу
+	teacher  models.Teacher
+	lesson   models.Lesson // Урок всей группы
+	subLab   models.Lesson // Урок подгруппы
+	today    time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &fixture{store: storage.NewMemoryStore()}
+	now := time.Now().UTC()
+	f.today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	insert := func(id primitive.ObjectID, err error) primitive.ObjectID {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	f.teacher = models.Teacher{IIN: "800000000001", FirstName: "Анна", LastName: "Иванова"}
+	f.teacher.ID = insert(f.store.Teachers.Insert(ctx, f.teacher))
+	subjectID := insert(f.store.Subjects.Insert(ctx, models.Subject{Name: "Физика", Code: "ФИЗ"}))
+	labID := insert(f.store.Subjects.Insert(ctx, models.Subject{Name: "Лабораторная по физике", Code: "ЛАБ"}))
+
+	f.group = models.Group{Name: "ПО-31", Shift: 1}
+	f.group.ID = insert(f.store.Groups.Insert(ctx, f.group))
+	f.member = models.Student{IIN: "900000000001", FirstName: "Иван", LastName: "Петров", GroupID: f.group.ID}
+	f.member.ID = insert(f.store.Students.Insert(ctx, f.member))
+	f.outsider = models.Student{IIN: "900000000002", FirstName: "Олег", LastName: "Сидоров", GroupID: f.group.ID}
+	f.outsider.ID = insert(f.store.Students.Insert(ctx, f.outsider))
+
+	subgroup := models.Subgroup{ID: primitive.NewObjectID(), Name: "1 подгруппа", StudentIDs: []primitive.ObjectID{f.member.ID}}
+	f.group.Subgroups = []models.Subgroup{subgroup}
+	if _, err := f.store.Groups.Update(ctx, bson.M{"_id": f.group.ID}, bson.M{"$set": bson.M{"subgroups": f.group.Subgroups}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Уроки в конце дня, чтобы они еще не начались
+	f.lesson = models.Lesson{GroupID: f.group.ID, TeacherID: f.teacher.ID, SubjectID: subjectID, Date: &f.today, StartTime: "23:00", EndTime: "23:30", Room: "201"}
+	f.lesson.ID = insert(f.store.Lessons.Insert(ctx, f.lesson))
+	f.subLab = models.Lesson{GroupID: f.group.ID, SubgroupID: subgroup.ID, TeacherID: f.teacher.ID, SubjectID: labID, Date: &f.today, StartTime: "23:30", EndTime: "23:59", Room: "305"}
+	f.subLab.ID = insert(f.store.Lessons.Insert(ctx, f.subLab))
+
+	// Урок завтра: /next находит его, даже если сегодняшние уже начались
+	tomorrow := f.today.AddDate(0, 0, 1)
+	insert(f.store.Lessons.Insert(ctx, models.Lesson{GroupID: f.group.ID, TeacherID: f.teacher.ID, SubjectID: subjectID, Date: &tomorrow, StartTime: "08:00", EndTime: "09:20"}))
+	return f
+}
+
+func TestLinkAndSchedule(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	client := newFakeClient()
+	bot := NewBot(f.store, client, time.UTC, time.Second)
+
+	const memberChat, outsiderChat, teacherChat = 101, 102, 103
+	steps := []struct {
+		chatID int64
+		text   string
+		want   []string
+		absent []string
+	}{
+		{memberChat, "/today", []string{"Сначала привяжите чат"}, nil},
+		{memberChat, "/link 000000000000", []string{"не найден"}, nil},
+		{memberChat, "/link", []string{"Укажите ИИН"}, nil},
+		{memberChat, "/link " + f.member.IIN, []string{"Петров Иван, студент"}, nil},
+		{memberChat, "/today", []string{"23:00–23:30 Физика, Иванова А., ауд. 201", "Лабораторная по физике"}, nil},
+		{memberChat, "/next", []string{"Следующий урок"}, nil},
+		{outsiderChat, "/start " + f.outsider.IIN, []string{"Сидоров Олег, студент"}, nil},
+		{outsiderChat, "/today", []string{"Физика"}, []string{"Лабораторная"}},
+		{teacherChat, "/link@college_bot " + f.teacher.IIN, []string{"Иванова Анна, преподаватель"}, nil},
+		{teacherChat, "/week", []string{"Физика, ПО-31", "Лабораторная по физике, ПО-31"}, nil},
+		{teacherChat, "/unlink", []string{"Чат отвязан"}, nil},
+		{teacherChat, "/today", []string{"Сначала привяжите чат"}, nil},
+		{teacherChat, "/unknown", []string{"Неизвестная команда"}, nil},
+	}
+
+	for i, step := range steps {
+		bot.HandleUpdate(ctx, message(int64(i+1), step.chatID, step.text))
+		reply := client.last(t, step.chatID)
+		for _, want := range step.want {
+			if !strings.Contains(reply, want) {
+				t.Fatalf("%s: ответ %q не содержит %q", step.text, reply, want)
+			}
+		}
+		for _, absent := range step.absent {
+			if strings.Contains(reply, absent) {
+				t.Fatalf("%s: ответ %q не должен содержать %q", step.text, reply, absent)
+			}
+		}
+	}
+
+	// Повторная привязка чата не создает второй записи
+	bot.HandleUpdate(ctx, message(100, memberChat, "/link "+f.member.IIN))
+	if count, _ := f.store.TelegramChats.Count(ctx, bson.M{"chat_id": memberChat}); count != 1 {
+		t.Fatalf("записей чата %d, ожидали одну", count)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	client := newFakeClient()
+	bot := NewBot(f.store, client, time.UTC, time.Second)
+
+	const memberChat, outsiderChat, teacherChat = 201, 202, 203
+	bot.HandleUpdate(ctx, message(1, memberChat, "/link "+f.member.IIN))
+	bot.HandleUpdate(ctx, message(2, outsiderChat, "/link "+f.outsider.IIN))
+	bot.HandleUpdate(ctx, message(3, teacherChat, "/link "+f.teacher.IIN))
+
+	event := func(eventType string, lesson models.Lesson, previous *models.Lesson) events.Event {
+		data := map[string]interface{}{"lesson": lesson}
+		if previous != nil {
+			data["previous"] = previous
+		}
+		return events.Event{Type: eventType, Data: data, GroupIDs: []primitive.ObjectID{lesson.GroupID}, TeacherIDs: []primitive.ObjectID{lesson.TeacherID}}
+	}
+
+	// Отмена урока всей группы приходит всем
+	cancelled := f.lesson
+	cancelled.Status, cancelled.StatusReason = models.LessonCancelled, "болезнь преподавателя"
+	bot.notify(ctx, event(models.EventLessonCancelled, cancelled, nil))
+	for _, chatID := range []int64{memberChat, outsiderChat, teacherChat} {
+		text := client.last(t, chatID)
+		if !strings.HasPrefix(text, "Урок отменен") || !strings.Contains(text, "Причина: болезнь преподавателя") {
+			t.Fatalf("чат %d: %q", chatID, text)
+		}
+	}
+
+	// Перенос урока подгруппы - только её студентам и преподавателю
+	before := client.count(outsiderChat)
+	moved := f.subLab
+	tomorrow := f.today.AddDate(0, 0, 1)
+	moved.Date, moved.StartTime, moved.EndTime = &tomorrow, "08:00", "09:20"
+	bot.notify(ctx, event(models.EventLessonMoved, moved, &f.subLab))
+
+	text := client.last(t, memberChat)
+	if !strings.HasPrefix(text, "Урок перенесен") || !strings.Contains(text, "Было: "+formatDay(f.today)+", 23:30–23:59") || !strings.Contains(text, "Стало: "+formatDay(tomorrow)+", 08:00–09:20") {
+		t.Fatalf("уведомление о переносе: %q", text)
+	}
+	if !strings.Contains(client.last(t, teacherChat), "Урок перенесен") {
+		t.Fatalf("преподаватель не получил уведомление о переносе")
+	}
+	if client.count(outsiderChat) != before {
+		t.Fatalf("студент не из подгруппы получил уведомление: %q", client.last(t, outsiderChat))
+	}
+
+	// Перенос урока подгруппы в другую группу - уведомляются и прежняя подгруппа, и новая группа
+	otherGroupID, err := f.store.Groups.Insert(ctx, models.Group{Name: "ПО-32", Shift: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := models.Student{IIN: "900000000003", FirstName: "Мария", LastName: "Ким", GroupID: otherGroupID}
+	if other.ID, err = f.store.Students.Insert(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	const otherChat = 204
+	bot.HandleUpdate(ctx, message(4, otherChat, "/link "+other.IIN))
+
+	memberBefore, outsiderBefore := client.count(memberChat), client.count(outsiderChat)
+	regrouped := moved
+	regrouped.GroupID, regrouped.SubgroupID = otherGroupID, primitive.NilObjectID
+	movedEvent := event(models.EventLessonMoved, regrouped, &f.subLab)
+	movedEvent.GroupIDs = []primitive.ObjectID{otherGroupID, f.group.ID}
+	bot.notify(ctx, movedEvent)
+
+	if client.count(memberChat) != memberBefore+1 || !strings.HasPrefix(client.last(t, memberChat), "Урок перенесен") {
+		t.Fatalf("прежняя подгруппа не получила уведомление о переносе в другую группу")
+	}
+	if !strings.HasPrefix(client.last(t, otherChat), "Урок перенесен") {
+		t.Fatalf("новая группа не получила уведомление: %q", client.last(t, otherChat))
+	}
+	if client.count(outsiderChat) != outsiderBefore {
+		t.Fatalf("студент не из подгруппы получил уведомление: %q", client.last(t, outsiderChat))
+	}
+}
+
+func TestRunHandlesUpdatesAndEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f := newFixture(t)
+	const chatID = 301
+	client := newFakeClient(message(1, chatID, "/link "+f.member.IIN))
+	bot := NewBot(f.store, client, time.UTC, time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		bot.Run(ctx)
+		close(done)
+	}()
+
+	wait := func(what string) {
+		t.Helper()
+		select {
+		case <-client.notify:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("не дождались: %s", what)
+		}
+	}
+	wait("ответ на /link")
+
+	// События, не касающиеся отмены и переноса, не рассылаются
+	bot.HandleEvent(ctx, events.Event{Type: models.EventLessonCreated, Data: map[string]interface{}{"lesson": f.lesson}, GroupIDs: []primitive.ObjectID{f.group.ID}})
+	cancelled := f.lesson
+	cancelled.Status = models.LessonCancelled
+	bot.HandleEvent(ctx, events.Event{Type: models.EventLessonCancelled, Data: map[string]interface{}{"lesson": cancelled}, GroupIDs: []primitive.ObjectID{f.group.ID}})
+	wait("уведомление об отмене")
+
+	if client.count(chatID) != 2 || !strings.HasPrefix(client.last(t, chatID), "Урок отменен") {
+		t.Fatalf("сообщения: %+v", client.sent)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run не остановился после отмены контекста")
+	}
+}
+
+// Клиент Bot API против локального сервера вместо api.telegram.org
+func TestHTTPClient(t *testing.T) {
+	var sent map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bottest-token/getUpdates":
+			w.Write([]byte(`{"ok": true, "result": [{"update_id": 7, "message": {"message_id": 1, "chat": {"id": 42}, "text": "/today"}}]}`))
+		case "/bottest-token/sendMessage":
+			json.NewDecoder(r.Body).Decode(&sent)
+			w.Write([]byte(`{"ok": true, "result": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ok": false, "description": "Not Found"}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewHTTPClient(server.URL+"/", "test-token", server.Client())
+
+	updates, err := client.GetUpdates(ctx, 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].UpdateID != 7 || updates[0].Message.Chat.ID != 42 || updates[0].Message.Text != "/today" {
+		t.Fatalf("обновления: %+v", updates)
+	}
+
+	if err := client.SendMessage(ctx, 42, "Привет"); err != nil {
+		t.Fatal(err)
+	}
+	if sent["chat_id"] != float64(42) || sent["text"] != "Привет" {
+		t.Fatalf("отправлено: %v", sent)
+	}
+
+	err = NewHTTPClient(server.URL, "wrong-token", server.Client()).SendMessage(ctx, 42, "Привет")
+	if err == nil || !strings.Contains(err.Error(), "Not Found") || strings.Contains(err.Error(), "wrong-token") {
+		t.Fatalf("ошибка Bot API: %v", err)
+	}
+}
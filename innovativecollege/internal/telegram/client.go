@@ -0,0 +1,127 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Update входящее обновление Bot API. Бот обрабатывает только сообщения
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message,omitempty"`
+}
+
+// Message сообщение в чате
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	Chat      Chat   `json:"chat"`
+	From      *User  `json:"from,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+// Chat чат, из которого пришло сообщение
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type,omitempty"`
+}
+
+// User отправитель сообщения
+type User struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+}
+
+// Client методы Bot API, которые нужны боту. В проверках вместо HTTPClient
+// подставляется локальная реализация
+type Client interface {
+	// GetUpdates ждет новые обновления с номером не меньше offset не дольше timeout
+	GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error)
+	// SendMessage отправляет текстовое сообщение в чат
+	SendMessage(ctx context.Context, chatID int64, text string) error
+}
+
+// HTTPClient клиент Bot API по HTTP
+type HTTPClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// NewHTTPClient создает клиент. baseURL - адрес Bot API (https://api.telegram.org
+// или локальный сервер), client без таймаута: long polling ограничивается контекстом
+func NewHTTPClient(baseURL, token string, client *http.Client) *HTTPClient {
+	return &HTTPClient{baseURL: strings.TrimRight(baseURL, "/"), token: token, client: client}
+}
+
+// GetUpdates получает обновления методом getUpdates (long polling)
+func (c *HTTPClient) GetUpdates(ctx context.Context, offset int64, timeout time.Duration) ([]Update, error) {
+	// Запрос ждет ответа до timeout секунд, поэтому контекст запроса чуть длиннее
+	ctx, cancel := context.WithTimeout(ctx, timeout+10*time.Second)
+	defer cancel()
+
+	var updates []Update
+	err := c.call(ctx, "getUpdates", map[string]interface{}{
+		"offset":          offset,
+		"timeout":         int(timeout.Seconds()),
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+// SendMessage отправляет сообщение методом sendMessage
+func (c *HTTPClient) SendMessage(ctx context.Context, chatID int64, text string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return c.call(ctx, "sendMessage", map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}, nil)
+}
+
+// call вызывает метод Bot API и разбирает поле result ответа
+func (c *HTTPClient) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		// В url.Error адрес запроса с токеном бота, его нельзя писать в лог
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer response.Body.Close()
+
+	var reply struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("%s: неверный ответ Bot API (%d)", method, response.StatusCode)
+	}
+	if !reply.OK {
+		return fmt.Errorf("%s: %s", method, reply.Description)
+	}
+	if result != nil {
+		return json.Unmarshal(reply.Result, result)
+	}
+	return nil
+}
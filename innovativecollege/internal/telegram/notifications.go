@@ -0,0 +1,177 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"innovativecollege/internal/auth"
+	"innovativecollege/internal/events"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HandleEvent ставит в очередь уведомление об отмене или переносе урока.
+// Не ждет отправки: шина вызывает обработчики во время запроса к API
+func (b *Bot) HandleEvent(ctx context.Context, event events.Event) {
+	if event.Type != models.EventLessonCancelled && event.Type != models.EventLessonMoved {
+		return
+	}
+
+	select {
+	case b.notifications <- event:
+	default:
+		log.Printf("Очередь уведомлений Telegram переполнена, событие %d пропущено", event.ID)
+	}
+}
+
+// runNotifications отправляет уведомления из очереди по одному, в порядке событий
+func (b *Bot) runNotifications(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-b.notifications:
+			b.notify(ctx, event)
+		}
+	}
+}
+
+// lessonPayload данные событий урока (см. publishLesson и publishLessonMoved)
+type lessonPayload struct {
+	Lesson   models.Lesson  `json:"lesson"`
+	Previous *models.Lesson `json:"previous,omitempty"`
+}
+
+// notify отправляет уведомление студентам групп и преподавателям урока
+func (b *Bot) notify(ctx context.Context, event events.Event) {
+	// Данные события те же, что получают вебхуки: разбираем их из JSON
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return
+	}
+	var payload lessonPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Lesson.Date == nil {
+		return
+	}
+
+	lessons := []models.Lesson{payload.Lesson}
+	if payload.Previous != nil {
+		lessons = append(lessons, *payload.Previous)
+	}
+
+	chats, err := b.recipients(ctx, event, lessons)
+	if err != nil {
+		log.Println("Ошибка поиска чатов для уведомления Telegram:", err)
+		return
+	}
+	if len(chats) == 0 {
+		return
+	}
+
+	names, err := loadNames(ctx, b.store, lessons)
+	if err != nil {
+		log.Println("Ошибка загрузки названий для уведомления Telegram:", err)
+		return
+	}
+
+	for _, chat := range chats {
+		text := notificationText(event.Type, payload, names, chat.Role)
+		if err := b.client.SendMessage(ctx, chat.ChatID, text); err != nil {
+			log.Printf("Ошибка отправки уведомления в чат %d: %v", chat.ChatID, err)
+		}
+	}
+}
+
+// recipients чаты студентов групп события и его преподавателей. Для урока подгруппы
+// уведомляются только её студенты: при переносе - и старой, и новой подгруппы
+func (b *Bot) recipients(ctx context.Context, event events.Event, lessons []models.Lesson) ([]models.TelegramChat, error) {
+	var conditions []bson.M
+
+	if len(event.GroupIDs) > 0 {
+		studentFilters, err := b.studentFilters(ctx, event.GroupIDs, lessons)
+		if err != nil {
+			return nil, err
+		}
+		students, err := b.store.Students.Find(ctx, bson.M{"$or": studentFilters})
+		if err != nil {
+			return nil, err
+		}
+		studentIDs := make([]primitive.ObjectID, 0, len(students))
+		for _, student := range students {
+			studentIDs = append(studentIDs, student.ID)
+		}
+		conditions = append(conditions, bson.M{"role": auth.RoleStudent, "owner_id": bson.M{"$in": studentIDs}})
+	}
+	if len(event.TeacherIDs) > 0 {
+		conditions = append(conditions, bson.M{"role": auth.RoleTeacher, "owner_id": bson.M{"$in": event.TeacherIDs}})
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	return b.store.TelegramChats.Find(ctx, bson.M{"$or": conditions})
+}
+
+// studentFilters фильтры студентов по каждой группе события: вся группа, если в ней
+// есть урок всей группы, иначе студенты подгрупп её уроков
+func (b *Bot) studentFilters(ctx context.Context, groupIDs []primitive.ObjectID, lessons []models.Lesson) ([]bson.M, error) {
+	var filters []bson.M
+	for _, groupID := range groupIDs {
+		var subgroupIDs []primitive.ObjectID
+		wholeGroup := false
+		for _, lesson := range lessons {
+			if lesson.GroupID != groupID {
+				continue
+			}
+			if lesson.SubgroupID.IsZero() {
+				wholeGroup = true
+				break
+			}
+			subgroupIDs = append(subgroupIDs, lesson.SubgroupID)
+		}
+		if wholeGroup || len(subgroupIDs) == 0 {
+			filters = append(filters, bson.M{"group_id": groupID})
+			continue
+		}
+
+		group, err := b.store.Groups.FindByID(ctx, groupID)
+		if err == storage.ErrNotFound {
+			group = &models.Group{ID: groupID}
+		} else if err != nil {
+			return nil, err
+		}
+		for _, subgroupID := range subgroupIDs {
+			filters = append(filters, group.LessonStudentFilter(subgroupID))
+		}
+	}
+	return filters, nil
+}
+
+// notificationText текст уведомления для студента или преподавателя
+func notificationText(eventType string, payload lessonPayload, names *names, role string) string {
+	lesson := payload.Lesson
+	if eventType == models.EventLessonCancelled {
+		text := "Урок отменен: " + formatDay(*lesson.Date) + "\n" + names.lessonLine(lesson, role)
+		if lesson.StatusReason != "" {
+			text += "\nПричина: " + lesson.StatusReason
+		}
+		return text
+	}
+
+	text := "Урок перенесен"
+	if payload.Previous != nil && payload.Previous.Date != nil {
+		previous := *payload.Previous
+		// Старый урок при переносе получает статус rescheduled, показываем его как запланированный
+		previous.Status = ""
+		text += "\nБыло: " + formatDay(*previous.Date) + ", " + names.lessonLine(previous, role)
+	}
+	text += "\nСтало: " + formatDay(*lesson.Date) + ", " + names.lessonLine(lesson, role)
+	if lesson.StatusReason != "" {
+		text += "\nПричина: " + lesson.StatusReason
+	}
+	return text
+}
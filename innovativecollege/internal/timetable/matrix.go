@@ -0,0 +1,215 @@
+package timetable
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Столбцы матрицы: день недели, номер пары, далее группы
+const (
+	dayColumn   = 0
+	pairColumn  = 1
+	firstGroup  = 2
+	cellPattern = "Код / Преподаватель / Аудитория"
+)
+
+// Entry одна пара из матрицы расписания
+type Entry struct {
+	Sheet       string `json:"sheet"`
+	Cell        string `json:"cell"` // Адрес ячейки, например "C5"
+	Group       string `json:"group"`
+	DayOfWeek   int    `json:"day_of_week"`
+	Pair        int    `json:"pair,omitempty"`       // Номер пары
+	StartTime   string `json:"start_time,omitempty"` // Если вместо номера пары указано время
+	EndTime     string `json:"end_time,omitempty"`
+	SubjectCode string `json:"subject_code"`
+	Teacher     string `json:"teacher"`
+	Room        string `json:"room"`
+}
+
+// Issue ячейка, которую не удалось разобрать
+type Issue struct {
+	Sheet string `json:"sheet"`
+	Cell  string `json:"cell"`
+	Value string `json:"value"`
+	Error string `json:"error"`
+}
+
+// dayNames названия дней недели и их сокращения
+var dayNames = map[string]int{
+	"понедельник": 1, "пн": 1,
+	"вторник": 2, "вт": 2,
+	"среда": 3, "ср": 3,
+	"четверг": 4, "чт": 4,
+	"пятница": 5, "пт": 5,
+	"суббота": 6, "сб": 6,
+	"воскресенье": 7, "вс": 7,
+}
+
+// timeRange время пары вида "08:00-09:20" или "8.00 – 9.20"
+var timeRange = regexp.MustCompile(`^(\d{1,2})[:.](\d{2})\s*[-–—]\s*(\d{1,2})[:.](\d{2})$`)
+
+// leadingNumber номер пары вида "1", "1 пара", "2-я пара"
+var leadingNumber = regexp.MustCompile(`^(\d+)`)
+
+// ReadWorkbook читает .xlsx и разбирает матрицу расписания. Если лист не указан,
+// разбираются все листы книги
+func ReadWorkbook(r io.Reader, sheet string) ([]Entry, []Issue, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if sheet != "" {
+		if index, _ := file.GetSheetIndex(sheet); index < 0 {
+			return nil, nil, errors.New("лист " + sheet + " не найден")
+		}
+		sheets = []string{sheet}
+	}
+
+	var entries []Entry
+	var issues []Issue
+	for _, name := range sheets {
+		rows, err := file.GetRows(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		sheetEntries, sheetIssues := ParseMatrix(name, rows)
+		entries = append(entries, sheetEntries...)
+		issues = append(issues, sheetIssues...)
+	}
+
+	return entries, issues, nil
+}
+
+// ParseMatrix разбирает лист: группы по столбцам, дни и пары по строкам, в ячейках
+// "ОН 3.1 / Караев / 201". Название дня пишется в первой строке дня (объединенные ячейки)
+func ParseMatrix(sheet string, rows [][]string) ([]Entry, []Issue) {
+	var entries []Entry
+	var issues []Issue
+
+	// Заголовок с группами - последняя непустая строка перед первым днем недели
+	header := -1
+	for i, row := range rows {
+		if parseDay(cellAt(row, dayColumn)) != 0 {
+			break
+		}
+		if len(row) > firstGroup {
+			header = i
+		}
+	}
+	if header < 0 {
+		return nil, []Issue{{Sheet: sheet, Error: "Не найдена строка с названиями групп"}}
+	}
+	groups := rows[header]
+
+	day := 0
+	for i := header + 1; i < len(rows); i++ {
+		row := rows[i]
+		if value := cellAt(row, dayColumn); value != "" {
+			day = parseDay(value)
+			if day == 0 {
+				issues = append(issues, Issue{Sheet: sheet, Cell: cellName(dayColumn, i), Value: value, Error: "Неизвестный день недели"})
+			}
+		}
+		if day == 0 || len(row) <= firstGroup {
+			continue
+		}
+
+		pairValue := cellAt(row, pairColumn)
+		pair, startTime, endTime, ok := parsePair(pairValue)
+		if !ok {
+			if hasLessons(row) {
+				issues = append(issues, Issue{Sheet: sheet, Cell: cellName(pairColumn, i), Value: pairValue, Error: "Ожидается номер пары или время вида 08:00-09:20"})
+			}
+			continue
+		}
+
+		for col := firstGroup; col < len(row); col++ {
+			value := strings.TrimSpace(row[col])
+			group := cellAt(groups, col)
+			if value == "" || group == "" {
+				continue
+			}
+
+			parts := strings.Split(value, "/")
+			if len(parts) != 3 {
+				issues = append(issues, Issue{Sheet: sheet, Cell: cellName(col, i), Value: value, Error: "Ожидается формат «" + cellPattern + "»"})
+				continue
+			}
+
+			entries = append(entries, Entry{
+				Sheet:       sheet,
+				Cell:        cellName(col, i),
+				Group:       group,
+				DayOfWeek:   day,
+				Pair:        pair,
+				StartTime:   startTime,
+				EndTime:     endTime,
+				SubjectCode: collapseSpaces(parts[0]),
+				Teacher:     collapseSpaces(parts[1]),
+				Room:        collapseSpaces(parts[2]),
+			})
+		}
+	}
+
+	return entries, issues
+}
+
+func parseDay(value string) int {
+	return dayNames[strings.Trim(strings.ToLower(strings.TrimSpace(value)), ".")]
+}
+
+// parsePair разбирает номер пары или ее время
+func parsePair(value string) (int, string, string, bool) {
+	value = strings.TrimSpace(value)
+	if match := timeRange.FindStringSubmatch(value); match != nil {
+		return 0, clock(match[1], match[2]), clock(match[3], match[4]), true
+	}
+	if match := leadingNumber.FindStringSubmatch(value); match != nil {
+		pair, err := strconv.Atoi(match[1])
+		if err == nil && pair > 0 {
+			return pair, "", "", true
+		}
+	}
+	return 0, "", "", false
+}
+
+func clock(hours, minutes string) string {
+	if len(hours) == 1 {
+		hours = "0" + hours
+	}
+	return hours + ":" + minutes
+}
+
+func hasLessons(row []string) bool {
+	for col := firstGroup; col < len(row); col++ {
+		if strings.TrimSpace(row[col]) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func cellAt(row []string, col int) string {
+	if col >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[col])
+}
+
+func cellName(col, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col+1, row+1)
+	return name
+}
+
+func collapseSpaces(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
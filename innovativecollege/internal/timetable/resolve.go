@@ -0,0 +1,255 @@
+package timetable
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"innovativecollege/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Unresolved названия из книги, которых нет в базе
+type Unresolved struct {
+	Groups    []string `json:"groups"`
+	Subjects  []string `json:"subjects"` // Коды предметов
+	Teachers  []string `json:"teachers"`
+	TimeSlots []string `json:"time_slots"`
+	Rooms     []string `json:"rooms"`
+}
+
+// Empty проверяет, что все названия распознаны
+func (u Unresolved) Empty() bool {
+	return len(u.Groups)+len(u.Subjects)+len(u.Teachers)+len(u.TimeSlots)+len(u.Rooms) == 0
+}
+
+// Clash пересечение двух пар внутри импортируемой книги
+type Clash struct {
+	Type  string   `json:"type"`  // teacher, room или group
+	Cells []string `json:"cells"` // Адреса пересекающихся ячеек
+}
+
+// Resolver сопоставляет названия из книги с группами, предметами, преподавателями,
+// временными слотами и аудиториями
+type Resolver struct {
+	groups   map[string]models.Group
+	subjects map[string]models.Subject
+	teachers map[string][]models.Teacher
+	slots    []models.TimeSlot
+	rooms    map[string]models.Room
+}
+
+// NewResolver создает справочник для сопоставления. Учитываются только активные слоты
+func NewResolver(groups []models.Group, subjects []models.Subject, teachers []models.Teacher, slots []models.TimeSlot, rooms []models.Room) *Resolver {
+	r := &Resolver{
+		groups:   make(map[string]models.Group),
+		subjects: make(map[string]models.Subject),
+		teachers: make(map[string][]models.Teacher),
+		rooms:    make(map[string]models.Room),
+	}
+	for _, group := range groups {
+		r.groups[strings.ToLower(collapseSpaces(group.Name))] = group
+	}
+	for _, subject := range subjects {
+		r.subjects[subjectKey(subject.Code)] = subject
+	}
+	for _, teacher := range teachers {
+		key := strings.ToLower(teacher.LastName)
+		r.teachers[key] = append(r.teachers[key], teacher)
+	}
+	for _, slot := range slots {
+		if slot.IsActive {
+			r.slots = append(r.slots, slot)
+		}
+	}
+	sort.SliceStable(r.slots, func(i, j int) bool {
+		a, _ := models.ParseClock(r.slots[i].StartTime)
+		b, _ := models.ParseClock(r.slots[j].StartTime)
+		return a < b
+	})
+	for _, room := range rooms {
+		r.rooms[models.NormalizeRoomNumber(room.Number)] = room
+	}
+	return r
+}
+
+// Resolve превращает пары из книги в записи расписания. Пары с нераспознанными
+// названиями пропускаются и попадают в список Unresolved
+func (r *Resolver) Resolve(entries []Entry) ([]models.Schedule, []Entry, Unresolved) {
+	missing := map[string]map[string]bool{
+		"groups": {}, "subjects": {}, "teachers": {}, "time_slots": {}, "rooms": {},
+	}
+
+	var schedules []models.Schedule
+	var resolved []Entry
+	for _, entry := range entries {
+		group, groupOK := r.groups[strings.ToLower(entry.Group)]
+		if !groupOK {
+			missing["groups"][entry.Group] = true
+		}
+		subject, subjectOK := r.subjects[subjectKey(entry.SubjectCode)]
+		if !subjectOK {
+			missing["subjects"][entry.SubjectCode] = true
+		}
+		teacher, teacherOK := r.teacher(entry.Teacher)
+		if !teacherOK {
+			missing["teachers"][entry.Teacher] = true
+		}
+		room, roomOK := r.rooms[models.NormalizeRoomNumber(entry.Room)]
+		if !roomOK {
+			missing["rooms"][entry.Room] = true
+		}
+
+		var slot models.TimeSlot
+		slotOK := false
+		if groupOK {
+			slot, slotOK = r.slot(entry, group.Shift)
+			if !slotOK {
+				missing["time_slots"][slotName(entry, group.Shift)] = true
+			}
+		}
+
+		if !groupOK || !subjectOK || !teacherOK || !roomOK || !slotOK {
+			continue
+		}
+
+		schedules = append(schedules, models.Schedule{
+			GroupID:   group.ID,
+			TeacherID: teacher.ID,
+			SubjectID: subject.ID,
+			RoomID:    room.ID,
+			Room:      room.Number,
+			DayOfWeek: entry.DayOfWeek,
+			StartTime: slot.StartTime,
+			EndTime:   slot.EndTime,
+			Shift:     slot.Shift,
+		})
+		resolved = append(resolved, entry)
+	}
+
+	return schedules, resolved, Unresolved{
+		Groups:    sortedKeys(missing["groups"]),
+		Subjects:  sortedKeys(missing["subjects"]),
+		Teachers:  sortedKeys(missing["teachers"]),
+		TimeSlots: sortedKeys(missing["time_slots"]),
+		Rooms:     sortedKeys(missing["rooms"]),
+	}
+}
+
+// teacher ищет преподавателя по фамилии ("Караев" или "Караев А.С."). При однофамильцах
+// учитывается первая буква имени; если выбрать однозначно нельзя, преподаватель не распознан
+func (r *Resolver) teacher(value string) (models.Teacher, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return models.Teacher{}, false
+	}
+
+	candidates := r.teachers[strings.ToLower(strings.Trim(fields[0], ".,"))]
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	if len(candidates) == 0 || len(fields) < 2 {
+		return models.Teacher{}, false
+	}
+
+	initial := []rune(strings.ToLower(fields[1]))[0]
+	var match []models.Teacher
+	for _, teacher := range candidates {
+		name := []rune(strings.ToLower(teacher.FirstName))
+		if len(name) > 0 && name[0] == initial {
+			match = append(match, teacher)
+		}
+	}
+	if len(match) != 1 {
+		return models.Teacher{}, false
+	}
+	return match[0], true
+}
+
+// slot ищет временной слот: по времени, если оно указано, иначе N-ю пару смены группы
+func (r *Resolver) slot(entry Entry, shift int) (models.TimeSlot, bool) {
+	if entry.StartTime != "" {
+		for _, slot := range r.slots {
+			if slot.StartTime == entry.StartTime && slot.EndTime == entry.EndTime {
+				return slot, true
+			}
+		}
+		return models.TimeSlot{}, false
+	}
+
+	number := 0
+	for _, slot := range r.slots {
+		if shift != 0 && slot.Shift != shift {
+			continue
+		}
+		number++
+		if number == entry.Pair {
+			return slot, true
+		}
+	}
+	return models.TimeSlot{}, false
+}
+
+// FindClashes ищет пары книги, которые занимают одного преподавателя, аудиторию
+// или группу в одно время. schedules и entries идут в одном порядке
+func FindClashes(schedules []models.Schedule, entries []Entry) []Clash {
+	clashes := []Clash{}
+	for i := range schedules {
+		for j := i + 1; j < len(schedules); j++ {
+			a, b := schedules[i], schedules[j]
+			if a.DayOfWeek != b.DayOfWeek || !models.TimesOverlap(a.StartTime, a.EndTime, b.StartTime, b.EndTime) {
+				continue
+			}
+			cells := []string{entries[i].Sheet + "!" + entries[i].Cell, entries[j].Sheet + "!" + entries[j].Cell}
+			if a.TeacherID == b.TeacherID {
+				clashes = append(clashes, Clash{Type: models.ConflictTeacher, Cells: cells})
+			}
+			if a.RoomID == b.RoomID {
+				clashes = append(clashes, Clash{Type: models.ConflictRoom, Cells: cells})
+			}
+			if a.GroupID == b.GroupID {
+				clashes = append(clashes, Clash{Type: models.ConflictGroup, Cells: cells})
+			}
+		}
+	}
+	return clashes
+}
+
+// GroupIDs группы, расписание которых содержится в импорте
+func GroupIDs(schedules []models.Schedule) []primitive.ObjectID {
+	seen := make(map[primitive.ObjectID]bool)
+	ids := []primitive.ObjectID{}
+	for _, schedule := range schedules {
+		if !seen[schedule.GroupID] {
+			seen[schedule.GroupID] = true
+			ids = append(ids, schedule.GroupID)
+		}
+	}
+	return ids
+}
+
+func slotName(entry Entry, shift int) string {
+	if entry.StartTime != "" {
+		return entry.StartTime + "-" + entry.EndTime
+	}
+	name := strconv.Itoa(entry.Pair) + " пара"
+	if shift != 0 {
+		name += " (" + strconv.Itoa(shift) + " смена)"
+	}
+	return name
+}
+
+// subjectKey код предмета без пробелов и регистра: "ОН 3.1" и "он3.1" совпадают
+func subjectKey(code string) string {
+	return strings.ToLower(strings.Join(strings.Fields(code), ""))
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
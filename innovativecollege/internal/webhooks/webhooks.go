@@ -0,0 +1,249 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"innovativecollege/internal/events"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Заголовки запроса доставки
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// maxErrorBody сколько байт ответа получателя сохраняется в попытке при ошибке
+const maxErrorBody = 512
+
+// Payload тело запроса доставки
+type Payload struct {
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Dispatcher ставит события шины в очередь доставок и отправляет их подписчикам.
+// Очередь хранится в коллекции webhook_deliveries, поэтому повторные попытки
+// переживают перезапуск сервера
+type Dispatcher struct {
+	store       *storage.Store
+	client      *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+	wake        chan struct{}
+}
+
+// NewDispatcher создает диспетчер. client позволяет подменить транспорт, например
+// на локальный HTTP-сервер в тестах
+func NewDispatcher(store *storage.Store, client *http.Client, maxAttempts int, retryDelay time.Duration) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Dispatcher{
+		store:       store,
+		client:      client,
+		maxAttempts: maxAttempts,
+		retryDelay:  retryDelay,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// HandleEvent ставит событие шины в очередь для всех активных вебхуков, подписанных
+// на него. Ошибки только пишутся в лог: доставка не должна ломать изменение расписания
+func (d *Dispatcher) HandleEvent(ctx context.Context, event events.Event) {
+	hooks, err := d.store.Webhooks.Find(ctx, bson.M{"is_active": true, "events": event.Type})
+	if err != nil {
+		log.Println("Ошибка получения вебхуков:", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if _, err := d.Enqueue(ctx, hook, event); err != nil {
+			log.Printf("Ошибка постановки события %s в очередь вебхука %s: %v", event.Type, hook.ID.Hex(), err)
+		}
+	}
+}
+
+// Enqueue ставит событие в очередь доставок одного вебхука
+func (d *Dispatcher) Enqueue(ctx context.Context, hook models.Webhook, event events.Event) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = now
+	}
+	body, err := json.Marshal(Payload{Event: event.Type, OccurredAt: event.OccurredAt, Data: event.Data})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID:     hook.ID,
+		Event:         event.Type,
+		Payload:       string(body),
+		Status:        models.DeliveryPending,
+		Attempts:      []models.DeliveryAttempt{},
+		NextAttemptAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	id, err := d.store.WebhookDeliveries.Insert(ctx, delivery)
+	if err != nil {
+		return nil, err
+	}
+	delivery.ID = id
+
+	d.Wake()
+	return &delivery, nil
+}
+
+// Wake будит Run, не дожидаясь следующей проверки очереди
+func (d *Dispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run отправляет доставки, которым пришло время, раз в interval и сразу после
+// постановки нового события. Работает до отмены ctx
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		d.DeliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue один проход очереди: отправляет все доставки, которым пришло время
+func (d *Dispatcher) DeliverDue(ctx context.Context) {
+	due, err := d.store.WebhookDeliveries.Find(ctx, bson.M{
+		"status":          models.DeliveryPending,
+		"next_attempt_at": bson.M{"$lte": time.Now()},
+	}, storage.FindOptions{Sort: bson.D{{Key: "next_attempt_at", Value: 1}}})
+	if err != nil {
+		log.Println("Ошибка получения очереди вебхуков:", err)
+		return
+	}
+
+	for _, delivery := range due {
+		if err := d.deliver(ctx, delivery); err != nil {
+			log.Printf("Ошибка сохранения доставки %s: %v", delivery.ID.Hex(), err)
+		}
+	}
+}
+
+// deliver выполняет одну попытку и сохраняет ее результат. После неудачи следующая
+// попытка назначается через retryDelay, 2*retryDelay, 4*retryDelay и так далее
+func (d *Dispatcher) deliver(ctx context.Context, delivery models.WebhookDelivery) error {
+	update := bson.M{"updated_at": time.Now()}
+
+	hook, err := d.store.Webhooks.FindByID(ctx, delivery.WebhookID)
+	if err != nil {
+		if err != storage.ErrNotFound {
+			return err
+		}
+		// Вебхук удален: доставлять некуда
+		update["status"] = models.DeliveryFailed
+		_, err = d.store.WebhookDeliveries.Update(ctx, bson.M{"_id": delivery.ID}, bson.M{
+			"$set":   update,
+			"$unset": bson.M{"next_attempt_at": ""},
+		})
+		return err
+	}
+
+	attempt := d.send(ctx, hook, delivery)
+	attempts := append(delivery.Attempts, attempt)
+	update["attempts"] = attempts
+
+	unset := bson.M{}
+	switch {
+	case attempt.Error == "":
+		update["status"] = models.DeliveryDelivered
+		unset["next_attempt_at"] = ""
+	case len(attempts) >= d.maxAttempts:
+		update["status"] = models.DeliveryFailed
+		unset["next_attempt_at"] = ""
+	default:
+		update["next_attempt_at"] = attempt.At.Add(d.retryDelay << (len(attempts) - 1))
+	}
+
+	changes := bson.M{"$set": update}
+	if len(unset) > 0 {
+		changes["$unset"] = unset
+	}
+	_, err = d.store.WebhookDeliveries.Update(ctx, bson.M{"_id": delivery.ID}, changes)
+	return err
+}
+
+// send отправляет тело доставки. Успехом считается любой ответ 2xx
+func (d *Dispatcher) send(ctx context.Context, hook *models.Webhook, delivery models.WebhookDelivery) models.DeliveryAttempt {
+	started := time.Now()
+	attempt := models.DeliveryAttempt{At: started}
+
+	body := []byte(delivery.Payload)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		attempt.DurationMs = time.Since(started).Milliseconds()
+		return attempt
+	}
+
+	timestamp := strconv.FormatInt(started.Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "InnovativeCollege-Webhooks/1.0")
+	request.Header.Set(HeaderEvent, delivery.Event)
+	request.Header.Set(HeaderDelivery, delivery.ID.Hex())
+	request.Header.Set(HeaderTimestamp, timestamp)
+	request.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, body))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		attempt.Error = err.Error()
+		attempt.DurationMs = time.Since(started).Milliseconds()
+		return attempt
+	}
+	defer response.Body.Close()
+
+	attempt.StatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		text, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+		attempt.Error = fmt.Sprintf("получатель ответил %d: %s", response.StatusCode, bytes.TrimSpace(text))
+	}
+	attempt.DurationMs = time.Since(started).Milliseconds()
+	return attempt
+}
+
+// Sign подпись доставки: "sha256=" и HMAC-SHA256 ключом вебхука от строки
+// "<X-Webhook-Timestamp>.<тело запроса>" в hex. Получатель вычисляет подпись так же
+// и сравнивает с заголовком X-Webhook-Signature
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
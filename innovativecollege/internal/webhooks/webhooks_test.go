@@ -0,0 +1,201 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"innovativecollege/internal/events"
+	"innovativecollege/internal/models"
+	"innovativecollege/internal/storage"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// receiver локальный получатель вебхуков, отвечающий заданным статусом
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, status int) (*receiver, *httptest.Server) {
+	t.Helper()
+	r := &receiver{status: status}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		r.mu.Unlock()
+		w.WriteHeader(r.status)
+		w.Write([]byte("временно недоступен"))
+	}))
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *receiver) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+func newHook(t *testing.T, store *storage.Store, url string, active bool, eventTypes ...string) models.Webhook {
+	t.Helper()
+	hook := models.Webhook{URL: url, Events: eventTypes, Secret: "top-secret", IsActive: active}
+	id, err := store.Webhooks.Insert(context.Background(), hook)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook.ID = id
+	return hook
+}
+
+func onlyDelivery(t *testing.T, store *storage.Store) models.WebhookDelivery {
+	t.Helper()
+	deliveries, err := store.WebhookDeliveries.Find(context.Background(), bson.M{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("доставок %d, ожидали одну", len(deliveries))
+	}
+	return deliveries[0]
+}
+
+// makeDue переносит следующую попытку в прошлое, чтобы не ждать паузу
+func makeDue(t *testing.T, store *storage.Store, delivery models.WebhookDelivery) {
+	t.Helper()
+	past := time.Now().Add(-time.Second)
+	if _, err := store.WebhookDeliveries.Update(context.Background(), bson.M{"_id": delivery.ID}, bson.M{"$set": bson.M{"next_attempt_at": past}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeliverySignature(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	received, server := newReceiver(t, http.StatusNoContent)
+	hook := newHook(t, store, server.URL, true, "lesson.moved")
+	newHook(t, store, server.URL, false, "lesson.moved")  // Отключен
+	newHook(t, store, server.URL, true, "lesson.created") // Не подписан на событие
+
+	dispatcher := NewDispatcher(store, server.Client(), 3, time.Minute)
+	dispatcher.HandleEvent(ctx, events.Event{Type: "lesson.moved", Data: map[string]string{"room": "201"}})
+	dispatcher.DeliverDue(ctx)
+
+	if received.count() != 1 {
+		t.Fatalf("запросов %d, ожидали один", received.count())
+	}
+	request, body := received.requests[0], received.bodies[0]
+
+	var payload Payload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != "lesson.moved" || request.Header.Get(HeaderEvent) != "lesson.moved" {
+		t.Fatalf("событие %q, заголовок %q", payload.Event, request.Header.Get(HeaderEvent))
+	}
+
+	// Подпись считается независимо от Sign: HMAC-SHA256 от "<timestamp>.<тело>"
+	mac := hmac.New(sha256.New, []byte(hook.Secret))
+	mac.Write([]byte(request.Header.Get(HeaderTimestamp) + "." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := request.Header.Get(HeaderSignature); got != want {
+		t.Fatalf("подпись %q, ожидали %q", got, want)
+	}
+
+	delivery := onlyDelivery(t, store)
+	if request.Header.Get(HeaderDelivery) != delivery.ID.Hex() {
+		t.Fatalf("заголовок доставки %q, ожидали %s", request.Header.Get(HeaderDelivery), delivery.ID.Hex())
+	}
+	if delivery.Status != models.DeliveryDelivered || delivery.NextAttemptAt != nil {
+		t.Fatalf("доставка: %+v", delivery)
+	}
+	if len(delivery.Attempts) != 1 || delivery.Attempts[0].StatusCode != http.StatusNoContent || delivery.Attempts[0].Error != "" {
+		t.Fatalf("попытки: %+v", delivery.Attempts)
+	}
+}
+
+func TestDeliveryRetryBackoff(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	received, server := newReceiver(t, http.StatusServiceUnavailable)
+	hook := newHook(t, store, server.URL, true, "lesson.cancelled")
+
+	const retryDelay = time.Minute
+	dispatcher := NewDispatcher(store, server.Client(), 3, retryDelay)
+	if _, err := dispatcher.Enqueue(ctx, hook, events.Event{Type: "lesson.cancelled"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt < 3; attempt++ {
+		dispatcher.DeliverDue(ctx)
+
+		delivery := onlyDelivery(t, store)
+		if delivery.Status != models.DeliveryPending || len(delivery.Attempts) != attempt {
+			t.Fatalf("после попытки %d: статус %s, попыток %d", attempt, delivery.Status, len(delivery.Attempts))
+		}
+		last := delivery.Attempts[attempt-1]
+		if last.StatusCode != http.StatusServiceUnavailable || !strings.Contains(last.Error, "503") {
+			t.Fatalf("попытка %d: %+v", attempt, last)
+		}
+
+		// Пауза удваивается: 1, 2, 4... retryDelay
+		wantNext := last.At.Add(retryDelay << (attempt - 1))
+		if delivery.NextAttemptAt == nil || !delivery.NextAttemptAt.Equal(wantNext) {
+			t.Fatalf("попытка %d: следующая в %v, ожидали %v", attempt, delivery.NextAttemptAt, wantNext)
+		}
+
+		// Пока пауза не прошла, доставка не повторяется
+		dispatcher.DeliverDue(ctx)
+		if received.count() != attempt {
+			t.Fatalf("запросов %d до окончания паузы, ожидали %d", received.count(), attempt)
+		}
+		makeDue(t, store, delivery)
+	}
+
+	// Последняя попытка исчерпывает maxAttempts
+	dispatcher.DeliverDue(ctx)
+	delivery := onlyDelivery(t, store)
+	if delivery.Status != models.DeliveryFailed || len(delivery.Attempts) != 3 || delivery.NextAttemptAt != nil {
+		t.Fatalf("после всех попыток: статус %s, попыток %d, следующая %v", delivery.Status, len(delivery.Attempts), delivery.NextAttemptAt)
+	}
+
+	dispatcher.DeliverDue(ctx)
+	if received.count() != 3 {
+		t.Fatalf("запросов %d, ожидали 3", received.count())
+	}
+}
+
+func TestDeliveryToDeletedWebhookFails(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	received, server := newReceiver(t, http.StatusOK)
+	hook := newHook(t, store, server.URL, true, "lesson.deleted")
+
+	dispatcher := NewDispatcher(store, server.Client(), 3, time.Minute)
+	if _, err := dispatcher.Enqueue(ctx, hook, events.Event{Type: "lesson.deleted"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Webhooks.Delete(ctx, bson.M{"_id": hook.ID}); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.DeliverDue(ctx)
+
+	delivery := onlyDelivery(t, store)
+	if delivery.Status != models.DeliveryFailed || received.count() != 0 {
+		t.Fatalf("статус %s, запросов %d", delivery.Status, received.count())
+	}
+}
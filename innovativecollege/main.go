@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
 	"os"
+	"time"
 
+	"innovativecollege/internal/audit"
+	"innovativecollege/internal/auth"
 	"innovativecollege/internal/config"
 	"innovativecollege/internal/database"
+	"innovativecollege/internal/events"
 	"innovativecollege/internal/handlers"
+	"innovativecollege/internal/jobs"
 	"innovativecollege/internal/routes"
+	"innovativecollege/internal/storage"
+	"innovativecollege/internal/telegram"
+	"innovativecollege/internal/webhooks"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -22,6 +32,15 @@ func main() {
 	// Инициализируем конфигурацию
 	cfg := config.Load()
 
+	// Проверяем секрет токенов до подключения к базе: с небезопасной
+	// конфигурацией сервер не должен ничего менять в данных
+	if cfg.JWTSecret == "" || cfg.JWTSecret == config.DefaultJWTSecret {
+		log.Fatal("JWT_SECRET не задан или равен значению по умолчанию, задайте случайную строку")
+	}
+	if cfg.AdminPassword == "" {
+		log.Println("ADMIN_PASSWORD не задан, вход администратора отключен")
+	}
+
 	// Подключаемся к MongoDB
 	db, err := database.Connect(cfg.MongoURI, cfg.DatabaseName)
 	if err != nil {
@@ -31,13 +50,53 @@ func main() {
 
 	// Создаем коллекции
 	database.CreateCollections(db)
+	// Все изменения документов записываются в журнал audit_log,
+	// удаленные документы попадают в корзину
+	store := storage.WithSoftDelete(audit.Wrap(storage.NewMongoStore(db)), func(ctx context.Context) string {
+		return audit.ActorFrom(ctx).Login
+	})
+
+	// Переносим текстовые номера аудиторий в коллекцию rooms
+	if _, err := database.MigrateRooms(context.Background(), store); err != nil {
+		log.Println("Ошибка миграции аудиторий:", err)
+	}
+
+	// Окончательно удаляем документы, которые пролежали в корзине дольше TRASH_RETENTION_DAYS
+	go jobs.RunTrashPurge(context.Background(), store, cfg.TrashRetentionDays, cfg.TrashPurgeInterval)
+
+	// Инициализируем выпуск токенов
+	authManager := auth.NewManager(cfg.JWTSecret, time.Duration(cfg.JWTTTLHours)*time.Hour)
+
+	// Шина событий: изменения расписания получают поток событий и вебхуки
+	bus := events.NewBus()
+
+	// Доставляем события расписания внешним системам по подпискам
+	dispatcher := webhooks.NewDispatcher(store, &http.Client{Timeout: cfg.WebhookTimeout}, cfg.WebhookMaxAttempts, cfg.WebhookRetryDelay)
+	bus.Handle(dispatcher.HandleEvent)
+	go dispatcher.Run(context.Background(), cfg.WebhookPollInterval)
+
+	// Telegram-бот: расписание по командам и уведомления об отмене и переносе уроков
+	if cfg.TelegramBotToken != "" {
+		location, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			log.Println("Неизвестный часовой пояс TIMEZONE, бот использует UTC:", err)
+			location = time.UTC
+		}
+		client := telegram.NewHTTPClient(cfg.TelegramAPIURL, cfg.TelegramBotToken, &http.Client{})
+		bot := telegram.NewBot(store, client, location, cfg.TelegramPollTimeout)
+		bus.Handle(bot.HandleEvent)
+		go bot.Run(context.Background())
+	} else {
+		log.Println("TELEGRAM_BOT_TOKEN не задан, Telegram-бот отключен")
+	}
 
 	// Инициализируем обработчики
-	h := handlers.New(db)
+	h := handlers.New(store, cfg, authManager, bus, dispatcher)
 
 	// Настраиваем роуты
-	r := gin.Default()
-	routes.SetupRoutes(r, h)
+	r := gin.New()
+	r.Use(routes.Logger(), gin.Recovery())
+	routes.SetupRoutes(r, h, authManager)
 
 	// Запускаем сервер
 	port := os.Getenv("PORT")
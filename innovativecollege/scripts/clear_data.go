@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -46,6 +47,10 @@ func makeRequest(method, url string, data interface{}) ([]byte, error) {
 	if data != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	// Токен администратора (POST /api/v1/auth/login)
+	if token := os.Getenv("API_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
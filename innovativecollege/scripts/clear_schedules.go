@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -64,7 +65,13 @@ func main() {
 
 func getAllSchedules() ([]map[string]interface{}, error) {
 	url := baseURL + "/schedules"
-	resp, err := http.Get(url)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	setAuthHeader(req)
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +102,7 @@ func deleteSchedule(id string) error {
 	if err != nil {
 		return err
 	}
+	setAuthHeader(req)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -110,3 +118,10 @@ func deleteSchedule(id string) error {
 
 	return nil
 }
+
+// setAuthHeader добавляет токен администратора (POST /api/v1/auth/login)
+func setAuthHeader(req *http.Request) {
+	if token := os.Getenv("API_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+const baseURL = "http://localhost:8080/api/v1"
+
+// Импорт расписания из Excel-матрицы:
+//
+//	go run scripts/import_timetable.go -file raspisanie.xlsx            # только отчет
+//	go run scripts/import_timetable.go -file raspisanie.xlsx -apply     # запись в базу
+func main() {
+	path := flag.String("file", "", "Путь к файлу .xlsx")
+	sheet := flag.String("sheet", "", "Лист книги (по умолчанию все листы)")
+	apply := flag.Bool("apply", false, "Записать расписание (без флага - только проверка)")
+	replace := flag.Bool("replace", false, "Заменить существующее расписание импортируемых групп")
+	force := flag.Bool("force", false, "Сохранить несмотря на пересечения с существующим расписанием")
+	flag.Parse()
+
+	if *path == "" {
+		fmt.Println("Укажите файл: -file raspisanie.xlsx")
+		os.Exit(1)
+	}
+
+	query := url.Values{}
+	query.Set("dry_run", fmt.Sprint(!*apply))
+	query.Set("replace", fmt.Sprint(*replace))
+	query.Set("force", fmt.Sprint(*force))
+	if *sheet != "" {
+		query.Set("sheet", *sheet)
+	}
+
+	status, report, err := uploadWorkbook(*path, query)
+	if err != nil {
+		fmt.Printf("❌ Ошибка импорта: %v\n", err)
+		os.Exit(1)
+	}
+
+	printReport(report)
+
+	switch {
+	case status == http.StatusCreated:
+		fmt.Printf("🎉 Создано записей расписания: %v (заменено: %v)\n", report["created"], report["replaced"])
+	case status == http.StatusOK:
+		fmt.Println("ℹ️  Проверка завершена, ничего не записано. Для записи запустите с флагом -apply")
+	default:
+		fmt.Printf("❌ HTTP %d: %v\n", status, report["error"])
+		os.Exit(1)
+	}
+}
+
+func uploadWorkbook(path string, query url.Values) (int, map[string]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, err = io.Copy(part, file); err != nil {
+		return 0, nil, err
+	}
+	writer.Close()
+
+	req, err := http.NewRequest("POST", baseURL+"/schedules/import?"+query.Encode(), &body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	setAuthHeader(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	var report map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		return resp.StatusCode, nil, err
+	}
+	return resp.StatusCode, report, nil
+}
+
+func printReport(report map[string]interface{}) {
+	fmt.Printf("📋 Найдено пар: %v, распознано: %v\n", report["entries"], report["resolved"])
+
+	if issues, ok := report["issues"].([]interface{}); ok && len(issues) > 0 {
+		fmt.Println("\n⚠️  Ячейки, которые не удалось разобрать:")
+		for _, item := range issues {
+			issue := item.(map[string]interface{})
+			fmt.Printf("  %v!%v «%v»: %v\n", issue["sheet"], issue["cell"], issue["value"], issue["error"])
+		}
+	}
+
+	if unresolved, ok := report["unresolved"].(map[string]interface{}); ok {
+		titles := []struct{ key, title string }{
+			{"groups", "Группы"},
+			{"subjects", "Предметы (коды)"},
+			{"teachers", "Преподаватели"},
+			{"time_slots", "Пары"},
+			{"rooms", "Аудитории"},
+		}
+		for _, t := range titles {
+			if names, ok := unresolved[t.key].([]interface{}); ok && len(names) > 0 {
+				fmt.Printf("\n❓ %s не найдены в базе:\n", t.title)
+				for _, name := range names {
+					fmt.Printf("  - %v\n", name)
+				}
+			}
+		}
+	}
+
+	if clashes, ok := report["clashes"].([]interface{}); ok && len(clashes) > 0 {
+		fmt.Println("\n⛔ Пересечения внутри книги:")
+		for _, item := range clashes {
+			clash := item.(map[string]interface{})
+			fmt.Printf("  %v: %v\n", clash["type"], clash["cells"])
+		}
+	}
+
+	if conflicts, ok := report["conflicts"].([]interface{}); ok && len(conflicts) > 0 {
+		fmt.Printf("\n⛔ Пересечений с существующим расписанием: %d\n", len(conflicts))
+	}
+
+	if unavailable, ok := report["unavailable"].([]interface{}); ok && len(unavailable) > 0 {
+		fmt.Println("\n⛔ Преподаватель недоступен:")
+		for _, item := range unavailable {
+			entry := item.(map[string]interface{})
+			fmt.Printf("  день %v, %v-%v (запись %v)\n", entry["day_of_week"], entry["start_time"], entry["end_time"], entry["index"])
+		}
+	}
+	fmt.Println()
+}
+
+// setAuthHeader добавляет токен администратора (POST /api/v1/auth/login)
+func setAuthHeader(req *http.Request) {
+	if token := os.Getenv("API_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
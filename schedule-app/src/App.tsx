@@ -1,7 +1,8 @@
 import React, { useState, useEffect } from 'react';
-import { groupsApi, teachersApi, studentsApi, schedulesApi, lessonsApi, subjectsApi } from './services/api';
+import { groupsApi, teachersApi, studentsApi, schedulesApi, lessonsApi, subjectsApi, eventsApi } from './services/api';
 import { Group, Teacher, Schedule, Lesson, FilterType, StudentScheduleResponse, TeacherScheduleResponse, Subject } from './types';
 import FilterSelector from './components/FilterSelector';
+import LoginForm from './components/LoginForm';
 import ScheduleView from './components/ScheduleView';
 import './App.css';
 
@@ -16,10 +17,30 @@ const App: React.FC = () => {
   const [filterType, setFilterType] = useState<FilterType>('group');
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
+  const [authenticated, setAuthenticated] = useState(() => Boolean(localStorage.getItem('token')));
 
   useEffect(() => {
-    fetchData();
-  }, []);
+    if (authenticated) {
+      fetchData();
+    }
+  }, [authenticated]);
+
+  // Токен истек или отозван - возвращаемся к форме входа
+  const handleRequestError = (prefix: string, err: any) => {
+    if (err.response?.status === 401) {
+      setAuthenticated(false);
+    }
+    setError(prefix + (err.response?.data?.error || err.message));
+  };
+
+  const handleLogout = () => {
+    localStorage.removeItem('token');
+    localStorage.removeItem('role');
+    setAuthenticated(false);
+    setSelectedGroup(null);
+    setSelectedTeacher(null);
+    setLessons([]);
+  };
 
   useEffect(() => {
     if (selectedGroup || selectedTeacher) {
@@ -27,6 +48,23 @@ const App: React.FC = () => {
     }
   }, [selectedGroup, selectedTeacher, filterType]);
 
+  // Живые обновления: перезагружаем уроки выбранной группы или преподавателя,
+  // когда их меняют в админке
+  useEffect(() => {
+    const params = filterType === 'group' && selectedGroup
+      ? { group_id: selectedGroup.id }
+      : filterType === 'teacher' && selectedTeacher
+        ? { teacher_id: selectedTeacher.id }
+        : null;
+    if (!authenticated || !params) return;
+
+    const reload = () => fetchScheduleData();
+    const listeners: Record<string, () => void> = {};
+    ['lesson.created', 'lesson.updated', 'lesson.moved', 'lesson.cancelled', 'lesson.deleted',
+      'lessons.generated'].forEach((type) => { listeners[type] = reload; });
+    return eventsApi.subscribe(params, listeners);
+  }, [authenticated, selectedGroup, selectedTeacher, filterType]);
+
   const fetchData = async () => {
     try {
       setLoading(true);
@@ -51,7 +89,7 @@ const App: React.FC = () => {
       // Логируем детали групп
       console.log('Groups details:', groupsResponse.data);
     } catch (err: any) {
-      setError('Ошибка загрузки данных: ' + (err.response?.data?.error || err.message));
+      handleRequestError('Ошибка загрузки данных: ', err);
     } finally {
       setLoading(false);
     }
@@ -81,7 +119,7 @@ const App: React.FC = () => {
         setLessons(teacherLessons);
       }
     } catch (err: any) {
-      setError('Ошибка загрузки расписания: ' + (err.response?.data?.error || err.message));
+      handleRequestError('Ошибка загрузки расписания: ', err);
     }
   };
 
@@ -120,6 +158,11 @@ const App: React.FC = () => {
               <h1>Расписание колледжа</h1>
               <p>Выберите группу или преподавателя для просмотра расписания</p>
             </div>
+            {authenticated && (
+              <button className="filter-type-btn logout-btn" onClick={handleLogout}>
+                Выйти
+              </button>
+            )}
           </div>
         </div>
       </header>
@@ -132,19 +175,23 @@ const App: React.FC = () => {
             </div>
           )}
 
-          <FilterSelector
-            groups={groups}
-            teachers={teachers}
-            selectedGroup={selectedGroup}
-            selectedTeacher={selectedTeacher}
-            onGroupSelect={handleGroupSelect}
-            onTeacherSelect={handleTeacherSelect}
-            filterType={filterType}
-            onFilterTypeChange={handleFilterTypeChange}
-            loading={loading}
-          />
-
-          {(selectedGroup || selectedTeacher) && (
+          {!authenticated ? (
+            <LoginForm onLogin={() => { setError(null); setAuthenticated(true); }} />
+          ) : (
+            <FilterSelector
+              groups={groups}
+              teachers={teachers}
+              selectedGroup={selectedGroup}
+              selectedTeacher={selectedTeacher}
+              onGroupSelect={handleGroupSelect}
+              onTeacherSelect={handleTeacherSelect}
+              filterType={filterType}
+              onFilterTypeChange={handleFilterTypeChange}
+              loading={loading}
+            />
+          )}
+
+          {authenticated && (selectedGroup || selectedTeacher) && (
             <ScheduleView
               schedules={schedules}
               lessons={lessons}
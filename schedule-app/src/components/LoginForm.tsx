@@ -0,0 +1,70 @@
+import React, { useState } from 'react';
+import { authApi } from '../services/api';
+
+interface LoginFormProps {
+  onLogin: () => void;
+}
+
+const LoginForm: React.FC<LoginFormProps> = ({ onLogin }) => {
+  const [login, setLogin] = useState('');
+  const [password, setPassword] = useState('');
+  const [error, setError] = useState<string | null>(null);
+  const [loading, setLoading] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    setLoading(true);
+    setError(null);
+
+    try {
+      const response = await authApi.login(login.trim(), password || undefined);
+      localStorage.setItem('token', response.data.token);
+      localStorage.setItem('role', response.data.role);
+      onLogin();
+    } catch (err: any) {
+      setError(err.response?.data?.error || 'Неверный логин или пароль');
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="filter-selector login-form">
+      <div className="filter-header">
+        <h2>Вход</h2>
+      </div>
+
+      {error && (
+        <div className="error-message">
+          {error}
+        </div>
+      )}
+
+      <form onSubmit={handleSubmit}>
+        <label htmlFor="login">ИИН или логин</label>
+        <input
+          id="login"
+          type="text"
+          value={login}
+          onChange={(e) => setLogin(e.target.value)}
+          placeholder="Введите ИИН"
+          required
+        />
+
+        <label htmlFor="password">Пароль (только для администратора)</label>
+        <input
+          id="password"
+          type="password"
+          value={password}
+          onChange={(e) => setPassword(e.target.value)}
+        />
+
+        <button type="submit" className="filter-type-btn active" disabled={loading || !login.trim()}>
+          {loading ? 'Вход...' : 'Войти'}
+        </button>
+      </form>
+    </div>
+  );
+};
+
+export default LoginForm;
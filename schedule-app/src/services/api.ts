@@ -12,6 +12,15 @@ const api = axios.create({
   timeout: 10000, // 10 секунд таймаут
 });
 
+// Добавляем JWT токен к каждому запросу
+api.interceptors.request.use((config) => {
+  const token = localStorage.getItem('token');
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`;
+  }
+  return config;
+});
+
 // Интерцептор для обработки ошибок
 api.interceptors.response.use(
   (response) => response,
@@ -22,6 +31,12 @@ api.interceptors.response.use(
       error.message = 'Ошибка сети. Проверьте подключение к серверу';
     } else if (error.response?.status === 500) {
       error.message = 'Внутренняя ошибка сервера';
+    } else if (error.response?.status === 401) {
+      error.message = 'Требуется авторизация';
+      localStorage.removeItem('token');
+      localStorage.removeItem('role');
+    } else if (error.response?.status === 403) {
+      error.message = 'Недостаточно прав';
     } else if (error.response?.status === 404) {
       error.message = 'Ресурс не найден';
     }
@@ -29,6 +44,12 @@ api.interceptors.response.use(
   }
 );
 
+// Авторизация: студенты и преподаватели входят по ИИН, администратор - по логину и паролю
+export const authApi = {
+  login: (login: string, password?: string) => api.post('/auth/login', { login, password }),
+  me: () => api.get('/auth/me'),
+};
+
 // Группы
 export const groupsApi = {
   getAll: () => api.get('/groups'),
@@ -66,4 +87,49 @@ export const lessonsApi = {
   getByDateAndShift: (date: string, shift: number) => api.get('/lessons', { params: { date, shift } }),
 };
 
+// Поток событий: EventSource не умеет отправлять заголовки, поэтому подключаемся
+// по билету из POST /events/ticket. Билет действует минуту, поэтому после обрыва
+// берем новый и продолжаем с последнего полученного события.
+// listeners - обработчики по типам событий, возвращает функцию отписки
+export const eventsApi = {
+  subscribe: (params: Record<string, string>, listeners: Record<string, (event: MessageEvent) => void>) => {
+    let source: EventSource | null = null;
+    let retry: ReturnType<typeof setTimeout> | undefined;
+    let closed = false;
+    let lastEventId = '';
+
+    const reconnect = () => {
+      if (!closed) retry = setTimeout(connect, 3000);
+    };
+    const connect = async () => {
+      try {
+        const response = await api.post('/events/ticket');
+        if (closed) return;
+        const query = new URLSearchParams({ ...params, ticket: response.data.ticket });
+        if (lastEventId) query.set('last_event_id', lastEventId);
+        const current = new EventSource(`${API_BASE_URL}/events/stream?${query}`);
+        Object.entries(listeners).forEach(([type, listener]) => current.addEventListener(type, (event) => {
+          const message = event as MessageEvent;
+          lastEventId = message.lastEventId || lastEventId;
+          listener(message);
+        }));
+        current.onerror = () => {
+          current.close();
+          reconnect();
+        };
+        source = current;
+      } catch (err: any) {
+        if (err.response?.status !== 401) reconnect();
+      }
+    };
+
+    connect();
+    return () => {
+      closed = true;
+      clearTimeout(retry);
+      if (source) source.close();
+    };
+  },
+};
+
 export default api;